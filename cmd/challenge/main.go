@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/config"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/taskpayload"
+	"go.uber.org/zap"
+)
+
+func main() {
+	var (
+		taskPath   = flag.String("task-file", "", "Task payload to check: a task file or a payload of any version the performer accepts")
+		configPath = flag.String("config", "", "Performer YAML configuration; PERFORMER_CONFIG and the PERFORMER_ variables also apply")
+		output     = flag.String("output", "", "Output file for the fraud proofs; stdout if empty")
+		format     = flag.String("format", "json", "Output format: json, or abi for hex calldata to pass to challengeSettlement")
+	)
+	flag.Parse()
+
+	if *taskPath == "" {
+		fmt.Printf("Usage: %s -task-file <task.json> [options]\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if *format != "json" && *format != "abi" {
+		fmt.Printf("Invalid format: %s\n", *format)
+		fmt.Printf("Available formats: json, abi\n")
+		os.Exit(1)
+	}
+
+	// Verify with the performer's policy, so the proofs match what operators attest to
+	var configArgs []string
+	if *configPath != "" {
+		configArgs = []string{"-config", *configPath}
+	}
+	cfg, err := config.Load(configArgs, os.Getenv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		fmt.Printf("Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*taskPath)
+	if err != nil {
+		logger.Fatal("Failed to read task file", zap.Error(err))
+	}
+	input, err := loadTask(data, cfg)
+	if err != nil {
+		logger.Fatal("Failed to parse task file", zap.Error(err))
+	}
+
+	verifier := orderbookchecker.NewOrderbookVerifier(logger, cfg.Verification.Options()...)
+	result, err := taskpayload.Verify(verifier, input)
+	if err != nil {
+		logger.Fatal("Verification failed", zap.Error(err))
+	}
+
+	if len(result.FraudProofs) == 0 {
+		fmt.Fprintf(os.Stderr, "No skipped orders found (valid: %t, %d/%d trades verified)\n",
+			result.Valid, result.VerifiedTrades, result.TotalTrades)
+		if result.ErrorCode != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.ErrorCode, result.ErrorMessage)
+		}
+		return
+	}
+
+	out, err := encodeProofs(result.FraudProofs, *format)
+	if err != nil {
+		logger.Fatal("Failed to encode fraud proofs", zap.Error(err))
+	}
+
+	if *output == "" {
+		os.Stdout.Write(out)
+	} else if err := os.WriteFile(*output, out, 0644); err != nil {
+		logger.Fatal("Failed to write fraud proofs", zap.Error(err))
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d fraud proofs\n", len(result.FraudProofs))
+}
+
+// loadTask decodes a task payload as the performer does. Task files written by the
+// aggregator and task inputs written by the publisher decode as version 1 payloads;
+// reference payloads are resolved with the configured fetchers.
+func loadTask(data []byte, cfg *config.Config) (*taskpayload.TaskInput, error) {
+	if len(data) > cfg.Payload.MaxSize {
+		return nil, fmt.Errorf("payload of %d bytes exceeds the limit of %d bytes", len(data), cfg.Payload.MaxSize)
+	}
+	envelope, err := taskpayload.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if envelope.Ref == nil {
+		return envelope.Input, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Fetchers.Timeout)
+	defer cancel()
+	return taskpayload.Resolve(ctx, envelope.Ref, cfg.Fetchers.Fetcher(cfg.Payload.MaxSize))
+}
+
+// encodeProofs renders the proofs as an indented JSON array, or as one line of 0x-prefixed
+// ABI hex per proof
+func encodeProofs(proofs []orderbookchecker.FraudProof, format string) ([]byte, error) {
+	if format == "json" {
+		out, err := json.MarshalIndent(proofs, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	}
+
+	var out []byte
+	for _, proof := range proofs {
+		encoded, err := proof.EncodeABI()
+		if err != nil {
+			return nil, fmt.Errorf("trade %s: %v", proof.Trade.ID, err)
+		}
+		out = append(out, "0x"+hex.EncodeToString(encoded)+"\n"...)
+	}
+	return out, nil
+}
@@ -1,18 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"math/big"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/Layr-Labs/hourglass-avs-template/pkg/aggregator"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/cas"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
 	"github.com/Layr-Labs/hourglass-avs-template/pkg/publisher"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/settlement"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/store"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 )
 
 func main() {
@@ -22,9 +34,19 @@ func main() {
 		mode        = flag.String("mode", "full", "Demo mode: full, publish, watch, verify")
 		taskID      = flag.String("task-id", "", "Task ID for verification (verify mode)")
 		interval    = flag.Duration("interval", 5*time.Second, "Watch interval")
+		storeURL    = flag.String("store-url", "", "S3-compatible bucket URL to watch instead of the snapshot directory (watch mode)")
+		markets     = flag.String("markets", "", "Comma-separated markets to watch; all markets if empty (watch mode)")
+		encoding    = flag.String("payload-encoding", "json", "Task payload encoding: json or binary (watch mode)")
+		blobDir     = flag.String("blob-dir", "", "Publish task data to this content-addressed blob directory and submit reference payloads (watch mode)")
+		mailboxPath = flag.String("mailbox-config", "", "YAML file configuring task creation on a live TaskMailbox; tasks are only recorded locally if empty (watch mode)")
 	)
 	flag.Parse()
 
+	if *interval <= 0 {
+		fmt.Printf("Invalid -interval %s: must be positive\n", *interval)
+		os.Exit(1)
+	}
+
 	logger, err := zap.NewDevelopment()
 	if err != nil {
 		fmt.Printf("Failed to create logger: %v\n", err)
@@ -37,7 +59,7 @@ func main() {
 	case "publish":
 		runPublishDemo(logger, *snapshotDir, *marketID)
 	case "watch":
-		runWatchDemo(logger, *snapshotDir, *interval)
+		runWatchDemo(logger, *snapshotDir, *storeURL, splitMarkets(*markets), *encoding, *blobDir, *mailboxPath, *interval)
 	case "verify":
 		runVerifyDemo(logger, *snapshotDir, *taskID)
 	default:
@@ -55,6 +77,9 @@ func runFullDemo(logger *zap.Logger, snapshotDir, marketID string, interval time
 	// Step 1: Publish a snapshot with sample data
 	fmt.Println("\n📸 Step 1: Publishing orderbook snapshot...")
 	pub := publisher.NewSnapshotPublisher(logger, snapshotDir)
+	if err := pub.Recover(marketID, false); err != nil {
+		logger.Fatal("Failed to recover publisher state", zap.Error(err))
+	}
 	orders, trades := pub.GenerateSampleData(marketID)
 
 	snapshot, err := pub.PublishSnapshot(marketID, orders, trades)
@@ -106,6 +131,15 @@ func runFullDemo(logger *zap.Logger, snapshotDir, marketID string, interval time
 	fmt.Printf("   - Snapshot Hash: %s\n", task.SnapshotHash)
 	fmt.Printf("   - Trades: %d\n", len(task.Trades))
 
+	// The operator registers the settlement of the batch on a simulated chain
+	contract, operatorClient, challengerClient := newDemoChain(logger)
+	settlementID, err := operatorClient.RegisterSettlement(context.Background(),
+		merkle.Keccak256([]byte(task.BatchID)), task.SnapshotHash, task.Snapshot.MerkleRoot, task.BatchID, settlement.MinStake)
+	if err != nil {
+		logger.Fatal("Failed to register settlement", zap.Error(err))
+	}
+	fmt.Printf("   - Settlement: %s\n", settlementID.Hex())
+
 	// Step 4: Simulate task execution (verification)
 	fmt.Println("\n🔍 Step 4: Simulating AVS verification...")
 	result, err := submitter.SimulateTaskExecution(task.TaskID)
@@ -121,26 +155,59 @@ func runFullDemo(logger *zap.Logger, snapshotDir, marketID string, interval time
 		fmt.Printf("   - Error: %s\n", result.ErrorMessage)
 	}
 
-	// Step 5: Show what would happen next
-	fmt.Println("\n🏁 Step 5: Next steps in production:")
+	// Step 5: Challenge an invalid settlement
+	fmt.Println("\n🏁 Step 5: Acting on the verdict:")
+	challengeID, challenged, err := challengerClient.ChallengeIfInvalid(context.Background(), settlementID, result)
+	if err != nil {
+		logger.Fatal("Failed to challenge settlement", zap.Error(err))
+	}
 	if result.Valid {
 		fmt.Println("   ✅ Settlement is valid - no action needed")
 		fmt.Println("   📝 Result would be signed and submitted to aggregator")
+	} else if !challenged {
+		fmt.Println("   ❌ Settlement is INVALID - no fraud proof the contract can check, not challenged")
+		fmt.Printf("   📝 Error code: %s\n", result.ErrorCode)
 	} else {
-		fmt.Println("   ❌ Settlement is INVALID - challenge would be submitted")
-		fmt.Println("   ⚖️  Challenge contract would freeze the settlement")
-		fmt.Println("   💰 Fraudulent operator would be slashed")
+		fmt.Println("   ❌ Settlement is INVALID - challenge submitted")
+		fmt.Printf("   ⚖️  Challenge ID: %s\n", challengeID.Hex())
+		if stake, err := contract.OperatorStakes(context.Background(), demoOperator); err == nil {
+			fmt.Printf("   💰 Operator stake at risk: %s wei\n", stake.String())
+		}
 	}
 
 	fmt.Println("\n🎉 Demo completed successfully!")
 	fmt.Printf("📁 Demo files saved in: %s\n", snapshotDir)
 }
 
+// Accounts of the simulated settlement chain
+var (
+	demoOwner      = chain.Address{0x01}
+	demoOperator   = chain.Address{0x02}
+	demoChallenger = chain.Address{0x03}
+)
+
+// newDemoChain deploys SettlementVerifier on a simulated backend with a funded operator and an
+// authorized challenger, returning the contract and a client for each of them
+func newDemoChain(logger *zap.Logger) (*settlement.SettlementVerifier, *settlement.Client, *settlement.Client) {
+	backend := settlement.NewSimulatedBackend(demoOwner, time.Now())
+	backend.Fund(demoOperator, new(big.Int).Mul(settlement.MinStake, big.NewInt(10)))
+
+	contract := settlement.NewSettlementVerifier(backend.ContractAddress(), backend)
+	if _, err := contract.AuthorizeChallenger(context.Background(), chain.TransactOpts{From: demoOwner}, demoChallenger); err != nil {
+		logger.Fatal("Failed to authorize challenger", zap.Error(err))
+	}
+
+	return contract, settlement.NewClient(logger, contract, demoOperator), settlement.NewClient(logger, contract, demoChallenger)
+}
+
 // runPublishDemo publishes sample snapshots
 func runPublishDemo(logger *zap.Logger, snapshotDir, marketID string) {
 	fmt.Println("📸 Publishing snapshot demo...")
 
 	pub := publisher.NewSnapshotPublisher(logger, snapshotDir)
+	if err := pub.Recover(marketID, false); err != nil {
+		logger.Fatal("Failed to recover publisher state", zap.Error(err))
+	}
 	orders, trades := pub.GenerateSampleData(marketID)
 
 	snapshot, err := pub.PublishSnapshot(marketID, orders, trades)
@@ -151,7 +218,10 @@ func runPublishDemo(logger *zap.Logger, snapshotDir, marketID string) {
 	fmt.Printf("Snapshot published: %s\n", snapshot.MerkleRoot)
 
 	// Also create a task input file
-	taskInput := pub.CreateTaskInput(snapshot, trades, fmt.Sprintf("batch-%d", snapshot.SequenceNumber))
+	taskInput, err := pub.CreateTaskInput(snapshot, trades, fmt.Sprintf("batch-%d", snapshot.SequenceNumber))
+	if err != nil {
+		logger.Fatal("Failed to create task input", zap.Error(err))
+	}
 	taskData, _ := json.MarshalIndent(taskInput, "", "  ")
 
 	taskFile := fmt.Sprintf("%s/task_input_%d.json", snapshotDir, snapshot.SequenceNumber)
@@ -159,12 +229,44 @@ func runPublishDemo(logger *zap.Logger, snapshotDir, marketID string) {
 	fmt.Printf("Task input saved: %s\n", taskFile)
 }
 
-// runWatchDemo runs the snapshot watcher
-func runWatchDemo(logger *zap.Logger, snapshotDir string, interval time.Duration) {
+// splitMarkets parses a comma-separated market allow-list
+func splitMarkets(list string) []string {
+	var markets []string
+	for _, marketID := range strings.Split(list, ",") {
+		if marketID = strings.TrimSpace(marketID); marketID != "" {
+			markets = append(markets, marketID)
+		}
+	}
+	return markets
+}
+
+// runWatchDemo runs the snapshot watcher against the snapshot directory, or against an
+// S3-compatible bucket if storeURL is set, submitting payloads in the given encoding. If
+// blobDir is set, task data is published there and payloads only reference it. If
+// mailboxPath is set, tasks are created on the TaskMailbox it configures.
+func runWatchDemo(logger *zap.Logger, snapshotDir, storeURL string, markets []string, encoding, blobDir, mailboxPath string, interval time.Duration) {
 	fmt.Printf("👁️  Starting snapshot watcher (interval: %v)...\n", interval)
 	fmt.Println("Press Ctrl+C to stop")
 
-	submitter := aggregator.NewTaskSubmitter(logger, snapshotDir)
+	var snapshotStore store.SnapshotStore = store.NewFSStore(snapshotDir)
+	if storeURL != "" {
+		snapshotStore = store.NewHTTPStore(storeURL, nil)
+	}
+	var sink aggregator.TaskSink = aggregator.NewFileTaskSink(snapshotDir)
+	if mailboxPath != "" {
+		mailboxSink, err := newMailboxSink(logger, mailboxPath, os.Getenv(mailboxKeyEnv), snapshotDir)
+		if err != nil {
+			logger.Fatal("Invalid mailbox configuration", zap.Error(err))
+		}
+		sink = mailboxSink
+	}
+	submitter := aggregator.NewTaskSubmitterWithSink(logger, snapshotStore, snapshotDir, markets, sink)
+	if err := submitter.SetPayloadEncoding(encoding); err != nil {
+		logger.Fatal("Invalid payload encoding", zap.Error(err))
+	}
+	if blobDir != "" {
+		submitter.SetContentStore(cas.NewDirStore(blobDir))
+	}
 
 	// Set up signal handling
 	ctx, cancel := context.WithCancel(context.Background())
@@ -186,6 +288,63 @@ func runWatchDemo(logger *zap.Logger, snapshotDir string, interval time.Duration
 	fmt.Println("Watcher stopped")
 }
 
+// mailboxKeyEnv names the variable holding the hex private key of the account creating
+// tasks; keys are kept out of configuration files
+const mailboxKeyEnv = "AGGREGATOR_PRIVATE_KEY"
+
+// mailboxFileConfig is the file configuring task creation on a live TaskMailbox: the L2
+// node and the aggregator.MailboxConfig settings. The sending account is the account of
+// the key; refunds go to it unless refund_collector is set.
+type mailboxFileConfig struct {
+	RPCURL                   string `yaml:"rpc_url"`
+	aggregator.MailboxConfig `yaml:",inline"`
+}
+
+// newMailboxSink creates a sink creating tasks on the TaskMailbox configured by the file at
+// path, through the L2 node it names, and writing task records to taskDir
+func newMailboxSink(logger *zap.Logger, path, hexKey, taskDir string) (*aggregator.MailboxTaskSink, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mailbox config: %v", err)
+	}
+	var cfg mailboxFileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse mailbox config %s: %v", path, err)
+	}
+
+	if u, err := url.Parse(cfg.RPCURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("rpc_url must be an http(s) URL, got %q", cfg.RPCURL)
+	}
+	if cfg.Mailbox == (chain.Address{}) || cfg.AVS == (chain.Address{}) {
+		return nil, fmt.Errorf("mailbox and avs are required")
+	}
+
+	rawKey, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil || len(rawKey) != 32 {
+		return nil, fmt.Errorf("%s must hold a 32-byte hex private key", mailboxKeyEnv)
+	}
+	key := secp256k1.PrivKeyFromBytes(rawKey)
+	from := chain.KeyAddress(key)
+	if cfg.From != (chain.Address{}) && cfg.From != from {
+		return nil, fmt.Errorf("from %s is not the account of %s (%s)", cfg.From, mailboxKeyEnv, from)
+	}
+	cfg.From = from
+	if cfg.RefundCollector == (chain.Address{}) {
+		cfg.RefundCollector = from
+	}
+
+	logger.Info("Creating tasks on the TaskMailbox",
+		zap.String("rpc_url", cfg.RPCURL),
+		zap.String("mailbox", cfg.Mailbox.Hex()),
+		zap.String("from", from.Hex()),
+		zap.Uint32("executor_operator_set_id", cfg.ExecutorOperatorSetID),
+	)
+	backend := chain.NewRPCBackend(cfg.RPCURL, nil, key)
+	return aggregator.NewMailboxTaskSink(logger, backend, cfg.MailboxConfig, taskDir), nil
+}
+
 // runVerifyDemo verifies a specific task
 func runVerifyDemo(logger *zap.Logger, snapshotDir, taskID string) {
 	if taskID == "" {
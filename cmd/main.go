@@ -2,11 +2,18 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/cas"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/config"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
 	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/taskpayload"
 	"github.com/Layr-Labs/hourglass-monorepo/ponos/pkg/performer/server"
 	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
 	"go.uber.org/zap"
@@ -20,23 +27,118 @@ import (
 // Aggregator to place in the outbox once the signing threshold is met.
 
 // TaskInput represents the input data for orderbook verification tasks
-type TaskInput struct {
-	SnapshotHash string                             `json:"snapshot_hash"`
-	Snapshot     orderbookchecker.OrderbookSnapshot `json:"snapshot"`
-	Trades       []orderbookchecker.Trade           `json:"trades"`
-	TradeBatchID string                             `json:"trade_batch_id"`
+type TaskInput = taskpayload.TaskInput
+
+// maxParsedPayloads bounds the payloads kept between validation and handling
+const maxParsedPayloads = 64
+
+// parsedPayload is a payload parsed by ValidateTask, kept for HandleTask
+type parsedPayload struct {
+	digest merkle.Hash // keccak256 of the payload
+	input  *TaskInput
 }
 
 type TaskWorker struct {
 	logger   *zap.Logger
 	verifier *orderbookchecker.OrderbookVerifier
+	fetcher  cas.Fetcher // Resolves reference payloads; nil rejects them
+
+	maxPayloadSize int           // Larger payloads are rejected unparsed
+	fetchTimeout   time.Duration // Bounds fetching the blobs of a reference payload
+
+	mu     sync.Mutex
+	parsed map[string]parsedPayload // Validated payloads by task ID
 }
 
 func NewTaskWorker(logger *zap.Logger) *TaskWorker {
+	return NewTaskWorkerWithFetcher(logger, nil)
+}
+
+// NewTaskWorkerWithFetcher creates a task worker that resolves reference payloads with fetcher
+func NewTaskWorkerWithFetcher(logger *zap.Logger, fetcher cas.Fetcher) *TaskWorker {
+	defaults := config.Default()
 	return &TaskWorker{
-		logger:   logger,
-		verifier: orderbookchecker.NewOrderbookVerifier(logger),
+		logger:         logger,
+		verifier:       orderbookchecker.NewOrderbookVerifier(logger),
+		fetcher:        fetcher,
+		maxPayloadSize: defaults.Payload.MaxSize,
+		fetchTimeout:   defaults.Fetchers.Timeout,
+		parsed:         make(map[string]parsedPayload),
+	}
+}
+
+// NewTaskWorkerWithConfig creates a task worker applying the verification policy, payload
+// limit and fetchers of a validated configuration
+func NewTaskWorkerWithConfig(logger *zap.Logger, cfg *config.Config) *TaskWorker {
+	tw := NewTaskWorkerWithFetcher(logger, cfg.Fetchers.Fetcher(cfg.Payload.MaxSize))
+	tw.verifier = orderbookchecker.NewOrderbookVerifier(logger, cfg.Verification.Options()...)
+	tw.maxPayloadSize = cfg.Payload.MaxSize
+	tw.fetchTimeout = cfg.Fetchers.Timeout
+	return tw
+}
+
+// parseTask returns the input of a task, reusing the input parsed during validation when the
+// payload is unchanged. When consume is set the cached input is released.
+func (tw *TaskWorker) parseTask(t *performerV1.TaskRequest, consume bool) (*TaskInput, error) {
+	taskID := string(t.TaskId)
+	if len(t.Payload) > tw.maxPayloadSize {
+		return nil, fmt.Errorf("payload of %d bytes exceeds the limit of %d bytes", len(t.Payload), tw.maxPayloadSize)
+	}
+
+	tw.mu.Lock()
+	cached, ok := tw.parsed[taskID]
+	if ok && consume {
+		delete(tw.parsed, taskID)
+	}
+	tw.mu.Unlock()
+	if ok && cached.digest == merkle.Keccak256(t.Payload) {
+		return cached.input, nil
+	}
+
+	envelope, err := taskpayload.Decode(t.Payload)
+	if err != nil {
+		return nil, err
 	}
+	tw.logger.Debug("Task payload decoded",
+		zap.String("task_id", taskID),
+		zap.Uint8("payload_version", envelope.Version),
+		zap.String("payload_type", envelope.Type),
+	)
+	if envelope.Ref == nil {
+		return envelope.Input, nil
+	}
+
+	if tw.fetcher == nil {
+		return nil, fmt.Errorf("reference payloads are not supported: no blob fetcher configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tw.fetchTimeout)
+	defer cancel()
+	startTime := time.Now()
+	input, err := taskpayload.Resolve(ctx, envelope.Ref, tw.fetcher)
+	if err != nil {
+		return nil, err
+	}
+	tw.logger.Debug("Task payload resolved",
+		zap.String("task_id", taskID),
+		zap.String("snapshot_locator", envelope.Ref.SnapshotLocator),
+		zap.String("trade_batch_locator", envelope.Ref.TradeBatchLocator),
+		zap.Duration("fetch_duration", time.Since(startTime)),
+	)
+	return input, nil
+}
+
+// cacheTask keeps a validated input for HandleTask. When the cache is full an arbitrary
+// entry is dropped; its task is parsed again when handled.
+func (tw *TaskWorker) cacheTask(t *performerV1.TaskRequest, input *TaskInput) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if len(tw.parsed) >= maxParsedPayloads {
+		for taskID := range tw.parsed {
+			delete(tw.parsed, taskID)
+			break
+		}
+	}
+	tw.parsed[string(t.TaskId)] = parsedPayload{digest: merkle.Keccak256(t.Payload), input: input}
 }
 
 func (tw *TaskWorker) ValidateTask(t *performerV1.TaskRequest) error {
@@ -49,8 +151,8 @@ func (tw *TaskWorker) ValidateTask(t *performerV1.TaskRequest) error {
 	)
 
 	// Parse task input
-	var taskInput TaskInput
-	if err := json.Unmarshal(t.Payload, &taskInput); err != nil {
+	taskInput, err := tw.parseTask(t, false)
+	if err != nil {
 		tw.logger.Error("Failed to parse task payload",
 			zap.String("task_id", string(t.TaskId)),
 			zap.Error(err),
@@ -92,7 +194,27 @@ func (tw *TaskWorker) ValidateTask(t *performerV1.TaskRequest) error {
 		return fmt.Errorf("snapshot market_id is required")
 	}
 
-	if len(taskInput.Trades) == 0 {
+	if taskInput.Mode != "" && taskInput.Mode != orderbookchecker.VerificationModeTrades && taskInput.Mode != orderbookchecker.VerificationModeReplay {
+		tw.logger.Error("Validation failed: unknown verification mode",
+			zap.String("task_id", string(t.TaskId)),
+			zap.String("mode", taskInput.Mode),
+			zap.Duration("duration", time.Since(startTime)),
+		)
+		return fmt.Errorf("unknown verification mode: %s", taskInput.Mode)
+	}
+
+	replay := taskInput.Mode == orderbookchecker.VerificationModeReplay
+
+	if replay && len(taskInput.IncomingOrders) == 0 {
+		tw.logger.Error("Validation failed: replay mode without incoming orders",
+			zap.String("task_id", string(t.TaskId)),
+			zap.Duration("duration", time.Since(startTime)),
+		)
+		return fmt.Errorf("incoming_orders are required in replay mode")
+	}
+
+	// In replay mode an empty trades array is meaningful: it claims no fills happened
+	if !replay && len(taskInput.Trades) == 0 {
 		tw.logger.Error("Validation failed: empty trades array",
 			zap.String("task_id", string(t.TaskId)),
 			zap.Duration("duration", time.Since(startTime)),
@@ -114,6 +236,20 @@ func (tw *TaskWorker) ValidateTask(t *performerV1.TaskRequest) error {
 	for _, order := range taskInput.Snapshot.Orders {
 		orderIDs[order.ID] = true
 	}
+	for _, order := range taskInput.IncomingOrders {
+		orderIDs[order.ID] = true
+	}
+
+	for _, cancellation := range taskInput.Cancellations {
+		if !orderIDs[cancellation.OrderID] {
+			tw.logger.Error("Validation failed: cancellation references unknown order",
+				zap.String("task_id", string(t.TaskId)),
+				zap.String("order_id", cancellation.OrderID),
+				zap.Duration("duration", time.Since(startTime)),
+			)
+			return fmt.Errorf("cancellation references unknown order %s", cancellation.OrderID)
+		}
+	}
 
 	for _, trade := range taskInput.Trades {
 		if !orderIDs[trade.BuyOrderID] {
@@ -136,6 +272,8 @@ func (tw *TaskWorker) ValidateTask(t *performerV1.TaskRequest) error {
 		}
 	}
 
+	tw.cacheTask(t, taskInput)
+
 	tw.logger.Info("Task validation completed successfully",
 		zap.String("task_id", string(t.TaskId)),
 		zap.String("snapshot_hash", taskInput.SnapshotHash),
@@ -159,9 +297,9 @@ func (tw *TaskWorker) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskR
 		zap.Time("started_at", startTime),
 	)
 
-	// Parse task input
-	var taskInput TaskInput
-	if err := json.Unmarshal(t.Payload, &taskInput); err != nil {
+	// Parse task input, or take the input parsed during validation
+	taskInput, err := tw.parseTask(t, true)
+	if err != nil {
 		tw.logger.Error("Failed to parse task payload during execution",
 			zap.String("task_id", string(t.TaskId)),
 			zap.Error(err),
@@ -180,9 +318,10 @@ func (tw *TaskWorker) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskR
 		zap.Int("trades_count", len(taskInput.Trades)),
 	)
 
-	// Perform orderbook verification
+	// Perform orderbook verification. A snapshot that fails its integrity check yields an
+	// invalid result, logged below with the other failed settlements.
 	verificationStart := time.Now()
-	result, err := tw.verifier.VerifySnapshot(taskInput.Trades, taskInput.Snapshot)
+	result, err := taskpayload.Verify(tw.verifier, taskInput)
 	verificationDuration := time.Since(verificationStart)
 
 	if err != nil {
@@ -199,6 +338,7 @@ func (tw *TaskWorker) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskR
 
 	tw.logger.Info("Orderbook verification completed",
 		zap.String("task_id", string(t.TaskId)),
+		zap.String("mode", result.Mode),
 		zap.Bool("valid", result.Valid),
 		zap.Int("verified_trades", result.VerifiedTrades),
 		zap.Int("total_trades", result.TotalTrades),
@@ -213,35 +353,17 @@ func (tw *TaskWorker) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskR
 			zap.String("snapshot_hash", taskInput.SnapshotHash),
 			zap.String("trade_batch_id", taskInput.TradeBatchID),
 			zap.String("market_id", taskInput.Snapshot.MarketID),
+			zap.String("error_code", result.ErrorCode),
 			zap.String("error_message", result.ErrorMessage),
 			zap.Any("failed_trades", result.FailedTrades),
+			zap.Any("fill_diffs", result.FillDiffs),
 		)
 	}
 
-	// Prepare result
-	resultData := map[string]interface{}{
-		"verification_result": result,
-		"snapshot_hash":       taskInput.SnapshotHash,
-		"trade_batch_id":      taskInput.TradeBatchID,
-		"verified_at":         time.Now().UTC(),
-		"verifier_version":    "1.0.0",
-		"performance_metrics": map[string]interface{}{
-			"verification_duration_ms": verificationDuration.Milliseconds(),
-			"total_duration_ms":        time.Since(startTime).Milliseconds(),
-			"orders_processed":         len(taskInput.Snapshot.Orders),
-			"trades_processed":         len(taskInput.Trades),
-		},
-	}
-
-	resultBytes, err := json.Marshal(resultData)
-	if err != nil {
-		tw.logger.Error("Failed to marshal task result",
-			zap.String("task_id", string(t.TaskId)),
-			zap.Error(err),
-			zap.Duration("total_duration", time.Since(startTime)),
-		)
-		return nil, fmt.Errorf("failed to marshal result: %v", err)
-	}
+	// Only the verdict goes into the result: operators sign it, so it must not depend on when
+	// or how fast it was computed
+	taskResult := orderbookchecker.NewTaskResult(result, tw.verifier.PolicyHash(), taskInput.SnapshotHash, taskInput.Snapshot, taskInput.TradeBatchID, taskInput.Trades)
+	resultBytes := taskResult.EncodeABI()
 
 	tw.logger.Info("Task execution completed successfully",
 		zap.String("task_id", string(t.TaskId)),
@@ -251,6 +373,7 @@ func (tw *TaskWorker) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskR
 		zap.Int("verified_trades", result.VerifiedTrades),
 		zap.Int("total_trades", result.TotalTrades),
 		zap.Int("failed_trades", len(result.FailedTrades)),
+		zap.Int("orders_processed", len(taskInput.Snapshot.Orders)),
 		zap.Int("result_size_bytes", len(resultBytes)),
 		zap.Duration("verification_duration", verificationDuration),
 		zap.Duration("total_duration", time.Since(startTime)),
@@ -264,13 +387,34 @@ func (tw *TaskWorker) HandleTask(t *performerV1.TaskRequest) (*performerV1.TaskR
 
 func main() {
 	ctx := context.Background()
-	l, _ := zap.NewProduction()
 
-	w := NewTaskWorker(l)
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	l, err := cfg.Log.Logger()
+	if err != nil {
+		panic(fmt.Errorf("failed to create logger: %w", err))
+	}
+
+	l.Info("Starting performer",
+		zap.Int("port", cfg.Server.Port),
+		zap.Duration("timeout", cfg.Server.Timeout),
+		zap.String("execution_price", cfg.Verification.ExecutionPrice),
+		zap.String("self_trade", cfg.Verification.SelfTrade),
+		zap.Bool("signed_orders", cfg.Verification.SignedOrders.Enabled),
+		zap.Int("max_payload_size", cfg.Payload.MaxSize),
+	)
+
+	w := NewTaskWorkerWithConfig(l, cfg)
 
 	pp, err := server.NewPonosPerformerWithRpcServer(&server.PonosPerformerConfig{
-		Port:    8080,
-		Timeout: 5 * time.Second,
+		Port:    cfg.Server.Port,
+		Timeout: cfg.Server.Timeout,
 	}, w, l)
 	if err != nil {
 		panic(fmt.Errorf("failed to create performer: %w", err))
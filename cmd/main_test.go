@@ -1,14 +1,59 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/cas"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/config"
 	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/taskpayload"
 	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
 	"go.uber.org/zap"
 	"math/big"
+	"strings"
 	"testing"
+	"time"
 )
 
+// commitSnapshot fills in the merkle root and snapshot hash the publisher would have committed
+func commitSnapshot(t *testing.T, taskInput *TaskInput) {
+	root, err := orderbookchecker.ComputeMerkleRoot(taskInput.Snapshot.Orders)
+	if err != nil {
+		t.Fatalf("Failed to compute merkle root: %v", err)
+	}
+	taskInput.Snapshot.MerkleRoot = root
+
+	hash, err := orderbookchecker.HashSnapshot(taskInput.Snapshot)
+	if err != nil {
+		t.Fatalf("Failed to hash snapshot: %v", err)
+	}
+	taskInput.SnapshotHash = hash
+}
+
+// handleVerification runs a task end to end and returns the decoded task result
+func handleVerification(t *testing.T, taskWorker *TaskWorker, taskInput TaskInput) *orderbookchecker.TaskResult {
+	payloadBytes, err := json.Marshal(taskInput)
+	if err != nil {
+		t.Fatalf("Failed to marshal task input: %v", err)
+	}
+
+	resp, err := taskWorker.HandleTask(&performerV1.TaskRequest{
+		TaskId:  []byte("test-task-id"),
+		Payload: payloadBytes,
+	})
+	if err != nil {
+		t.Fatalf("HandleTask failed: %v", err)
+	}
+
+	result, err := orderbookchecker.DecodeTaskResult(resp.Result)
+	if err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+
+	return result
+}
+
 func Test_TaskRequestPayload(t *testing.T) {
 	logger, err := zap.NewDevelopment()
 	if err != nil {
@@ -19,7 +64,6 @@ func Test_TaskRequestPayload(t *testing.T) {
 
 	// Create valid JSON payload
 	taskInput := TaskInput{
-		SnapshotHash: "0x1234567890abcdef",
 		TradeBatchID: "test-batch",
 		Snapshot: orderbookchecker.OrderbookSnapshot{
 			SequenceNumber: 1,
@@ -52,6 +96,8 @@ func Test_TaskRequestPayload(t *testing.T) {
 		},
 	}
 
+	commitSnapshot(t, &taskInput)
+
 	payloadBytes, err := json.Marshal(taskInput)
 	if err != nil {
 		t.Fatalf("Failed to marshal task input: %v", err)
@@ -79,3 +125,498 @@ func Test_TaskRequestPayload(t *testing.T) {
 		t.Logf("Response received with %d bytes", len(resp.Result))
 	}
 }
+
+func Test_TaskRequestPayload_ReplayMode(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	taskWorker := NewTaskWorker(logger)
+
+	// Replay an incoming buy against the resting sell and report the matching fill
+	taskInput := TaskInput{
+		TradeBatchID: "test-batch",
+		Mode:         orderbookchecker.VerificationModeReplay,
+		Snapshot: orderbookchecker.OrderbookSnapshot{
+			SequenceNumber: 1,
+			MarketID:       "TEST-MARKET",
+			Orders: []orderbookchecker.Order{
+				{
+					ID:       "sell-1",
+					Side:     "sell",
+					Price:    big.NewInt(95),
+					Quantity: big.NewInt(30),
+					UserID:   "user2",
+				},
+			},
+		},
+		IncomingOrders: []orderbookchecker.Order{
+			{
+				ID:       "buy-1",
+				Side:     "buy",
+				Price:    big.NewInt(100),
+				Quantity: big.NewInt(50),
+				UserID:   "user1",
+			},
+		},
+		Trades: []orderbookchecker.Trade{
+			{
+				ID:          "trade-1",
+				BuyOrderID:  "buy-1",
+				SellOrderID: "sell-1",
+				Price:       big.NewInt(95),
+				Quantity:    big.NewInt(30),
+			},
+		},
+	}
+
+	commitSnapshot(t, &taskInput)
+
+	payloadBytes, err := json.Marshal(taskInput)
+	if err != nil {
+		t.Fatalf("Failed to marshal task input: %v", err)
+	}
+
+	taskRequest := &performerV1.TaskRequest{
+		TaskId:   []byte("test-task-id"),
+		Payload:  payloadBytes,
+		Metadata: []byte("test-metadata"),
+	}
+
+	if err := taskWorker.ValidateTask(taskRequest); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+
+	result := handleVerification(t, taskWorker, taskInput)
+	if !result.Valid || result.TotalTrades != 1 {
+		t.Errorf("Expected valid replay result, got %+v", result)
+	}
+}
+
+func Test_HandleTask_SnapshotIntegrity(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	taskWorker := NewTaskWorker(logger)
+
+	newInput := func() TaskInput {
+		taskInput := TaskInput{
+			TradeBatchID: "test-batch",
+			Snapshot: orderbookchecker.OrderbookSnapshot{
+				SequenceNumber: 1,
+				MarketID:       "TEST-MARKET",
+				Orders: []orderbookchecker.Order{
+					{ID: "buy-1", Side: "buy", Price: big.NewInt(100), Quantity: big.NewInt(50), UserID: "user1"},
+					{ID: "sell-1", Side: "sell", Price: big.NewInt(95), Quantity: big.NewInt(30), UserID: "user2"},
+				},
+			},
+			Trades: []orderbookchecker.Trade{
+				{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(95), Quantity: big.NewInt(30)},
+			},
+		}
+		commitSnapshot(t, &taskInput)
+		return taskInput
+	}
+
+	tests := []struct {
+		name     string
+		tamper   func(*TaskInput)
+		wantCode string
+	}{
+		{
+			name: "orders tampered after commitment",
+			tamper: func(in *TaskInput) {
+				in.Snapshot.Orders[1].Quantity = big.NewInt(3000)
+			},
+			wantCode: orderbookchecker.ErrorCodeMerkleRootMismatch,
+		},
+		{
+			name: "snapshot relinked to a different parent",
+			tamper: func(in *TaskInput) {
+				in.Snapshot.PrevHash = "0x" + strings.Repeat("ab", 32)
+			},
+			wantCode: orderbookchecker.ErrorCodeSnapshotHashMismatch,
+		},
+		{
+			name: "snapshot hash does not match committed hash",
+			tamper: func(in *TaskInput) {
+				in.SnapshotHash = "0x1234567890abcdef"
+			},
+			wantCode: orderbookchecker.ErrorCodeSnapshotHashMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskInput := newInput()
+			tt.tamper(&taskInput)
+
+			result := handleVerification(t, taskWorker, taskInput)
+			if result.Valid {
+				t.Fatal("Expected invalid result for tampered snapshot")
+			}
+			if result.ErrorCode != tt.wantCode {
+				t.Errorf("Expected error code %s, got %s", tt.wantCode, result.ErrorCode)
+			}
+		})
+	}
+}
+
+func Test_HandleTask_Cancellations(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	taskWorker := NewTaskWorker(logger)
+
+	tradeTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	taskInput := TaskInput{
+		TradeBatchID: "test-batch",
+		Snapshot: orderbookchecker.OrderbookSnapshot{
+			SequenceNumber: 1,
+			MarketID:       "TEST-MARKET",
+			Orders: []orderbookchecker.Order{
+				{ID: "buy-1", Side: "buy", Price: big.NewInt(100), Quantity: big.NewInt(50), UserID: "user1"},
+				{ID: "sell-1", Side: "sell", Price: big.NewInt(95), Quantity: big.NewInt(30), UserID: "user2"},
+			},
+		},
+		Trades: []orderbookchecker.Trade{
+			{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(95), Quantity: big.NewInt(30), Timestamp: tradeTime},
+		},
+		Cancellations: []orderbookchecker.Cancellation{
+			{OrderID: "sell-1", Timestamp: tradeTime.Add(-time.Second)},
+		},
+	}
+	commitSnapshot(t, &taskInput)
+
+	result := handleVerification(t, taskWorker, taskInput)
+	if result.Valid {
+		t.Fatal("Expected trade against a cancelled order to be invalid")
+	}
+	if result.ErrorCode != orderbookchecker.ErrorCodeOrderCancelled {
+		t.Errorf("Expected error code %s, got %s", orderbookchecker.ErrorCodeOrderCancelled, result.ErrorCode)
+	}
+
+	taskInput.Cancellations = []orderbookchecker.Cancellation{{OrderID: "unknown-order", Timestamp: tradeTime}}
+	payloadBytes, err := json.Marshal(taskInput)
+	if err != nil {
+		t.Fatalf("Failed to marshal task input: %v", err)
+	}
+	if err := taskWorker.ValidateTask(&performerV1.TaskRequest{TaskId: []byte("test-task-id"), Payload: payloadBytes}); err == nil {
+		t.Error("Expected validation error for a cancellation of an unknown order")
+	}
+}
+
+func Test_HandleTask_DeterministicResult(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	taskInput := TaskInput{
+		TradeBatchID: "test-batch",
+		Snapshot: orderbookchecker.OrderbookSnapshot{
+			SequenceNumber: 1,
+			MarketID:       "TEST-MARKET",
+			Orders: []orderbookchecker.Order{
+				{ID: "buy-1", Side: "buy", Price: big.NewInt(100), Quantity: big.NewInt(50), UserID: "user1"},
+				{ID: "sell-1", Side: "sell", Price: big.NewInt(95), Quantity: big.NewInt(30), UserID: "user2"},
+			},
+		},
+		Trades: []orderbookchecker.Trade{
+			{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(95), Quantity: big.NewInt(20)},
+			{ID: "trade-2", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(105), Quantity: big.NewInt(5)},
+			{ID: "trade-3", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(95), Quantity: big.NewInt(5)},
+		},
+	}
+	commitSnapshot(t, &taskInput)
+	payloadBytes, err := json.Marshal(taskInput)
+	if err != nil {
+		t.Fatalf("Failed to marshal task input: %v", err)
+	}
+
+	// Two operators handling the same task at different times must produce identical bytes
+	var results [][]byte
+	for i := 0; i < 2; i++ {
+		resp, err := NewTaskWorker(logger).HandleTask(&performerV1.TaskRequest{
+			TaskId:  []byte("test-task-id"),
+			Payload: payloadBytes,
+		})
+		if err != nil {
+			t.Fatalf("HandleTask failed: %v", err)
+		}
+		results = append(results, resp.Result)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !bytes.Equal(results[0], results[1]) {
+		t.Fatal("Expected byte-identical results across runs")
+	}
+
+	result, err := orderbookchecker.DecodeTaskResult(results[0])
+	if err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if result.Valid || result.BatchID != "test-batch" || result.TotalTrades != 3 ||
+		result.SnapshotHash.Hex() != taskInput.SnapshotHash || result.MerkleRoot.Hex() != taskInput.Snapshot.MerkleRoot {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if want := orderbookchecker.NewOrderbookVerifier(logger).PolicyHash(); result.PolicyHash != want {
+		t.Errorf("Expected the default policy hash %s, got %s", want, result.PolicyHash)
+	}
+	if result.TradeFailed(0) || !result.TradeFailed(1) || result.TradeFailed(2) {
+		t.Errorf("Expected only trade-2 to be marked failed, got bitmap %08b", result.FailedTradeBitmap)
+	}
+}
+
+func Test_TaskPayloadVersions(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	taskInput := TaskInput{
+		TradeBatchID: "test-batch",
+		Snapshot: orderbookchecker.OrderbookSnapshot{
+			SequenceNumber: 1,
+			MarketID:       "TEST-MARKET",
+			Orders: []orderbookchecker.Order{
+				{ID: "buy-1", Side: "buy", Price: big.NewInt(100), Quantity: big.NewInt(50), UserID: "user1"},
+				{ID: "sell-1", Side: "sell", Price: big.NewInt(95), Quantity: big.NewInt(30), UserID: "user2"},
+			},
+		},
+		Trades: []orderbookchecker.Trade{
+			{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(95), Quantity: big.NewInt(30)},
+		},
+	}
+	commitSnapshot(t, &taskInput)
+
+	v1, err := json.Marshal(taskInput)
+	if err != nil {
+		t.Fatalf("Failed to marshal task input: %v", err)
+	}
+	v2JSON, err := taskpayload.Encode(&taskInput, taskpayload.EncodingJSON)
+	if err != nil {
+		t.Fatalf("Failed to encode task input: %v", err)
+	}
+	v2Binary, err := taskpayload.Encode(&taskInput, taskpayload.EncodingBinary)
+	if err != nil {
+		t.Fatalf("Failed to encode task input: %v", err)
+	}
+
+	// Every encoding of the same task yields the same signed result
+	var want []byte
+	for _, payload := range [][]byte{v1, v2JSON, v2Binary} {
+		taskWorker := NewTaskWorker(logger)
+		request := &performerV1.TaskRequest{TaskId: []byte("test-task-id"), Payload: payload}
+		if err := taskWorker.ValidateTask(request); err != nil {
+			t.Fatalf("ValidateTask failed: %v", err)
+		}
+		resp, err := taskWorker.HandleTask(request)
+		if err != nil {
+			t.Fatalf("HandleTask failed: %v", err)
+		}
+		if want == nil {
+			want = resp.Result
+		} else if !bytes.Equal(resp.Result, want) {
+			t.Errorf("Expected identical results across payload encodings")
+		}
+	}
+
+	unknown := []byte(`{"version":2,"type":"price_feed","input":{}}`)
+	if err := NewTaskWorker(logger).ValidateTask(&performerV1.TaskRequest{TaskId: []byte("test-task-id"), Payload: unknown}); err == nil {
+		t.Error("Expected validation error for an unknown payload type")
+	}
+}
+
+func Test_TaskWorker_ParsesPayloadOnce(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+	taskWorker := NewTaskWorker(logger)
+
+	taskInput := TaskInput{
+		TradeBatchID: "test-batch",
+		Snapshot: orderbookchecker.OrderbookSnapshot{
+			SequenceNumber: 1,
+			MarketID:       "TEST-MARKET",
+			Orders: []orderbookchecker.Order{
+				{ID: "buy-1", Side: "buy", Price: big.NewInt(100), Quantity: big.NewInt(50), UserID: "user1"},
+				{ID: "sell-1", Side: "sell", Price: big.NewInt(95), Quantity: big.NewInt(30), UserID: "user2"},
+			},
+		},
+		Trades: []orderbookchecker.Trade{
+			{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(95), Quantity: big.NewInt(30)},
+		},
+	}
+	commitSnapshot(t, &taskInput)
+	payload, err := taskpayload.Encode(&taskInput, taskpayload.EncodingBinary)
+	if err != nil {
+		t.Fatalf("Failed to encode task input: %v", err)
+	}
+	request := &performerV1.TaskRequest{TaskId: []byte("test-task-id"), Payload: payload}
+
+	if err := taskWorker.ValidateTask(request); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	validated := taskWorker.parsed["test-task-id"].input
+	if validated == nil {
+		t.Fatal("Expected the validated input to be cached")
+	}
+	if input, err := taskWorker.parseTask(request, true); err != nil || input != validated {
+		t.Errorf("Expected HandleTask to reuse the validated input, got %p (%v)", input, err)
+	}
+	if len(taskWorker.parsed) != 0 {
+		t.Errorf("Expected the cached input to be released, %d left", len(taskWorker.parsed))
+	}
+
+	// A different payload under the same task ID is parsed afresh
+	if err := taskWorker.ValidateTask(request); err != nil {
+		t.Fatalf("ValidateTask failed: %v", err)
+	}
+	otherInput := taskInput
+	otherInput.TradeBatchID = "other-batch"
+	otherPayload, err := taskpayload.Encode(&otherInput, taskpayload.EncodingBinary)
+	if err != nil {
+		t.Fatalf("Failed to encode task input: %v", err)
+	}
+	changed := &performerV1.TaskRequest{TaskId: []byte("test-task-id"), Payload: otherPayload}
+	input, err := taskWorker.parseTask(changed, true)
+	if err != nil {
+		t.Fatalf("Failed to parse the changed payload: %v", err)
+	}
+	if input == validated || input.TradeBatchID != "other-batch" {
+		t.Errorf("Expected the changed payload to be parsed afresh, got batch %q", input.TradeBatchID)
+	}
+
+	for i := 0; i < maxParsedPayloads+10; i++ {
+		taskWorker.cacheTask(&performerV1.TaskRequest{TaskId: []byte{byte(i)}, Payload: payload}, validated)
+	}
+	if len(taskWorker.parsed) > maxParsedPayloads {
+		t.Errorf("Expected at most %d cached payloads, got %d", maxParsedPayloads, len(taskWorker.parsed))
+	}
+}
+
+func Test_TaskPayloadRefs(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	taskInput := TaskInput{
+		TradeBatchID: "test-batch",
+		Snapshot: orderbookchecker.OrderbookSnapshot{
+			SequenceNumber: 1,
+			MarketID:       "TEST-MARKET",
+			Orders: []orderbookchecker.Order{
+				{ID: "buy-1", Side: "buy", Price: big.NewInt(100), Quantity: big.NewInt(50), UserID: "user1"},
+				{ID: "sell-1", Side: "sell", Price: big.NewInt(95), Quantity: big.NewInt(30), UserID: "user2"},
+			},
+		},
+		Trades: []orderbookchecker.Trade{
+			{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(95), Quantity: big.NewInt(30)},
+		},
+	}
+	commitSnapshot(t, &taskInput)
+
+	store := cas.NewDirStore(t.TempDir())
+	ref, err := taskpayload.PutRef(context.Background(), store, &taskInput)
+	if err != nil {
+		t.Fatalf("PutRef failed: %v", err)
+	}
+	payload, err := taskpayload.EncodeRef(ref, taskpayload.EncodingBinary)
+	if err != nil {
+		t.Fatalf("Failed to encode task ref: %v", err)
+	}
+	inline, err := taskpayload.Encode(&taskInput, taskpayload.EncodingBinary)
+	if err != nil {
+		t.Fatalf("Failed to encode task input: %v", err)
+	}
+
+	// A reference payload yields the same signed result as the inline payload
+	var results [][]byte
+	for _, p := range [][]byte{inline, payload} {
+		taskWorker := NewTaskWorkerWithFetcher(logger, store)
+		request := &performerV1.TaskRequest{TaskId: []byte("test-task-id"), Payload: p}
+		if err := taskWorker.ValidateTask(request); err != nil {
+			t.Fatalf("ValidateTask failed: %v", err)
+		}
+		resp, err := taskWorker.HandleTask(request)
+		if err != nil {
+			t.Fatalf("HandleTask failed: %v", err)
+		}
+		results = append(results, resp.Result)
+	}
+	if !bytes.Equal(results[0], results[1]) {
+		t.Error("Expected a reference payload to yield the inline result")
+	}
+
+	request := &performerV1.TaskRequest{TaskId: []byte("test-task-id"), Payload: payload}
+	if err := NewTaskWorker(logger).ValidateTask(request); err == nil || !strings.Contains(err.Error(), "no blob fetcher") {
+		t.Errorf("Expected a worker without a fetcher to reject reference payloads, got: %v", err)
+	}
+
+	// A reference whose blob does not match its hash is rejected
+	tampered := *ref
+	tampered.TradeBatchHash = ref.SnapshotHash
+	tamperedPayload, err := taskpayload.EncodeRef(&tampered, taskpayload.EncodingJSON)
+	if err != nil {
+		t.Fatalf("Failed to encode task ref: %v", err)
+	}
+	request = &performerV1.TaskRequest{TaskId: []byte("test-task-id"), Payload: tamperedPayload}
+	if err := NewTaskWorkerWithFetcher(logger, store).ValidateTask(request); err == nil || !strings.Contains(err.Error(), cas.ErrHashMismatch.Error()) {
+		t.Errorf("Expected a hash mismatch, got: %v", err)
+	}
+}
+
+func Test_TaskWorker_Config(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		t.Errorf("Failed to create logger: %v", err)
+	}
+
+	// Both orders belong to the same user, which only a self-trade prevention policy rejects
+	taskInput := TaskInput{
+		TradeBatchID: "test-batch",
+		Snapshot: orderbookchecker.OrderbookSnapshot{
+			SequenceNumber: 1,
+			MarketID:       "TEST-MARKET",
+			Orders: []orderbookchecker.Order{
+				{ID: "buy-1", Side: "buy", Price: big.NewInt(100), Quantity: big.NewInt(50), UserID: "user1"},
+				{ID: "sell-1", Side: "sell", Price: big.NewInt(95), Quantity: big.NewInt(30), UserID: "user1"},
+			},
+		},
+		Trades: []orderbookchecker.Trade{
+			{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(95), Quantity: big.NewInt(30)},
+		},
+	}
+	commitSnapshot(t, &taskInput)
+
+	if result := handleVerification(t, NewTaskWorker(logger), taskInput); !result.Valid {
+		t.Errorf("Expected the default policy to allow self-trades, got %+v", result)
+	}
+
+	cfg := config.Default()
+	cfg.Verification.SelfTrade = orderbookchecker.SelfTradeCancelResting
+	result := handleVerification(t, NewTaskWorkerWithConfig(logger, cfg), taskInput)
+	if result.Valid {
+		t.Error("Expected the configured self-trade prevention to reject the trade")
+	}
+	if want := orderbookchecker.NewOrderbookVerifier(logger, cfg.Verification.Options()...).PolicyHash(); result.PolicyHash != want {
+		t.Errorf("Expected the result to commit to the configured policy %s, got %s", want, result.PolicyHash)
+	}
+
+	payload, err := json.Marshal(taskInput)
+	if err != nil {
+		t.Fatalf("Failed to marshal task input: %v", err)
+	}
+	cfg.Payload.MaxSize = len(payload) - 1
+	request := &performerV1.TaskRequest{TaskId: []byte("test-task-id"), Payload: payload}
+	if err := NewTaskWorkerWithConfig(logger, cfg).ValidateTask(request); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("Expected an oversized payload to be rejected, got: %v", err)
+	}
+}
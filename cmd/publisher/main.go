@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"math"
+	"math/big"
 	"os"
 
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
 	"github.com/Layr-Labs/hourglass-avs-template/pkg/publisher"
 	"go.uber.org/zap"
 )
@@ -16,6 +19,13 @@ func main() {
 		marketID  = flag.String("market", "TRUMP-2024-WIN", "Market ID for the snapshot")
 		generate  = flag.Bool("generate", false, "Generate sample data")
 		taskFile  = flag.String("task-file", "", "Output file for task input JSON")
+		verify    = flag.Bool("verify-chain", false, "Verify the snapshot hash chain in the output directory")
+		repair    = flag.Bool("repair", false, "Quarantine a corrupt last snapshot instead of refusing to start")
+		tickSize  = flag.String("tick-size", "", "Price tick size in collateral base units; enables market parameters")
+		minSize   = flag.String("min-order-size", "", "Minimum order quantity; enables market parameters")
+		decimals  = flag.Uint("collateral-decimals", orderbookchecker.DefaultCollateralDecimals, "Decimals of one unit of collateral")
+		makerFee  = flag.Uint("maker-fee-bps", 0, "Fee rate charged to makers in basis points; enables market parameters")
+		takerFee  = flag.Uint("taker-fee-bps", 0, "Fee rate charged to takers in basis points; enables market parameters")
 	)
 	flag.Parse()
 
@@ -25,7 +35,25 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *verify {
+		verifyChain(logger, *outputDir)
+		return
+	}
+
 	pub := publisher.NewSnapshotPublisher(logger, *outputDir)
+	if err := pub.Recover(*marketID, *repair); err != nil {
+		logger.Fatal("Failed to recover publisher state", zap.Error(err))
+	}
+
+	if *tickSize != "" || *minSize != "" || *makerFee != 0 || *takerFee != 0 {
+		params, err := marketParams(*tickSize, *minSize, *decimals, *makerFee, *takerFee)
+		if err != nil {
+			logger.Fatal("Invalid market parameters", zap.Error(err))
+		}
+		if err := pub.SetMarketParams(*marketID, params); err != nil {
+			logger.Fatal("Invalid market parameters", zap.Error(err))
+		}
+	}
 
 	if *generate {
 		// Generate sample data
@@ -45,7 +73,10 @@ func main() {
 
 		// Generate task input if requested
 		if *taskFile != "" {
-			taskInput := pub.CreateTaskInput(snapshot, trades, fmt.Sprintf("batch-%d", snapshot.SequenceNumber))
+			taskInput, err := pub.CreateTaskInput(snapshot, trades, fmt.Sprintf("batch-%d", snapshot.SequenceNumber))
+			if err != nil {
+				logger.Fatal("Failed to create task input", zap.Error(err))
+			}
 
 			taskData, err := json.MarshalIndent(taskInput, "", "  ")
 			if err != nil {
@@ -67,6 +98,77 @@ func main() {
 		fmt.Printf("Trades: %d\n", len(trades))
 	} else {
 		fmt.Printf("Usage: %s -generate [options]\n", os.Args[0])
+		fmt.Printf("       %s -verify-chain [options]\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 }
+
+// verifyChain walks the snapshot directory, prints the chain report of every market and
+// exits non-zero if any chain is broken
+func verifyChain(logger *zap.Logger, dir string) {
+	reports, err := publisher.VerifyChainDir(dir)
+	if err != nil {
+		logger.Fatal("Failed to verify snapshot chain", zap.Error(err))
+	}
+
+	if len(reports) == 0 {
+		fmt.Printf("No committed snapshots in %s\n", dir)
+		return
+	}
+
+	valid := true
+	for _, report := range reports {
+		fmt.Printf("Market: %s\n", report.MarketID)
+		fmt.Printf("  Snapshots: %d\n", report.Snapshots)
+		fmt.Printf("  Sequences: %d..%d\n", report.FirstSequence, report.LastSequence)
+		fmt.Printf("  Head Hash: %s\n", report.HeadHash)
+
+		if report.Valid {
+			fmt.Printf("  Chain is valid\n")
+			continue
+		}
+
+		valid = false
+		fmt.Printf("  Chain is INVALID (%d issues)\n", len(report.Issues))
+		for _, issue := range report.Issues {
+			fmt.Printf("    [%s] sequence %d: %s\n", issue.Kind, issue.SequenceNumber, issue.Message)
+		}
+	}
+
+	if !valid {
+		os.Exit(1)
+	}
+}
+
+// marketParams builds market parameters from the command line flags
+func marketParams(tickSize, minSize string, decimals, makerFee, takerFee uint) (*orderbookchecker.MarketParams, error) {
+	if decimals > 255 {
+		return nil, fmt.Errorf("collateral decimals %d out of range", decimals)
+	}
+	if makerFee > math.MaxUint32 || takerFee > math.MaxUint32 {
+		return nil, fmt.Errorf("fee rates %d/%d bps out of range", makerFee, takerFee)
+	}
+	params := &orderbookchecker.MarketParams{
+		CollateralDecimals: uint8(decimals),
+		MakerFeeBps:        uint32(makerFee),
+		TakerFeeBps:        uint32(takerFee),
+	}
+
+	if tickSize != "" {
+		tick, ok := new(big.Int).SetString(tickSize, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid tick size: %s", tickSize)
+		}
+		params.TickSize = tick
+	}
+
+	if minSize != "" {
+		size, ok := new(big.Int).SetString(minSize, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid min order size: %s", minSize)
+		}
+		params.MinOrderSize = size
+	}
+
+	return params, nil
+}
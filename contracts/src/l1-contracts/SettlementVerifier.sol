@@ -26,6 +26,7 @@ contract SettlementVerifier is Ownable, ReentrancyGuard {
         uint256 timestamp;
         SettlementStatus status;
         bytes32 snapshotHash;
+        bytes32 orderRoot;
         string tradeBatchId;
         uint256 challengeDeadline;
     }
@@ -40,19 +41,37 @@ contract SettlementVerifier is Ownable, ReentrancyGuard {
         bool successful;
     }
     
+    // Order and its inclusion proof in a snapshot's order Merkle tree
+    struct OrderProof {
+        bytes order;
+        bytes32[] siblings;
+        uint256 path;
+    }
+    
+    // Fraud proof of a trade that skipped an order with time priority (pkg/orderbookchecker FraudProof)
+    struct SkippedOrderProof {
+        uint8 version;
+        bytes32 orderRoot;
+        OrderProof skipped;
+        OrderProof matched;
+        bytes trade;
+    }
+    
     // Events
     event SettlementRegistered(
         bytes32 indexed settlementId,
         bytes32 indexed txId,
         address indexed operator,
         bytes32 snapshotHash,
+        bytes32 orderRoot,
         string tradeBatchId
     );
     
     event SettlementChallenged(
         bytes32 indexed settlementId,
         address indexed challenger,
-        bytes32 indexed txId
+        bytes32 indexed txId,
+        bytes32 challengeId
     );
     
     event ChallengeResolved(
@@ -77,6 +96,7 @@ contract SettlementVerifier is Ownable, ReentrancyGuard {
     uint256 public constant CHALLENGE_PERIOD = 7 days;
     uint256 public constant MIN_STAKE = 1 ether;
     uint256 public constant SLASH_PERCENTAGE = 50; // 50% of stake
+    uint8 public constant FRAUD_PROOF_VERSION = 1;
     
     // Modifiers
     modifier onlyAuthorizedChallenger() {
@@ -97,11 +117,13 @@ contract SettlementVerifier is Ownable, ReentrancyGuard {
      * @dev Register a new settlement
      * @param txId The transaction ID of the settlement
      * @param snapshotHash Hash of the orderbook snapshot
+     * @param orderRoot Order Merkle root of the snapshot, which fraud proofs are checked against
      * @param tradeBatchId Identifier for the trade batch
      */
     function registerSettlement(
         bytes32 txId,
         bytes32 snapshotHash,
+        bytes32 orderRoot,
         string calldata tradeBatchId
     ) external payable {
         require(msg.value >= MIN_STAKE, "Insufficient stake");
@@ -116,19 +138,20 @@ contract SettlementVerifier is Ownable, ReentrancyGuard {
             timestamp: block.timestamp,
             status: SettlementStatus.Active,
             snapshotHash: snapshotHash,
+            orderRoot: orderRoot,
             tradeBatchId: tradeBatchId,
             challengeDeadline: block.timestamp + CHALLENGE_PERIOD
         });
         
         operatorStakes[msg.sender] += msg.value;
         
-        emit SettlementRegistered(settlementId, txId, msg.sender, snapshotHash, tradeBatchId);
+        emit SettlementRegistered(settlementId, txId, msg.sender, snapshotHash, orderRoot, tradeBatchId);
     }
     
     /**
      * @dev Challenge a settlement with proof of fraud
      * @param settlementId The ID of the settlement to challenge
-     * @param proof Proof data showing the settlement is fraudulent
+     * @param proof Skipped-order fraud proof against the settlement's order root
      */
     function challengeSettlement(
         bytes32 settlementId,
@@ -138,6 +161,7 @@ contract SettlementVerifier is Ownable, ReentrancyGuard {
         
         require(settlement.status == SettlementStatus.Active, "Settlement not active");
         require(block.timestamp <= settlement.challengeDeadline, "Challenge period expired");
+        require(verifySettlementProof(settlementId, proof), "Invalid fraud proof");
         
         bytes32 challengeId = keccak256(abi.encodePacked(settlementId, msg.sender, block.timestamp));
         
@@ -152,7 +176,7 @@ contract SettlementVerifier is Ownable, ReentrancyGuard {
         
         settlement.status = SettlementStatus.Challenged;
         
-        emit SettlementChallenged(settlementId, msg.sender, settlement.txId);
+        emit SettlementChallenged(settlementId, msg.sender, settlement.txId, challengeId);
     }
     
     /**
@@ -242,6 +266,83 @@ contract SettlementVerifier is Ownable, ReentrancyGuard {
         return challenges[challengeId];
     }
     
+    /**
+     * @dev Verify that an order is included in a snapshot's order Merkle tree
+     * @notice Mirrors pkg/merkle: leaves are keccak256(0x00 || orderData), internal nodes are
+     *         keccak256(0x01 || left || right), and unpaired nodes are promoted unchanged
+     * @param root Merkle root committed in the snapshot
+     * @param orderData Encoded order committed as the leaf
+     * @param siblings Sibling hashes from the leaf level up to the root
+     * @param path Bitmap where bit i is set when siblings[i] is the left-hand input
+     * @return True if the proof is valid for the given root
+     */
+    function verifyOrderInclusion(
+        bytes32 root,
+        bytes calldata orderData,
+        bytes32[] calldata siblings,
+        uint256 path
+    ) public pure returns (bool) {
+        return _verifyOrderInclusion(root, orderData, siblings, path);
+    }
+    
+    /**
+     * @dev Verify the inclusion proofs of a skipped-order fraud proof
+     * @notice The proof is abi.encode(SkippedOrderProof) as produced by FraudProof.EncodeABI.
+     *         Only the inclusion of both orders under orderRoot is checked here, see
+     *         verifySettlementProof for the check against a settlement; the priority claim
+     *         is judged when the challenge is resolved.
+     * @param proof Encoded fraud proof, as submitted to challengeSettlement
+     * @return True if both orders are included under the proof's order root
+     */
+    function verifySkippedOrderProof(bytes calldata proof) public pure returns (bool) {
+        SkippedOrderProof memory p = abi.decode(proof, (SkippedOrderProof));
+        require(p.version == FRAUD_PROOF_VERSION, "Unsupported fraud proof version");
+        
+        return _verifyOrderInclusion(p.orderRoot, p.skipped.order, p.skipped.siblings, p.skipped.path) &&
+            _verifyOrderInclusion(p.orderRoot, p.matched.order, p.matched.siblings, p.matched.path);
+    }
+    
+    /**
+     * @dev Verify a skipped-order fraud proof against a registered settlement
+     * @notice The proof must be built on the order root the operator registered; a tree the
+     *         challenger built themselves proves nothing about the settled snapshot
+     * @param settlementId The settlement the proof is filed against
+     * @param proof Encoded fraud proof, as submitted to challengeSettlement
+     * @return True if the proof's order root is the settlement's and both orders are included
+     */
+    function verifySettlementProof(
+        bytes32 settlementId,
+        bytes calldata proof
+    ) public view validSettlement(settlementId) returns (bool) {
+        SkippedOrderProof memory p = abi.decode(proof, (SkippedOrderProof));
+        if (p.orderRoot != settlements[settlementId].orderRoot) {
+            return false;
+        }
+        return verifySkippedOrderProof(proof);
+    }
+    
+    /**
+     * @dev Recompute the root of an order inclusion proof, see verifyOrderInclusion
+     */
+    function _verifyOrderInclusion(
+        bytes32 root,
+        bytes memory orderData,
+        bytes32[] memory siblings,
+        uint256 path
+    ) internal pure returns (bool) {
+        bytes32 node = keccak256(abi.encodePacked(bytes1(0x00), orderData));
+        
+        for (uint256 i = 0; i < siblings.length; i++) {
+            if ((path >> i) & 1 == 1) {
+                node = keccak256(abi.encodePacked(bytes1(0x01), siblings[i], node));
+            } else {
+                node = keccak256(abi.encodePacked(bytes1(0x01), node, siblings[i]));
+            }
+        }
+        
+        return node == root;
+    }
+    
     /**
      * @dev Emergency function to freeze a settlement
      * @param settlementId The settlement to freeze
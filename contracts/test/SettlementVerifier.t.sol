@@ -14,6 +14,8 @@ contract SettlementVerifierTest is Test {
     
     bytes32 public constant TEST_TX_ID = keccak256("test-tx-id");
     bytes32 public constant TEST_SNAPSHOT_HASH = keccak256("test-snapshot-hash");
+    // Order root of the skipped-order golden vector, see skippedOrderVector
+    bytes32 public constant TEST_ORDER_ROOT = 0x440f32b63af59b6fb13d7dd2752a7fd800ac6922531627eb510d127a34f7cbf2;
     string public constant TEST_TRADE_BATCH_ID = "test-batch-001";
     
     event SettlementRegistered(
@@ -21,13 +23,15 @@ contract SettlementVerifierTest is Test {
         bytes32 indexed txId,
         address indexed operator,
         bytes32 snapshotHash,
+        bytes32 orderRoot,
         string tradeBatchId
     );
     
     event SettlementChallenged(
         bytes32 indexed settlementId,
         address indexed challenger,
-        bytes32 indexed txId
+        bytes32 indexed txId,
+        bytes32 challengeId
     );
     
     event ChallengeResolved(
@@ -43,6 +47,30 @@ contract SettlementVerifierTest is Test {
         uint256 slashAmount
     );
     
+    // Vector "sell-early-skipped" of pkg/orderbookchecker/testdata/encoding_vectors.json,
+    // produced by FraudProof.EncodeABI (TestFraudProof_GoldenVectors)
+    function skippedOrderVector() internal pure returns (bytes memory) {
+        return bytes.concat(
+            hex"00000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000001",
+            hex"440f32b63af59b6fb13d7dd2752a7fd800ac6922531627eb510d127a34f7cbf200000000000000000000000000000000000000000000000000000000000000a0",
+            hex"00000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000360",
+            hex"00000000000000000000000000000000000000000000000000000000000000600000000000000000000000000000000000000000000000000000000000000100",
+            hex"00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000062",
+            hex"01010000000a73656c6c2d6561726c7901000000000000000000000000000000000000000000000000000000000000c3b4000000000000000000000000000000",
+            hex"00000000000000000000000000000001f40000018bcfe2a8e0000000057573657231000000000000000000000000000000000000000000000000000000000000",
+            hex"0000000000000000000000000000000000000000000000000000000000000002cd7d39f93a33cc91a82f745ebc76438538d65525eca16336ee57c8a74b6b7459",
+            hex"bb7fbb6c8f46de7dd90d9dca659eef1a06830956eb4c353110cb9a7fe327636b0000000000000000000000000000000000000000000000000000000000000060",
+            hex"00000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000003",
+            hex"000000000000000000000000000000000000000000000000000000000000006101010000000973656c6c2d6c6174650100000000000000000000000000000000",
+            hex"0000000000000000000000000000c3b400000000000000000000000000000000000000000000000000000000000001f40000018bcfe393400000000575736572",
+            hex"32000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002",
+            hex"14debd56330346de33254e96ab04052bebd2eea7933190a24514ad6766f5613b14d1695a7b7eebcab76a0987ad748554572a0d6ab19f815db1704be06a934ee4",
+            hex"000000000000000000000000000000000000000000000000000000000000007701020000000774726164652d31000000056275792d310000000973656c6c2d6c",
+            hex"617465000000000000000000000000000000000000000000000000000000000000c3b40000000000000000000000000000000000000000000000000000000000",
+            hex"00012c0000018bcfe56800000000000000000000000000000000000000000000"
+        );
+    }
+    
     function setUp() public {
         owner = address(this);
         operator = vm.addr(1);
@@ -66,15 +94,16 @@ contract SettlementVerifierTest is Test {
         bytes32 expectedSettlementId = keccak256(abi.encodePacked(TEST_TX_ID, operator, block.timestamp));
         
         vm.expectEmit(true, true, true, true);
-        emit SettlementRegistered(expectedSettlementId, TEST_TX_ID, operator, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        emit SettlementRegistered(expectedSettlementId, TEST_TX_ID, operator, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         
-        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         
         SettlementVerifier.Settlement memory settlement = verifier.getSettlement(expectedSettlementId);
         
         assertEq(settlement.txId, TEST_TX_ID);
         assertEq(settlement.operator, operator);
         assertEq(settlement.snapshotHash, TEST_SNAPSHOT_HASH);
+        assertEq(settlement.orderRoot, TEST_ORDER_ROOT);
         assertEq(settlement.tradeBatchId, TEST_TRADE_BATCH_ID);
         assertEq(uint256(settlement.status), uint256(SettlementVerifier.SettlementStatus.Active));
         assertEq(settlement.challengeDeadline, block.timestamp + 7 days);
@@ -88,7 +117,7 @@ contract SettlementVerifierTest is Test {
         vm.startPrank(operator);
         
         vm.expectRevert("Insufficient stake");
-        verifier.registerSettlement{value: 0.5 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        verifier.registerSettlement{value: 0.5 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         
         vm.stopPrank();
     }
@@ -96,17 +125,17 @@ contract SettlementVerifierTest is Test {
     function testChallengeSettlement() public {
         // First register a settlement
         vm.startPrank(operator);
-        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         vm.stopPrank();
         
         bytes32 settlementId = keccak256(abi.encodePacked(TEST_TX_ID, operator, block.timestamp));
         
         // Challenge the settlement
         vm.startPrank(challenger);
-        bytes memory proof = abi.encode("fraud-proof-data");
+        bytes memory proof = skippedOrderVector();
         
         vm.expectEmit(true, true, true, true);
-        emit SettlementChallenged(settlementId, challenger, TEST_TX_ID);
+        emit SettlementChallenged(settlementId, challenger, TEST_TX_ID, keccak256(abi.encodePacked(settlementId, challenger, block.timestamp)));
         
         verifier.challengeSettlement(settlementId, proof);
         
@@ -119,14 +148,14 @@ contract SettlementVerifierTest is Test {
     function testChallengeSettlementFailsForUnauthorizedChallenger() public {
         // First register a settlement
         vm.startPrank(operator);
-        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         vm.stopPrank();
         
         bytes32 settlementId = keccak256(abi.encodePacked(TEST_TX_ID, operator, block.timestamp));
         
         // Try to challenge with unauthorized user
         vm.startPrank(user);
-        bytes memory proof = abi.encode("fraud-proof-data");
+        bytes memory proof = skippedOrderVector();
         
         vm.expectRevert("Not authorized to challenge");
         verifier.challengeSettlement(settlementId, proof);
@@ -137,14 +166,14 @@ contract SettlementVerifierTest is Test {
     function testResolveSuccessfulChallenge() public {
         // Register settlement
         vm.startPrank(operator);
-        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         vm.stopPrank();
         
         bytes32 settlementId = keccak256(abi.encodePacked(TEST_TX_ID, operator, block.timestamp));
         
         // Challenge settlement
         vm.startPrank(challenger);
-        bytes memory proof = abi.encode("fraud-proof-data");
+        bytes memory proof = skippedOrderVector();
         verifier.challengeSettlement(settlementId, proof);
         vm.stopPrank();
         
@@ -181,14 +210,14 @@ contract SettlementVerifierTest is Test {
     function testResolveUnsuccessfulChallenge() public {
         // Register settlement
         vm.startPrank(operator);
-        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         vm.stopPrank();
         
         bytes32 settlementId = keccak256(abi.encodePacked(TEST_TX_ID, operator, block.timestamp));
         
         // Challenge settlement
         vm.startPrank(challenger);
-        bytes memory proof = abi.encode("fraud-proof-data");
+        bytes memory proof = skippedOrderVector();
         verifier.challengeSettlement(settlementId, proof);
         vm.stopPrank();
         
@@ -218,7 +247,7 @@ contract SettlementVerifierTest is Test {
     function testChallengeAfterDeadlineExpired() public {
         // Register settlement
         vm.startPrank(operator);
-        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         vm.stopPrank();
         
         bytes32 settlementId = keccak256(abi.encodePacked(TEST_TX_ID, operator, block.timestamp));
@@ -228,7 +257,7 @@ contract SettlementVerifierTest is Test {
         
         // Try to challenge after deadline
         vm.startPrank(challenger);
-        bytes memory proof = abi.encode("fraud-proof-data");
+        bytes memory proof = skippedOrderVector();
         
         vm.expectRevert("Challenge period expired");
         verifier.challengeSettlement(settlementId, proof);
@@ -236,10 +265,45 @@ contract SettlementVerifierTest is Test {
         vm.stopPrank();
     }
     
+    function testChallengeWithForeignOrderRoot() public {
+        vm.startPrank(operator);
+        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
+        vm.stopPrank();
+        
+        bytes32 settlementId = keccak256(abi.encodePacked(TEST_TX_ID, operator, block.timestamp));
+        
+        // A well-formed proof over a tree the challenger built, not the registered snapshot
+        // (the tree of testVerifySkippedOrderProof)
+        bytes32[] memory skippedSiblings = new bytes32[](1);
+        skippedSiblings[0] = 0x980458e1759f08525064c5d03949e615a8aa9db3de4d835e16f5161aa2417e2a;
+        bytes32[] memory matchedSiblings = new bytes32[](2);
+        matchedSiblings[0] = keccak256(abi.encodePacked(bytes1(0x00), bytes("order-b")));
+        matchedSiblings[1] = keccak256(abi.encodePacked(bytes1(0x00), bytes("order-c")));
+        bytes memory proof = abi.encode(SettlementVerifier.SkippedOrderProof({
+            version: 1,
+            orderRoot: 0x3fe94767685c81ec1f6496e1ff2bf15a209827d75c1ac05156b5c57ee120ea6e,
+            skipped: SettlementVerifier.OrderProof(bytes("order-c"), skippedSiblings, 1),
+            matched: SettlementVerifier.OrderProof(bytes("order-a"), matchedSiblings, 0),
+            trade: bytes("trade")
+        }));
+        
+        assertTrue(verifier.verifySkippedOrderProof(proof));
+        assertFalse(verifier.verifySettlementProof(settlementId, proof));
+        assertTrue(verifier.verifySettlementProof(settlementId, skippedOrderVector()));
+        
+        vm.startPrank(challenger);
+        vm.expectRevert("Invalid fraud proof");
+        verifier.challengeSettlement(settlementId, proof);
+        vm.stopPrank();
+        
+        SettlementVerifier.Settlement memory settlement = verifier.getSettlement(settlementId);
+        assertEq(uint256(settlement.status), uint256(SettlementVerifier.SettlementStatus.Active));
+    }
+    
     function testWithdrawStake() public {
         // Register settlement and add stake
         vm.startPrank(operator);
-        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         
         uint256 initialBalance = operator.balance;
         
@@ -279,7 +343,7 @@ contract SettlementVerifierTest is Test {
     function testFreezeAndUnfreezeSettlement() public {
         // Register settlement
         vm.startPrank(operator);
-        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_TRADE_BATCH_ID);
+        verifier.registerSettlement{value: 2 ether}(TEST_TX_ID, TEST_SNAPSHOT_HASH, TEST_ORDER_ROOT, TEST_TRADE_BATCH_ID);
         vm.stopPrank();
         
         bytes32 settlementId = keccak256(abi.encodePacked(TEST_TX_ID, operator, block.timestamp));
@@ -296,4 +360,59 @@ contract SettlementVerifierTest is Test {
         settlement = verifier.getSettlement(settlementId);
         assertEq(uint256(settlement.status), uint256(SettlementVerifier.SettlementStatus.Active));
     }
-} 
\ No newline at end of file
+    
+    function testVerifyOrderInclusion() public {
+        // Vector produced by pkg/merkle (TestTree_SolidityVector)
+        bytes32 root = 0x3fe94767685c81ec1f6496e1ff2bf15a209827d75c1ac05156b5c57ee120ea6e;
+        bytes32[] memory siblings = new bytes32[](1);
+        siblings[0] = 0x980458e1759f08525064c5d03949e615a8aa9db3de4d835e16f5161aa2417e2a;
+        
+        assertTrue(verifier.verifyOrderInclusion(root, bytes("order-c"), siblings, 1));
+        assertFalse(verifier.verifyOrderInclusion(root, bytes("order-x"), siblings, 1));
+        assertFalse(verifier.verifyOrderInclusion(root, bytes("order-c"), siblings, 0));
+    }
+    
+    function testVerifySkippedOrderProof() public {
+        // Same tree as testVerifyOrderInclusion: "order-c" skipped in favour of "order-a"
+        bytes32 root = 0x3fe94767685c81ec1f6496e1ff2bf15a209827d75c1ac05156b5c57ee120ea6e;
+        bytes32[] memory skippedSiblings = new bytes32[](1);
+        skippedSiblings[0] = 0x980458e1759f08525064c5d03949e615a8aa9db3de4d835e16f5161aa2417e2a;
+        bytes32[] memory matchedSiblings = new bytes32[](2);
+        matchedSiblings[0] = keccak256(abi.encodePacked(bytes1(0x00), bytes("order-b")));
+        matchedSiblings[1] = keccak256(abi.encodePacked(bytes1(0x00), bytes("order-c")));
+        
+        SettlementVerifier.SkippedOrderProof memory proof = SettlementVerifier.SkippedOrderProof({
+            version: 1,
+            orderRoot: root,
+            skipped: SettlementVerifier.OrderProof(bytes("order-c"), skippedSiblings, 1),
+            matched: SettlementVerifier.OrderProof(bytes("order-a"), matchedSiblings, 0),
+            trade: bytes("trade")
+        });
+        assertTrue(verifier.verifySkippedOrderProof(abi.encode(proof)));
+        
+        proof.matched.order = bytes("order-x");
+        assertFalse(verifier.verifySkippedOrderProof(abi.encode(proof)));
+        
+        proof.version = 2;
+        vm.expectRevert("Unsupported fraud proof version");
+        verifier.verifySkippedOrderProof(abi.encode(proof));
+    }
+    
+    function testSkippedOrderProofGoldenVector() public {
+        bytes memory vector = skippedOrderVector();
+        assertEq(keccak256(vector), 0xfe00bbb2cfb7dd3fbd06e4ce8f96aaeac322bdcf09362ca3372f8c401b2d7d68);
+        assertTrue(verifier.verifySkippedOrderProof(vector));
+        
+        // Re-encoding the decoded struct reproduces the vector byte for byte
+        SettlementVerifier.SkippedOrderProof memory proof = abi.decode(vector, (SettlementVerifier.SkippedOrderProof));
+        assertEq(proof.version, 1);
+        assertEq(proof.orderRoot, TEST_ORDER_ROOT);
+        assertEq(proof.skipped.siblings.length, 2);
+        assertEq(proof.matched.siblings.length, 2);
+        assertEq(abi.encode(proof), vector);
+        
+        // Swapping the orders' positions breaks both inclusion proofs
+        (proof.skipped.path, proof.matched.path) = (proof.matched.path, proof.skipped.path);
+        assertFalse(verifier.verifySkippedOrderProof(abi.encode(proof)));
+    }
+}
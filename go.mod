@@ -5,7 +5,10 @@ go 1.23.6
 require (
 	github.com/Layr-Labs/hourglass-monorepo/ponos v0.0.0-20250516160557-195c62a908e3
 	github.com/Layr-Labs/protocol-apis v1.12.1
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.0
 	go.uber.org/zap v1.27.0
+	golang.org/x/crypto v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,283 @@
+// Package abi implements the subset of the Solidity contract ABI encoding used to exchange
+// data with the AVS contracts: abi.encode of elementary values, bytes, strings, dynamic
+// arrays and tuples.
+package abi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// WordSize is the size of an ABI word in bytes
+const WordSize = 32
+
+// ErrInvalidEncoding is returned when data cannot be decoded as the requested value
+var ErrInvalidEncoding = errors.New("invalid ABI encoding")
+
+// maxUint256 is the largest value of a uint256
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// Value is a single ABI-encoded value. Static values hold their head words; dynamic values
+// hold their tail encoding and are referenced from the head by offset.
+type Value struct {
+	dynamic bool
+	data    []byte
+}
+
+// Dynamic reports whether the value is encoded in the tail of its enclosing tuple
+func (v Value) Dynamic() bool {
+	return v.dynamic
+}
+
+// word returns n as a big-endian ABI word
+func word(n uint64) []byte {
+	w := make([]byte, WordSize)
+	binary.BigEndian.PutUint64(w[WordSize-8:], n)
+	return w
+}
+
+// padRight pads data with zeros to a whole number of words
+func padRight(data []byte) []byte {
+	padded := make([]byte, (len(data)+WordSize-1)/WordSize*WordSize)
+	copy(padded, data)
+	return padded
+}
+
+// Uint64 encodes an unsigned integer of up to 64 bits (uint8 to uint64, or a larger uint)
+func Uint64(v uint64) Value {
+	return Value{data: word(v)}
+}
+
+// Uint256 encodes an unsigned integer of up to 256 bits
+func Uint256(v *big.Int) (Value, error) {
+	if v == nil || v.Sign() < 0 || v.Cmp(maxUint256) > 0 {
+		return Value{}, fmt.Errorf("value %v out of uint256 range", v)
+	}
+	return Value{data: v.FillBytes(make([]byte, WordSize))}, nil
+}
+
+// Bool encodes a boolean
+func Bool(v bool) Value {
+	if v {
+		return Uint64(1)
+	}
+	return Uint64(0)
+}
+
+// Address encodes a 20-byte address
+func Address(a [20]byte) Value {
+	w := make([]byte, WordSize)
+	copy(w[WordSize-len(a):], a[:])
+	return Value{data: w}
+}
+
+// Bytes32 encodes a 32-byte value
+func Bytes32(b [32]byte) Value {
+	return Value{data: append([]byte(nil), b[:]...)}
+}
+
+// FixedBytes encodes a bytesN value of up to 32 bytes, left-aligned in its word
+func FixedBytes(b []byte) (Value, error) {
+	if len(b) == 0 || len(b) > WordSize {
+		return Value{}, fmt.Errorf("fixed bytes length %d out of range", len(b))
+	}
+	return Value{data: padRight(b)}, nil
+}
+
+// Bytes encodes a dynamic byte string
+func Bytes(b []byte) Value {
+	return Value{dynamic: true, data: append(word(uint64(len(b))), padRight(b)...)}
+}
+
+// String encodes a string as its UTF-8 bytes
+func String(s string) Value {
+	return Bytes([]byte(s))
+}
+
+// Array encodes a dynamic array (T[]) of values of the same type
+func Array(values ...Value) Value {
+	return Value{dynamic: true, data: append(word(uint64(len(values))), Encode(values...)...)}
+}
+
+// Tuple encodes a struct. A tuple is dynamic if any of its fields is.
+func Tuple(values ...Value) Value {
+	tuple := Value{data: Encode(values...)}
+	for _, v := range values {
+		tuple.dynamic = tuple.dynamic || v.dynamic
+	}
+	return tuple
+}
+
+// Encode returns abi.encode of the values: the head of every value, followed by the tails
+// of the dynamic ones
+func Encode(values ...Value) []byte {
+	headSize := 0
+	for _, v := range values {
+		if v.dynamic {
+			headSize += WordSize
+		} else {
+			headSize += len(v.data)
+		}
+	}
+
+	head := make([]byte, 0, headSize)
+	var tail []byte
+	for _, v := range values {
+		if v.dynamic {
+			head = append(head, word(uint64(headSize+len(tail)))...)
+			tail = append(tail, v.data...)
+		} else {
+			head = append(head, v.data...)
+		}
+	}
+
+	return append(head, tail...)
+}
+
+// Decoder reads the values of an ABI-encoded tuple by the index of their head word. Indices
+// match field positions as long as every preceding static field is a single word.
+type Decoder struct {
+	data []byte
+}
+
+// NewDecoder returns a decoder over abi.encode output
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data}
+}
+
+// word returns the head word at index i
+func (d *Decoder) word(i int) ([]byte, error) {
+	start := i * WordSize
+	if i < 0 || start+WordSize > len(d.data) {
+		return nil, fmt.Errorf("%w: word %d out of range of %d bytes", ErrInvalidEncoding, i, len(d.data))
+	}
+	return d.data[start : start+WordSize], nil
+}
+
+// offset reads a tail offset or length, which must fit in the data
+func (d *Decoder) offset(w []byte) (int, error) {
+	n := new(big.Int).SetBytes(w)
+	if !n.IsUint64() || n.Uint64() > uint64(len(d.data)) {
+		return 0, fmt.Errorf("%w: offset %s out of range of %d bytes", ErrInvalidEncoding, n.String(), len(d.data))
+	}
+	return int(n.Uint64()), nil
+}
+
+// Uint64 decodes an unsigned integer that must fit in 64 bits
+func (d *Decoder) Uint64(i int) (uint64, error) {
+	v, err := d.Uint256(i)
+	if err != nil {
+		return 0, err
+	}
+	if !v.IsUint64() {
+		return 0, fmt.Errorf("%w: word %d value %s overflows uint64", ErrInvalidEncoding, i, v.String())
+	}
+	return v.Uint64(), nil
+}
+
+// Uint256 decodes an unsigned integer
+func (d *Decoder) Uint256(i int) (*big.Int, error) {
+	w, err := d.word(i)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(w), nil
+}
+
+// Bool decodes a boolean
+func (d *Decoder) Bool(i int) (bool, error) {
+	v, err := d.Uint64(i)
+	if err != nil {
+		return false, err
+	}
+	if v > 1 {
+		return false, fmt.Errorf("%w: word %d is not a bool", ErrInvalidEncoding, i)
+	}
+	return v == 1, nil
+}
+
+// Address decodes a 20-byte address
+func (d *Decoder) Address(i int) ([20]byte, error) {
+	var a [20]byte
+	w, err := d.word(i)
+	if err != nil {
+		return a, err
+	}
+	for _, b := range w[:WordSize-len(a)] {
+		if b != 0 {
+			return a, fmt.Errorf("%w: word %d is not an address", ErrInvalidEncoding, i)
+		}
+	}
+	copy(a[:], w[WordSize-len(a):])
+	return a, nil
+}
+
+// Bytes32 decodes a 32-byte value
+func (d *Decoder) Bytes32(i int) ([32]byte, error) {
+	var b [32]byte
+	w, err := d.word(i)
+	if err != nil {
+		return b, err
+	}
+	copy(b[:], w)
+	return b, nil
+}
+
+// Bytes decodes a dynamic byte string
+func (d *Decoder) Bytes(i int) ([]byte, error) {
+	tail, n, err := d.dynamic(i)
+	if err != nil {
+		return nil, err
+	}
+	if n > len(tail.data)-WordSize {
+		return nil, fmt.Errorf("%w: word %d length %d out of range", ErrInvalidEncoding, i, n)
+	}
+	return append([]byte(nil), tail.data[WordSize:WordSize+n]...), nil
+}
+
+// String decodes a string
+func (d *Decoder) String(i int) (string, error) {
+	b, err := d.Bytes(i)
+	return string(b), err
+}
+
+// Array decodes a dynamic array, returning a decoder over its elements and their count
+func (d *Decoder) Array(i int) (*Decoder, int, error) {
+	tail, n, err := d.dynamic(i)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &Decoder{data: tail.data[WordSize:]}, n, nil
+}
+
+// Tuple returns a decoder over a dynamic tuple
+func (d *Decoder) Tuple(i int) (*Decoder, error) {
+	w, err := d.word(i)
+	if err != nil {
+		return nil, err
+	}
+	start, err := d.offset(w)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{data: d.data[start:]}, nil
+}
+
+// dynamic follows the offset at word i to a length-prefixed tail
+func (d *Decoder) dynamic(i int) (*Decoder, int, error) {
+	tail, err := d.Tuple(i)
+	if err != nil {
+		return nil, 0, err
+	}
+	w, err := tail.word(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := tail.offset(w)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tail, n, nil
+}
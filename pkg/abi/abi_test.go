@@ -0,0 +1,146 @@
+package abi
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestEncode_SolidityDocsVector(t *testing.T) {
+	// abi.encode(uint256 0x123, uint32[] [0x456, 0x789], bytes10 "1234567890", bytes "Hello, world!")
+	// from the Solidity ABI specification
+	fixed, err := FixedBytes([]byte("1234567890"))
+	if err != nil {
+		t.Fatalf("FixedBytes failed: %v", err)
+	}
+
+	encoded := Encode(
+		Uint64(0x123),
+		Array(Uint64(0x456), Uint64(0x789)),
+		fixed,
+		Bytes([]byte("Hello, world!")),
+	)
+
+	want := strings.Join([]string{
+		"0000000000000000000000000000000000000000000000000000000000000123",
+		"0000000000000000000000000000000000000000000000000000000000000080",
+		"3132333435363738393000000000000000000000000000000000000000000000",
+		"00000000000000000000000000000000000000000000000000000000000000e0",
+		"0000000000000000000000000000000000000000000000000000000000000002",
+		"0000000000000000000000000000000000000000000000000000000000000456",
+		"0000000000000000000000000000000000000000000000000000000000000789",
+		"000000000000000000000000000000000000000000000000000000000000000d",
+		"48656c6c6f2c20776f726c642100000000000000000000000000000000000000",
+	}, "")
+	if got := hex.EncodeToString(encoded); got != want {
+		t.Fatalf("Unexpected encoding:\n got %s\nwant %s", got, want)
+	}
+
+	d := NewDecoder(encoded)
+	if v, err := d.Uint64(0); err != nil || v != 0x123 {
+		t.Errorf("Expected 0x123, got %d (%v)", v, err)
+	}
+	elems, n, err := d.Array(1)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 array elements, got %d (%v)", n, err)
+	}
+	if v, err := elems.Uint64(1); err != nil || v != 0x789 {
+		t.Errorf("Expected 0x789, got %d (%v)", v, err)
+	}
+	if s, err := d.String(3); err != nil || s != "Hello, world!" {
+		t.Errorf("Expected Hello, world!, got %q (%v)", s, err)
+	}
+}
+
+func TestEncode_NestedTupleRoundTrip(t *testing.T) {
+	amount, err := Uint256(new(big.Int).Lsh(big.NewInt(1), 200))
+	if err != nil {
+		t.Fatalf("Uint256 failed: %v", err)
+	}
+	var root [32]byte
+	root[31] = 0xaa
+	var addr [20]byte
+	addr[0] = 0x11
+
+	encoded := Encode(Tuple(
+		Bool(true),
+		Bytes32(root),
+		Tuple(Bytes([]byte("leaf")), Array(Bytes32(root)), Uint64(3)),
+		Address(addr),
+		amount,
+	))
+
+	outer, err := NewDecoder(encoded).Tuple(0)
+	if err != nil {
+		t.Fatalf("Tuple failed: %v", err)
+	}
+	if ok, err := outer.Bool(0); err != nil || !ok {
+		t.Errorf("Expected true, got %v (%v)", ok, err)
+	}
+	if got, err := outer.Bytes32(1); err != nil || got != root {
+		t.Errorf("Expected root, got %x (%v)", got, err)
+	}
+	if got, err := outer.Address(3); err != nil || got != addr {
+		t.Errorf("Expected address, got %x (%v)", got, err)
+	}
+	if got, err := outer.Uint256(4); err != nil || got.BitLen() != 201 {
+		t.Errorf("Expected 2^200, got %v (%v)", got, err)
+	}
+
+	inner, err := outer.Tuple(2)
+	if err != nil {
+		t.Fatalf("inner Tuple failed: %v", err)
+	}
+	if leaf, err := inner.Bytes(0); err != nil || string(leaf) != "leaf" {
+		t.Errorf("Expected leaf, got %q (%v)", leaf, err)
+	}
+	siblings, n, err := inner.Array(1)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 sibling, got %d (%v)", n, err)
+	}
+	if got, err := siblings.Bytes32(0); err != nil || got != root {
+		t.Errorf("Expected sibling root, got %x (%v)", got, err)
+	}
+	if path, err := inner.Uint64(2); err != nil || path != 3 {
+		t.Errorf("Expected path 3, got %d (%v)", path, err)
+	}
+}
+
+func TestEncode_InvalidValues(t *testing.T) {
+	if _, err := Uint256(big.NewInt(-1)); err == nil {
+		t.Errorf("Expected negative uint256 to be rejected")
+	}
+	if _, err := Uint256(new(big.Int).Lsh(big.NewInt(1), 256)); err == nil {
+		t.Errorf("Expected 2^256 to be rejected")
+	}
+	if _, err := FixedBytes(make([]byte, 33)); err == nil {
+		t.Errorf("Expected 33 fixed bytes to be rejected")
+	}
+}
+
+func TestDecoder_Malformed(t *testing.T) {
+	encoded := Encode(Bytes([]byte("Hello, world!")))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated head", encoded[:16]},
+		{"truncated tail", encoded[:len(encoded)-WordSize]},
+		{"offset past end", append(word(1<<20), encoded[WordSize:]...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDecoder(tt.data).Bytes(0); !errors.Is(err, ErrInvalidEncoding) {
+				t.Errorf("Expected ErrInvalidEncoding, got %v", err)
+			}
+		})
+	}
+
+	if _, err := NewDecoder(word(2)).Bool(0); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Expected non-boolean word to be rejected, got %v", err)
+	}
+}
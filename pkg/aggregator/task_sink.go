@@ -0,0 +1,276 @@
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/fsutil"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/mailbox"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"go.uber.org/zap"
+)
+
+// Task delivery statuses recorded in TaskSubmissionResult.Status
+const (
+	TaskStatusSubmitted = "submitted" // Included on-chain, awaiting confirmations
+	TaskStatusConfirmed = "confirmed" // Delivered; final for the file sink
+	TaskStatusCompleted = "completed" // The executors' result was accepted by the mailbox
+	TaskStatusExpired   = "expired"   // The task SLA passed without a result
+	TaskStatusCanceled  = "canceled"  // The task was canceled by its creator
+	TaskStatusFailed    = "failed"    // Submission failed or the task disappeared from the chain
+)
+
+// TaskSink delivers verification tasks to the executors and writes the task records read by
+// GetTaskSubmissions and SimulateTaskExecution
+type TaskSink interface {
+	// SubmitTask delivers the payload of a task, assigns its task ID and records its delivery
+	// state. Failed submissions are recorded too before the error is returned.
+	SubmitTask(ctx context.Context, task *TaskSubmissionResult, payload []byte) error
+	// ConfirmTask refreshes the status of a submitted or confirmed task and records it
+	ConfirmTask(ctx context.Context, task *TaskSubmissionResult) error
+	// CanRetry reports whether a failed task should be submitted again
+	CanRetry(task *TaskSubmissionResult) bool
+}
+
+// writeTaskRecord atomically writes the record of a task to task_<id>.json in dir
+func writeTaskRecord(dir string, task *TaskSubmissionResult) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create task directory: %v", err)
+	}
+	taskFile := filepath.Join(dir, fmt.Sprintf("task_%s.json", task.TaskID))
+	taskData, err := json.MarshalIndent(task, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal task result: %v", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(taskFile, taskData, 0644); err != nil {
+		return fmt.Errorf("failed to write task file: %v", err)
+	}
+	return nil
+}
+
+// FileTaskSink is a mock sink that only writes task records to a local directory, with task
+// IDs derived from the market, sequence and submission time
+type FileTaskSink struct {
+	dir string
+}
+
+// NewFileTaskSink creates a sink writing task records to dir
+func NewFileTaskSink(dir string) *FileTaskSink {
+	return &FileTaskSink{dir: dir}
+}
+
+// SubmitTask implements TaskSink. The task is confirmed as soon as its record is written.
+func (s *FileTaskSink) SubmitTask(ctx context.Context, task *TaskSubmissionResult, payload []byte) error {
+	task.TaskID = fmt.Sprintf("task-%s-%d-%d", task.Snapshot.MarketID, task.Snapshot.SequenceNumber, time.Now().Unix())
+	task.Status = TaskStatusConfirmed
+	task.Attempts = 1
+	return writeTaskRecord(s.dir, task)
+}
+
+// ConfirmTask implements TaskSink; file tasks are confirmed on submission
+func (s *FileTaskSink) ConfirmTask(ctx context.Context, task *TaskSubmissionResult) error {
+	return nil
+}
+
+// CanRetry implements TaskSink; file tasks only fail when their record cannot be written, so
+// there is no record to retry
+func (s *FileTaskSink) CanRetry(task *TaskSubmissionResult) bool {
+	return false
+}
+
+// MailboxConfig configures task creation on the TaskMailbox
+type MailboxConfig struct {
+	Mailbox               chain.Address `yaml:"mailbox"` // TaskMailbox deployment on L2
+	From                  chain.Address `yaml:"from"`    // Account sending createTask transactions
+	AVS                   chain.Address `yaml:"avs"`
+	ExecutorOperatorSetID uint32        `yaml:"executor_operator_set_id"`
+	RefundCollector       chain.Address `yaml:"refund_collector"`
+	AVSFee                *big.Int      `yaml:"avs_fee"`       // nil for no fee
+	MaxAttempts           int           `yaml:"max_attempts"`  // Attempts per task, including requeued ones; 3 if unset
+	RetryBackoff          time.Duration `yaml:"retry_backoff"` // Wait before the first retry, doubled for each further one; 1s if unset
+	Confirmations         uint64        `yaml:"confirmations"` // Blocks, including the one holding the task, before it is confirmed; 1 if unset
+}
+
+// MailboxTaskSink creates tasks on the Hourglass TaskMailbox and records the task hash
+// assigned by the mailbox as the task ID
+type MailboxTaskSink struct {
+	logger  *zap.Logger
+	backend mailbox.Backend
+	mailbox *mailbox.TaskMailbox
+	config  MailboxConfig
+	dir     string
+}
+
+// NewMailboxTaskSink creates a sink submitting tasks through backend and writing task records
+// to dir
+func NewMailboxTaskSink(logger *zap.Logger, backend mailbox.Backend, config MailboxConfig, dir string) *MailboxTaskSink {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Second
+	}
+	if config.Confirmations == 0 {
+		config.Confirmations = 1
+	}
+
+	return &MailboxTaskSink{
+		logger:  logger,
+		backend: backend,
+		mailbox: mailbox.NewTaskMailbox(config.Mailbox, backend),
+		config:  config,
+		dir:     dir,
+	}
+}
+
+// SubmitTask implements TaskSink. Transient errors are retried with exponential backoff;
+// reverts are not, as the same transaction would revert again. A task that could not be
+// created is recorded as failed under its batch ID, and is requeued by the submitter until
+// it has used MaxAttempts attempts.
+func (s *MailboxTaskSink) SubmitTask(ctx context.Context, task *TaskSubmissionResult, payload []byte) error {
+	params := mailbox.TaskParams{
+		RefundCollector:     s.config.RefundCollector,
+		AVSFee:              s.config.AVSFee,
+		ExecutorOperatorSet: mailbox.OperatorSet{AVS: s.config.AVS, ID: s.config.ExecutorOperatorSetID},
+		Payload:             payload,
+	}
+
+	backoff := s.config.RetryBackoff
+	var err error
+	for task.Attempts < s.config.MaxAttempts {
+		if task.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				return s.recordFailure(task, ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		task.Attempts++
+
+		var created *createdTask
+		if created, err = s.createTask(ctx, params); err == nil {
+			task.TaskID = created.TaskHash.Hex()
+			task.TxHash = created.txHash.Hex()
+			task.BlockNumber = created.blockNumber
+			task.Status = TaskStatusSubmitted
+			task.LastError = ""
+
+			s.logger.Sugar().Infow("Task created on mailbox",
+				"task_hash", task.TaskID,
+				"tx_hash", task.TxHash,
+				"block_number", task.BlockNumber,
+				"attempts", task.Attempts,
+			)
+			return writeTaskRecord(s.dir, task)
+		}
+
+		s.logger.Sugar().Warnw("Failed to create task on mailbox",
+			"batch_id", task.BatchID,
+			"attempt", task.Attempts,
+			"max_attempts", s.config.MaxAttempts,
+			"error", err,
+		)
+		if errors.Is(err, chain.ErrReverted) {
+			break
+		}
+	}
+
+	return s.recordFailure(task, err)
+}
+
+// createdTask is a TaskCreated event with the transaction that emitted it
+type createdTask struct {
+	*mailbox.TaskCreated
+	txHash      merkle.Hash
+	blockNumber uint64
+}
+
+// createTask sends createTask and returns the task the mailbox created
+func (s *MailboxTaskSink) createTask(ctx context.Context, params mailbox.TaskParams) (*createdTask, error) {
+	receipt, err := s.mailbox.CreateTask(ctx, chain.TransactOpts{From: s.config.From}, params)
+	if err != nil {
+		return nil, err
+	}
+	for _, log := range receipt.Logs {
+		if event, err := s.mailbox.ParseTaskCreated(log); err == nil {
+			return &createdTask{TaskCreated: event, txHash: receipt.TxHash, blockNumber: receipt.BlockNumber}, nil
+		}
+	}
+	return nil, fmt.Errorf("createTask %s emitted no TaskCreated event", receipt.TxHash)
+}
+
+// recordFailure records a task whose submission failed and returns the error. A task that
+// had no attempts left fails without a new error.
+func (s *MailboxTaskSink) recordFailure(task *TaskSubmissionResult, err error) error {
+	if err == nil {
+		err = fmt.Errorf("no attempts left of %d", s.config.MaxAttempts)
+	}
+	task.TaskID = task.BatchID
+	task.Status = TaskStatusFailed
+	task.LastError = err.Error()
+	if werr := writeTaskRecord(s.dir, task); werr != nil {
+		return fmt.Errorf("failed to create task after %d attempts: %v (%v)", task.Attempts, err, werr)
+	}
+	return fmt.Errorf("failed to create task after %d attempts: %v", task.Attempts, err)
+}
+
+// CanRetry implements TaskSink. Failed tasks are retried until they have used MaxAttempts
+// attempts, counting those of earlier submissions.
+func (s *MailboxTaskSink) CanRetry(task *TaskSubmissionResult) bool {
+	return task.Status == TaskStatusFailed && task.Attempts < s.config.MaxAttempts
+}
+
+// ConfirmTask implements TaskSink. A task is confirmed once the configured number of blocks
+// holds it, and then follows its status on the mailbox; a task the mailbox no longer knows,
+// e.g. after a reorg, is marked failed.
+func (s *MailboxTaskSink) ConfirmTask(ctx context.Context, task *TaskSubmissionResult) error {
+	taskHash, err := merkle.ParseHash(task.TaskID)
+	if err != nil {
+		return fmt.Errorf("invalid task hash: %v", err)
+	}
+	status, err := s.mailbox.GetTaskStatus(ctx, taskHash)
+	if err != nil {
+		return fmt.Errorf("failed to get task status: %v", err)
+	}
+	head, err := s.backend.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %v", err)
+	}
+
+	previous := task.Status
+	switch status {
+	case mailbox.TaskStatusNone:
+		task.Status = TaskStatusFailed
+		task.LastError = "task no longer exists on the mailbox"
+	case mailbox.TaskStatusCreated:
+		if head+1 >= task.BlockNumber+s.config.Confirmations {
+			task.Status = TaskStatusConfirmed
+		}
+	case mailbox.TaskStatusVerified:
+		task.Status = TaskStatusCompleted
+	case mailbox.TaskStatusExpired:
+		task.Status = TaskStatusExpired
+	case mailbox.TaskStatusCanceled:
+		task.Status = TaskStatusCanceled
+	}
+	if task.Status == previous {
+		return nil
+	}
+
+	s.logger.Sugar().Infow("Task status changed",
+		"task_hash", task.TaskID,
+		"from", previous,
+		"to", task.Status,
+		"block_number", head,
+	)
+	return writeTaskRecord(s.dir, task)
+}
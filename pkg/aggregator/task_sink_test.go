@@ -0,0 +1,196 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/mailbox"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/publisher"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/store"
+	"go.uber.org/zap"
+)
+
+var (
+	testAVS         = chain.Address{0xa5}
+	testAggregator  = chain.Address{0xa6}
+	testOperatorSet = mailbox.OperatorSet{AVS: testAVS, ID: 1}
+	errConnection   = errors.New("connection reset by peer")
+)
+
+// newMailboxSubmitter creates a submitter for a single published snapshot of MARKET-A that
+// delivers tasks to a simulated mailbox
+func newMailboxSubmitter(t *testing.T, registered bool) (*TaskSubmitter, *mailbox.SimulatedBackend) {
+	t.Helper()
+	logger, _ := zap.NewDevelopment()
+
+	snapshotStore := store.NewMemoryStore()
+	pub := publisher.NewSnapshotPublisherWithStore(logger, snapshotStore)
+	orders, trades := pub.GenerateSampleData("MARKET-A")
+	if _, err := pub.PublishSnapshot("MARKET-A", orders, trades); err != nil {
+		t.Fatalf("PublishSnapshot failed: %v", err)
+	}
+
+	backend := mailbox.NewSimulatedBackend(31337, time.Unix(1700000000, 0), time.Minute)
+	if registered {
+		backend.RegisterExecutorOperatorSet(testOperatorSet)
+	}
+
+	taskDir := t.TempDir()
+	sink := NewMailboxTaskSink(logger, backend, MailboxConfig{
+		Mailbox:               backend.ContractAddress(),
+		From:                  testAggregator,
+		AVS:                   testAVS,
+		ExecutorOperatorSetID: testOperatorSet.ID,
+		RefundCollector:       testAggregator,
+		RetryBackoff:          time.Millisecond,
+		Confirmations:         2,
+	}, taskDir)
+	return NewTaskSubmitterWithSink(logger, snapshotStore, taskDir, nil, sink), backend
+}
+
+// onlyTask returns the single task record of a submitter
+func onlyTask(t *testing.T, submitter *TaskSubmitter) *TaskSubmissionResult {
+	t.Helper()
+	tasks, err := submitter.GetTaskSubmissions()
+	if err != nil {
+		t.Fatalf("GetTaskSubmissions failed: %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("Expected 1 task record, got %d", len(tasks))
+	}
+	return tasks[0]
+}
+
+func TestMailboxTaskSink_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	submitter, backend := newMailboxSubmitter(t, true)
+
+	backend.FailNextSends(errConnection)
+	if err := submitter.processSnapshot(ctx, "MARKET-A", 1); err != nil {
+		t.Fatalf("processSnapshot failed: %v", err)
+	}
+
+	task := onlyTask(t, submitter)
+	if task.Status != TaskStatusSubmitted || task.Attempts != 2 || task.TxHash == "" || task.BlockNumber != 1 {
+		t.Fatalf("Unexpected task record %+v", task)
+	}
+	taskHash, err := merkle.ParseHash(task.TaskID)
+	if err != nil {
+		t.Fatalf("Expected the task hash as task ID, got %q: %v", task.TaskID, err)
+	}
+	status, err := mailbox.NewTaskMailbox(backend.ContractAddress(), backend).GetTaskStatus(ctx, taskHash)
+	if err != nil || status != mailbox.TaskStatusCreated {
+		t.Fatalf("Expected task created on the mailbox, got %d (%v)", status, err)
+	}
+
+	steps := []struct {
+		name    string
+		advance func()
+		want    string
+	}{
+		{"one confirmation", func() {}, TaskStatusSubmitted},
+		{"two confirmations", func() { backend.Mine(1) }, TaskStatusConfirmed},
+		{"result submitted", func() { backend.SetTaskStatus(taskHash, mailbox.TaskStatusVerified) }, TaskStatusCompleted},
+	}
+	for _, step := range steps {
+		step.advance()
+		if err := submitter.ConfirmTasks(ctx); err != nil {
+			t.Fatalf("ConfirmTasks failed: %v", err)
+		}
+		if got := onlyTask(t, submitter).Status; got != step.want {
+			t.Errorf("%s: expected status %s, got %s", step.name, step.want, got)
+		}
+	}
+
+	result, err := submitter.SimulateTaskExecution(task.TaskID)
+	if err != nil {
+		t.Fatalf("SimulateTaskExecution failed: %v", err)
+	}
+	if result.TotalTrades != len(task.Trades) {
+		t.Errorf("Expected %d trades verified, got %d", len(task.Trades), result.TotalTrades)
+	}
+}
+
+func TestMailboxTaskSink_Failures(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		registered   bool
+		sendErrs     []error
+		wantAttempts int
+		wantError    string
+	}{
+		{"revert is not retried", false, nil, 1, "ExecutorOperatorSetTaskConfigNotSet"},
+		{"transient errors exhaust attempts", true, []error{errConnection, errConnection, errConnection}, 3, errConnection.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submitter, backend := newMailboxSubmitter(t, tt.registered)
+			backend.FailNextSends(tt.sendErrs...)
+
+			if err := submitter.processSnapshot(ctx, "MARKET-A", 1); err == nil {
+				t.Fatal("Expected submission to fail")
+			}
+
+			task := onlyTask(t, submitter)
+			if task.Status != TaskStatusFailed || task.Attempts != tt.wantAttempts || task.TaskID != task.BatchID ||
+				!strings.Contains(task.LastError, tt.wantError) {
+				t.Errorf("Unexpected task record %+v", task)
+			}
+			if submitter.LastSequence("MARKET-A") != 0 {
+				t.Errorf("Expected the failed sequence not to be processed, got %d", submitter.LastSequence("MARKET-A"))
+			}
+
+			// The next refresh requeues the task if it has attempts left
+			backend.RegisterExecutorOperatorSet(testOperatorSet)
+			if err := submitter.ConfirmTasks(ctx); err != nil {
+				t.Fatalf("ConfirmTasks failed: %v", err)
+			}
+			task = onlyTask(t, submitter)
+			if tt.wantAttempts < 3 {
+				if task.Status != TaskStatusSubmitted || task.Attempts != tt.wantAttempts+1 || task.TaskID == task.BatchID {
+					t.Errorf("Expected the task to be requeued and created, got %+v", task)
+				}
+				if submitter.LastSequence("MARKET-A") != 1 {
+					t.Errorf("Expected the requeued sequence to be processed, got %d", submitter.LastSequence("MARKET-A"))
+				}
+			} else if task.Status != TaskStatusFailed || task.Attempts != 3 {
+				t.Errorf("Expected a task without attempts left to stay failed, got %+v", task)
+			}
+		})
+	}
+
+	t.Run("no attempts left", func(t *testing.T) {
+		submitter, _ := newMailboxSubmitter(t, true)
+		task := &TaskSubmissionResult{BatchID: "batch-MARKET-A-1", Attempts: 3}
+		if err := submitter.sink.SubmitTask(ctx, task, []byte("payload")); err == nil || !strings.Contains(err.Error(), "no attempts left") {
+			t.Errorf("Expected submission without attempts left to fail, got: %v", err)
+		}
+		if task.Status != TaskStatusFailed || task.Attempts != 3 {
+			t.Errorf("Unexpected task record %+v", task)
+		}
+	})
+
+	t.Run("task dropped from the chain", func(t *testing.T) {
+		submitter, backend := newMailboxSubmitter(t, true)
+		if err := submitter.processSnapshot(ctx, "MARKET-A", 1); err != nil {
+			t.Fatalf("processSnapshot failed: %v", err)
+		}
+
+		taskHash, _ := merkle.ParseHash(onlyTask(t, submitter).TaskID)
+		backend.SetTaskStatus(taskHash, mailbox.TaskStatusNone)
+		if err := submitter.ConfirmTasks(ctx); err != nil {
+			t.Fatalf("ConfirmTasks failed: %v", err)
+		}
+		if task := onlyTask(t, submitter); task.Status != TaskStatusFailed || task.LastError == "" {
+			t.Errorf("Expected dropped task to be marked failed, got %+v", task)
+		}
+	})
+}
@@ -6,202 +6,415 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/cas"
 	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
 	"github.com/Layr-Labs/hourglass-avs-template/pkg/publisher"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/store"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/taskpayload"
 	"go.uber.org/zap"
 )
 
-// TaskSubmitter watches for new snapshots and submits verification tasks
+// TaskSubmitter watches for new snapshots and submits verification tasks. Progress is
+// tracked independently for every market.
 type TaskSubmitter struct {
-	logger       *zap.Logger
-	snapshotDir  string
-	publisher    *publisher.SnapshotPublisher
-	lastSequence uint64
+	logger         *zap.Logger
+	store          store.SnapshotStore
+	taskDir        string
+	publisher      *publisher.SnapshotPublisher
+	allowedMarkets []string
+	lastSequence   map[string]uint64
+	pending        map[string]map[uint64]bool // Sequences whose task could not be built yet
+	sink           TaskSink
+	encoding       string
+	contentStore   cas.Store
 }
 
 // TaskSubmissionResult represents the result of submitting a task
 type TaskSubmissionResult struct {
 	TaskID       string                              `json:"task_id"`
+	MarketID     string                              `json:"market_id"`
 	SnapshotHash string                              `json:"snapshot_hash"`
 	Snapshot     *orderbookchecker.OrderbookSnapshot `json:"snapshot"`
 	Trades       []orderbookchecker.Trade            `json:"trades"`
 	BatchID      string                              `json:"batch_id"`
 	SubmittedAt  time.Time                           `json:"submitted_at"`
+
+	// Delivery state recorded by the TaskSink
+	Status      string `json:"status,omitempty"`
+	TxHash      string `json:"tx_hash,omitempty"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	Attempts    int    `json:"attempts,omitempty"`
+	LastError   string `json:"last_error,omitempty"`
 }
 
-// NewTaskSubmitter creates a new task submitter
+// NewTaskSubmitter creates a new task submitter watching every market in a local snapshot
+// directory. Task records are written to the same directory.
 func NewTaskSubmitter(logger *zap.Logger, snapshotDir string) *TaskSubmitter {
-	pub := publisher.NewSnapshotPublisher(logger, snapshotDir)
+	return NewTaskSubmitterWithStore(logger, store.NewFSStore(snapshotDir), snapshotDir, nil)
+}
+
+// NewTaskSubmitterWithStore creates a new task submitter watching the given snapshot store.
+// If allowedMarkets is empty every market in the store is watched, including markets that
+// appear later; otherwise only the listed markets are. Task records are written to taskDir.
+func NewTaskSubmitterWithStore(logger *zap.Logger, snapshotStore store.SnapshotStore, taskDir string, allowedMarkets []string) *TaskSubmitter {
+	return NewTaskSubmitterWithSink(logger, snapshotStore, taskDir, allowedMarkets, NewFileTaskSink(taskDir))
+}
 
+// NewTaskSubmitterWithSink creates a new task submitter delivering tasks to sink. The sink
+// must write its task records to taskDir.
+func NewTaskSubmitterWithSink(logger *zap.Logger, snapshotStore store.SnapshotStore, taskDir string, allowedMarkets []string, sink TaskSink) *TaskSubmitter {
 	return &TaskSubmitter{
-		logger:       logger,
-		snapshotDir:  snapshotDir,
-		publisher:    pub,
-		lastSequence: 0,
+		logger:         logger,
+		store:          snapshotStore,
+		taskDir:        taskDir,
+		publisher:      publisher.NewSnapshotPublisherWithStore(logger, snapshotStore),
+		allowedMarkets: allowedMarkets,
+		lastSequence:   make(map[string]uint64),
+		pending:        make(map[string]map[uint64]bool),
+		sink:           sink,
+		encoding:       taskpayload.EncodingJSON,
 	}
 }
 
-// WatchAndSubmit watches for new snapshots and submits verification tasks
-func (ts *TaskSubmitter) WatchAndSubmit(ctx context.Context, interval time.Duration) error {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// SetPayloadEncoding selects the encoding of subsequently submitted task payloads, one of
+// the taskpayload encodings. Payloads are JSON by default.
+func (ts *TaskSubmitter) SetPayloadEncoding(encoding string) error {
+	if err := taskpayload.ValidateEncoding(encoding); err != nil {
+		return err
+	}
+	ts.encoding = encoding
+	return nil
+}
+
+// SetContentStore makes subsequently submitted tasks reference payloads: the snapshot and
+// trade batch are published to contentStore and the payload carries only their hashes and
+// locators. A nil store restores inline payloads.
+func (ts *TaskSubmitter) SetContentStore(contentStore cas.Store) {
+	ts.contentStore = contentStore
+}
+
+// LastSequence returns the last sequence processed for a market
+func (ts *TaskSubmitter) LastSequence(marketID string) uint64 {
+	return ts.lastSequence[marketID]
+}
 
+// marketEvent is a watch event tagged with its market
+type marketEvent struct {
+	marketID string
+	event    store.WatchEvent
+}
+
+// WatchAndSubmit watches for newly committed snapshots and submits verification tasks.
+// Every market is watched separately; new markets are discovered, snapshots whose task could
+// not be built retried, and the status of pending tasks refreshed, at each interval, which
+// must be positive.
+func (ts *TaskSubmitter) WatchAndSubmit(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("watch interval must be positive, got %s", interval)
+	}
 	ts.logger.Info("Starting snapshot watcher",
-		zap.String("snapshot_dir", ts.snapshotDir),
+		zap.Strings("allowed_markets", ts.allowedMarkets),
 		zap.Duration("interval", interval),
 	)
 
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	events := make(chan marketEvent)
+	watching := make(map[string]bool)
+
+	// Only committed sequences are reported, so the watcher never sees a snapshot that is
+	// still being written
+	watchNewMarkets := func() {
+		markets, err := ts.markets(ctx)
+		if err != nil {
+			ts.logger.Error("Failed to list markets", zap.Error(err))
+			return
+		}
+
+		for _, marketID := range markets {
+			if watching[marketID] {
+				continue
+			}
+			watching[marketID] = true
+
+			ts.logger.Info("Watching market", zap.String("market_id", marketID))
+			go forwardEvents(ctx, marketID, ts.store.Watch(ctx, marketID, ts.lastSequence[marketID], interval), events)
+		}
+	}
+	watchNewMarkets()
+
 	for {
 		select {
 		case <-ctx.Done():
 			ts.logger.Info("Stopping snapshot watcher")
 			return ctx.Err()
 		case <-ticker.C:
-			if err := ts.checkForNewSnapshots(); err != nil {
-				ts.logger.Error("Failed to check for new snapshots", zap.Error(err))
+			watchNewMarkets()
+			ts.retryPending(ctx)
+			if err := ts.ConfirmTasks(ctx); err != nil {
+				ts.logger.Error("Failed to confirm tasks", zap.Error(err))
+			}
+		case e := <-events:
+			if e.event.Err != nil {
+				ts.logger.Error("Failed to check for new snapshots",
+					zap.String("market_id", e.marketID),
+					zap.Error(e.event.Err),
+				)
+				continue
 			}
+
+			// The watch does not report a sequence twice: a task the sink failed to deliver
+			// is requeued by ConfirmTasks from its record, and a snapshot whose task could
+			// not be built is kept pending and retried at the next interval
+			if err := ts.processSnapshot(ctx, e.marketID, e.event.Sequence); err != nil {
+				ts.logger.Error("Failed to process snapshot",
+					zap.String("market_id", e.marketID),
+					zap.Uint64("sequence", e.event.Sequence),
+					zap.Error(err),
+				)
+				continue
+			}
+			ts.advance(e.marketID, e.event.Sequence)
 		}
 	}
 }
 
-// checkForNewSnapshots scans for new snapshot files and submits tasks
-func (ts *TaskSubmitter) checkForNewSnapshots() error {
-	files, err := os.ReadDir(ts.snapshotDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Directory doesn't exist yet, nothing to do
-			return nil
+// retryPending processes again the snapshots whose task could not be built, oldest first
+func (ts *TaskSubmitter) retryPending(ctx context.Context) {
+	for marketID, sequences := range ts.pending {
+		retry := make([]uint64, 0, len(sequences))
+		for sequence := range sequences {
+			retry = append(retry, sequence)
 		}
-		return fmt.Errorf("failed to read snapshot directory: %v", err)
-	}
-
-	var maxSequence uint64
-	var newSnapshots []uint64
+		sort.Slice(retry, func(i, j int) bool { return retry[i] < retry[j] })
 
-	// Find all snapshot files and determine the latest sequence number
-	for _, file := range files {
-		if !strings.HasPrefix(file.Name(), "snapshot_") || !strings.HasSuffix(file.Name(), ".json") {
-			continue
+		for _, sequence := range retry {
+			ts.logger.Info("Retrying snapshot",
+				zap.String("market_id", marketID),
+				zap.Uint64("sequence", sequence),
+			)
+			if err := ts.processSnapshot(ctx, marketID, sequence); err != nil {
+				ts.logger.Error("Failed to process snapshot",
+					zap.String("market_id", marketID),
+					zap.Uint64("sequence", sequence),
+					zap.Error(err),
+				)
+				continue
+			}
+			ts.advance(marketID, sequence)
 		}
+	}
+}
 
-		// Extract sequence number from filename
-		seqStr := strings.TrimPrefix(file.Name(), "snapshot_")
-		seqStr = strings.TrimSuffix(seqStr, ".json")
-
-		sequence, err := strconv.ParseUint(seqStr, 10, 64)
-		if err != nil {
-			ts.logger.Warn("Invalid snapshot filename", zap.String("filename", file.Name()))
-			continue
+// setPending records whether a snapshot's task still has to be built
+func (ts *TaskSubmitter) setPending(marketID string, sequence uint64, pending bool) {
+	if pending {
+		if ts.pending[marketID] == nil {
+			ts.pending[marketID] = make(map[uint64]bool)
 		}
+		ts.pending[marketID][sequence] = true
+		return
+	}
+	delete(ts.pending[marketID], sequence)
+	if len(ts.pending[marketID]) == 0 {
+		delete(ts.pending, marketID)
+	}
+}
 
-		if sequence > maxSequence {
-			maxSequence = sequence
-		}
+// advance records a processed sequence of a market; sequences retried out of order do not
+// move the market back
+func (ts *TaskSubmitter) advance(marketID string, sequence uint64) {
+	if sequence > ts.lastSequence[marketID] {
+		ts.lastSequence[marketID] = sequence
+	}
+}
 
-		if sequence > ts.lastSequence {
-			newSnapshots = append(newSnapshots, sequence)
-		}
+// markets returns the markets to watch: the allow-list if set, otherwise every market in
+// the store
+func (ts *TaskSubmitter) markets(ctx context.Context) ([]string, error) {
+	if len(ts.allowedMarkets) > 0 {
+		return ts.allowedMarkets, nil
 	}
+	return ts.store.ListMarkets(ctx)
+}
 
-	// Process new snapshots
-	for _, sequence := range newSnapshots {
-		if err := ts.processSnapshot(sequence); err != nil {
-			ts.logger.Error("Failed to process snapshot",
-				zap.Uint64("sequence", sequence),
-				zap.Error(err),
-			)
+// forwardEvents tags the events of a market watch and forwards them to out
+func forwardEvents(ctx context.Context, marketID string, in <-chan store.WatchEvent, out chan<- marketEvent) {
+	for event := range in {
+		select {
+		case out <- marketEvent{marketID: marketID, event: event}:
+		case <-ctx.Done():
+			return
 		}
 	}
+}
 
-	ts.lastSequence = maxSequence
-	return nil
+// processSnapshot processes a single snapshot and submits a verification task. A snapshot
+// whose task cannot be built is left pending until it is processed again; once the task
+// reaches the sink, a failed delivery is left to its record.
+func (ts *TaskSubmitter) processSnapshot(ctx context.Context, marketID string, sequence uint64) error {
+	ts.logger.Info("Processing new snapshot",
+		zap.String("market_id", marketID),
+		zap.Uint64("sequence", sequence),
+	)
+
+	result, payload, err := ts.buildTask(ctx, marketID, sequence)
+	ts.setPending(marketID, sequence, err != nil)
+	if err != nil {
+		return err
+	}
+	return ts.deliver(ctx, result, payload, nil)
 }
 
-// processSnapshot processes a single snapshot and submits a verification task
-func (ts *TaskSubmitter) processSnapshot(sequence uint64) error {
-	ts.logger.Info("Processing new snapshot", zap.Uint64("sequence", sequence))
+// submit builds the task of a snapshot and delivers it to the sink. A requeued task passes
+// its previous record, whose attempts count towards the new submission and which is
+// replaced by the new record.
+func (ts *TaskSubmitter) submit(ctx context.Context, marketID string, sequence uint64, previous *TaskSubmissionResult) error {
+	result, payload, err := ts.buildTask(ctx, marketID, sequence)
+	if err != nil {
+		return err
+	}
+	return ts.deliver(ctx, result, payload, previous)
+}
+
+// buildTask loads a snapshot and its trades and returns the task record and payload
+func (ts *TaskSubmitter) buildTask(ctx context.Context, marketID string, sequence uint64) (*TaskSubmissionResult, []byte, error) {
 
 	// Load snapshot
-	snapshot, err := ts.publisher.LoadSnapshot(sequence)
+	snapshot, err := ts.store.Get(ctx, marketID, sequence)
 	if err != nil {
-		return fmt.Errorf("failed to load snapshot: %v", err)
+		return nil, nil, fmt.Errorf("failed to load snapshot: %v", err)
 	}
 
-	// Load trades (may not exist for all snapshots)
-	trades, err := ts.publisher.LoadTrades(sequence)
+	// Load trades (empty for snapshots without trades)
+	trades, err := ts.store.GetTrades(ctx, marketID, sequence)
 	if err != nil {
-		// If trades file doesn't exist, continue with empty trades
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("failed to load trades: %v", err)
-		}
-		trades = []orderbookchecker.Trade{}
+		return nil, nil, fmt.Errorf("failed to load trades: %v", err)
 	}
 
 	// Create task input
-	batchID := fmt.Sprintf("batch-%d", sequence)
-	taskInput := ts.publisher.CreateTaskInput(snapshot, trades, batchID)
+	batchID := fmt.Sprintf("batch-%s-%d", marketID, sequence)
+	taskInput, err := ts.publisher.CreateTaskInput(snapshot, trades, batchID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create task input: %v", err)
+	}
+
+	payload, err := ts.encodePayload(ctx, taskInput)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &TaskSubmissionResult{
+		MarketID:     snapshot.MarketID,
+		SnapshotHash: taskInput.SnapshotHash,
+		Snapshot:     snapshot,
+		Trades:       trades,
+		BatchID:      batchID,
+		SubmittedAt:  time.Now().UTC(),
+	}, payload, nil
+}
 
-	// Submit task (in a real implementation, this would submit to the TaskMailbox)
-	result, err := ts.submitVerificationTask(taskInput, snapshot, trades, batchID)
+// deliver hands a built task to the sink, replacing the record of the task it requeues
+func (ts *TaskSubmitter) deliver(ctx context.Context, result *TaskSubmissionResult, payload []byte, previous *TaskSubmissionResult) error {
+	if previous != nil {
+		result.Attempts = previous.Attempts
+	}
+	err := ts.sink.SubmitTask(ctx, result, payload)
+	if previous != nil && previous.TaskID != result.TaskID {
+		ts.removeTaskRecord(previous.TaskID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to submit verification task: %v", err)
 	}
 
 	ts.logger.Info("Successfully submitted verification task",
 		zap.String("task_id", result.TaskID),
-		zap.Uint64("sequence", sequence),
-		zap.String("batch_id", batchID),
-		zap.Int("trades_count", len(trades)),
+		zap.String("status", result.Status),
+		zap.String("market_id", result.MarketID),
+		zap.Uint64("sequence", result.Snapshot.SequenceNumber),
+		zap.String("batch_id", result.BatchID),
+		zap.Int("trades_count", len(result.Trades)),
+		zap.String("payload_encoding", ts.encoding),
+		zap.Bool("payload_ref", ts.contentStore != nil),
+		zap.Int("payload_size", len(payload)),
 	)
 
 	return nil
 }
 
-// submitVerificationTask submits a verification task (mock implementation)
-func (ts *TaskSubmitter) submitVerificationTask(
-	taskInput map[string]interface{},
-	snapshot *orderbookchecker.OrderbookSnapshot,
-	trades []orderbookchecker.Trade,
-	batchID string,
-) (*TaskSubmissionResult, error) {
-	// In a real implementation, this would:
-	// 1. Connect to the TaskMailbox contract
-	// 2. Submit the task with the JSON payload
-	// 3. Return the transaction hash as task ID
-
-	// For now, we'll simulate this by saving the task to a file
-	taskID := fmt.Sprintf("task-%d-%d", snapshot.SequenceNumber, time.Now().Unix())
-
-	result := &TaskSubmissionResult{
-		TaskID:       taskID,
-		SnapshotHash: snapshot.MerkleRoot,
-		Snapshot:     snapshot,
-		Trades:       trades,
-		BatchID:      batchID,
-		SubmittedAt:  time.Now().UTC(),
+// encodePayload returns the payload of a task, publishing its data first when tasks carry
+// references
+func (ts *TaskSubmitter) encodePayload(ctx context.Context, taskInput *taskpayload.TaskInput) ([]byte, error) {
+	if ts.contentStore == nil {
+		payload, err := taskpayload.Encode(taskInput, ts.encoding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode task payload: %v", err)
+		}
+		return payload, nil
 	}
 
-	// Save task submission record
-	taskFile := filepath.Join(ts.snapshotDir, fmt.Sprintf("task_%s.json", taskID))
-	taskData, err := json.MarshalIndent(result, "", "  ")
+	ref, err := taskpayload.PutRef(ctx, ts.contentStore, taskInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish task data: %v", err)
+	}
+	payload, err := taskpayload.EncodeRef(ref, ts.encoding)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal task result: %v", err)
+		return nil, fmt.Errorf("failed to encode task payload: %v", err)
 	}
+	return payload, nil
+}
 
-	if err := os.WriteFile(taskFile, taskData, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write task file: %v", err)
+// ConfirmTasks refreshes the status of every task that is still awaiting its result, and
+// submits again the failed tasks the sink can retry
+func (ts *TaskSubmitter) ConfirmTasks(ctx context.Context) error {
+	tasks, err := ts.GetTaskSubmissions()
+	if err != nil {
+		return err
 	}
 
-	return result, nil
+	for _, task := range tasks {
+		switch task.Status {
+		case TaskStatusSubmitted, TaskStatusConfirmed:
+			if err := ts.sink.ConfirmTask(ctx, task); err != nil {
+				ts.logger.Warn("Failed to confirm task", zap.String("task_id", task.TaskID), zap.Error(err))
+			}
+		case TaskStatusFailed:
+			if !ts.sink.CanRetry(task) || task.Snapshot == nil {
+				continue
+			}
+			sequence := task.Snapshot.SequenceNumber
+			ts.logger.Info("Requeueing failed task",
+				zap.String("task_id", task.TaskID),
+				zap.String("market_id", task.MarketID),
+				zap.Uint64("sequence", sequence),
+				zap.Int("attempts", task.Attempts),
+			)
+			if err := ts.submit(ctx, task.MarketID, sequence, task); err != nil {
+				ts.logger.Warn("Failed to resubmit task", zap.String("batch_id", task.BatchID), zap.Error(err))
+				continue
+			}
+			ts.advance(task.MarketID, sequence)
+		}
+	}
+	return nil
+}
+
+// removeTaskRecord deletes the record of a task replaced by a resubmission
+func (ts *TaskSubmitter) removeTaskRecord(taskID string) {
+	taskFile := filepath.Join(ts.taskDir, fmt.Sprintf("task_%s.json", taskID))
+	if err := os.Remove(taskFile); err != nil && !os.IsNotExist(err) {
+		ts.logger.Warn("Failed to remove replaced task record", zap.String("task_id", taskID), zap.Error(err))
+	}
 }
 
 // GetTaskSubmissions returns all task submissions
 func (ts *TaskSubmitter) GetTaskSubmissions() ([]*TaskSubmissionResult, error) {
-	files, err := os.ReadDir(ts.snapshotDir)
+	files, err := os.ReadDir(ts.taskDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read snapshot directory: %v", err)
 	}
@@ -213,7 +426,7 @@ func (ts *TaskSubmitter) GetTaskSubmissions() ([]*TaskSubmissionResult, error) {
 			continue
 		}
 
-		taskFile := filepath.Join(ts.snapshotDir, file.Name())
+		taskFile := filepath.Join(ts.taskDir, file.Name())
 		data, err := os.ReadFile(taskFile)
 		if err != nil {
 			ts.logger.Warn("Failed to read task file", zap.String("file", file.Name()), zap.Error(err))
@@ -235,7 +448,7 @@ func (ts *TaskSubmitter) GetTaskSubmissions() ([]*TaskSubmissionResult, error) {
 // SimulateTaskExecution simulates executing a verification task locally
 func (ts *TaskSubmitter) SimulateTaskExecution(taskID string) (*orderbookchecker.VerificationResult, error) {
 	// Load task submission
-	taskFile := filepath.Join(ts.snapshotDir, fmt.Sprintf("task_%s.json", taskID))
+	taskFile := filepath.Join(ts.taskDir, fmt.Sprintf("task_%s.json", taskID))
 	data, err := os.ReadFile(taskFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read task file: %v", err)
@@ -246,6 +459,21 @@ func (ts *TaskSubmitter) SimulateTaskExecution(taskID string) (*orderbookchecker
 		return nil, fmt.Errorf("failed to unmarshal task submission: %v", err)
 	}
 
+	// Reject tampered snapshots before looking at the trades, as the performer does
+	if err := orderbookchecker.VerifySnapshotIntegrity(*submission.Snapshot, submission.SnapshotHash); err != nil {
+		ts.logger.Warn("Snapshot integrity check failed",
+			zap.String("task_id", taskID),
+			zap.Error(err),
+		)
+		return &orderbookchecker.VerificationResult{
+			Mode:         orderbookchecker.VerificationModeTrades,
+			Valid:        false,
+			ErrorCode:    orderbookchecker.ErrorCode(err),
+			ErrorMessage: fmt.Sprintf("snapshot integrity check failed: %v", err),
+			TotalTrades:  len(submission.Trades),
+		}, nil
+	}
+
 	// Create verifier and run verification
 	verifier := orderbookchecker.NewOrderbookVerifier(ts.logger)
 	result, err := verifier.VerifySnapshot(submission.Trades, *submission.Snapshot)
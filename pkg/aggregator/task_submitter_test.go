@@ -0,0 +1,240 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/cas"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/publisher"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/store"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/taskpayload"
+	"go.uber.org/zap"
+)
+
+func TestTaskSubmitter_PerMarketProgress(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	snapshotStore := store.NewMemoryStore()
+
+	pub := publisher.NewSnapshotPublisherWithStore(logger, snapshotStore)
+	for _, marketID := range []string{"MARKET-A", "MARKET-B", "MARKET-A", "MARKET-C"} {
+		orders, trades := pub.GenerateSampleData(marketID)
+		if _, err := pub.PublishSnapshot(marketID, orders, trades); err != nil {
+			t.Fatalf("PublishSnapshot failed: %v", err)
+		}
+	}
+
+	taskDir := t.TempDir()
+	sink := &recordingSink{FileTaskSink: NewFileTaskSink(taskDir), submitted: make(chan string, 8)}
+	submitter := NewTaskSubmitterWithSink(logger, snapshotStore, taskDir, []string{"MARKET-A", "MARKET-B"}, sink)
+
+	// Stop the watcher once the three allowed snapshots have been submitted; the watcher
+	// records a sequence before it looks at the context again
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- submitter.WatchAndSubmit(ctx, 10*time.Millisecond) }()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-sink.submitted:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for submission %d", i+1)
+		}
+	}
+	cancel()
+	<-done
+
+	if seq := submitter.LastSequence("MARKET-A"); seq != 2 {
+		t.Errorf("Expected MARKET-A at sequence 2, got %d", seq)
+	}
+	if seq := submitter.LastSequence("MARKET-B"); seq != 1 {
+		t.Errorf("Expected MARKET-B at sequence 1, got %d", seq)
+	}
+	if seq := submitter.LastSequence("MARKET-C"); seq != 0 {
+		t.Errorf("Expected MARKET-C to be skipped, got sequence %d", seq)
+	}
+
+	tasks, err := submitter.GetTaskSubmissions()
+	if err != nil {
+		t.Fatalf("GetTaskSubmissions failed: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("Expected 3 task submissions, got %d", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.MarketID == "MARKET-C" {
+			t.Errorf("Unexpected task for market outside the allow-list: %s", task.TaskID)
+		}
+	}
+
+	if err := submitter.WatchAndSubmit(context.Background(), 0); err == nil {
+		t.Error("Expected a non-positive watch interval to be rejected")
+	}
+}
+
+// flakyStore is a snapshot store whose GetTrades fails once for a given sequence
+type flakyStore struct {
+	store.SnapshotStore
+	failSequence uint64
+	failed       atomic.Bool
+}
+
+func (s *flakyStore) GetTrades(ctx context.Context, marketID string, sequenceNum uint64) ([]orderbookchecker.Trade, error) {
+	if sequenceNum == s.failSequence && s.failed.CompareAndSwap(false, true) {
+		return nil, errors.New("transient read failure")
+	}
+	return s.SnapshotStore.GetTrades(ctx, marketID, sequenceNum)
+}
+
+func TestTaskSubmitter_RetriesUnbuiltTasks(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	memoryStore := store.NewMemoryStore()
+	pub := publisher.NewSnapshotPublisherWithStore(logger, memoryStore)
+	for i := 0; i < 2; i++ {
+		orders, trades := pub.GenerateSampleData("MARKET-A")
+		if _, err := pub.PublishSnapshot("MARKET-A", orders, trades); err != nil {
+			t.Fatalf("PublishSnapshot failed: %v", err)
+		}
+	}
+
+	// The watch reports sequence 1 only once, while its trades cannot be read
+	snapshotStore := &flakyStore{SnapshotStore: memoryStore, failSequence: 1}
+	taskDir := t.TempDir()
+	sink := &recordingSink{FileTaskSink: NewFileTaskSink(taskDir), submitted: make(chan string, 8)}
+	submitter := NewTaskSubmitterWithSink(logger, snapshotStore, taskDir, nil, sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- submitter.WatchAndSubmit(ctx, 10*time.Millisecond) }()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-sink.submitted:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for submission %d", i+1)
+		}
+	}
+	cancel()
+	<-done
+
+	if !snapshotStore.failed.Load() {
+		t.Fatal("Expected GetTrades to fail once")
+	}
+	if len(submitter.pending) != 0 {
+		t.Errorf("Expected no pending snapshots, got %v", submitter.pending)
+	}
+	if seq := submitter.LastSequence("MARKET-A"); seq != 2 {
+		t.Errorf("Expected MARKET-A at sequence 2, got %d", seq)
+	}
+
+	tasks, err := submitter.GetTaskSubmissions()
+	if err != nil {
+		t.Fatalf("GetTaskSubmissions failed: %v", err)
+	}
+	batches := make(map[string]bool)
+	for _, task := range tasks {
+		batches[task.BatchID] = true
+	}
+	if len(tasks) != 2 || !batches["batch-MARKET-A-1"] || !batches["batch-MARKET-A-2"] {
+		t.Errorf("Expected tasks for both sequences, got %v", batches)
+	}
+}
+
+// recordingSink is a file sink that keeps the payloads it was given and, if submitted is
+// set, reports the market of every submission on it
+type recordingSink struct {
+	*FileTaskSink
+	payloads  [][]byte
+	submitted chan string
+}
+
+func (s *recordingSink) SubmitTask(ctx context.Context, task *TaskSubmissionResult, payload []byte) error {
+	s.payloads = append(s.payloads, payload)
+	err := s.FileTaskSink.SubmitTask(ctx, task, payload)
+	if s.submitted != nil {
+		s.submitted <- task.MarketID
+	}
+	return err
+}
+
+func TestTaskSubmitter_PayloadEncoding(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	snapshotStore := store.NewMemoryStore()
+	pub := publisher.NewSnapshotPublisherWithStore(logger, snapshotStore)
+	orders, trades := pub.GenerateSampleData("MARKET-A")
+	if _, err := pub.PublishSnapshot("MARKET-A", orders, trades); err != nil {
+		t.Fatalf("PublishSnapshot failed: %v", err)
+	}
+
+	for _, encoding := range []string{taskpayload.EncodingJSON, taskpayload.EncodingBinary} {
+		t.Run(encoding, func(t *testing.T) {
+			taskDir := t.TempDir()
+			sink := &recordingSink{FileTaskSink: NewFileTaskSink(taskDir)}
+			submitter := NewTaskSubmitterWithSink(logger, snapshotStore, taskDir, nil, sink)
+			if err := submitter.SetPayloadEncoding(encoding); err != nil {
+				t.Fatalf("SetPayloadEncoding failed: %v", err)
+			}
+			if err := submitter.processSnapshot(context.Background(), "MARKET-A", 1); err != nil {
+				t.Fatalf("processSnapshot failed: %v", err)
+			}
+
+			envelope, err := taskpayload.Decode(sink.payloads[0])
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if envelope.Version != taskpayload.Version2 || envelope.Input.TradeBatchID != "batch-MARKET-A-1" ||
+				len(envelope.Input.Trades) != len(trades) {
+				t.Errorf("Unexpected payload %+v", envelope)
+			}
+			if err := orderbookchecker.VerifySnapshotIntegrity(envelope.Input.Snapshot, envelope.Input.SnapshotHash); err != nil {
+				t.Errorf("Payload snapshot does not match its commitment: %v", err)
+			}
+		})
+	}
+
+	submitter := NewTaskSubmitterWithStore(logger, snapshotStore, t.TempDir(), nil)
+	if err := submitter.SetPayloadEncoding("xml"); err == nil {
+		t.Error("Expected unknown payload encoding to be rejected")
+	}
+}
+
+func TestTaskSubmitter_ContentStore(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	snapshotStore := store.NewMemoryStore()
+	pub := publisher.NewSnapshotPublisherWithStore(logger, snapshotStore)
+	orders, trades := pub.GenerateSampleData("MARKET-A")
+	if _, err := pub.PublishSnapshot("MARKET-A", orders, trades); err != nil {
+		t.Fatalf("PublishSnapshot failed: %v", err)
+	}
+
+	taskDir := t.TempDir()
+	blobs := cas.NewDirStore(t.TempDir())
+	sink := &recordingSink{FileTaskSink: NewFileTaskSink(taskDir)}
+	submitter := NewTaskSubmitterWithSink(logger, snapshotStore, taskDir, nil, sink)
+	submitter.SetContentStore(blobs)
+	if err := submitter.processSnapshot(context.Background(), "MARKET-A", 1); err != nil {
+		t.Fatalf("processSnapshot failed: %v", err)
+	}
+
+	envelope, err := taskpayload.Decode(sink.payloads[0])
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if envelope.Type != taskpayload.TypeOrderbookVerificationRef || envelope.Ref == nil {
+		t.Fatalf("Expected a reference payload, got %+v", envelope)
+	}
+
+	input, err := taskpayload.Resolve(context.Background(), envelope.Ref, blobs)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if input.TradeBatchID != "batch-MARKET-A-1" || len(input.Trades) != len(trades) {
+		t.Errorf("Unexpected resolved input %+v", input)
+	}
+	if err := orderbookchecker.VerifySnapshotIntegrity(input.Snapshot, input.SnapshotHash); err != nil {
+		t.Errorf("Resolved snapshot does not match its commitment: %v", err)
+	}
+}
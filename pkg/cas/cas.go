@@ -0,0 +1,137 @@
+// Package cas publishes and fetches the content-addressed blobs task payloads refer to. A
+// blob is named by the keccak256 hash of its content, so whoever fetches it can check it
+// against the hash it was referenced by, whatever the source.
+package cas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// SchemeCAS is the scheme of locators that name a blob by its hash alone, cas://<hex hash>,
+// leaving the choice of source to the fetcher
+const SchemeCAS = "cas"
+
+// MaxBlobSize bounds the size of a fetched blob unless WithMaxBlobSize sets another bound
+const MaxBlobSize = 256 << 20
+
+// Option configures a fetcher
+type Option func(*options)
+
+type options struct {
+	maxSize int
+}
+
+// WithMaxBlobSize bounds the size of the blobs a fetcher returns
+func WithMaxBlobSize(n int) Option {
+	return func(o *options) { o.maxSize = n }
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) options {
+	o := options{maxSize: MaxBlobSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+var (
+	// ErrNotFound is returned when a source does not hold the requested blob
+	ErrNotFound = errors.New("blob not found")
+	// ErrHashMismatch is returned when a fetched blob does not hash to the expected hash
+	ErrHashMismatch = errors.New("blob hash mismatch")
+	// ErrTooLarge is returned when a blob exceeds the size a fetcher accepts
+	ErrTooLarge = errors.New("blob too large")
+	// ErrHostNotAllowed is returned when a locator or a redirect leads to a host that is not
+	// allowed
+	ErrHostNotAllowed = errors.New("host not allowed")
+)
+
+// Fetcher retrieves blobs by locator
+type Fetcher interface {
+	// Fetch returns the blob at locator, or ErrNotFound
+	Fetch(ctx context.Context, locator string) ([]byte, error)
+}
+
+// Store is a content-addressed store blobs are published to
+type Store interface {
+	Fetcher
+	// Put stores a blob and returns a locator it can be fetched by
+	Put(ctx context.Context, data []byte) (string, error)
+}
+
+// Locator returns the cas:// locator of a blob hash
+func Locator(hash merkle.Hash) string {
+	return SchemeCAS + "://" + strings.TrimPrefix(hash.Hex(), "0x")
+}
+
+// ParseLocator returns the hash named by a cas:// locator
+func ParseLocator(locator string) (merkle.Hash, error) {
+	rest, ok := strings.CutPrefix(locator, SchemeCAS+"://")
+	if !ok {
+		return merkle.Hash{}, fmt.Errorf("not a %s locator: %q", SchemeCAS, locator)
+	}
+	return merkle.ParseHash(rest)
+}
+
+// scheme returns the scheme of a locator
+func scheme(locator string) string {
+	if i := strings.Index(locator, "://"); i > 0 {
+		return locator[:i]
+	}
+	return ""
+}
+
+// readLimited reads a blob from r, failing with ErrTooLarge if it exceeds maxSize bytes
+func readLimited(r io.Reader, locator string, maxSize int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %v", err)
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("%w: %s exceeds %d bytes", ErrTooLarge, locator, maxSize)
+	}
+	return data, nil
+}
+
+// FetchVerified fetches the blob at locator and checks that it hashes to hash
+func FetchVerified(ctx context.Context, f Fetcher, locator string, hash merkle.Hash) ([]byte, error) {
+	data, err := f.Fetch(ctx, locator)
+	if err != nil {
+		return nil, err
+	}
+	if got := merkle.Keccak256(data); got != hash {
+		return nil, fmt.Errorf("%w: %s holds %s, expected %s", ErrHashMismatch, locator, got, hash)
+	}
+	return data, nil
+}
+
+// Router dispatches locators to a fetcher by their scheme
+type Router struct {
+	fetchers map[string]Fetcher
+}
+
+// NewRouter creates a router without any schemes
+func NewRouter() *Router {
+	return &Router{fetchers: make(map[string]Fetcher)}
+}
+
+// Handle routes locators of scheme to f
+func (r *Router) Handle(scheme string, f Fetcher) {
+	r.fetchers[scheme] = f
+}
+
+// Fetch implements Fetcher
+func (r *Router) Fetch(ctx context.Context, locator string) ([]byte, error) {
+	f, ok := r.fetchers[scheme(locator)]
+	if !ok {
+		return nil, fmt.Errorf("no fetcher for locator %q", locator)
+	}
+	return f.Fetch(ctx, locator)
+}
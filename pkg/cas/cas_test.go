@@ -0,0 +1,208 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// gateway is a local stand-in for an IPFS-style content gateway
+type gateway struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		g.blobs[name] = data
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		data, ok := g.blobs[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestStores(t *testing.T) {
+	server := httptest.NewServer(&gateway{blobs: make(map[string][]byte)})
+	defer server.Close()
+
+	stores := map[string]Store{
+		"dir":     NewDirStore(t.TempDir()),
+		"gateway": NewGatewayStore(server.URL+"/", server.Client()),
+	}
+
+	ctx := context.Background()
+	blob := []byte("orderbook snapshot")
+	hash := merkle.Keccak256(blob)
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			locator, err := store.Put(ctx, blob)
+			if err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if locator != Locator(hash) {
+				t.Errorf("Expected locator %s, got %s", Locator(hash), locator)
+			}
+
+			data, err := FetchVerified(ctx, store, locator, hash)
+			if err != nil {
+				t.Fatalf("FetchVerified failed: %v", err)
+			}
+			if string(data) != string(blob) {
+				t.Errorf("Fetched %q, expected %q", data, blob)
+			}
+
+			if _, err := FetchVerified(ctx, store, locator, merkle.Keccak256([]byte("other"))); !errors.Is(err, ErrHashMismatch) {
+				t.Errorf("Expected ErrHashMismatch, got: %v", err)
+			}
+			if _, err := store.Fetch(ctx, Locator(merkle.Keccak256([]byte("missing")))); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound, got: %v", err)
+			}
+			if _, err := store.Fetch(ctx, "ipfs://abc"); err == nil {
+				t.Error("Expected a foreign locator to be rejected")
+			}
+		})
+	}
+}
+
+func TestRouter(t *testing.T) {
+	blob := []byte("trade batch")
+	hash := merkle.Keccak256(blob)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/batch":
+			w.Write(blob)
+		case "/redirect":
+			http.Redirect(w, r, "/batch", http.StatusFound)
+		case "/escape":
+			// localhost names the server too, but is not on the allowlist
+			http.Redirect(w, r, strings.Replace(serverURL(r), "127.0.0.1", "localhost", 1)+"/batch", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	dir := NewDirStore(t.TempDir())
+	casLocator, err := dir.Put(ctx, blob)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	router := NewRouter()
+	router.Handle(SchemeCAS, dir)
+	router.Handle("http", NewHTTPFetcher(server.Client(), []string{"127.0.0.1"}))
+	router.Handle("https", NewHTTPFetcher(nil, []string{"example.com"}))
+
+	tests := []struct {
+		name    string
+		locator string
+		wantErr error
+		want    string
+	}{
+		{"cas", casLocator, nil, ""},
+		{"http", server.URL + "/batch", nil, ""},
+		{"http not found", server.URL + "/missing", ErrNotFound, ""},
+		{"http redirect", server.URL + "/redirect", nil, ""},
+		{"http redirect to another host", server.URL + "/escape", ErrHostNotAllowed, ""},
+		{"http host not allowed", strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/batch", ErrHostNotAllowed, ""},
+		{"https host not allowed", "https://169.254.169.254/latest/meta-data", ErrHostNotAllowed, ""},
+		{"unrouted scheme", "ipfs://" + strings.TrimPrefix(hash.Hex(), "0x"), nil, "no fetcher"},
+		{"malformed cas locator", "cas://zz", nil, "hash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FetchVerified(ctx, router, tt.locator, hash)
+			if tt.wantErr == nil && tt.want == "" {
+				if err != nil {
+					t.Fatalf("FetchVerified failed: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected fetch error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+			if tt.want != "" && !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// serverURL returns the scheme and host a request was sent to
+func serverURL(r *http.Request) string {
+	return "http://" + r.Host
+}
+
+func TestMaxBlobSize(t *testing.T) {
+	server := httptest.NewServer(&gateway{blobs: make(map[string][]byte)})
+	defer server.Close()
+
+	ctx := context.Background()
+	blob := []byte("orderbook snapshot")
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	dir := t.TempDir()
+	fetchers := map[string]struct {
+		store   Store
+		limited Fetcher
+	}{
+		"dir":     {NewDirStore(dir), NewDirStore(dir, WithMaxBlobSize(len(blob)-1))},
+		"gateway": {NewGatewayStore(server.URL, server.Client()), NewGatewayStore(server.URL, server.Client(), WithMaxBlobSize(len(blob)-1))},
+	}
+
+	for name, f := range fetchers {
+		t.Run(name, func(t *testing.T) {
+			locator, err := f.store.Put(ctx, blob)
+			if err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if _, err := f.store.Fetch(ctx, locator); err != nil {
+				t.Errorf("Expected a blob within the default bound to be fetched, got: %v", err)
+			}
+			if _, err := f.limited.Fetch(ctx, locator); !errors.Is(err, ErrTooLarge) {
+				t.Errorf("Expected ErrTooLarge, got: %v", err)
+			}
+		})
+	}
+
+	t.Run("http", func(t *testing.T) {
+		locator, err := fetchers["gateway"].store.Put(ctx, blob)
+		if err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		hash, _ := ParseLocator(locator)
+		blobURL := server.URL + "/" + strings.TrimPrefix(hash.Hex(), "0x")
+
+		if _, err := NewHTTPFetcher(server.Client(), []string{host}, WithMaxBlobSize(len(blob))).Fetch(ctx, blobURL); err != nil {
+			t.Errorf("Expected a blob of exactly the bound to be fetched, got: %v", err)
+		}
+		if _, err := NewHTTPFetcher(server.Client(), []string{host}, WithMaxBlobSize(len(blob)-1)).Fetch(ctx, blobURL); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Expected ErrTooLarge, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/fsutil"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// DirStore keeps blobs in a local directory, one file per blob named by its hex hash. It
+// serves cas:// locators and stands in for a shared content store in tests and demos.
+type DirStore struct {
+	dir     string
+	maxSize int
+}
+
+// NewDirStore creates a store in dir
+func NewDirStore(dir string, opts ...Option) *DirStore {
+	return &DirStore{dir: dir, maxSize: newOptions(opts).maxSize}
+}
+
+// path returns the file holding the blob with the given hash
+func (s *DirStore) path(hash merkle.Hash) string {
+	return filepath.Join(s.dir, strings.TrimPrefix(hash.Hex(), "0x"))
+}
+
+// Put implements Store
+func (s *DirStore) Put(ctx context.Context, data []byte) (string, error) {
+	hash := merkle.Keccak256(data)
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create blob directory: %v", err)
+	}
+	if err := fsutil.WriteFileAtomic(s.path(hash), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write blob: %v", err)
+	}
+	return Locator(hash), nil
+}
+
+// Fetch implements Fetcher
+func (s *DirStore) Fetch(ctx context.Context, locator string) ([]byte, error) {
+	hash, err := ParseLocator(locator)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(s.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, locator)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %v", err)
+	}
+	defer file.Close()
+	return readLimited(file, locator, s.maxSize)
+}
@@ -0,0 +1,161 @@
+package cas
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// HTTPFetcher fetches http:// and https:// locators with a GET request. Locators name
+// arbitrary URLs, so only those on an allowed host are fetched, and redirects are only
+// followed to allowed hosts.
+type HTTPFetcher struct {
+	client  *http.Client
+	allowed hostAllowlist
+	maxSize int
+}
+
+// NewHTTPFetcher creates a fetcher of locators on the allowed hosts, each a host name or a
+// host:port; a nil client uses http.DefaultClient
+func NewHTTPFetcher(client *http.Client, allowedHosts []string, opts ...Option) *HTTPFetcher {
+	allowed := newHostAllowlist(allowedHosts...)
+	return &HTTPFetcher{client: allowed.confine(client), allowed: allowed, maxSize: newOptions(opts).maxSize}
+}
+
+// Fetch implements Fetcher
+func (f *HTTPFetcher) Fetch(ctx context.Context, locator string) ([]byte, error) {
+	if s := scheme(locator); s != "http" && s != "https" {
+		return nil, fmt.Errorf("not an http locator: %q", locator)
+	}
+	u, err := url.Parse(locator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid locator %q: %v", locator, err)
+	}
+	if !f.allowed.allows(u) {
+		return nil, fmt.Errorf("%w: %s", ErrHostNotAllowed, u.Host)
+	}
+	return get(ctx, f.client, locator, f.maxSize)
+}
+
+// GatewayStore keeps blobs in an IPFS-style content gateway that serves every blob at
+// <baseURL>/<hex hash> and accepts uploads with PUT at the same URL. It serves cas://
+// locators.
+// Redirects are only followed within the gateway host.
+type GatewayStore struct {
+	baseURL string
+	client  *http.Client
+	maxSize int
+}
+
+// NewGatewayStore creates a store for the gateway at baseURL; a nil client uses
+// http.DefaultClient
+func NewGatewayStore(baseURL string, client *http.Client, opts ...Option) *GatewayStore {
+	var allowed hostAllowlist
+	if u, err := url.Parse(baseURL); err == nil {
+		allowed = newHostAllowlist(u.Host)
+	}
+	return &GatewayStore{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  allowed.confine(client),
+		maxSize: newOptions(opts).maxSize,
+	}
+}
+
+// blobURL returns the gateway URL of the blob with the given hash
+func (s *GatewayStore) blobURL(hash merkle.Hash) string {
+	return s.baseURL + "/" + strings.TrimPrefix(hash.Hex(), "0x")
+}
+
+// Put implements Store
+func (s *GatewayStore) Put(ctx context.Context, data []byte) (string, error) {
+	hash := merkle.Keccak256(data)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.blobURL(hash), bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("PUT %s failed: %v", req.URL, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return "", fmt.Errorf("failed to put blob %s: %s", hash, resp.Status)
+	}
+	return Locator(hash), nil
+}
+
+// Fetch implements Fetcher
+func (s *GatewayStore) Fetch(ctx context.Context, locator string) ([]byte, error) {
+	hash, err := ParseLocator(locator)
+	if err != nil {
+		return nil, err
+	}
+	return get(ctx, s.client, s.blobURL(hash), s.maxSize)
+}
+
+// hostAllowlist is a set of lower-cased host names and host:port pairs
+type hostAllowlist map[string]bool
+
+func newHostAllowlist(hosts ...string) hostAllowlist {
+	allowed := make(hostAllowlist, len(hosts))
+	for _, host := range hosts {
+		allowed[strings.ToLower(host)] = true
+	}
+	return allowed
+}
+
+// allows reports whether u is on an allowed host, named with or without its port
+func (a hostAllowlist) allows(u *url.URL) bool {
+	return a[strings.ToLower(u.Host)] || a[strings.ToLower(u.Hostname())]
+}
+
+// confine returns a copy of client that only follows redirects to allowed hosts; a nil
+// client stands for http.DefaultClient
+func (a hostAllowlist) confine(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	confined := *client
+	confined.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if !a.allows(req.URL) {
+			return fmt.Errorf("%w: redirect to %s", ErrHostNotAllowed, req.URL.Host)
+		}
+		return nil
+	}
+	return &confined
+}
+
+// maxRedirects is the number of redirects followed, as by http.DefaultClient
+const maxRedirects = 10
+
+// get downloads a blob of at most maxSize bytes
+func get(ctx context.Context, client *http.Client, rawURL string, maxSize int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s failed: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, rawURL)
+	default:
+		return nil, fmt.Errorf("failed to get %s: %s", rawURL, resp.Status)
+	}
+
+	return readLimited(resp.Body, rawURL, maxSize)
+}
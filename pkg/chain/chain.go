@@ -0,0 +1,153 @@
+// Package chain holds the types shared by the contract bindings in pkg/settlement and
+// pkg/mailbox: addresses, calls, receipts and the Backend they are executed on, the
+// selector and event topic helpers the bindings are built from, and a Backend for nodes
+// speaking the Ethereum JSON-RPC API.
+package chain
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// ErrReverted is returned when a call or transaction reverts
+var ErrReverted = errors.New("execution reverted")
+
+// Revert returns an ErrReverted error with the contract's revert reason
+func Revert(reason string) error {
+	return fmt.Errorf("%w: %s", ErrReverted, reason)
+}
+
+// Address is a 20-byte account or contract address
+type Address [20]byte
+
+// Hex returns the 0x-prefixed hex encoding of the address
+func (a Address) Hex() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// String implements fmt.Stringer
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// MarshalText encodes the address as 0x-prefixed hex
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalText decodes a 0x-prefixed hex address
+func (a *Address) UnmarshalText(text []byte) error {
+	parsed, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// ParseAddress decodes a 20-byte address from hex, with or without a 0x prefix
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	raw, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return a, fmt.Errorf("invalid address %q: %v", s, err)
+	}
+	if len(raw) != len(a) {
+		return a, fmt.Errorf("invalid address %q: expected %d bytes, got %d", s, len(a), len(raw))
+	}
+	copy(a[:], raw)
+	return a, nil
+}
+
+// CallMsg is a contract call or transaction
+type CallMsg struct {
+	From  Address
+	To    Address
+	Value *big.Int // Wei sent with a transaction, nil for none
+	Data  []byte   // Function selector followed by the ABI-encoded arguments
+}
+
+// Log is an event emitted by a transaction
+type Log struct {
+	Address Address
+	Topics  []merkle.Hash
+	Data    []byte
+}
+
+// Receipt is the outcome of a mined transaction
+type Receipt struct {
+	TxHash         merkle.Hash
+	BlockNumber    uint64
+	BlockTimestamp uint64
+	Logs           []Log
+}
+
+// TransactOpts holds the sender and the value of a transaction
+type TransactOpts struct {
+	From  Address
+	Value *big.Int
+}
+
+// Backend executes calls and transactions against a chain. Implementations sign and send
+// transactions from msg.From and wait for them to be mined.
+type Backend interface {
+	// CallContract executes a read-only call and returns its return data
+	CallContract(ctx context.Context, msg CallMsg) ([]byte, error)
+	// SendTransaction executes a transaction and returns its receipt. A reverted transaction
+	// returns an error wrapping ErrReverted.
+	SendTransaction(ctx context.Context, msg CallMsg) (*Receipt, error)
+}
+
+// Selector returns the 4-byte function selector of a function signature
+func Selector(signature string) [4]byte {
+	var sel [4]byte
+	hash := merkle.Keccak256([]byte(signature))
+	copy(sel[:], hash[:4])
+	return sel
+}
+
+// EventTopic returns the topic identifying an event signature
+func EventTopic(signature string) merkle.Hash {
+	return merkle.Keccak256([]byte(signature))
+}
+
+// Calldata returns the selector of a function followed by its encoded arguments
+func Calldata(signature string, args ...abi.Value) []byte {
+	sel := Selector(signature)
+	return append(sel[:], abi.Encode(args...)...)
+}
+
+// Dispatch matches calldata to one of the given function signatures and returns a decoder
+// over its arguments, as a contract's dispatcher would; unknown selectors revert
+func Dispatch(data []byte, signatures ...string) (string, *abi.Decoder, error) {
+	if len(data) < 4 {
+		return "", nil, Revert("missing function selector")
+	}
+	for _, sig := range signatures {
+		if sel := Selector(sig); string(sel[:]) == string(data[:4]) {
+			return sig, abi.NewDecoder(data[4:]), nil
+		}
+	}
+	return "", nil, Revert(fmt.Sprintf("unknown function selector 0x%x", data[:4]))
+}
+
+// AddressTopic returns an address as an indexed event topic
+func AddressTopic(a Address) merkle.Hash {
+	var topic merkle.Hash
+	copy(topic[12:], a[:])
+	return topic
+}
+
+// TopicAddress returns the address held in an indexed event topic
+func TopicAddress(topic merkle.Hash) Address {
+	var a Address
+	copy(a[:], topic[12:])
+	return a
+}
@@ -0,0 +1,15 @@
+package chain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestSelector(t *testing.T) {
+	if sel := Selector("transfer(address,uint256)"); hex.EncodeToString(sel[:]) != "a9059cbb" {
+		t.Errorf("Unexpected transfer selector %x", sel)
+	}
+	if topic := EventTopic("Transfer(address,address,uint256)"); topic.Hex() != "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef" {
+		t.Errorf("Unexpected Transfer topic %s", topic)
+	}
+}
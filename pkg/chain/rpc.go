@@ -0,0 +1,349 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// RPCBackend is a Backend for a node speaking the Ethereum JSON-RPC API. It signs legacy
+// EIP-155 transactions locally with the keys it holds, so the node needs no unlocked
+// accounts, and polls for the receipt of each transaction it sends.
+type RPCBackend struct {
+	url    string
+	client *http.Client
+	keys   map[Address]*secp256k1.PrivateKey
+
+	pollInterval time.Duration // Between receipt polls
+
+	mu      sync.Mutex // Serializes sends, so nonces are assigned in order
+	chainID *big.Int   // Fetched on the first send
+	nextID  atomic.Int64
+}
+
+// NewRPCBackend creates a backend for the node at url sending transactions from the
+// accounts of keys; a nil client uses http.DefaultClient
+func NewRPCBackend(url string, client *http.Client, keys ...*secp256k1.PrivateKey) *RPCBackend {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	b := &RPCBackend{
+		url:          url,
+		client:       client,
+		keys:         make(map[Address]*secp256k1.PrivateKey),
+		pollInterval: time.Second,
+	}
+	for _, key := range keys {
+		b.keys[KeyAddress(key)] = key
+	}
+	return b
+}
+
+// KeyAddress returns the address of the account controlled by key
+func KeyAddress(key *secp256k1.PrivateKey) Address {
+	var a Address
+	hash := merkle.Keccak256(key.PubKey().SerializeUncompressed()[1:])
+	copy(a[:], hash[12:])
+	return a
+}
+
+// CallContract implements Backend
+func (b *RPCBackend) CallContract(ctx context.Context, msg CallMsg) ([]byte, error) {
+	var out string
+	if err := b.call(ctx, &out, "eth_call", callArgs(msg), "latest"); err != nil {
+		return nil, err
+	}
+	return decodeHex(out)
+}
+
+// BlockNumber returns the number of the latest block
+func (b *RPCBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	var out string
+	if err := b.call(ctx, &out, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return decodeUint64(out)
+}
+
+// SendTransaction implements Backend. The gas limit comes from eth_estimateGas, so a
+// transaction that would revert is reported without being sent.
+func (b *RPCBackend) SendTransaction(ctx context.Context, msg CallMsg) (*Receipt, error) {
+	key, ok := b.keys[msg.From]
+	if !ok {
+		return nil, fmt.Errorf("no key for account %s", msg.From)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.chainID == nil {
+		var out string
+		if err := b.call(ctx, &out, "eth_chainId"); err != nil {
+			return nil, err
+		}
+		chainID, err := decodeBig(out)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chain ID: %v", err)
+		}
+		b.chainID = chainID
+	}
+
+	tx := legacyTx{To: msg.To, Value: msg.Value, Data: msg.Data}
+	var nonce, gasPrice, gas string
+	if err := b.call(ctx, &nonce, "eth_getTransactionCount", msg.From.Hex(), "pending"); err != nil {
+		return nil, err
+	}
+	if err := b.call(ctx, &gasPrice, "eth_gasPrice"); err != nil {
+		return nil, err
+	}
+	if err := b.call(ctx, &gas, "eth_estimateGas", callArgs(msg)); err != nil {
+		return nil, err
+	}
+	var err error
+	if tx.Nonce, err = decodeUint64(nonce); err != nil {
+		return nil, fmt.Errorf("invalid nonce: %v", err)
+	}
+	if tx.GasPrice, err = decodeBig(gasPrice); err != nil {
+		return nil, fmt.Errorf("invalid gas price: %v", err)
+	}
+	if tx.Gas, err = decodeUint64(gas); err != nil {
+		return nil, fmt.Errorf("invalid gas estimate: %v", err)
+	}
+
+	raw := tx.sign(key, b.chainID)
+	var txHash string
+	if err := b.call(ctx, &txHash, "eth_sendRawTransaction", "0x"+hex.EncodeToString(raw)); err != nil {
+		return nil, err
+	}
+	return b.waitMined(ctx, txHash)
+}
+
+// rpcReceipt is a transaction receipt as returned by eth_getTransactionReceipt
+type rpcReceipt struct {
+	TransactionHash string `json:"transactionHash"`
+	BlockNumber     string `json:"blockNumber"`
+	Status          string `json:"status"`
+	Logs            []struct {
+		Address string   `json:"address"`
+		Topics  []string `json:"topics"`
+		Data    string   `json:"data"`
+	} `json:"logs"`
+}
+
+// waitMined polls for the receipt of a transaction and converts it
+func (b *RPCBackend) waitMined(ctx context.Context, txHash string) (*Receipt, error) {
+	var raw *rpcReceipt
+	for {
+		if err := b.call(ctx, &raw, "eth_getTransactionReceipt", txHash); err != nil {
+			return nil, err
+		}
+		if raw != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("transaction %s not mined: %v", txHash, ctx.Err())
+		case <-time.After(b.pollInterval):
+		}
+	}
+
+	receipt := &Receipt{}
+	var err error
+	if receipt.TxHash, err = merkle.ParseHash(raw.TransactionHash); err != nil {
+		return nil, fmt.Errorf("invalid transaction hash: %v", err)
+	}
+	if receipt.BlockNumber, err = decodeUint64(raw.BlockNumber); err != nil {
+		return nil, fmt.Errorf("invalid block number: %v", err)
+	}
+	if raw.Status != "0x1" {
+		return nil, Revert(fmt.Sprintf("transaction %s failed in block %d", txHash, receipt.BlockNumber))
+	}
+
+	var block struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := b.call(ctx, &block, "eth_getBlockByNumber", raw.BlockNumber, false); err != nil {
+		return nil, err
+	}
+	if receipt.BlockTimestamp, err = decodeUint64(block.Timestamp); err != nil {
+		return nil, fmt.Errorf("invalid block timestamp: %v", err)
+	}
+
+	for _, l := range raw.Logs {
+		var log Log
+		if log.Address, err = ParseAddress(l.Address); err != nil {
+			return nil, err
+		}
+		for _, topic := range l.Topics {
+			hash, err := merkle.ParseHash(topic)
+			if err != nil {
+				return nil, fmt.Errorf("invalid log topic: %v", err)
+			}
+			log.Topics = append(log.Topics, hash)
+		}
+		if log.Data, err = decodeHex(l.Data); err != nil {
+			return nil, fmt.Errorf("invalid log data: %v", err)
+		}
+		receipt.Logs = append(receipt.Logs, log)
+	}
+	return receipt, nil
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// call invokes a JSON-RPC method and decodes its result into out. Errors reporting a revert
+// wrap ErrReverted.
+func (b *RPCBackend) call(ctx context.Context, out any, method string, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": b.nextID.Add(1), "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s failed: %v", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s failed: %s", method, resp.Status)
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("%s: invalid response: %v", method, err)
+	}
+	if response.Error != nil {
+		// Nodes report reverts with code 3, or -32000 and an "execution reverted" message
+		if response.Error.Code == 3 || strings.HasPrefix(response.Error.Message, "execution reverted") {
+			return Revert(strings.TrimPrefix(strings.TrimPrefix(response.Error.Message, "execution reverted"), ": "))
+		}
+		return fmt.Errorf("%s failed: %s (code %d)", method, response.Error.Message, response.Error.Code)
+	}
+	if err := json.Unmarshal(response.Result, out); err != nil {
+		return fmt.Errorf("%s: invalid result: %v", method, err)
+	}
+	return nil
+}
+
+// callArgs returns the transaction call object of a message
+func callArgs(msg CallMsg) map[string]string {
+	args := map[string]string{"to": msg.To.Hex(), "data": "0x" + hex.EncodeToString(msg.Data)}
+	if msg.From != (Address{}) {
+		args["from"] = msg.From.Hex()
+	}
+	if msg.Value != nil && msg.Value.Sign() > 0 {
+		args["value"] = "0x" + msg.Value.Text(16)
+	}
+	return args
+}
+
+// legacyTx is a pre-EIP-1559 transaction
+type legacyTx struct {
+	Nonce    uint64
+	GasPrice *big.Int
+	Gas      uint64
+	To       Address
+	Value    *big.Int // nil for none
+	Data     []byte
+}
+
+// sign returns the RLP encoding of the transaction signed for chainID as EIP-155 specifies:
+// the signature covers rlp([nonce, gasPrice, gas, to, value, data, chainID, 0, 0]) and
+// v = chainID*2 + 35 + the recovery id
+func (tx legacyTx) sign(key *secp256k1.PrivateKey, chainID *big.Int) []byte {
+	value := tx.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	fields := [][]byte{
+		rlpUint(new(big.Int).SetUint64(tx.Nonce)),
+		rlpUint(tx.GasPrice),
+		rlpUint(new(big.Int).SetUint64(tx.Gas)),
+		rlpBytes(tx.To[:]),
+		rlpUint(value),
+		rlpBytes(tx.Data),
+	}
+
+	hash := merkle.Keccak256(rlpList(append(fields, rlpUint(chainID), rlpUint(new(big.Int)), rlpUint(new(big.Int)))...))
+	// SignCompact returns v || r || s with v = 27 + recovery id
+	compact := ecdsa.SignCompact(key, hash[:], false)
+	v := new(big.Int).Add(new(big.Int).Lsh(chainID, 1), big.NewInt(int64(compact[0]-27)+35))
+	r := new(big.Int).SetBytes(compact[1:33])
+	s := new(big.Int).SetBytes(compact[33:65])
+	return rlpList(append(fields, rlpUint(v), rlpUint(r), rlpUint(s))...)
+}
+
+// rlpBytes returns the RLP encoding of a byte string
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+// rlpUint returns the RLP encoding of a non-negative integer: its big-endian bytes without
+// leading zeros
+func rlpUint(n *big.Int) []byte {
+	return rlpBytes(n.Bytes())
+}
+
+// rlpList returns the RLP encoding of a list of encoded items
+func rlpList(items ...[]byte) []byte {
+	payload := bytes.Join(items, nil)
+	return append(rlpHeader(0xc0, len(payload)), payload...)
+}
+
+// rlpHeader returns the prefix of a string (offset 0x80) or list (offset 0xc0) payload
+func rlpHeader(offset byte, length int) []byte {
+	if length < 56 {
+		return []byte{offset + byte(length)}
+	}
+	size := new(big.Int).SetInt64(int64(length)).Bytes()
+	return append([]byte{offset + 55 + byte(len(size))}, size...)
+}
+
+// decodeHex decodes a 0x-prefixed hex string
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// decodeUint64 decodes a 0x-prefixed hex quantity
+func decodeUint64(s string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+}
+
+// decodeBig decodes a 0x-prefixed hex quantity of any size
+func decodeBig(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid quantity %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,387 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// testKey returns a private key whose 32 bytes all equal b
+func testKey(b byte) *secp256k1.PrivateKey {
+	return secp256k1.PrivKeyFromBytes(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestLegacyTx_SignEIP155Vector(t *testing.T) {
+	// The example transaction of EIP-155
+	tx := legacyTx{
+		Nonce:    9,
+		GasPrice: big.NewInt(20000000000),
+		Gas:      21000,
+		To:       Address(bytes.Repeat([]byte{0x35}, 20)),
+		Value:    new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil),
+	}
+	want := "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+
+	if got := hex.EncodeToString(tx.sign(testKey(0x46), big.NewInt(1))); got != want {
+		t.Errorf("Signed transaction mismatch:\n got %s\nwant %s", got, want)
+	}
+}
+
+// rpcNode is a local stand-in for an Ethereum node. It recovers the sender of each raw
+// transaction and executes it on a backend, serving receipts one poll late.
+type rpcNode struct {
+	chainID *big.Int
+	backend Backend // Executes the recovered transactions
+
+	mu       sync.Mutex
+	nonces   map[Address]uint64
+	receipts map[string]map[string]any
+	polled   map[string]bool
+	blocks   map[string]uint64 // Block timestamps by hex number
+	block    uint64
+}
+
+func (n *rpcNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := n.handle(r.Context(), req.Method, req.Params)
+	response := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	if errors.Is(err, ErrReverted) {
+		response["error"] = map[string]any{"code": 3, "message": err.Error()}
+	} else if err != nil {
+		response["error"] = map[string]any{"code": -32000, "message": err.Error()}
+	} else {
+		response["result"] = result
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (n *rpcNode) handle(ctx context.Context, method string, params []json.RawMessage) (any, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	param := func(i int) string {
+		var s string
+		json.Unmarshal(params[i], &s)
+		return s
+	}
+
+	switch method {
+	case "eth_chainId":
+		return "0x" + n.chainID.Text(16), nil
+	case "eth_blockNumber":
+		return fmt.Sprintf("0x%x", n.block), nil
+	case "eth_gasPrice":
+		return "0x3b9aca00", nil
+	case "eth_estimateGas":
+		return "0x30d40", nil
+	case "eth_getTransactionCount":
+		from, err := ParseAddress(param(0))
+		if err != nil {
+			return nil, err
+		}
+		return fmt.Sprintf("0x%x", n.nonces[from]), nil
+	case "eth_call":
+		var args map[string]string
+		json.Unmarshal(params[0], &args)
+		msg, err := callMsg(args)
+		if err != nil {
+			return nil, err
+		}
+		out, err := n.backend.CallContract(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		return "0x" + hex.EncodeToString(out), nil
+	case "eth_sendRawTransaction":
+		raw, err := decodeHex(param(0))
+		if err != nil {
+			return nil, err
+		}
+		msg, nonce, err := n.recoverTx(raw)
+		if err != nil {
+			return nil, err
+		}
+		if nonce != n.nonces[msg.From] {
+			return nil, fmt.Errorf("nonce too low")
+		}
+		n.nonces[msg.From]++
+		txHash := merkle.Keccak256(raw).Hex()
+
+		n.block++
+		blockNumber := fmt.Sprintf("0x%x", n.block)
+		receipt := map[string]any{"transactionHash": txHash, "blockNumber": blockNumber, "status": "0x1", "logs": []any{}}
+		simulated, err := n.backend.SendTransaction(ctx, msg)
+		if errors.Is(err, ErrReverted) {
+			receipt["status"] = "0x0"
+		} else if err != nil {
+			return nil, err
+		} else {
+			n.blocks[blockNumber] = simulated.BlockTimestamp
+			var logs []any
+			for _, log := range simulated.Logs {
+				var topics []string
+				for _, topic := range log.Topics {
+					topics = append(topics, topic.Hex())
+				}
+				logs = append(logs, map[string]any{"address": log.Address.Hex(), "topics": topics, "data": "0x" + hex.EncodeToString(log.Data)})
+			}
+			receipt["logs"] = logs
+		}
+		n.receipts[txHash] = receipt
+		return txHash, nil
+	case "eth_getTransactionReceipt":
+		txHash := param(0)
+		if !n.polled[txHash] {
+			n.polled[txHash] = true
+			return nil, nil
+		}
+		return n.receipts[txHash], nil
+	case "eth_getBlockByNumber":
+		return map[string]string{"timestamp": fmt.Sprintf("0x%x", n.blocks[param(0)])}, nil
+	default:
+		return nil, fmt.Errorf("method %s not supported", method)
+	}
+}
+
+// recoverTx decodes a signed EIP-155 transaction and recovers its sender
+func (n *rpcNode) recoverTx(raw []byte) (CallMsg, uint64, error) {
+	fields, err := rlpDecodeList(raw)
+	if err != nil || len(fields) != 9 {
+		return CallMsg{}, 0, fmt.Errorf("invalid transaction: %v", err)
+	}
+	var msg CallMsg
+	copy(msg.To[:], fields[3])
+	msg.Value = new(big.Int).SetBytes(fields[4])
+	msg.Data = fields[5]
+
+	v := new(big.Int).SetBytes(fields[6])
+	recovery := new(big.Int).Sub(v, new(big.Int).Add(new(big.Int).Lsh(n.chainID, 1), big.NewInt(35)))
+	if !recovery.IsInt64() || recovery.Int64() < 0 || recovery.Int64() > 1 {
+		return CallMsg{}, 0, fmt.Errorf("invalid v %s for chain %s", v, n.chainID)
+	}
+
+	var unsigned [][]byte
+	for _, field := range fields[:6] {
+		unsigned = append(unsigned, rlpBytes(field))
+	}
+	unsigned = append(unsigned, rlpUint(n.chainID), rlpUint(new(big.Int)), rlpUint(new(big.Int)))
+	hash := merkle.Keccak256(rlpList(unsigned...))
+
+	compact := make([]byte, 65)
+	compact[0] = byte(27 + recovery.Int64())
+	new(big.Int).SetBytes(fields[7]).FillBytes(compact[1:33])
+	new(big.Int).SetBytes(fields[8]).FillBytes(compact[33:])
+	key, _, err := ecdsa.RecoverCompact(compact, hash[:])
+	if err != nil {
+		return CallMsg{}, 0, err
+	}
+	sender := merkle.Keccak256(key.SerializeUncompressed()[1:])
+	copy(msg.From[:], sender[12:])
+	return msg, new(big.Int).SetBytes(fields[0]).Uint64(), nil
+}
+
+// callMsg converts a transaction call object
+func callMsg(args map[string]string) (CallMsg, error) {
+	var msg CallMsg
+	var err error
+	if msg.To, err = ParseAddress(args["to"]); err != nil {
+		return msg, err
+	}
+	if args["from"] != "" {
+		if msg.From, err = ParseAddress(args["from"]); err != nil {
+			return msg, err
+		}
+	}
+	msg.Data, err = decodeHex(args["data"])
+	return msg, err
+}
+
+// rlpDecodeList decodes an RLP list of byte strings
+func rlpDecodeList(data []byte) ([][]byte, error) {
+	payload, rest, err := rlpSplit(data, 0xc0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing bytes after list")
+	}
+	var items [][]byte
+	for len(payload) > 0 {
+		if payload[0] < 0x80 {
+			items = append(items, payload[:1])
+			payload = payload[1:]
+			continue
+		}
+		var item []byte
+		if item, payload, err = rlpSplit(payload, 0x80); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// rlpSplit returns the payload of the string (offset 0x80) or list (offset 0xc0) at the
+// start of data and the bytes after it
+func rlpSplit(data []byte, offset byte) ([]byte, []byte, error) {
+	if len(data) == 0 || data[0] < offset || (offset == 0x80 && data[0] >= 0xc0) {
+		return nil, nil, fmt.Errorf("unexpected RLP prefix")
+	}
+	length, header := int(data[0]-offset), 1
+	if length > 55 {
+		size := length - 55
+		if len(data) < 1+size {
+			return nil, nil, fmt.Errorf("truncated RLP length")
+		}
+		length, header = int(new(big.Int).SetBytes(data[1:1+size]).Int64()), 1+size
+	}
+	if len(data) < header+length {
+		return nil, nil, fmt.Errorf("truncated RLP item")
+	}
+	return data[header : header+length], data[header+length:], nil
+}
+
+// Function and event signatures of the registry contract
+const (
+	sigRegister   = "register(bytes)"
+	sigRegistered = "registered(address)"
+
+	sigRegisteredEvent = "Registered(address,bytes)"
+)
+
+// registry is a Backend holding a contract that stores one payload per sender
+type registry struct {
+	address   Address
+	blockTime uint64
+	payloads  map[Address][]byte
+}
+
+// CallContract implements Backend
+func (r *registry) CallContract(ctx context.Context, msg CallMsg) ([]byte, error) {
+	sig, args, err := Dispatch(msg.Data, sigRegister, sigRegistered)
+	if err != nil {
+		return nil, err
+	}
+	if sig != sigRegistered {
+		return nil, Revert(fmt.Sprintf("%s is not a view function", sig))
+	}
+	account, err := args.Address(0)
+	if err != nil {
+		return nil, err
+	}
+	return abi.Encode(abi.Bytes(r.payloads[account])), nil
+}
+
+// SendTransaction implements Backend
+func (r *registry) SendTransaction(ctx context.Context, msg CallMsg) (*Receipt, error) {
+	sig, args, err := Dispatch(msg.Data, sigRegister, sigRegistered)
+	if err != nil {
+		return nil, err
+	}
+	if sig != sigRegister {
+		return nil, Revert(fmt.Sprintf("%s is a view function", sig))
+	}
+	payload, err := args.Bytes(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) == 0 {
+		return nil, Revert("EmptyPayload")
+	}
+
+	r.blockTime++
+	r.payloads[msg.From] = payload
+	return &Receipt{
+		BlockTimestamp: r.blockTime,
+		Logs: []Log{{
+			Address: r.address,
+			Topics:  []merkle.Hash{EventTopic(sigRegisteredEvent), AddressTopic(msg.From)},
+			Data:    abi.Encode(abi.Bytes(payload)),
+		}},
+	}, nil
+}
+
+func TestRPCBackend(t *testing.T) {
+	ctx := context.Background()
+	key := testKey(0x11)
+	sender := KeyAddress(key)
+
+	contract := &registry{address: Address{0xaa}, blockTime: 1700000000, payloads: make(map[Address][]byte)}
+	node := &rpcNode{
+		chainID:  big.NewInt(31337),
+		backend:  contract,
+		nonces:   make(map[Address]uint64),
+		receipts: make(map[string]map[string]any),
+		polled:   make(map[string]bool),
+		blocks:   make(map[string]uint64),
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	backend := NewRPCBackend(server.URL, server.Client(), key)
+	backend.pollInterval = time.Millisecond
+
+	for i, payload := range []string{"first", "second"} {
+		receipt, err := backend.SendTransaction(ctx, CallMsg{From: sender, To: contract.address, Data: Calldata(sigRegister, abi.Bytes([]byte(payload)))})
+		if err != nil {
+			t.Fatalf("SendTransaction failed: %v", err)
+		}
+		if receipt.BlockNumber != uint64(i+1) || receipt.BlockTimestamp != contract.blockTime {
+			t.Errorf("Expected block %d at %d, got %d at %d", i+1, contract.blockTime, receipt.BlockNumber, receipt.BlockTimestamp)
+		}
+		if len(receipt.Logs) != 1 || receipt.Logs[0].Address != contract.address || TopicAddress(receipt.Logs[0].Topics[1]) != sender {
+			t.Fatalf("Expected a Registered log by %s, got %+v", sender, receipt.Logs)
+		}
+		if data, err := abi.NewDecoder(receipt.Logs[0].Data).Bytes(0); err != nil || string(data) != payload {
+			t.Errorf("Expected logged payload %q, got %q (%v)", payload, data, err)
+		}
+	}
+	if node.nonces[sender] != 2 {
+		t.Errorf("Expected two transactions from %s, got %d", sender, node.nonces[sender])
+	}
+
+	out, err := backend.CallContract(ctx, CallMsg{To: contract.address, Data: Calldata(sigRegistered, abi.Address(sender))})
+	if err != nil {
+		t.Fatalf("CallContract failed: %v", err)
+	}
+	if data, err := abi.NewDecoder(out).Bytes(0); err != nil || string(data) != "second" {
+		t.Errorf("Expected stored payload %q, got %q (%v)", "second", data, err)
+	}
+	if blockNumber, err := backend.BlockNumber(ctx); err != nil || blockNumber != 2 {
+		t.Errorf("Expected block number 2, got %d (err: %v)", blockNumber, err)
+	}
+
+	if _, err := backend.SendTransaction(ctx, CallMsg{From: sender, To: contract.address, Data: Calldata(sigRegister, abi.Bytes(nil))}); !errors.Is(err, ErrReverted) {
+		t.Errorf("Expected a failed transaction to revert, got: %v", err)
+	}
+	if _, err := backend.CallContract(ctx, CallMsg{To: contract.address, Data: []byte{1, 2, 3, 4}}); !errors.Is(err, ErrReverted) {
+		t.Errorf("Expected an unknown selector to revert, got: %v", err)
+	}
+	if _, err := backend.SendTransaction(ctx, CallMsg{From: Address{0x99}, To: contract.address}); err == nil || !strings.Contains(err.Error(), "no key") {
+		t.Errorf("Expected a send from an unknown account to fail, got: %v", err)
+	}
+}
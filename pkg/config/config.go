@@ -0,0 +1,317 @@
+// Package config loads the performer configuration. Settings are read from defaults, then
+// an optional YAML file, then environment variables, then command-line flags, each
+// overriding the one before, and the result is validated as a whole.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/cas"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// Log formats
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+// Config is the performer configuration
+type Config struct {
+	Server       ServerConfig       `yaml:"server"`
+	Log          LogConfig          `yaml:"log"`
+	Verification VerificationConfig `yaml:"verification"`
+	Payload      PayloadConfig      `yaml:"payload"`
+	Fetchers     FetchersConfig     `yaml:"fetchers"`
+}
+
+// ServerConfig configures the performer gRPC server
+type ServerConfig struct {
+	Port int `yaml:"port"`
+	// Timeout bounds the handling of a single task
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// LogConfig configures logging
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // json or console
+}
+
+// VerificationConfig selects the verification policy
+type VerificationConfig struct {
+	// ExecutionPrice is one of the orderbookchecker.ExecutionPrice* policies
+	ExecutionPrice string `yaml:"execution_price"`
+	// SelfTrade is one of the orderbookchecker.SelfTrade* modes
+	SelfTrade    string             `yaml:"self_trade"`
+	SignedOrders SignedOrdersConfig `yaml:"signed_orders"`
+	Rules        RulesConfig        `yaml:"rules"`
+	Tolerances   TolerancesConfig   `yaml:"tolerances"`
+}
+
+// RulesConfig switches the orderbookchecker.Rule* checks on and off; all are on by default
+type RulesConfig struct {
+	Fees         bool `yaml:"fees"`
+	FillOrKill   bool `yaml:"fill_or_kill"`
+	ResidualBook bool `yaml:"residual_book"`
+}
+
+// TolerancesConfig relaxes exact comparisons by a number of base units
+type TolerancesConfig struct {
+	Fee   uint64 `yaml:"fee"`   // collateral base units a charged fee may differ by
+	Price uint64 `yaml:"price"` // price base units a trade may deviate from the execution price policy by
+}
+
+// SignedOrdersConfig requires orders to carry EIP-712 maker signatures against an exchange
+// domain. The domain name and version are those of the CTF Exchange.
+type SignedOrdersConfig struct {
+	Enabled           bool   `yaml:"enabled"`
+	ChainID           uint64 `yaml:"chain_id"`
+	VerifyingContract string `yaml:"verifying_contract"`
+}
+
+// PayloadConfig bounds task payloads
+type PayloadConfig struct {
+	MaxSize int `yaml:"max_size"` // bytes
+}
+
+// FetchersConfig configures the fetchers of reference payload blobs. http(s) locators are
+// fetched directly when HTTP is set, and only from AllowedHosts, since a task would
+// otherwise make the performer request any URL; cas locators are served from BlobDir or
+// the gateway at GatewayURL, at most one of which may be set.
+type FetchersConfig struct {
+	HTTP         bool          `yaml:"http"`
+	AllowedHosts []string      `yaml:"allowed_hosts"` // host names or host:port pairs
+	BlobDir      string        `yaml:"blob_dir"`
+	GatewayURL   string        `yaml:"gateway_url"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+// Default returns the configuration used for settings that are not configured
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{Port: 8080, Timeout: 5 * time.Second},
+		Log:    LogConfig{Level: "info", Format: LogFormatJSON},
+		Verification: VerificationConfig{
+			ExecutionPrice: orderbookchecker.ExecutionPriceAny,
+			SelfTrade:      orderbookchecker.SelfTradeAllow,
+			SignedOrders: SignedOrdersConfig{
+				ChainID:           orderbookchecker.CTFExchangeDomain.ChainID,
+				VerifyingContract: orderbookchecker.CTFExchangeDomain.VerifyingContract,
+			},
+			Rules: RulesConfig{Fees: true, FillOrKill: true, ResidualBook: true},
+		},
+		Payload:  PayloadConfig{MaxSize: 32 << 20},
+		Fetchers: FetchersConfig{Timeout: 3 * time.Second},
+	}
+}
+
+// Load builds the configuration from the command-line arguments and the environment. The
+// YAML file is named by the -config flag or the PERFORMER_CONFIG variable. -h and -help
+// print the usage and return flag.ErrHelp.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	fs := flag.NewFlagSet("performer", flag.ContinueOnError)
+	path := fs.String("config", "", "Path to a YAML configuration file")
+	for _, s := range settings {
+		if s.flag != "" {
+			fs.Var(&flagValue{boolean: s.boolean}, s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg := Default()
+	if *path == "" {
+		*path = getenv("PERFORMER_CONFIG")
+	}
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := s.set(cfg, v); err != nil {
+				return nil, fmt.Errorf("%s: %v", s.env, err)
+			}
+		}
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if err == nil && s.flag == f.Name {
+				if setErr := s.set(cfg, f.Value.String()); setErr != nil {
+					err = fmt.Errorf("-%s: %v", f.Name, setErr)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile overrides the configuration with a YAML file. Unknown keys are rejected, so a
+// misspelt setting is not silently ignored.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// Validate checks every setting and reports all invalid ones together
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		invalid("server.port", "must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	if c.Server.Timeout <= 0 {
+		invalid("server.timeout", "must be positive, got %s", c.Server.Timeout)
+	}
+
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		invalid("log.level", "must be one of debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if c.Log.Format != LogFormatJSON && c.Log.Format != LogFormatConsole {
+		invalid("log.format", "must be %s or %s, got %q", LogFormatJSON, LogFormatConsole, c.Log.Format)
+	}
+
+	if err := orderbookchecker.NewOrderbookVerifier(zap.NewNop(), c.Verification.Options()...).ValidateOptions(); err != nil {
+		invalid("verification", "%v", err)
+	}
+	if c.Verification.SignedOrders.Enabled && c.Verification.SignedOrders.ChainID == 0 {
+		invalid("verification.signed_orders.chain_id", "is required when signed orders are enabled")
+	}
+
+	if c.Payload.MaxSize <= 0 {
+		invalid("payload.max_size", "must be positive, got %d", c.Payload.MaxSize)
+	}
+
+	if c.Fetchers.HTTP && len(c.Fetchers.AllowedHosts) == 0 {
+		invalid("fetchers.allowed_hosts", "is required when http fetches are enabled")
+	}
+	for _, host := range c.Fetchers.AllowedHosts {
+		if host == "" || strings.ContainsAny(host, "/?#@ ") {
+			invalid("fetchers.allowed_hosts", "must be host names or host:port pairs, got %q", host)
+		}
+	}
+	if c.Fetchers.BlobDir != "" && c.Fetchers.GatewayURL != "" {
+		invalid("fetchers", "set at most one of blob_dir and gateway_url")
+	}
+	if c.Fetchers.GatewayURL != "" {
+		if u, err := url.Parse(c.Fetchers.GatewayURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("fetchers.gateway_url", "must be an http(s) URL, got %q", c.Fetchers.GatewayURL)
+		}
+	}
+	if c.Fetchers.Timeout <= 0 {
+		invalid("fetchers.timeout", "must be positive, got %s", c.Fetchers.Timeout)
+	} else if c.Fetchers.Timeout >= c.Server.Timeout {
+		invalid("fetchers.timeout", "must be shorter than server.timeout (%s), got %s", c.Server.Timeout, c.Fetchers.Timeout)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Logger builds the logger the configuration describes
+func (c LogConfig) Logger() (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(c.Level)
+	if err != nil {
+		return nil, err
+	}
+	zapConfig := zap.NewProductionConfig()
+	if c.Format == LogFormatConsole {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	return zapConfig.Build()
+}
+
+// Options returns the verifier options of the policy
+func (c VerificationConfig) Options() []orderbookchecker.VerifierOption {
+	opts := []orderbookchecker.VerifierOption{
+		orderbookchecker.WithExecutionPricePolicy(c.ExecutionPrice),
+		orderbookchecker.WithSelfTradePrevention(c.SelfTrade),
+	}
+	if c.SignedOrders.Enabled {
+		domain := orderbookchecker.CTFExchangeDomain
+		domain.ChainID = c.SignedOrders.ChainID
+		domain.VerifyingContract = c.SignedOrders.VerifyingContract
+		opts = append(opts, orderbookchecker.WithSignedOrders(domain))
+	}
+
+	var disabled []string
+	for _, rule := range []struct {
+		name    string
+		enabled bool
+	}{
+		{orderbookchecker.RuleFees, c.Rules.Fees},
+		{orderbookchecker.RuleFillOrKill, c.Rules.FillOrKill},
+		{orderbookchecker.RuleResidualBook, c.Rules.ResidualBook},
+	} {
+		if !rule.enabled {
+			disabled = append(disabled, rule.name)
+		}
+	}
+	if len(disabled) > 0 {
+		opts = append(opts, orderbookchecker.WithDisabledRules(disabled...))
+	}
+	if c.Tolerances.Fee > 0 {
+		opts = append(opts, orderbookchecker.WithFeeTolerance(new(big.Int).SetUint64(c.Tolerances.Fee)))
+	}
+	if c.Tolerances.Price > 0 {
+		opts = append(opts, orderbookchecker.WithPriceTolerance(new(big.Int).SetUint64(c.Tolerances.Price)))
+	}
+	return opts
+}
+
+// Fetcher returns the fetcher of reference payload blobs, routing locators by scheme. A
+// blob is no larger than the payload it stands in for, so blobs are bounded by maxSize.
+func (c FetchersConfig) Fetcher(maxSize int) cas.Fetcher {
+	limit := cas.WithMaxBlobSize(maxSize)
+	router := cas.NewRouter()
+	if c.HTTP {
+		httpFetcher := cas.NewHTTPFetcher(nil, c.AllowedHosts, limit)
+		router.Handle("http", httpFetcher)
+		router.Handle("https", httpFetcher)
+	}
+	if c.BlobDir != "" {
+		router.Handle(cas.SchemeCAS, cas.NewDirStore(c.BlobDir, limit))
+	} else if c.GatewayURL != "" {
+		router.Handle(cas.SchemeCAS, cas.NewGatewayStore(c.GatewayURL, nil, limit))
+	}
+	return router
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/cas"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// env returns a getenv over a fixed environment
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+// writeConfig writes a YAML config file and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "performer.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Server.Timeout != 5*time.Second || cfg.Log.Level != "info" || cfg.Log.Format != LogFormatJSON {
+		t.Errorf("Unexpected defaults %+v", cfg)
+	}
+	if _, err := cfg.Log.Logger(); err != nil {
+		t.Errorf("Failed to build the default logger: %v", err)
+	}
+	if cfg.Fetchers.HTTP {
+		t.Error("Expected http fetches to be off by default")
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  port: 9000
+  timeout: 10s
+log:
+  level: debug
+verification:
+  execution_price: maker
+  signed_orders:
+    enabled: true
+    chain_id: 80002
+payload:
+  max_size: 1024
+fetchers:
+  http: true
+  allowed_hosts: [blobs.example.com]
+  blob_dir: /var/blobs
+`)
+
+	cfg, err := Load(
+		[]string{"-config", path, "-port", "9100", "-self-trade", orderbookchecker.SelfTradeCancelResting},
+		env(map[string]string{"PERFORMER_PORT": "9050", "PERFORMER_LOG_LEVEL": "warn", "PERFORMER_FETCH_TIMEOUT": "2s"}),
+	)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"flag over env over file", cfg.Server.Port, 9100},
+		{"file over default", cfg.Server.Timeout, 10 * time.Second},
+		{"env over file", cfg.Log.Level, "warn"},
+		{"default", cfg.Log.Format, LogFormatJSON},
+		{"file policy", cfg.Verification.ExecutionPrice, orderbookchecker.ExecutionPriceMaker},
+		{"flag policy", cfg.Verification.SelfTrade, orderbookchecker.SelfTradeCancelResting},
+		{"file chain id", cfg.Verification.SignedOrders.ChainID, uint64(80002)},
+		{"default contract", cfg.Verification.SignedOrders.VerifyingContract, orderbookchecker.CTFExchangeDomain.VerifyingContract},
+		{"file payload size", cfg.Payload.MaxSize, 1024},
+		{"file http", cfg.Fetchers.HTTP, true},
+		{"file allowed hosts", strings.Join(cfg.Fetchers.AllowedHosts, ","), "blobs.example.com"},
+		{"file blob dir", cfg.Fetchers.BlobDir, "/var/blobs"},
+		{"env fetch timeout", cfg.Fetchers.Timeout, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if opts := cfg.Verification.Options(); len(opts) != 3 {
+		t.Errorf("Expected the signed orders option to be set, got %d options", len(opts))
+	}
+
+	// The file can also be named by the environment
+	cfg, err = Load(nil, env(map[string]string{"PERFORMER_CONFIG": path}))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Server.Port != 9000 {
+		t.Errorf("Expected the port from the file, got %d", cfg.Server.Port)
+	}
+}
+
+func TestLoad_Flags(t *testing.T) {
+	// A bare boolean flag sets it, and an explicit value overrides the environment
+	cfg, err := Load(
+		[]string{"-signed-orders", "-http-fetch=false", "-fetch-allowed-hosts", "a.example.com, b.example.com:8443"},
+		env(map[string]string{"PERFORMER_HTTP_FETCH": "true"}),
+	)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.Verification.SignedOrders.Enabled {
+		t.Error("Expected a bare -signed-orders to enable signed orders")
+	}
+	if cfg.Fetchers.HTTP {
+		t.Error("Expected -http-fetch=false to override the environment")
+	}
+	if got := strings.Join(cfg.Fetchers.AllowedHosts, ","); got != "a.example.com,b.example.com:8443" {
+		t.Errorf("Unexpected allowed hosts %q", got)
+	}
+
+	// An empty list clears the hosts instead of allowing an empty host name
+	for _, hosts := range []string{"", " , "} {
+		cfg, err := Load([]string{"-fetch-allowed-hosts=" + hosts}, env(nil))
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if cfg.Fetchers.AllowedHosts != nil {
+			t.Errorf("%q: expected no allowed hosts, got %q", hosts, cfg.Fetchers.AllowedHosts)
+		}
+		if _, err := Load([]string{"-http-fetch", "-fetch-allowed-hosts=" + hosts}, env(nil)); err == nil || !strings.Contains(err.Error(), "allowed_hosts") {
+			t.Errorf("%q: expected http fetches without allowed hosts to be rejected, got: %v", hosts, err)
+		}
+	}
+
+	for _, arg := range []string{"-h", "-help"} {
+		if _, err := Load([]string{arg}, env(nil)); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("%s: expected flag.ErrHelp, got: %v", arg, err)
+		}
+	}
+}
+
+func TestVerificationConfig_Options(t *testing.T) {
+	path := writeConfig(t, `
+verification:
+  execution_price: maker
+  rules:
+    fill_or_kill: false
+  tolerances:
+    fee: 10000
+`)
+	cfg, err := Load([]string{"-config", path}, env(map[string]string{"PERFORMER_RULE_FEES": "false", "PERFORMER_PRICE_TOLERANCE": "1"}))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := VerificationConfig{
+		ExecutionPrice: orderbookchecker.ExecutionPriceMaker,
+		SelfTrade:      orderbookchecker.SelfTradeAllow,
+		SignedOrders:   Default().Verification.SignedOrders,
+		Rules:          RulesConfig{Fees: false, FillOrKill: false, ResidualBook: true},
+		Tolerances:     TolerancesConfig{Fee: 10000, Price: 1},
+	}
+	if cfg.Verification != want {
+		t.Errorf("Expected %+v, got %+v", want, cfg.Verification)
+	}
+
+	// Execution price, self-trade, disabled rules and both tolerances
+	if opts := cfg.Verification.Options(); len(opts) != 5 {
+		t.Errorf("Expected 5 verifier options, got %d", len(opts))
+	}
+	if opts := Default().Verification.Options(); len(opts) != 2 {
+		t.Errorf("Expected the default policy to set 2 verifier options, got %d", len(opts))
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+		want []string
+	}{
+		{"invalid port", []string{"-port", "70000"}, nil, "", []string{"server.port"}},
+		{"malformed env", nil, map[string]string{"PERFORMER_TIMEOUT": "soon"}, "", []string{"PERFORMER_TIMEOUT", "invalid duration"}},
+		{"malformed flag", []string{"-max-payload-size", "big"}, nil, "", []string{"-max-payload-size", "invalid integer"}},
+		{"malformed bool flag", []string{"-signed-orders=maybe"}, nil, "", []string{"-signed-orders", "invalid boolean"}},
+		{"malformed tolerance", nil, map[string]string{"PERFORMER_FEE_TOLERANCE": "-1"}, "", []string{"PERFORMER_FEE_TOLERANCE", "invalid unsigned integer"}},
+		{"unknown flag", []string{"-verbose"}, nil, "", []string{"not defined"}},
+		{"unknown file key", nil, nil, "server:\n  prot: 9000\n", []string{"prot", "not found"}},
+		{"missing file", []string{"-config", "/nonexistent/performer.yaml"}, nil, "", []string{"failed to read config file"}},
+		{"unknown policy", []string{"-execution-price", "best"}, nil, "", []string{"verification", "invalid execution price policy"}},
+		{"invalid verifying contract", nil, map[string]string{"PERFORMER_SIGNED_ORDERS": "true", "PERFORMER_SIGNED_ORDERS_VERIFYING_CONTRACT": "0x12"}, "", []string{"verifying contract"}},
+		{"http without allowed hosts", nil, map[string]string{"PERFORMER_HTTP_FETCH": "true"}, "", []string{"fetchers.allowed_hosts", "required"}},
+		{"allowed host with a path", []string{"-fetch-allowed-hosts", "blobs.example.com,https://blobs.example.com/"}, nil, "", []string{"fetchers.allowed_hosts", "https://blobs.example.com/"}},
+		{"two cas sources", []string{"-blob-dir", "/var/blobs", "-blob-gateway-url", "https://gateway.example.com"}, nil, "", []string{"at most one"}},
+		{"relative gateway", []string{"-blob-gateway-url", "gateway/blobs"}, nil, "", []string{"fetchers.gateway_url"}},
+		{"fetch outlasts task", []string{"-fetch-timeout", "5s"}, nil, "", []string{"shorter than server.timeout"}},
+		{"all errors reported", []string{"-log-level", "loud", "-log-format", "xml"}, nil, "", []string{"log.level", "log.format"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfig(t, tt.file)}, args...)
+			}
+			_, err := Load(args, env(tt.env))
+			if err == nil {
+				t.Fatal("Expected configuration error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error containing %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchersConfig_Fetcher(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	locator, err := cas.NewDirStore(dir).Put(ctx, []byte("trade batch"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		config  FetchersConfig
+		maxSize int
+		locator string
+		wantErr error
+		want    string
+	}{
+		{"within the payload size", FetchersConfig{BlobDir: dir}, 1024, locator, nil, ""},
+		{"larger than the payload size", FetchersConfig{BlobDir: dir}, 4, locator, cas.ErrTooLarge, ""},
+		{"http off", FetchersConfig{}, 1024, "http://169.254.169.254/latest", nil, "no fetcher"},
+		{"host not allowed", FetchersConfig{HTTP: true, AllowedHosts: []string{"blobs.example.com"}}, 1024, "http://169.254.169.254/latest", cas.ErrHostNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.config.Fetcher(tt.maxSize).Fetch(ctx, tt.locator)
+			if tt.wantErr == nil && tt.want == "" {
+				if err != nil {
+					t.Fatalf("Fetch failed: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected fetch error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+			if tt.want != "" && !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is a configuration value that can be overridden by an environment variable and,
+// if flag is set, a command-line flag. Boolean settings are bool flags, so a bare -flag
+// means true.
+type setting struct {
+	env     string
+	flag    string
+	usage   string
+	boolean bool
+	set     func(c *Config, v string) error
+}
+
+// settings lists the overridable settings. Variables without a flag are rarely changed per
+// run and are left to the file and the environment.
+var settings = []setting{
+	intSetting("PERFORMER_PORT", "port", "gRPC server port", func(c *Config) *int { return &c.Server.Port }),
+	durationSetting("PERFORMER_TIMEOUT", "timeout", "Task handling timeout", func(c *Config) *time.Duration { return &c.Server.Timeout }),
+	stringSetting("PERFORMER_LOG_LEVEL", "log-level", "Log level: debug, info, warn or error", func(c *Config) *string { return &c.Log.Level }),
+	stringSetting("PERFORMER_LOG_FORMAT", "log-format", "Log format: json or console", func(c *Config) *string { return &c.Log.Format }),
+	stringSetting("PERFORMER_EXECUTION_PRICE", "execution-price", "Execution price policy: any, maker, midpoint or taker", func(c *Config) *string {
+		return &c.Verification.ExecutionPrice
+	}),
+	stringSetting("PERFORMER_SELF_TRADE", "self-trade", "Self-trade prevention: allow, cancel_resting or cancel_incoming", func(c *Config) *string {
+		return &c.Verification.SelfTrade
+	}),
+	boolSetting("PERFORMER_SIGNED_ORDERS", "signed-orders", "Require EIP-712 signed orders", func(c *Config) *bool {
+		return &c.Verification.SignedOrders.Enabled
+	}),
+	uint64Setting("PERFORMER_SIGNED_ORDERS_CHAIN_ID", "", "", func(c *Config) *uint64 { return &c.Verification.SignedOrders.ChainID }),
+	stringSetting("PERFORMER_SIGNED_ORDERS_VERIFYING_CONTRACT", "", "", func(c *Config) *string {
+		return &c.Verification.SignedOrders.VerifyingContract
+	}),
+	boolSetting("PERFORMER_RULE_FEES", "", "", func(c *Config) *bool { return &c.Verification.Rules.Fees }),
+	boolSetting("PERFORMER_RULE_FILL_OR_KILL", "", "", func(c *Config) *bool { return &c.Verification.Rules.FillOrKill }),
+	boolSetting("PERFORMER_RULE_RESIDUAL_BOOK", "", "", func(c *Config) *bool { return &c.Verification.Rules.ResidualBook }),
+	uint64Setting("PERFORMER_FEE_TOLERANCE", "", "", func(c *Config) *uint64 { return &c.Verification.Tolerances.Fee }),
+	uint64Setting("PERFORMER_PRICE_TOLERANCE", "", "", func(c *Config) *uint64 { return &c.Verification.Tolerances.Price }),
+	intSetting("PERFORMER_MAX_PAYLOAD_SIZE", "max-payload-size", "Maximum task payload size in bytes", func(c *Config) *int { return &c.Payload.MaxSize }),
+	boolSetting("PERFORMER_HTTP_FETCH", "http-fetch", "Fetch http(s) blobs from the allowed hosts", func(c *Config) *bool { return &c.Fetchers.HTTP }),
+	{
+		env:   "PERFORMER_FETCH_ALLOWED_HOSTS",
+		flag:  "fetch-allowed-hosts",
+		usage: "Comma-separated hosts http(s) blobs may be fetched from",
+		set: func(c *Config, v string) error {
+			c.Fetchers.AllowedHosts = splitList(v)
+			return nil
+		},
+	},
+	stringSetting("PERFORMER_BLOB_DIR", "blob-dir", "Directory serving cas:// blobs", func(c *Config) *string { return &c.Fetchers.BlobDir }),
+	stringSetting("PERFORMER_BLOB_GATEWAY_URL", "blob-gateway-url", "Content gateway serving cas:// blobs", func(c *Config) *string {
+		return &c.Fetchers.GatewayURL
+	}),
+	durationSetting("PERFORMER_FETCH_TIMEOUT", "fetch-timeout", "Timeout for fetching the blobs of a task", func(c *Config) *time.Duration {
+		return &c.Fetchers.Timeout
+	}),
+}
+
+// flagValue holds the raw value of a setting's flag until it is applied over the file and
+// the environment
+type flagValue struct {
+	value   string
+	boolean bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *flagValue) Set(v string) error {
+	f.value = v
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be set with a bare -flag
+func (f *flagValue) IsBoolFlag() bool {
+	return f.boolean
+}
+
+func stringSetting(env, flag, usage string, field func(c *Config) *string) setting {
+	return setting{env: env, flag: flag, usage: usage, set: func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}}
+}
+
+func intSetting(env, flag, usage string, field func(c *Config) *int) setting {
+	return setting{env: env, flag: flag, usage: usage, set: func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+func uint64Setting(env, flag, usage string, field func(c *Config) *uint64) setting {
+	return setting{env: env, flag: flag, usage: usage, set: func(c *Config, v string) error {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", v)
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+func boolSetting(env, flag, usage string, field func(c *Config) *bool) setting {
+	return setting{env: env, flag: flag, usage: usage, boolean: true, set: func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*field(c) = b
+		return nil
+	}}
+}
+
+func durationSetting(env, flag, usage string, field func(c *Config) *time.Duration) setting {
+	return setting{env: env, flag: flag, usage: usage, set: func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*field(c) = d
+		return nil
+	}}
+}
+
+// splitList splits a comma-separated list, dropping surrounding spaces and empty items, so
+// an empty value is an empty list
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,166 @@
+// Package mailbox contains Go bindings for the Hourglass TaskMailbox contract deployed on L2,
+// through which the aggregator creates tasks for the executor operator set, and an in-process
+// simulated backend for tests and demos.
+//
+// Like pkg/settlement, the bindings are maintained by hand on top of pkg/abi and cover only
+// the functions the aggregator uses; the signatures must be kept in sync with the TaskMailbox
+// ABI in .hourglass/config/aggregator-template.yaml.
+package mailbox
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// Function and event signatures of TaskMailbox
+const (
+	sigCreateTask    = "createTask((address,uint96,(address,uint32),bytes))"
+	sigGetTaskStatus = "getTaskStatus(bytes32)"
+
+	sigTaskCreated = "TaskCreated(address,bytes32,address,uint32,address,uint96,uint256,bytes)"
+)
+
+// Task statuses, matching ITaskMailboxTypes.TaskStatus
+const (
+	TaskStatusNone uint8 = iota
+	TaskStatusCreated
+	TaskStatusCanceled
+	TaskStatusVerified
+	TaskStatusExpired
+)
+
+// maxAVSFee is the largest fee that fits the uint96 avsFee field
+var maxAVSFee = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1))
+
+// Backend is a chain backend that also reports the latest block, so callers can count the
+// confirmations of a transaction
+type Backend interface {
+	chain.Backend
+	// BlockNumber returns the number of the latest block
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// OperatorSet mirrors the EigenLayer OperatorSet struct
+type OperatorSet struct {
+	AVS chain.Address
+	ID  uint32
+}
+
+// TaskParams mirrors ITaskMailboxTypes.TaskParams
+type TaskParams struct {
+	RefundCollector     chain.Address
+	AVSFee              *big.Int // nil for no fee
+	ExecutorOperatorSet OperatorSet
+	Payload             []byte
+}
+
+// TaskCreated is the TaskCreated event
+type TaskCreated struct {
+	Creator               chain.Address
+	TaskHash              merkle.Hash
+	AVS                   chain.Address
+	ExecutorOperatorSetID uint32
+	RefundCollector       chain.Address
+	AVSFee                *big.Int
+	TaskDeadline          uint64
+	Payload               []byte
+}
+
+// TaskMailbox is a binding to a deployed TaskMailbox contract
+type TaskMailbox struct {
+	address chain.Address
+	backend chain.Backend
+}
+
+// NewTaskMailbox binds the contract deployed at address
+func NewTaskMailbox(address chain.Address, backend chain.Backend) *TaskMailbox {
+	return &TaskMailbox{address: address, backend: backend}
+}
+
+// Address returns the address of the bound contract
+func (m *TaskMailbox) Address() chain.Address {
+	return m.address
+}
+
+// CreateTask creates a task for the executor operator set. The task hash is reported by the
+// TaskCreated event of the receipt.
+func (m *TaskMailbox) CreateTask(ctx context.Context, opts chain.TransactOpts, params TaskParams) (*chain.Receipt, error) {
+	fee := params.AVSFee
+	if fee == nil {
+		fee = new(big.Int)
+	}
+	if fee.Sign() < 0 || fee.Cmp(maxAVSFee) > 0 {
+		return nil, fmt.Errorf("avs fee %s does not fit in uint96", fee)
+	}
+	encodedFee, err := abi.Uint256(fee)
+	if err != nil {
+		return nil, err
+	}
+
+	data := chain.Calldata(sigCreateTask, abi.Tuple(
+		abi.Address(params.RefundCollector),
+		encodedFee,
+		abi.Tuple(abi.Address(params.ExecutorOperatorSet.AVS), abi.Uint64(uint64(params.ExecutorOperatorSet.ID))),
+		abi.Bytes(params.Payload),
+	))
+	return m.backend.SendTransaction(ctx, chain.CallMsg{From: opts.From, To: m.address, Value: opts.Value, Data: data})
+}
+
+// GetTaskStatus returns the status of a task; unknown tasks are TaskStatusNone
+func (m *TaskMailbox) GetTaskStatus(ctx context.Context, taskHash merkle.Hash) (uint8, error) {
+	out, err := m.backend.CallContract(ctx, chain.CallMsg{To: m.address, Data: chain.Calldata(sigGetTaskStatus, abi.Bytes32(taskHash))})
+	if err != nil {
+		return 0, err
+	}
+	status, err := abi.NewDecoder(out).Uint64(0)
+	if err != nil {
+		return 0, err
+	}
+	if status > uint64(TaskStatusExpired) {
+		return 0, fmt.Errorf("%w: unknown task status %d", abi.ErrInvalidEncoding, status)
+	}
+	return uint8(status), nil
+}
+
+// ParseTaskCreated decodes a TaskCreated log
+func (m *TaskMailbox) ParseTaskCreated(log chain.Log) (*TaskCreated, error) {
+	if log.Address != m.address {
+		return nil, fmt.Errorf("log emitted by %s, not %s", log.Address, m.address)
+	}
+	if len(log.Topics) != 4 || log.Topics[0] != chain.EventTopic(sigTaskCreated) {
+		return nil, fmt.Errorf("log is not a %s event", sigTaskCreated)
+	}
+
+	d := abi.NewDecoder(log.Data)
+	event := &TaskCreated{
+		Creator:  chain.TopicAddress(log.Topics[1]),
+		TaskHash: log.Topics[2],
+		AVS:      chain.TopicAddress(log.Topics[3]),
+	}
+	setID, err := d.Uint64(0)
+	if err != nil {
+		return nil, err
+	}
+	if setID > 1<<32-1 {
+		return nil, fmt.Errorf("%w: operator set ID %d overflows uint32", abi.ErrInvalidEncoding, setID)
+	}
+	event.ExecutorOperatorSetID = uint32(setID)
+	if event.RefundCollector, err = d.Address(1); err != nil {
+		return nil, err
+	}
+	if event.AVSFee, err = d.Uint256(2); err != nil {
+		return nil, err
+	}
+	if event.TaskDeadline, err = d.Uint64(3); err != nil {
+		return nil, err
+	}
+	if event.Payload, err = d.Bytes(4); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -0,0 +1,83 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+)
+
+var (
+	creator     = chain.Address{0x01}
+	avs         = chain.Address{0x02}
+	operatorSet = OperatorSet{AVS: avs, ID: 1}
+)
+
+func TestSimulatedBackend_TaskLifecycle(t *testing.T) {
+	ctx := context.Background()
+	backend := NewSimulatedBackend(31337, time.Unix(1700000000, 0), time.Minute)
+	mailbox := NewTaskMailbox(backend.ContractAddress(), backend)
+	opts := chain.TransactOpts{From: creator}
+	params := TaskParams{
+		RefundCollector:     creator,
+		AVSFee:              big.NewInt(7),
+		ExecutorOperatorSet: operatorSet,
+		Payload:             []byte(`{"trade_batch_id":"batch-1"}`),
+	}
+
+	if _, err := mailbox.CreateTask(ctx, opts, params); !errors.Is(err, chain.ErrReverted) {
+		t.Fatalf("Expected task for an unconfigured operator set to revert, got: %v", err)
+	}
+	backend.RegisterExecutorOperatorSet(operatorSet)
+
+	empty := params
+	empty.Payload = nil
+	if _, err := mailbox.CreateTask(ctx, opts, empty); !errors.Is(err, chain.ErrReverted) {
+		t.Fatalf("Expected task without payload to revert, got: %v", err)
+	}
+
+	receipt, err := mailbox.CreateTask(ctx, opts, params)
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	created, err := mailbox.ParseTaskCreated(receipt.Logs[0])
+	if err != nil {
+		t.Fatalf("ParseTaskCreated failed: %v", err)
+	}
+	if created.Creator != creator || created.AVS != avs || created.ExecutorOperatorSetID != 1 ||
+		created.AVSFee.Cmp(params.AVSFee) != 0 || string(created.Payload) != string(params.Payload) ||
+		created.TaskDeadline != receipt.BlockTimestamp+60 {
+		t.Errorf("Unexpected TaskCreated event %+v", created)
+	}
+
+	// The global task count makes every task hash unique
+	again, err := mailbox.CreateTask(ctx, opts, params)
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if second, err := mailbox.ParseTaskCreated(again.Logs[0]); err != nil || second.TaskHash == created.TaskHash {
+		t.Errorf("Expected a distinct task hash, got %+v (%v)", second, err)
+	}
+
+	if status, err := mailbox.GetTaskStatus(ctx, created.TaskHash); err != nil || status != TaskStatusCreated {
+		t.Errorf("Expected created task, got %d (%v)", status, err)
+	}
+	backend.AdjustTime(2 * time.Minute)
+	if status, err := mailbox.GetTaskStatus(ctx, created.TaskHash); err != nil || status != TaskStatusExpired {
+		t.Errorf("Expected expired task, got %d (%v)", status, err)
+	}
+
+	backend.SetTaskStatus(created.TaskHash, TaskStatusVerified)
+	if status, err := mailbox.GetTaskStatus(ctx, created.TaskHash); err != nil || status != TaskStatusVerified {
+		t.Errorf("Expected verified task, got %d (%v)", status, err)
+	}
+
+	tooLarge := params
+	tooLarge.AVSFee = new(big.Int).Lsh(big.NewInt(1), 96)
+	if _, err := mailbox.CreateTask(ctx, opts, tooLarge); err == nil {
+		t.Error("Expected fee overflowing uint96 to be rejected")
+	}
+}
@@ -0,0 +1,253 @@
+package mailbox
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// simulatedTask is the state the simulated mailbox keeps for a task
+type simulatedTask struct {
+	status   uint8
+	deadline uint64
+}
+
+// SimulatedBackend is an in-process L2 chain holding a single TaskMailbox deployment. It
+// executes calls with a Go model of the contract, one block per transaction, and lets tests
+// inject transient send failures and drive tasks through their lifecycle.
+type SimulatedBackend struct {
+	mu          sync.Mutex
+	contract    chain.Address
+	chainID     uint64
+	blockNumber uint64
+	blockTime   uint64
+	taskSLA     time.Duration
+	sendErrs    []error
+
+	taskCount    uint64
+	tasks        map[merkle.Hash]*simulatedTask
+	operatorSets map[OperatorSet]bool
+}
+
+// NewSimulatedBackend deploys TaskMailbox on a chain whose clock starts at start. Tasks
+// expire taskSLA after they are created.
+func NewSimulatedBackend(chainID uint64, start time.Time, taskSLA time.Duration) *SimulatedBackend {
+	var contract chain.Address
+	hash := merkle.Keccak256([]byte("TaskMailbox"))
+	copy(contract[:], hash[12:])
+
+	return &SimulatedBackend{
+		contract:     contract,
+		chainID:      chainID,
+		blockTime:    uint64(start.Unix()),
+		taskSLA:      taskSLA,
+		tasks:        make(map[merkle.Hash]*simulatedTask),
+		operatorSets: make(map[OperatorSet]bool),
+	}
+}
+
+// ContractAddress returns the address of the TaskMailbox deployment
+func (b *SimulatedBackend) ContractAddress() chain.Address {
+	return b.contract
+}
+
+// RegisterExecutorOperatorSet configures an executor operator set, as the AVS does with
+// setExecutorOperatorSetTaskConfig; tasks for unconfigured operator sets revert
+func (b *SimulatedBackend) RegisterExecutorOperatorSet(set OperatorSet) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.operatorSets[set] = true
+}
+
+// FailNextSends makes the next SendTransaction calls return the given errors, one per call,
+// before the transaction reaches the chain, as a dropped RPC connection would
+func (b *SimulatedBackend) FailNextSends(errs ...error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sendErrs = append(b.sendErrs, errs...)
+}
+
+// Mine mines n empty blocks, one second apart
+func (b *SimulatedBackend) Mine(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.blockNumber += uint64(n)
+	b.blockTime += uint64(n)
+}
+
+// AdjustTime moves the chain clock forward
+func (b *SimulatedBackend) AdjustTime(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.blockTime += uint64(d / time.Second)
+}
+
+// SetTaskStatus overrides the status of a task, e.g. to mark it verified once the aggregator
+// has submitted the executors' result, or to drop it as a reorg would with TaskStatusNone
+func (b *SimulatedBackend) SetTaskStatus(taskHash merkle.Hash, status uint8) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if status == TaskStatusNone {
+		delete(b.tasks, taskHash)
+		return
+	}
+	if task, ok := b.tasks[taskHash]; ok {
+		task.status = status
+	}
+}
+
+// BlockNumber implements Backend
+func (b *SimulatedBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.blockNumber, nil
+}
+
+// CallContract implements chain.Backend
+func (b *SimulatedBackend) CallContract(ctx context.Context, msg chain.CallMsg) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if msg.To != b.contract {
+		return nil, fmt.Errorf("no contract deployed at %s", msg.To)
+	}
+	sig, args, err := chain.Dispatch(msg.Data, sigCreateTask, sigGetTaskStatus)
+	if err != nil {
+		return nil, err
+	}
+	if sig != sigGetTaskStatus {
+		return nil, chain.Revert(fmt.Sprintf("%s is not a view function", sig))
+	}
+
+	taskHash, err := args.Bytes32(0)
+	if err != nil {
+		return nil, err
+	}
+	return abi.Encode(abi.Uint64(uint64(b.taskStatus(taskHash)))), nil
+}
+
+// SendTransaction implements chain.Backend. Each transaction is mined in its own block,
+// one second after the previous one; a reverted transaction leaves the state untouched.
+func (b *SimulatedBackend) SendTransaction(ctx context.Context, msg chain.CallMsg) (*chain.Receipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.sendErrs) > 0 {
+		err := b.sendErrs[0]
+		b.sendErrs = b.sendErrs[1:]
+		return nil, err
+	}
+	if msg.To != b.contract {
+		return nil, fmt.Errorf("no contract deployed at %s", msg.To)
+	}
+	sig, args, err := chain.Dispatch(msg.Data, sigCreateTask, sigGetTaskStatus)
+	if err != nil {
+		return nil, err
+	}
+	if sig != sigCreateTask {
+		return nil, chain.Revert(fmt.Sprintf("%s is a view function", sig))
+	}
+	if msg.Value != nil && msg.Value.Sign() != 0 {
+		return nil, chain.Revert(fmt.Sprintf("%s is not payable", sig))
+	}
+
+	b.blockNumber++
+	b.blockTime++
+	receipt := &chain.Receipt{
+		BlockNumber:    b.blockNumber,
+		BlockTimestamp: b.blockTime,
+	}
+	var nonce [8]byte
+	binary.BigEndian.PutUint64(nonce[:], b.blockNumber)
+	receipt.TxHash = merkle.Keccak256(msg.From[:], nonce[:], msg.Data)
+
+	if err := b.createTask(msg.From, args, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// taskStatus returns the status of a task; created tasks past their deadline are expired
+func (b *SimulatedBackend) taskStatus(taskHash merkle.Hash) uint8 {
+	task, ok := b.tasks[taskHash]
+	if !ok {
+		return TaskStatusNone
+	}
+	if task.status == TaskStatusCreated && b.blockTime > task.deadline {
+		return TaskStatusExpired
+	}
+	return task.status
+}
+
+// createTask executes createTask. The task hash commits to the global task count, the
+// mailbox address, the chain ID and the task parameters.
+func (b *SimulatedBackend) createTask(from chain.Address, args *abi.Decoder, receipt *chain.Receipt) error {
+	params, err := args.Tuple(0)
+	if err != nil {
+		return err
+	}
+	refundCollector, err := params.Address(0)
+	if err != nil {
+		return err
+	}
+	fee, err := params.Uint256(1)
+	if err != nil {
+		return err
+	}
+	// The operator set is a static tuple, so its fields sit inline in the head
+	avs, err := params.Address(2)
+	if err != nil {
+		return err
+	}
+	setID, err := params.Uint64(3)
+	if err != nil {
+		return err
+	}
+	payload, err := params.Bytes(4)
+	if err != nil {
+		return err
+	}
+
+	operatorSet := OperatorSet{AVS: avs, ID: uint32(setID)}
+	if setID > 1<<32-1 || !b.operatorSets[operatorSet] {
+		return chain.Revert("ExecutorOperatorSetTaskConfigNotSet")
+	}
+	if len(payload) == 0 {
+		return chain.Revert("PayloadIsEmpty")
+	}
+
+	encodedFee, _ := abi.Uint256(fee)
+	taskHash := merkle.Keccak256(abi.Encode(
+		abi.Uint64(b.taskCount),
+		abi.Address(b.contract),
+		abi.Uint64(b.chainID),
+		abi.Tuple(abi.Address(refundCollector), encodedFee,
+			abi.Tuple(abi.Address(avs), abi.Uint64(setID)), abi.Bytes(payload)),
+	))
+	b.taskCount++
+
+	deadline := b.blockTime + uint64(b.taskSLA/time.Second)
+	b.tasks[taskHash] = &simulatedTask{status: TaskStatusCreated, deadline: deadline}
+
+	receipt.Logs = append(receipt.Logs, chain.Log{
+		Address: b.contract,
+		Topics: []merkle.Hash{
+			chain.EventTopic(sigTaskCreated), chain.AddressTopic(from), taskHash, chain.AddressTopic(avs),
+		},
+		Data: abi.Encode(
+			abi.Uint64(setID),
+			abi.Address(refundCollector),
+			encodedFee,
+			abi.Uint64(deadline),
+			abi.Bytes(payload),
+		),
+	})
+	return nil
+}
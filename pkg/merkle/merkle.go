@@ -0,0 +1,193 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Domain separation prefixes so that a leaf can never be reinterpreted as an internal node
+const (
+	LeafPrefix byte = 0x00
+	NodePrefix byte = 0x01
+)
+
+// MaxDepth is the maximum tree height supported by the proof path bitmap
+const MaxDepth = 64
+
+// ErrLeafIndexOutOfRange is returned when a proof is requested for a leaf that does not exist
+var ErrLeafIndexOutOfRange = errors.New("leaf index out of range")
+
+// Hash is a 32-byte digest
+type Hash [32]byte
+
+// Hex returns the 0x-prefixed hex encoding of the hash
+func (h Hash) Hex() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// String implements fmt.Stringer
+func (h Hash) String() string {
+	return h.Hex()
+}
+
+// MarshalText encodes the hash as 0x-prefixed hex
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.Hex()), nil
+}
+
+// UnmarshalText decodes a 0x-prefixed hex hash
+func (h *Hash) UnmarshalText(text []byte) error {
+	parsed, err := ParseHash(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// ParseHash decodes a 32-byte hash from hex, with or without a 0x prefix
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	raw, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return h, fmt.Errorf("invalid hash %q: %v", s, err)
+	}
+	if len(raw) != len(h) {
+		return h, fmt.Errorf("invalid hash %q: expected %d bytes, got %d", s, len(h), len(raw))
+	}
+	copy(h[:], raw)
+	return h, nil
+}
+
+// HashFunc hashes the concatenation of its inputs
+type HashFunc func(data ...[]byte) Hash
+
+// SHA256 hashes data with SHA-256
+func SHA256(data ...[]byte) Hash {
+	return Hash(sha256.Sum256(bytes.Join(data, nil)))
+}
+
+// Keccak256 hashes data with the legacy Keccak-256 used by the EVM, for Solidity compatibility
+func Keccak256(data ...[]byte) Hash {
+	var h Hash
+	hasher := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hasher.Write(d)
+	}
+	hasher.Sum(h[:0])
+	return h
+}
+
+// Tree is a binary Merkle tree with domain-separated leaves and internal nodes.
+// When a level has an odd number of nodes, the last node is promoted to the next
+// level unchanged rather than duplicated, so no two leaf sets share a root.
+type Tree struct {
+	hash   HashFunc
+	levels [][]Hash // levels[0] holds the leaf hashes, the last level holds the root
+}
+
+// Proof is an inclusion proof for a single leaf
+type Proof struct {
+	LeafIndex uint64 `json:"leaf_index"`
+	Siblings  []Hash `json:"siblings"`
+	// Path has bit i set when Siblings[i] is the left-hand input of step i
+	Path uint64 `json:"path"`
+}
+
+// HashLeaf hashes raw leaf data with the leaf domain prefix
+func HashLeaf(hash HashFunc, data []byte) Hash {
+	return hash([]byte{LeafPrefix}, data)
+}
+
+// HashNode hashes two child nodes with the internal node domain prefix
+func HashNode(hash HashFunc, left, right Hash) Hash {
+	return hash([]byte{NodePrefix}, left[:], right[:])
+}
+
+// New builds a tree over the given leaf data in the order provided
+func New(leaves [][]byte, hash HashFunc) *Tree {
+	hashed := make([]Hash, len(leaves))
+	for i, leaf := range leaves {
+		hashed[i] = HashLeaf(hash, leaf)
+	}
+
+	tree := &Tree{
+		hash:   hash,
+		levels: [][]Hash{hashed},
+	}
+
+	for level := hashed; len(level) > 1; {
+		next := make([]Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i]) // Promote the unpaired node
+			} else {
+				next = append(next, HashNode(hash, level[i], level[i+1]))
+			}
+		}
+		tree.levels = append(tree.levels, next)
+		level = next
+	}
+
+	return tree
+}
+
+// Root returns the tree root, or the zero hash for an empty tree
+func (t *Tree) Root() Hash {
+	top := t.levels[len(t.levels)-1]
+	if len(top) == 0 {
+		return Hash{}
+	}
+	return top[0]
+}
+
+// LeafCount returns the number of leaves in the tree
+func (t *Tree) LeafCount() int {
+	return len(t.levels[0])
+}
+
+// Prove builds an inclusion proof for the leaf at the given index
+func (t *Tree) Prove(index int) (*Proof, error) {
+	if index < 0 || index >= t.LeafCount() {
+		return nil, fmt.Errorf("%w: %d of %d", ErrLeafIndexOutOfRange, index, t.LeafCount())
+	}
+
+	proof := &Proof{LeafIndex: uint64(index)}
+	pos := index
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := pos ^ 1
+		if sibling < len(level) {
+			if sibling < pos {
+				proof.Path |= 1 << uint(len(proof.Siblings))
+			}
+			proof.Siblings = append(proof.Siblings, level[sibling])
+		}
+		pos /= 2
+	}
+
+	return proof, nil
+}
+
+// Verify checks that leaf data is included under root according to the proof
+func Verify(root Hash, leaf []byte, proof *Proof, hash HashFunc) bool {
+	if proof == nil || len(proof.Siblings) > MaxDepth {
+		return false
+	}
+
+	node := HashLeaf(hash, leaf)
+	for i, sibling := range proof.Siblings {
+		if proof.Path&(1<<uint(i)) != 0 {
+			node = HashNode(hash, sibling, node)
+		} else {
+			node = HashNode(hash, node, sibling)
+		}
+	}
+
+	return node == root
+}
@@ -0,0 +1,118 @@
+package merkle
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = []byte(fmt.Sprintf("order-%d", i))
+	}
+	return leaves
+}
+
+func TestTree_EmptyRoot(t *testing.T) {
+	tree := New(nil, Keccak256)
+
+	if tree.Root() != (Hash{}) {
+		t.Errorf("Expected zero root for empty tree, got %s", tree.Root())
+	}
+
+	if _, err := tree.Prove(0); err == nil {
+		t.Error("Expected error proving a leaf of an empty tree")
+	}
+}
+
+func TestTree_ProveAndVerifyAllLeaves(t *testing.T) {
+	for _, hash := range []HashFunc{SHA256, Keccak256} {
+		for n := 1; n <= 9; n++ {
+			leaves := testLeaves(n)
+			tree := New(leaves, hash)
+
+			for i, leaf := range leaves {
+				proof, err := tree.Prove(i)
+				if err != nil {
+					t.Fatalf("Prove(%d) of %d leaves failed: %v", i, n, err)
+				}
+
+				if !Verify(tree.Root(), leaf, proof, hash) {
+					t.Errorf("Proof for leaf %d of %d did not verify", i, n)
+				}
+
+				if Verify(tree.Root(), []byte("tampered"), proof, hash) {
+					t.Errorf("Proof for leaf %d of %d verified tampered data", i, n)
+				}
+			}
+		}
+	}
+}
+
+func TestTree_OddLeafPromotion(t *testing.T) {
+	leaves := testLeaves(3)
+	tree := New(leaves, Keccak256)
+
+	// The third leaf has no sibling at the bottom level and is promoted unchanged
+	left := HashNode(Keccak256, HashLeaf(Keccak256, leaves[0]), HashLeaf(Keccak256, leaves[1]))
+	expected := HashNode(Keccak256, left, HashLeaf(Keccak256, leaves[2]))
+
+	if tree.Root() != expected {
+		t.Errorf("Expected root %s, got %s", expected, tree.Root())
+	}
+
+	// Duplicating the last leaf must not produce the same root
+	duplicated := New(append(testLeaves(3), leaves[2]), Keccak256)
+	if duplicated.Root() == tree.Root() {
+		t.Error("Expected different roots for trees with and without a duplicated last leaf")
+	}
+}
+
+func TestTree_DomainSeparation(t *testing.T) {
+	leaves := testLeaves(2)
+	tree := New(leaves, Keccak256)
+
+	// A leaf whose data is the concatenation of two child hashes must not collide with the parent node
+	a := HashLeaf(Keccak256, leaves[0])
+	b := HashLeaf(Keccak256, leaves[1])
+	forged := New([][]byte{append(a[:], b[:]...)}, Keccak256)
+
+	if forged.Root() == tree.Root() {
+		t.Error("Leaf and internal node hashes collide")
+	}
+}
+
+func TestTree_SolidityVector(t *testing.T) {
+	// Mirrored in contracts/test/SettlementVerifier.t.sol
+	tree := New([][]byte{[]byte("order-a"), []byte("order-b"), []byte("order-c")}, Keccak256)
+
+	if got := tree.Root().Hex(); got != "0x3fe94767685c81ec1f6496e1ff2bf15a209827d75c1ac05156b5c57ee120ea6e" {
+		t.Errorf("Unexpected root %s", got)
+	}
+
+	proof, err := tree.Prove(2)
+	if err != nil {
+		t.Fatalf("Prove failed: %v", err)
+	}
+
+	if len(proof.Siblings) != 1 || proof.Path != 1 ||
+		proof.Siblings[0].Hex() != "0x980458e1759f08525064c5d03949e615a8aa9db3de4d835e16f5161aa2417e2a" {
+		t.Errorf("Unexpected proof %+v", proof)
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	h := Keccak256([]byte{})
+	if h.Hex() != "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470" {
+		t.Fatalf("Unexpected keccak256 of empty input: %s", h)
+	}
+
+	parsed, err := ParseHash(h.Hex())
+	if err != nil || parsed != h {
+		t.Errorf("ParseHash round trip failed: %v", err)
+	}
+
+	if _, err := ParseHash("0x1234"); err == nil {
+		t.Error("Expected error for short hash")
+	}
+}
@@ -0,0 +1,156 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GenesisPrevHash is the previous hash of the first snapshot in a chain
+const GenesisPrevHash = "0x0000000000000000000000000000000000000000000000000000000000000000"
+
+// Kinds of problems reported by VerifyChain
+const (
+	ChainIssueInvalid   = "invalid"   // Snapshot cannot be hashed or its orders do not match its merkle root
+	ChainIssueGap       = "gap"       // One or more sequence numbers are missing
+	ChainIssueFork      = "fork"      // Two different snapshots claim the same sequence number or parent
+	ChainIssueReordered = "reordered" // Snapshots appear out of sequence or with timestamps going backwards
+	ChainIssueRewritten = "rewritten" // A snapshot's prev_hash does not commit to its predecessor's content
+)
+
+// ChainIssue describes a single problem found while walking a snapshot chain
+type ChainIssue struct {
+	Kind           string `json:"kind"`
+	SequenceNumber uint64 `json:"sequence_number"`
+	Message        string `json:"message"`
+}
+
+// ChainReport is the result of verifying a snapshot hash chain
+type ChainReport struct {
+	MarketID      string       `json:"market_id,omitempty"`
+	Valid         bool         `json:"valid"`
+	Snapshots     int          `json:"snapshots"`
+	FirstSequence uint64       `json:"first_sequence"`
+	LastSequence  uint64       `json:"last_sequence"`
+	HeadHash      string       `json:"head_hash,omitempty"`
+	Issues        []ChainIssue `json:"issues,omitempty"`
+}
+
+// HashSnapshot returns the chain hash of a snapshot: keccak256 over its canonical encoding,
+// which commits to the previous hash, the merkle root and every order in the snapshot
+func HashSnapshot(snapshot OrderbookSnapshot) (string, error) {
+	data, err := EncodeSnapshot(snapshot)
+	if err != nil {
+		return "", err
+	}
+	return SnapshotHashFunc(data).Hex(), nil
+}
+
+// VerifyChain checks that snapshots of a single market, given in the order they were
+// published or discovered, form a single unbroken hash chain
+func VerifyChain(snapshots []OrderbookSnapshot) *ChainReport {
+	report := &ChainReport{Snapshots: len(snapshots)}
+	if len(snapshots) == 0 {
+		report.Valid = true
+		return report
+	}
+	report.MarketID = snapshots[0].MarketID
+
+	addIssue := func(kind string, seq uint64, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, ChainIssue{
+			Kind:           kind,
+			SequenceNumber: seq,
+			Message:        fmt.Sprintf(format, args...),
+		})
+	}
+
+	hashes := make([]string, len(snapshots))
+	for i, snapshot := range snapshots {
+		if snapshot.MarketID != report.MarketID {
+			addIssue(ChainIssueInvalid, snapshot.SequenceNumber, "snapshot belongs to market %s, not %s", snapshot.MarketID, report.MarketID)
+		}
+
+		hash, err := HashSnapshot(snapshot)
+		if err != nil {
+			addIssue(ChainIssueInvalid, snapshot.SequenceNumber, "failed to hash snapshot: %v", err)
+			continue
+		}
+		hashes[i] = hash
+
+		root, err := ComputeMerkleRoot(snapshot.Orders)
+		if err != nil || !strings.EqualFold(root, snapshot.MerkleRoot) {
+			addIssue(ChainIssueInvalid, snapshot.SequenceNumber, "orders do not match merkle root %s", snapshot.MerkleRoot)
+		}
+	}
+
+	// Snapshots must be presented in publication order
+	for i := 1; i < len(snapshots); i++ {
+		prev, cur := snapshots[i-1], snapshots[i]
+		if cur.SequenceNumber < prev.SequenceNumber {
+			addIssue(ChainIssueReordered, cur.SequenceNumber, "sequence %d appears after sequence %d", cur.SequenceNumber, prev.SequenceNumber)
+		} else if cur.SequenceNumber > prev.SequenceNumber && cur.Timestamp.Before(prev.Timestamp) {
+			addIssue(ChainIssueReordered, cur.SequenceNumber, "timestamp %s is earlier than sequence %d at %s",
+				cur.Timestamp, prev.SequenceNumber, prev.Timestamp)
+		}
+	}
+
+	// Index snapshots by sequence number and by parent to detect forks
+	bySequence := make(map[uint64]int)
+	byParent := make(map[string]int)
+	for i, snapshot := range snapshots {
+		if hashes[i] == "" {
+			continue
+		}
+		if j, ok := bySequence[snapshot.SequenceNumber]; ok {
+			if hashes[j] != hashes[i] {
+				addIssue(ChainIssueFork, snapshot.SequenceNumber, "conflicting snapshots %s and %s", hashes[j], hashes[i])
+			}
+			continue
+		}
+		bySequence[snapshot.SequenceNumber] = i
+
+		parent := strings.ToLower(snapshot.PrevHash)
+		if j, ok := byParent[parent]; ok {
+			addIssue(ChainIssueFork, snapshot.SequenceNumber, "sequences %d and %d share parent %s",
+				snapshots[j].SequenceNumber, snapshot.SequenceNumber, snapshot.PrevHash)
+		} else {
+			byParent[parent] = i
+		}
+	}
+
+	// Walk the unique snapshots in sequence order and check each link
+	sequences := make([]uint64, 0, len(bySequence))
+	for seq := range bySequence {
+		sequences = append(sequences, seq)
+	}
+	sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+
+	if len(sequences) > 0 {
+		first := snapshots[bySequence[sequences[0]]]
+		if first.SequenceNumber == 1 && !strings.EqualFold(first.PrevHash, GenesisPrevHash) {
+			addIssue(ChainIssueRewritten, first.SequenceNumber, "first snapshot has non-genesis prev_hash %s", first.PrevHash)
+		}
+
+		report.FirstSequence = sequences[0]
+		report.LastSequence = sequences[len(sequences)-1]
+		report.HeadHash = hashes[bySequence[report.LastSequence]]
+	}
+
+	for k := 1; k < len(sequences); k++ {
+		prevSeq, seq := sequences[k-1], sequences[k]
+		if seq != prevSeq+1 {
+			addIssue(ChainIssueGap, seq, "missing sequences %d to %d", prevSeq+1, seq-1)
+			continue
+		}
+
+		prevHash := hashes[bySequence[prevSeq]]
+		cur := snapshots[bySequence[seq]]
+		if !strings.EqualFold(cur.PrevHash, prevHash) {
+			addIssue(ChainIssueRewritten, seq, "prev_hash %s does not match hash %s of sequence %d",
+				cur.PrevHash, prevHash, prevSeq)
+		}
+	}
+
+	report.Valid = len(report.Issues) == 0
+	return report
+}
@@ -0,0 +1,148 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// buildChain publishes n linked snapshots the way the snapshot publisher does
+func buildChain(t *testing.T, n int) []OrderbookSnapshot {
+	baseTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	prevHash := GenesisPrevHash
+
+	var chain []OrderbookSnapshot
+	for i := 1; i <= n; i++ {
+		snapshot := OrderbookSnapshot{
+			SequenceNumber: uint64(i),
+			Timestamp:      baseTime.Add(time.Duration(i) * time.Minute),
+			MarketID:       "TEST-MARKET",
+			Orders: []Order{
+				{ID: fmt.Sprintf("buy-%d", i), Side: "buy", Price: big.NewInt(100), Quantity: big.NewInt(int64(10 * i)), Timestamp: baseTime, UserID: "user1"},
+			},
+			PrevHash: prevHash,
+		}
+		chain = append(chain, sealSnapshot(t, snapshot))
+
+		hash, err := HashSnapshot(chain[len(chain)-1])
+		if err != nil {
+			t.Fatalf("HashSnapshot failed: %v", err)
+		}
+		prevHash = hash
+	}
+
+	return chain
+}
+
+// sealSnapshot recomputes the merkle root after the orders of a snapshot change
+func sealSnapshot(t *testing.T, snapshot OrderbookSnapshot) OrderbookSnapshot {
+	root, err := ComputeMerkleRoot(snapshot.Orders)
+	if err != nil {
+		t.Fatalf("ComputeMerkleRoot failed: %v", err)
+	}
+	snapshot.MerkleRoot = root
+	return snapshot
+}
+
+func TestHashSnapshot_CommitsToPrevHash(t *testing.T) {
+	snapshot := buildChain(t, 1)[0]
+
+	hash, err := HashSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("HashSnapshot failed: %v", err)
+	}
+
+	snapshot.PrevHash = "0x0000000000000000000000000000000000000000000000000000000000000001"
+	relinked, err := HashSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("HashSnapshot failed: %v", err)
+	}
+
+	if hash == relinked {
+		t.Error("Expected snapshot hash to change with prev_hash")
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name     string
+		chain    func([]OrderbookSnapshot) []OrderbookSnapshot
+		wantKind string
+	}{
+		{
+			name:  "valid chain",
+			chain: func(c []OrderbookSnapshot) []OrderbookSnapshot { return c },
+		},
+		{
+			name: "gap",
+			chain: func(c []OrderbookSnapshot) []OrderbookSnapshot {
+				return []OrderbookSnapshot{c[0], c[1], c[3]}
+			},
+			wantKind: ChainIssueGap,
+		},
+		{
+			name: "fork at the same sequence",
+			chain: func(c []OrderbookSnapshot) []OrderbookSnapshot {
+				fork := c[2]
+				fork.Orders = []Order{{ID: "fork", Side: "sell", Price: big.NewInt(1), Quantity: big.NewInt(1), Timestamp: fork.Timestamp}}
+				return append(c, sealSnapshot(t, fork))
+			},
+			wantKind: ChainIssueFork,
+		},
+		{
+			name: "reordered sequences",
+			chain: func(c []OrderbookSnapshot) []OrderbookSnapshot {
+				return []OrderbookSnapshot{c[0], c[2], c[1], c[3]}
+			},
+			wantKind: ChainIssueReordered,
+		},
+		{
+			name: "rewritten history",
+			chain: func(c []OrderbookSnapshot) []OrderbookSnapshot {
+				c[1].Orders[0].Quantity = big.NewInt(999)
+				c[1] = sealSnapshot(t, c[1])
+				return c
+			},
+			wantKind: ChainIssueRewritten,
+		},
+		{
+			name: "orders changed without updating the root",
+			chain: func(c []OrderbookSnapshot) []OrderbookSnapshot {
+				c[3].Orders[0].Price = big.NewInt(1)
+				return c
+			},
+			wantKind: ChainIssueInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := VerifyChain(tt.chain(buildChain(t, 4)))
+
+			if tt.wantKind == "" {
+				if !report.Valid {
+					t.Fatalf("Expected valid chain, got issues: %+v", report.Issues)
+				}
+				if report.FirstSequence != 1 || report.LastSequence != 4 {
+					t.Errorf("Expected sequences 1..4, got %d..%d", report.FirstSequence, report.LastSequence)
+				}
+				return
+			}
+
+			if report.Valid {
+				t.Fatal("Expected invalid chain")
+			}
+
+			found := false
+			for _, issue := range report.Issues {
+				if issue.Kind == tt.wantKind {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected %s issue, got %+v", tt.wantKind, report.Issues)
+			}
+		})
+	}
+}
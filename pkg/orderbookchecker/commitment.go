@@ -0,0 +1,111 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// SnapshotHashFunc is the hash used for snapshot order trees. Keccak-256 keeps the
+// commitments verifiable by SettlementVerifier.sol.
+var SnapshotHashFunc merkle.HashFunc = merkle.Keccak256
+
+// OrderLeaf returns the leaf data committed to for a single order: its canonical encoding
+func OrderLeaf(order Order) ([]byte, error) {
+	return EncodeOrder(order)
+}
+
+// BuildOrderTree builds the Merkle tree over the snapshot orders, sorted by ID for
+// deterministic hashing. The sorted orders are returned alongside the tree so callers
+// can map leaf indices back to orders.
+func BuildOrderTree(orders []Order) (*merkle.Tree, []Order, error) {
+	sortedOrders := make([]Order, len(orders))
+	copy(sortedOrders, orders)
+	sort.SliceStable(sortedOrders, func(i, j int) bool {
+		return sortedOrders[i].ID < sortedOrders[j].ID
+	})
+
+	leaves := make([][]byte, len(sortedOrders))
+	for i, order := range sortedOrders {
+		leaf, err := OrderLeaf(order)
+		if err != nil {
+			return nil, nil, err
+		}
+		leaves[i] = leaf
+	}
+
+	return merkle.New(leaves, SnapshotHashFunc), sortedOrders, nil
+}
+
+// ComputeMerkleRoot computes the snapshot Merkle root over the given orders
+func ComputeMerkleRoot(orders []Order) (string, error) {
+	tree, _, err := BuildOrderTree(orders)
+	if err != nil {
+		return "", err
+	}
+	return tree.Root().Hex(), nil
+}
+
+// ProveOrderInclusion builds an inclusion proof for a single order of the snapshot
+func ProveOrderInclusion(orders []Order, orderID string) (*merkle.Proof, error) {
+	tree, sortedOrders, err := BuildOrderTree(orders)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sortedOrders {
+		if sortedOrders[i].ID == orderID {
+			return tree.Prove(i)
+		}
+	}
+
+	return nil, fmt.Errorf("order not found: %s", orderID)
+}
+
+// VerifyOrderInclusion checks that an order is included under the given snapshot Merkle root
+func VerifyOrderInclusion(merkleRoot string, order Order, proof *merkle.Proof) (bool, error) {
+	root, err := merkle.ParseHash(merkleRoot)
+	if err != nil {
+		return false, fmt.Errorf("invalid merkle root: %v", err)
+	}
+
+	leaf, err := OrderLeaf(order)
+	if err != nil {
+		return false, err
+	}
+
+	return merkle.Verify(root, leaf, proof, SnapshotHashFunc), nil
+}
+
+// VerifySnapshotIntegrity recomputes the Merkle root from the snapshot orders and checks it
+// against the root stored in the snapshot, then checks the snapshot chain hash against the
+// snapshot hash committed in the task. Both commit to timestamps in milliseconds, so a
+// snapshot with a finer timestamp is rejected.
+func VerifySnapshotIntegrity(snapshot OrderbookSnapshot, snapshotHash string) error {
+	if err := checkSnapshotTimestamps(snapshot); err != nil {
+		return err
+	}
+
+	root, err := ComputeMerkleRoot(snapshot.Orders)
+	if err != nil {
+		return fmt.Errorf("failed to compute merkle root: %v", err)
+	}
+
+	if !strings.EqualFold(root, snapshot.MerkleRoot) {
+		return fmt.Errorf("%w: snapshot declares %s but orders hash to %s", ErrMerkleRootMismatch, snapshot.MerkleRoot, root)
+	}
+
+	// The publisher commits to the snapshot by its chain hash
+	hash, err := HashSnapshot(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to hash snapshot: %v", err)
+	}
+
+	if !strings.EqualFold(hash, snapshotHash) {
+		return fmt.Errorf("%w: committed %s but snapshot hashes to %s", ErrSnapshotHashMismatch, snapshotHash, hash)
+	}
+
+	return nil
+}
@@ -0,0 +1,356 @@
+package orderbookchecker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// Decoding reverses the canonical encoding. Values the encoding does not distinguish decode
+// to their absent form: zero amounts of optional fields, a zero expiration, maker and
+// signature decode as nil or empty, and GTC and normal matches as the empty string. A decoded
+// object encodes back to the exact input bytes; any other input is rejected as non-canonical.
+
+// decoder reads canonical fields, remembering the first error encountered
+type decoder struct {
+	data []byte
+	err  error
+}
+
+// next consumes n bytes
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.data) {
+		d.err = fmt.Errorf("unexpected end of data: need %d bytes, have %d", n, len(d.data))
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+// header reads the version and type tag of an encoding, which must be tag at a version up
+// to maxVersion
+func (d *decoder) header(tag, maxVersion byte) byte {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	if b[1] != tag {
+		d.err = fmt.Errorf("unexpected type tag 0x%02x, expected 0x%02x", b[1], tag)
+		return 0
+	}
+	if b[0] < EncodingVersion || b[0] > maxVersion {
+		d.err = fmt.Errorf("unsupported encoding version %d", b[0])
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) uint8() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) timestamp() time.Time {
+	return time.UnixMilli(int64(d.uint64())).UTC()
+}
+
+func (d *decoder) uint256() *big.Int {
+	if b := d.next(32); b != nil {
+		return new(big.Int).SetBytes(b)
+	}
+	return nil
+}
+
+// optionalUint256 reads an amount that encodes as zero when absent
+func (d *decoder) optionalUint256() *big.Int {
+	if v := d.uint256(); v != nil && v.Sign() != 0 {
+		return v
+	}
+	return nil
+}
+
+func (d *decoder) bytes32() string {
+	var h merkle.Hash
+	copy(h[:], d.next(len(h)))
+	return h.Hex()
+}
+
+// address reads an address, the zero address decoding as empty
+func (d *decoder) address() string {
+	b := d.next(20)
+	if b == nil || bytes.Equal(b, make([]byte, 20)) {
+		return ""
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// hexBytes reads length-prefixed bytes as a 0x-prefixed hex string, empty bytes decoding as
+// empty
+func (d *decoder) hexBytes() string {
+	b := d.next(int(d.uint32()))
+	if len(b) == 0 {
+		return ""
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+func (d *decoder) string() string {
+	return string(d.next(int(d.uint32())))
+}
+
+// lengthPrefixed reads a nested encoding prefixed with its uint32 length
+func (d *decoder) lengthPrefixed() []byte {
+	return d.next(int(d.uint32()))
+}
+
+// done returns the first error, or an error if data is left over
+func (d *decoder) done() error {
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("%d trailing bytes", len(d.data))
+	}
+	return d.err
+}
+
+// decodeSide maps a canonical byte to an order side
+func decodeSide(b byte) (string, error) {
+	switch b {
+	case encodedSideBuy:
+		return "buy", nil
+	case encodedSideSell:
+		return "sell", nil
+	default:
+		return "", fmt.Errorf("invalid encoded side %d", b)
+	}
+}
+
+// decodeOutcome maps a canonical byte to an order outcome
+func decodeOutcome(b byte) (string, error) {
+	switch b {
+	case encodedOutcomeNone:
+		return "", nil
+	case encodedOutcomeYes:
+		return OutcomeYes, nil
+	case encodedOutcomeNo:
+		return OutcomeNo, nil
+	default:
+		return "", fmt.Errorf("invalid encoded outcome %d", b)
+	}
+}
+
+// decodeTimeInForce maps a canonical byte to an order time in force
+func decodeTimeInForce(b byte) (string, error) {
+	switch b {
+	case encodedTimeInForceGTC:
+		return "", nil
+	case encodedTimeInForceGTD:
+		return TimeInForceGTD, nil
+	case encodedTimeInForceFOK:
+		return TimeInForceFOK, nil
+	case encodedTimeInForceFAK:
+		return TimeInForceFAK, nil
+	default:
+		return "", fmt.Errorf("invalid encoded time in force %d", b)
+	}
+}
+
+// decodeMatchType maps a canonical byte to a trade match type
+func decodeMatchType(b byte) (string, error) {
+	switch b {
+	case encodedMatchNormal:
+		return "", nil
+	case encodedMatchMint:
+		return MatchTypeMint, nil
+	case encodedMatchMerge:
+		return MatchTypeMerge, nil
+	default:
+		return "", fmt.Errorf("invalid encoded match type %d", b)
+	}
+}
+
+// checkCanonical checks that a decoded object encodes back to its input
+func checkCanonical(data []byte, encode func() ([]byte, error)) error {
+	encoded, err := encode()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(encoded, data) {
+		return fmt.Errorf("non-canonical encoding")
+	}
+	return nil
+}
+
+// decodeOrder decodes an order without checking that its encoding is canonical
+func decodeOrder(data []byte) (Order, error) {
+	d := &decoder{data: data}
+	version := d.header(encodingTagOrder, EncodingVersion4)
+
+	var order Order
+	order.ID = d.string()
+	side := d.uint8()
+	order.Price = d.uint256()
+	order.Quantity = d.uint256()
+	order.Timestamp = d.timestamp()
+	order.UserID = d.string()
+
+	var outcome, timeInForce byte
+	var expiration uint64
+	if version >= EncodingVersion2 {
+		order.TokenID = d.string()
+		outcome = d.uint8()
+		timeInForce = d.uint8()
+		expiration = d.uint64()
+	}
+	if version >= EncodingVersion3 {
+		order.FeeRateBps = d.uint32()
+	}
+	if version >= EncodingVersion4 {
+		order.Maker = d.address()
+		order.Salt = d.uint256()
+		order.Nonce = d.uint256()
+		order.Signature = d.hexBytes()
+	}
+	if err := d.done(); err != nil {
+		return Order{}, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+
+	var err error
+	if order.Side, err = decodeSide(side); err != nil {
+		return Order{}, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+	if order.Outcome, err = decodeOutcome(outcome); err != nil {
+		return Order{}, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+	if order.TimeInForce, err = decodeTimeInForce(timeInForce); err != nil {
+		return Order{}, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+	if expiration != 0 {
+		t := time.UnixMilli(int64(expiration)).UTC()
+		order.Expiration = &t
+	}
+	return order, nil
+}
+
+// DecodeOrder decodes the canonical encoding of an order
+func DecodeOrder(data []byte) (Order, error) {
+	order, err := decodeOrder(data)
+	if err != nil {
+		return Order{}, err
+	}
+	if err := checkCanonical(data, func() ([]byte, error) { return EncodeOrder(order) }); err != nil {
+		return Order{}, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+	return order, nil
+}
+
+// DecodeTrade decodes the canonical encoding of a trade
+func DecodeTrade(data []byte) (Trade, error) {
+	d := &decoder{data: data}
+	version := d.header(encodingTagTrade, EncodingVersion3)
+
+	var trade Trade
+	trade.ID = d.string()
+	trade.BuyOrderID = d.string()
+	trade.SellOrderID = d.string()
+	trade.Price = d.uint256()
+	trade.Quantity = d.uint256()
+	trade.Timestamp = d.timestamp()
+	trade.TxHash = d.string()
+	trade.BlockNumber = d.uint64()
+
+	var matchType byte
+	if version >= EncodingVersion2 {
+		matchType = d.uint8()
+	}
+	if version >= EncodingVersion3 {
+		trade.MakerOrderID = d.string()
+		trade.MakerFee = d.optionalUint256()
+		trade.TakerFee = d.optionalUint256()
+	}
+	if err := d.done(); err != nil {
+		return Trade{}, fmt.Errorf("trade %s: %v", trade.ID, err)
+	}
+
+	var err error
+	if trade.MatchType, err = decodeMatchType(matchType); err != nil {
+		return Trade{}, fmt.Errorf("trade %s: %v", trade.ID, err)
+	}
+	if err := checkCanonical(data, func() ([]byte, error) { return EncodeTrade(trade) }); err != nil {
+		return Trade{}, fmt.Errorf("trade %s: %v", trade.ID, err)
+	}
+	return trade, nil
+}
+
+// DecodeSnapshot decodes the canonical encoding of a snapshot, including all of its orders
+func DecodeSnapshot(data []byte) (OrderbookSnapshot, error) {
+	d := &decoder{data: data}
+	version := d.header(encodingTagSnapshot, EncodingVersion3)
+
+	var snapshot OrderbookSnapshot
+	snapshot.SequenceNumber = d.uint64()
+	snapshot.Timestamp = d.timestamp()
+	snapshot.MarketID = d.string()
+	snapshot.MerkleRoot = d.bytes32()
+	snapshot.PrevHash = d.bytes32()
+
+	count := d.uint32()
+	if d.err == nil && uint64(count) > uint64(len(d.data)) {
+		d.err = fmt.Errorf("order count %d exceeds the data", count)
+	}
+	for i := uint32(0); d.err == nil && i < count; i++ {
+		encoded := d.lengthPrefixed()
+		if d.err != nil {
+			break
+		}
+		order, err := decodeOrder(encoded)
+		if err != nil {
+			d.err = err
+			break
+		}
+		snapshot.Orders = append(snapshot.Orders, order)
+	}
+
+	if version >= EncodingVersion2 {
+		params := &MarketParams{}
+		params.TickSize = d.optionalUint256()
+		params.MinOrderSize = d.optionalUint256()
+		params.CollateralDecimals = d.uint8()
+		if version >= EncodingVersion3 {
+			params.MakerFeeBps = d.uint32()
+			params.TakerFeeBps = d.uint32()
+		}
+		snapshot.Params = params
+	}
+	if err := d.done(); err != nil {
+		return OrderbookSnapshot{}, fmt.Errorf("snapshot %d: %v", snapshot.SequenceNumber, err)
+	}
+
+	if err := checkCanonical(data, func() ([]byte, error) { return EncodeSnapshot(snapshot) }); err != nil {
+		return OrderbookSnapshot{}, fmt.Errorf("snapshot %d: %v", snapshot.SequenceNumber, err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,108 @@
+package orderbookchecker
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCanonicalDecoding_GoldenVectors(t *testing.T) {
+	vectors := loadEncodingVectors(t)
+
+	decoders := map[string]func([]byte) (string, error){
+		"orders": func(data []byte) (string, error) {
+			order, err := DecodeOrder(data)
+			if err != nil {
+				return "", err
+			}
+			if order.Signature != "" {
+				if err := verifyOrderSignature(order, CTFExchangeDomain, priceUnit(DefaultCollateralDecimals)); err != nil {
+					return "", err
+				}
+			}
+			hash, err := HashOrder(order)
+			return hash.Hex(), err
+		},
+		"trades": func(data []byte) (string, error) {
+			trade, err := DecodeTrade(data)
+			if err != nil {
+				return "", err
+			}
+			hash, err := HashTrade(trade)
+			return hash.Hex(), err
+		},
+		"snapshots": func(data []byte) (string, error) {
+			snapshot, err := DecodeSnapshot(data)
+			if err != nil {
+				return "", err
+			}
+			return ComputeMerkleRoot(snapshot.Orders)
+		},
+	}
+
+	for kind, decode := range decoders {
+		for _, vec := range vectors[kind] {
+			t.Run(kind+"/"+vec.Name, func(t *testing.T) {
+				data, err := hex.DecodeString(strings.TrimPrefix(vec.Encoding, "0x"))
+				if err != nil {
+					t.Fatalf("Invalid vector encoding: %v", err)
+				}
+				got, err := decode(data)
+				if err != nil {
+					t.Fatalf("Decoding failed: %v", err)
+				}
+				// Snapshot vectors carry no hash; their decoded orders must rebuild the root
+				if vec.Hash != "" && got != vec.Hash {
+					t.Errorf("Hash mismatch: got %s, want %s", got, vec.Hash)
+				}
+			})
+		}
+	}
+}
+
+func TestCanonicalDecoding_Errors(t *testing.T) {
+	order := Order{ID: "o-1", Side: "buy", Price: big.NewInt(1), Quantity: big.NewInt(1), Timestamp: time.Unix(0, 0), FeeRateBps: 5}
+	encoded, err := EncodeOrder(order)
+	if err != nil {
+		t.Fatalf("EncodeOrder failed: %v", err)
+	}
+
+	// A version 3 order without a fee rate is valid layout but not canonical
+	e := newEncoder(EncodingVersion3, encodingTagOrder)
+	e.string(order.ID)
+	e.uint8(encodedSideBuy)
+	e.uint256("price", order.Price)
+	e.uint256("quantity", order.Quantity)
+	e.timestamp(order.Timestamp)
+	e.string(order.UserID)
+	e.string("")
+	e.uint8(encodedOutcomeNone)
+	e.uint8(encodedTimeInForceGTC)
+	e.uint64(0)
+	e.uint32(0)
+	nonCanonical, _ := e.bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "unexpected end of data"},
+		{"truncated", encoded[:len(encoded)-1], "unexpected end of data"},
+		{"trailing bytes", append(append([]byte(nil), encoded...), 0), "trailing bytes"},
+		{"trade tag", append([]byte{EncodingVersion3, encodingTagTrade}, encoded[2:]...), "unexpected type tag"},
+		{"unknown version", append([]byte{EncodingVersion4 + 1}, encoded[1:]...), "unsupported encoding version"},
+		{"invalid side", append(append([]byte(nil), encoded[:9]...), append([]byte{7}, encoded[10:]...)...), "invalid encoded side"},
+		{"non-canonical", nonCanonical, "non-canonical"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeOrder(tt.data); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
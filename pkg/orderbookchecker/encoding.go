@@ -0,0 +1,445 @@
+package orderbookchecker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// Canonical encoding
+//
+// Orders, trades and snapshots are hashed over a fixed binary layout rather than JSON so
+// that any implementation (Go, Solidity, TypeScript) produces identical bytes. Every
+// encoding starts with a version byte and a type tag, followed by the fields in the order
+// listed below. All integers are big-endian.
+//
+//	uint8     single byte
+//	uint32    4 bytes
+//	uint64    8 bytes
+//	int64     8 bytes, two's complement
+//	uint256   32 bytes, unsigned (same as abi.encode)
+//	bytes32   32 raw bytes; an empty hex string encodes as zero
+//	address   20 raw bytes; an empty hex string encodes as zero
+//	bytes     uint32 byte length followed by the raw bytes of a hex string
+//	string    uint32 byte length followed by the UTF-8 bytes
+//	timestamp int64 milliseconds since the Unix epoch (sub-millisecond precision is dropped)
+//	side      uint8, 0 = buy, 1 = sell
+//	outcome   uint8, 0 = none, 1 = YES, 2 = NO
+//	match     uint8, 0 = normal, 1 = mint, 2 = merge
+//	time_in_force uint8, 0 = GTC, 1 = GTD, 2 = FOK, 3 = FAK (or IOC)
+//
+//	Order:    version, tag 0x01, id string, side, price uint256, quantity uint256,
+//	          timestamp, user_id string
+//	          version 2 appends: token_id string, outcome, time_in_force,
+//	          expiration timestamp (zero when the order does not expire)
+//	          version 3 appends: fee_rate_bps uint32
+//	          version 4 appends: maker address, salt uint256, nonce uint256, signature bytes
+//	          (absent salt and nonce encode as zero)
+//	Trade:    version, tag 0x02, id string, buy_order_id string, sell_order_id string,
+//	          price uint256, quantity uint256, timestamp, tx_hash string, block_number uint64
+//	          version 2 appends: match_type match
+//	          version 3 appends: maker_order_id string, maker_fee uint256, taker_fee uint256
+//	Snapshot: version, tag 0x03, sequence_number uint64, timestamp, market_id string,
+//	          merkle_root bytes32, prev_hash bytes32, order count uint32, then for each
+//	          order its encoding prefixed with a uint32 length
+//	          version 2 appends: tick_size uint256, min_order_size uint256,
+//	          collateral_decimals uint8 (absent amounts encode as zero)
+//	          version 3 appends: maker_fee_bps uint32, taker_fee_bps uint32
+//
+// Each object uses the lowest version that can represent it. Plain GTC orders without a
+// token, outcome, fee rate or signature, normal trades without fees and snapshots without market
+// parameters use version 1, so hashes of data published before these fields were
+// introduced are unchanged.
+//
+// Golden vectors live in testdata/encoding_vectors.json.
+
+// Version bytes prefixed to canonical encodings
+const (
+	// EncodingVersion is the original layout
+	EncodingVersion byte = 1
+	// EncodingVersion2 appends outcome token fields to orders, the match type to trades
+	// and market parameters to snapshots
+	EncodingVersion2 byte = 2
+	// EncodingVersion3 appends the signed fee rate to orders, maker and fees to trades and
+	// the fee schedule to snapshots
+	EncodingVersion3 byte = 3
+	// EncodingVersion4 appends the maker, salt, nonce and signature of signed orders
+	EncodingVersion4 byte = 4
+)
+
+// Type tags that keep encodings of different objects from colliding
+const (
+	encodingTagOrder    byte = 0x01
+	encodingTagTrade    byte = 0x02
+	encodingTagSnapshot byte = 0x03
+)
+
+// Side values in the canonical encoding
+const (
+	encodedSideBuy  byte = 0
+	encodedSideSell byte = 1
+)
+
+// Outcome values in the canonical encoding
+const (
+	encodedOutcomeNone byte = 0
+	encodedOutcomeYes  byte = 1
+	encodedOutcomeNo   byte = 2
+)
+
+// Time-in-force values in the canonical encoding
+const (
+	encodedTimeInForceGTC byte = 0
+	encodedTimeInForceGTD byte = 1
+	encodedTimeInForceFOK byte = 2
+	encodedTimeInForceFAK byte = 3
+)
+
+// Match type values in the canonical encoding
+const (
+	encodedMatchNormal byte = 0
+	encodedMatchMint   byte = 1
+	encodedMatchMerge  byte = 2
+)
+
+// encoder accumulates canonical fields, remembering the first error encountered
+type encoder struct {
+	buf bytes.Buffer
+	err error
+}
+
+func newEncoder(version, tag byte) *encoder {
+	e := &encoder{}
+	e.buf.WriteByte(version)
+	e.buf.WriteByte(tag)
+	return e
+}
+
+func (e *encoder) uint8(v byte) {
+	e.buf.WriteByte(v)
+}
+
+func (e *encoder) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) timestamp(t time.Time) {
+	e.uint64(uint64(t.UnixMilli()))
+}
+
+func (e *encoder) uint256(field string, v *big.Int) {
+	if e.err != nil {
+		return
+	}
+	if v == nil {
+		e.err = fmt.Errorf("%s is required", field)
+		return
+	}
+	if v.Sign() < 0 || v.BitLen() > 256 {
+		e.err = fmt.Errorf("%s %s does not fit in uint256", field, v.String())
+		return
+	}
+	var b [32]byte
+	v.FillBytes(b[:])
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) bytes32(field, hexValue string) {
+	if e.err != nil {
+		return
+	}
+	var h merkle.Hash
+	if hexValue != "" {
+		parsed, err := merkle.ParseHash(hexValue)
+		if err != nil {
+			e.err = fmt.Errorf("%s: %v", field, err)
+			return
+		}
+		h = parsed
+	}
+	e.buf.Write(h[:])
+}
+
+func (e *encoder) address(field, hexValue string) {
+	if e.err != nil {
+		return
+	}
+	var a [20]byte
+	if hexValue != "" {
+		parsed, err := parseAddress(hexValue)
+		if err != nil {
+			e.err = fmt.Errorf("%s: %v", field, err)
+			return
+		}
+		a = parsed
+	}
+	e.buf.Write(a[:])
+}
+
+func (e *encoder) hexBytes(field, hexValue string) {
+	if e.err != nil {
+		return
+	}
+	raw, err := hex.DecodeString(strings.TrimPrefix(hexValue, "0x"))
+	if err != nil {
+		e.err = fmt.Errorf("%s: invalid hex: %v", field, err)
+		return
+	}
+	e.uint32(uint32(len(raw)))
+	e.buf.Write(raw)
+}
+
+func (e *encoder) string(s string) {
+	e.uint32(uint32(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *encoder) bytes() ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// orZero returns v, or zero if v is nil
+func orZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+// encodeSide maps an order side to its canonical byte
+func encodeSide(side string) (byte, error) {
+	switch side {
+	case "buy":
+		return encodedSideBuy, nil
+	case "sell":
+		return encodedSideSell, nil
+	default:
+		return 0, fmt.Errorf("invalid order side: %s", side)
+	}
+}
+
+// encodeOutcome maps an order outcome to its canonical byte
+func encodeOutcome(outcome string) (byte, error) {
+	switch outcome {
+	case "":
+		return encodedOutcomeNone, nil
+	case OutcomeYes:
+		return encodedOutcomeYes, nil
+	case OutcomeNo:
+		return encodedOutcomeNo, nil
+	default:
+		return 0, fmt.Errorf("invalid outcome: %s", outcome)
+	}
+}
+
+// encodeTimeInForce maps an order time in force to its canonical byte
+func encodeTimeInForce(timeInForce string) (byte, error) {
+	normalized, err := normalizeTimeInForce(timeInForce)
+	if err != nil {
+		return 0, err
+	}
+	switch normalized {
+	case TimeInForceGTD:
+		return encodedTimeInForceGTD, nil
+	case TimeInForceFOK:
+		return encodedTimeInForceFOK, nil
+	case TimeInForceFAK:
+		return encodedTimeInForceFAK, nil
+	default:
+		return encodedTimeInForceGTC, nil
+	}
+}
+
+// encodeMatchType maps a trade match type to its canonical byte
+func encodeMatchType(matchType string) (byte, error) {
+	switch matchType {
+	case "", MatchTypeNormal:
+		return encodedMatchNormal, nil
+	case MatchTypeMint:
+		return encodedMatchMint, nil
+	case MatchTypeMerge:
+		return encodedMatchMerge, nil
+	default:
+		return 0, fmt.Errorf("invalid match type: %s", matchType)
+	}
+}
+
+// EncodeOrder returns the canonical encoding of an order
+func EncodeOrder(order Order) ([]byte, error) {
+	side, err := encodeSide(order.Side)
+	if err != nil {
+		return nil, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+	outcome, err := encodeOutcome(order.Outcome)
+	if err != nil {
+		return nil, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+
+	timeInForce, err := encodeTimeInForce(order.TimeInForce)
+	if err != nil {
+		return nil, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+
+	version := EncodingVersion
+	if order.TokenID != "" || outcome != encodedOutcomeNone || timeInForce != encodedTimeInForceGTC || order.Expiration != nil {
+		version = EncodingVersion2
+	}
+	if order.FeeRateBps != 0 {
+		version = EncodingVersion3
+	}
+	if order.Maker != "" || order.Salt != nil || order.Nonce != nil || order.Signature != "" {
+		version = EncodingVersion4
+	}
+
+	e := newEncoder(version, encodingTagOrder)
+	e.string(order.ID)
+	e.uint8(side)
+	e.uint256("price", order.Price)
+	e.uint256("quantity", order.Quantity)
+	e.timestamp(order.Timestamp)
+	e.string(order.UserID)
+	if version >= EncodingVersion2 {
+		e.string(order.TokenID)
+		e.uint8(outcome)
+		e.uint8(timeInForce)
+		if order.Expiration != nil {
+			e.timestamp(*order.Expiration)
+		} else {
+			e.uint64(0)
+		}
+	}
+	if version >= EncodingVersion3 {
+		e.uint32(order.FeeRateBps)
+	}
+	if version >= EncodingVersion4 {
+		e.address("maker", order.Maker)
+		e.uint256("salt", orZero(order.Salt))
+		e.uint256("nonce", orZero(order.Nonce))
+		e.hexBytes("signature", order.Signature)
+	}
+
+	data, err := e.bytes()
+	if err != nil {
+		return nil, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+	return data, nil
+}
+
+// EncodeTrade returns the canonical encoding of a trade
+func EncodeTrade(trade Trade) ([]byte, error) {
+	matchType, err := encodeMatchType(trade.MatchType)
+	if err != nil {
+		return nil, fmt.Errorf("trade %s: %v", trade.ID, err)
+	}
+
+	version := EncodingVersion
+	if matchType != encodedMatchNormal {
+		version = EncodingVersion2
+	}
+	if trade.MakerOrderID != "" || orZero(trade.MakerFee).Sign() != 0 || orZero(trade.TakerFee).Sign() != 0 {
+		version = EncodingVersion3
+	}
+
+	e := newEncoder(version, encodingTagTrade)
+	e.string(trade.ID)
+	e.string(trade.BuyOrderID)
+	e.string(trade.SellOrderID)
+	e.uint256("price", trade.Price)
+	e.uint256("quantity", trade.Quantity)
+	e.timestamp(trade.Timestamp)
+	e.string(trade.TxHash)
+	e.uint64(trade.BlockNumber)
+	if version >= EncodingVersion2 {
+		e.uint8(matchType)
+	}
+	if version >= EncodingVersion3 {
+		e.string(trade.MakerOrderID)
+		e.uint256("maker_fee", orZero(trade.MakerFee))
+		e.uint256("taker_fee", orZero(trade.TakerFee))
+	}
+
+	data, err := e.bytes()
+	if err != nil {
+		return nil, fmt.Errorf("trade %s: %v", trade.ID, err)
+	}
+	return data, nil
+}
+
+// EncodeSnapshot returns the canonical encoding of a snapshot, including all of its orders
+func EncodeSnapshot(snapshot OrderbookSnapshot) ([]byte, error) {
+	version := EncodingVersion
+	if snapshot.Params != nil {
+		version = EncodingVersion2
+		if snapshot.Params.MakerFeeBps != 0 || snapshot.Params.TakerFeeBps != 0 {
+			version = EncodingVersion3
+		}
+	}
+
+	e := newEncoder(version, encodingTagSnapshot)
+	e.uint64(snapshot.SequenceNumber)
+	e.timestamp(snapshot.Timestamp)
+	e.string(snapshot.MarketID)
+	e.bytes32("merkle_root", snapshot.MerkleRoot)
+	e.bytes32("prev_hash", snapshot.PrevHash)
+	e.uint32(uint32(len(snapshot.Orders)))
+
+	for _, order := range snapshot.Orders {
+		encoded, err := EncodeOrder(order)
+		if err != nil {
+			return nil, err
+		}
+		e.uint32(uint32(len(encoded)))
+		e.buf.Write(encoded)
+	}
+
+	if params := snapshot.Params; params != nil {
+		e.uint256("tick_size", orZero(params.TickSize))
+		e.uint256("min_order_size", orZero(params.MinOrderSize))
+		e.uint8(params.CollateralDecimals)
+		if version >= EncodingVersion3 {
+			e.uint32(params.MakerFeeBps)
+			e.uint32(params.TakerFeeBps)
+		}
+	}
+
+	data, err := e.bytes()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %d: %v", snapshot.SequenceNumber, err)
+	}
+	return data, nil
+}
+
+// HashOrder returns the keccak256 hash of the canonical order encoding
+func HashOrder(order Order) (merkle.Hash, error) {
+	data, err := EncodeOrder(order)
+	if err != nil {
+		return merkle.Hash{}, err
+	}
+	return merkle.Keccak256(data), nil
+}
+
+// HashTrade returns the keccak256 hash of the canonical trade encoding
+func HashTrade(trade Trade) (merkle.Hash, error) {
+	data, err := EncodeTrade(trade)
+	if err != nil {
+		return merkle.Hash{}, err
+	}
+	return merkle.Keccak256(data), nil
+}
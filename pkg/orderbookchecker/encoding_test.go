@@ -0,0 +1,211 @@
+package orderbookchecker
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"go.uber.org/zap"
+)
+
+// encodingVector is a golden vector shared with non-Go implementations of the canonical encoding
+type encodingVector struct {
+	Name     string          `json:"name"`
+	Input    json.RawMessage `json:"input"`
+	Encoding string          `json:"encoding"`
+	Hash     string          `json:"hash,omitempty"`
+}
+
+func loadEncodingVectors(t *testing.T) map[string][]encodingVector {
+	data, err := os.ReadFile("testdata/encoding_vectors.json")
+	if err != nil {
+		t.Fatalf("Failed to read golden vectors: %v", err)
+	}
+
+	var vectors map[string][]encodingVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatalf("Failed to parse golden vectors: %v", err)
+	}
+
+	return vectors
+}
+
+func TestCanonicalEncoding_GoldenVectors(t *testing.T) {
+	vectors := loadEncodingVectors(t)
+
+	for _, vec := range vectors["orders"] {
+		t.Run("order/"+vec.Name, func(t *testing.T) {
+			var order Order
+			if err := json.Unmarshal(vec.Input, &order); err != nil {
+				t.Fatalf("Failed to decode input: %v", err)
+			}
+
+			encoded, err := EncodeOrder(order)
+			if err != nil {
+				t.Fatalf("EncodeOrder failed: %v", err)
+			}
+			if got := "0x" + hex.EncodeToString(encoded); got != vec.Encoding {
+				t.Errorf("Encoding mismatch:\n got %s\nwant %s", got, vec.Encoding)
+			}
+
+			hash, err := HashOrder(order)
+			if err != nil {
+				t.Fatalf("HashOrder failed: %v", err)
+			}
+			if hash.Hex() != vec.Hash {
+				t.Errorf("Hash mismatch: got %s, want %s", hash.Hex(), vec.Hash)
+			}
+
+			// Signed vectors are signed against the CTF Exchange domain
+			if order.Signature != "" {
+				if err := verifyOrderSignature(order, CTFExchangeDomain, priceUnit(DefaultCollateralDecimals)); err != nil {
+					t.Errorf("Signature check failed: %v", err)
+				}
+			}
+		})
+	}
+
+	for _, vec := range vectors["trades"] {
+		t.Run("trade/"+vec.Name, func(t *testing.T) {
+			var trade Trade
+			if err := json.Unmarshal(vec.Input, &trade); err != nil {
+				t.Fatalf("Failed to decode input: %v", err)
+			}
+
+			encoded, err := EncodeTrade(trade)
+			if err != nil {
+				t.Fatalf("EncodeTrade failed: %v", err)
+			}
+			if got := "0x" + hex.EncodeToString(encoded); got != vec.Encoding {
+				t.Errorf("Encoding mismatch:\n got %s\nwant %s", got, vec.Encoding)
+			}
+
+			hash, err := HashTrade(trade)
+			if err != nil {
+				t.Fatalf("HashTrade failed: %v", err)
+			}
+			if hash.Hex() != vec.Hash {
+				t.Errorf("Hash mismatch: got %s, want %s", hash.Hex(), vec.Hash)
+			}
+		})
+	}
+
+	for _, vec := range vectors["snapshots"] {
+		t.Run("snapshot/"+vec.Name, func(t *testing.T) {
+			var snapshot OrderbookSnapshot
+			if err := json.Unmarshal(vec.Input, &snapshot); err != nil {
+				t.Fatalf("Failed to decode input: %v", err)
+			}
+
+			encoded, err := EncodeSnapshot(snapshot)
+			if err != nil {
+				t.Fatalf("EncodeSnapshot failed: %v", err)
+			}
+			if got := "0x" + hex.EncodeToString(encoded); got != vec.Encoding {
+				t.Errorf("Encoding mismatch:\n got %s\nwant %s", got, vec.Encoding)
+			}
+
+			root, err := ComputeMerkleRoot(snapshot.Orders)
+			if err != nil {
+				t.Fatalf("ComputeMerkleRoot failed: %v", err)
+			}
+			if root != snapshot.MerkleRoot {
+				t.Errorf("Merkle root mismatch: got %s, want %s", root, snapshot.MerkleRoot)
+			}
+		})
+	}
+}
+
+// TestFraudProof_GoldenVectors checks EncodeABI byte for byte against the vectors that
+// contracts/test/SettlementVerifier.t.sol decodes and verifies with verifySkippedOrderProof
+func TestFraudProof_GoldenVectors(t *testing.T) {
+	vectors := loadEncodingVectors(t)
+	if len(vectors["fraud_proofs"]) == 0 {
+		t.Fatal("Expected fraud proof vectors")
+	}
+
+	for _, vec := range vectors["fraud_proofs"] {
+		t.Run(vec.Name, func(t *testing.T) {
+			var input struct {
+				Snapshot OrderbookSnapshot `json:"snapshot"`
+				Trades   []Trade           `json:"trades"`
+			}
+			if err := json.Unmarshal(vec.Input, &input); err != nil {
+				t.Fatalf("Failed to decode input: %v", err)
+			}
+
+			result, err := NewOrderbookVerifier(zap.NewNop()).VerifySnapshot(input.Trades, input.Snapshot)
+			if err != nil || len(result.FraudProofs) != 1 {
+				t.Fatalf("Expected a fraud proof, got %+v (%v)", result, err)
+			}
+			encoded, err := result.FraudProofs[0].EncodeABI()
+			if err != nil {
+				t.Fatalf("EncodeABI failed: %v", err)
+			}
+			if got := "0x" + hex.EncodeToString(encoded); got != vec.Encoding {
+				t.Errorf("Encoding mismatch:\n got %s\nwant %s", got, vec.Encoding)
+			}
+			if hash := merkle.Keccak256(encoded); hash.Hex() != vec.Hash {
+				t.Errorf("Hash mismatch: got %s, want %s", hash.Hex(), vec.Hash)
+			}
+		})
+	}
+}
+
+func TestCanonicalEncoding_Errors(t *testing.T) {
+	order := Order{ID: "o-1", Side: "buy", Price: big.NewInt(1), Quantity: big.NewInt(1), Timestamp: time.Unix(0, 0)}
+
+	tests := []struct {
+		name   string
+		mutate func(*Order)
+	}{
+		{"invalid side", func(o *Order) { o.Side = "hold" }},
+		{"missing price", func(o *Order) { o.Price = nil }},
+		{"negative quantity", func(o *Order) { o.Quantity = big.NewInt(-1) }},
+		{"price above uint256", func(o *Order) { o.Price = new(big.Int).Lsh(big.NewInt(1), 256) }},
+		{"invalid outcome", func(o *Order) { o.Outcome = "MAYBE" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := order
+			tt.mutate(&o)
+			if _, err := EncodeOrder(o); err == nil {
+				t.Error("Expected encoding error")
+			}
+		})
+	}
+}
+
+func TestOrderJSON_AcceptsStringAndNumberAmounts(t *testing.T) {
+	var fromString, fromNumber Order
+
+	if err := json.Unmarshal([]byte(`{"id":"o-1","side":"buy","price":"520000000000000000","quantity":"1000000000000000000"}`), &fromString); err != nil {
+		t.Fatalf("Failed to decode string amounts: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"id":"o-1","side":"buy","price":520000000000000000,"quantity":1000000000000000000}`), &fromNumber); err != nil {
+		t.Fatalf("Failed to decode numeric amounts: %v", err)
+	}
+
+	if fromString.Price.Cmp(fromNumber.Price) != 0 || fromString.Quantity.Cmp(fromNumber.Quantity) != 0 {
+		t.Errorf("Expected identical amounts, got %v/%v and %v/%v",
+			fromString.Price, fromString.Quantity, fromNumber.Price, fromNumber.Quantity)
+	}
+
+	encoded, err := json.Marshal(fromNumber)
+	if err != nil {
+		t.Fatalf("Failed to encode order: %v", err)
+	}
+
+	var roundTrip map[string]interface{}
+	if err := json.Unmarshal(encoded, &roundTrip); err != nil {
+		t.Fatalf("Failed to decode encoded order: %v", err)
+	}
+	if roundTrip["price"] != "520000000000000000" {
+		t.Errorf("Expected price to be encoded as a decimal string, got %v", roundTrip["price"])
+	}
+}
@@ -0,0 +1,152 @@
+package orderbookchecker
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned when a snapshot does not match its commitments
+var (
+	ErrMerkleRootMismatch   = errors.New("merkle root mismatch")
+	ErrSnapshotHashMismatch = errors.New("snapshot hash mismatch")
+)
+
+// Sentinel errors returned when orders or trades fall outside the market's price domain
+var (
+	ErrInvalidMarketParams = errors.New("invalid market parameters")
+	ErrPriceOutOfRange     = errors.New("price out of range")
+	ErrPriceOffTick        = errors.New("price not on tick")
+	ErrOrderBelowMinSize   = errors.New("order below minimum size")
+)
+
+// Sentinel errors returned when a trade breaks an order's lifecycle
+var (
+	ErrInvalidTimeInForce = errors.New("invalid time in force")
+	ErrOrderCancelled     = errors.New("order cancelled")
+	ErrOrderExpired       = errors.New("order expired")
+	ErrFOKPartialFill     = errors.New("fill-or-kill order partially filled")
+)
+
+// ErrFeeDiscrepancy is returned when a trade charges fees that do not match the fees owed
+var ErrFeeDiscrepancy = errors.New("fee discrepancy")
+
+// ErrExecutionPrice is returned when a trade executes at a price other than the one its
+// execution price policy requires
+var ErrExecutionPrice = errors.New("execution price mismatch")
+
+// Sentinel errors returned when a snapshot is not a sane resting book
+var (
+	ErrCrossedBook      = errors.New("crossed book")
+	ErrDuplicateOrderID = errors.New("duplicate order ID")
+	ErrInvalidOrder     = errors.New("invalid order")
+	ErrFutureTimestamp  = errors.New("order timestamp after snapshot")
+	// ErrTimestampPrecision is returned for a timestamp finer than the milliseconds that
+	// snapshots and payloads commit to
+	ErrTimestampPrecision = errors.New("timestamp finer than a millisecond")
+)
+
+// ErrSelfTrade is returned when a trade matches two orders of the same user while self-trade
+// prevention is on
+var ErrSelfTrade = errors.New("self-trade")
+
+// Sentinel errors returned when signed orders are required and an order was not authorized
+// by its maker
+var (
+	ErrUnsignedOrder    = errors.New("unsigned order")
+	ErrInvalidSignature = errors.New("invalid order signature")
+)
+
+// Sentinel errors classifying why a trade failed verification
+var (
+	ErrUnknownOrder      = errors.New("unknown order")
+	ErrInvalidTrade      = errors.New("invalid trade")
+	ErrInvalidMatch      = errors.New("invalid match")
+	ErrPriceViolation    = errors.New("price violation")
+	ErrOverfill          = errors.New("overfill")
+	ErrPriorityViolation = errors.New("priority violation")
+	ErrFillMismatch      = errors.New("fill mismatch")
+)
+
+// VerificationError is a rule broken by a single trade. It unwraps to one of the sentinel
+// errors, names the offending orders and, for rules comparing two values, carries the value
+// the rule allowed and the value the trade had.
+type VerificationError struct {
+	Err      error
+	OrderIDs []string
+	Expected string
+	Actual   string
+	Detail   string
+}
+
+// Error implements error
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Err, e.Detail)
+}
+
+// Unwrap returns the sentinel error
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode maps a verification error to the code reported in VerificationResult.ErrorCode.
+// It returns an empty string for errors without a dedicated code.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrMerkleRootMismatch):
+		return ErrorCodeMerkleRootMismatch
+	case errors.Is(err, ErrSnapshotHashMismatch):
+		return ErrorCodeSnapshotHashMismatch
+	case errors.Is(err, ErrInvalidMarketParams):
+		return ErrorCodeInvalidMarketParams
+	case errors.Is(err, ErrPriceOutOfRange):
+		return ErrorCodePriceOutOfRange
+	case errors.Is(err, ErrPriceOffTick):
+		return ErrorCodePriceOffTick
+	case errors.Is(err, ErrOrderBelowMinSize):
+		return ErrorCodeOrderBelowMinSize
+	case errors.Is(err, ErrInvalidTimeInForce):
+		return ErrorCodeInvalidTimeInForce
+	case errors.Is(err, ErrOrderCancelled):
+		return ErrorCodeOrderCancelled
+	case errors.Is(err, ErrOrderExpired):
+		return ErrorCodeOrderExpired
+	case errors.Is(err, ErrFOKPartialFill):
+		return ErrorCodeFOKPartialFill
+	case errors.Is(err, ErrFeeDiscrepancy):
+		return ErrorCodeFeeDiscrepancy
+	case errors.Is(err, ErrExecutionPrice):
+		return ErrorCodeExecutionPrice
+	case errors.Is(err, ErrCrossedBook):
+		return ErrorCodeCrossedBook
+	case errors.Is(err, ErrDuplicateOrderID):
+		return ErrorCodeDuplicateOrderID
+	case errors.Is(err, ErrInvalidOrder):
+		return ErrorCodeInvalidOrder
+	case errors.Is(err, ErrFutureTimestamp):
+		return ErrorCodeFutureTimestamp
+	case errors.Is(err, ErrTimestampPrecision):
+		return ErrorCodeTimestampPrecision
+	case errors.Is(err, ErrSelfTrade):
+		return ErrorCodeSelfTrade
+	case errors.Is(err, ErrUnsignedOrder):
+		return ErrorCodeUnsignedOrder
+	case errors.Is(err, ErrInvalidSignature):
+		return ErrorCodeInvalidSignature
+	case errors.Is(err, ErrUnknownOrder):
+		return ErrorCodeUnknownOrder
+	case errors.Is(err, ErrInvalidTrade):
+		return ErrorCodeInvalidTrade
+	case errors.Is(err, ErrInvalidMatch):
+		return ErrorCodeInvalidMatch
+	case errors.Is(err, ErrPriceViolation):
+		return ErrorCodePriceViolation
+	case errors.Is(err, ErrOverfill):
+		return ErrorCodeOverfill
+	case errors.Is(err, ErrPriorityViolation):
+		return ErrorCodePriorityViolation
+	case errors.Is(err, ErrFillMismatch):
+		return ErrorCodeFillMismatch
+	default:
+		return ""
+	}
+}
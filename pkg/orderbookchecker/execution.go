@@ -0,0 +1,113 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// validateExecutionPricePolicy checks that a policy is one of the ExecutionPrice* values
+func validateExecutionPricePolicy(policy string) error {
+	switch policy {
+	case ExecutionPriceAny, ExecutionPriceMaker, ExecutionPriceMidpoint, ExecutionPriceTaker:
+		return nil
+	default:
+		return fmt.Errorf("invalid execution price policy: %s", policy)
+	}
+}
+
+// makerAndTaker returns the maker and the taker of a trade. The order placed first was
+// resting on the book and is the maker; orders placed at the same time fall back to the
+// trade's MakerOrderID. A MakerOrderID contradicting the timestamps is an error.
+func makerAndTaker(trade Trade, buyOrder, sellOrder *Order) (maker, taker *Order, err error) {
+	switch {
+	case buyOrder.Timestamp.Before(sellOrder.Timestamp):
+		maker, taker = buyOrder, sellOrder
+	case sellOrder.Timestamp.Before(buyOrder.Timestamp):
+		maker, taker = sellOrder, buyOrder
+	case trade.MakerOrderID == buyOrder.ID:
+		return buyOrder, sellOrder, nil
+	case trade.MakerOrderID == sellOrder.ID:
+		return sellOrder, buyOrder, nil
+	default:
+		return nil, nil, fmt.Errorf("orders %s and %s were placed at the same time and the trade does not name its maker",
+			buyOrder.ID, sellOrder.ID)
+	}
+
+	if trade.MakerOrderID != "" && trade.MakerOrderID != maker.ID {
+		return nil, nil, fmt.Errorf("maker order %s was placed after order %s", trade.MakerOrderID, maker.ID)
+	}
+	return maker, taker, nil
+}
+
+// policyPrice returns the price a trade must execute at under the policy, given the maker's
+// and the taker's limit prices quoted in the same token. Midpoints are rounded to the tick
+// (or to a base unit without one) in the taker's favour: down for a buying taker, up for a
+// selling one. ExecutionPriceAny returns nil.
+func policyPrice(policy string, makerPrice, takerPrice *big.Int, takerBuys bool, tick *big.Int) *big.Int {
+	switch policy {
+	case ExecutionPriceMaker:
+		return new(big.Int).Set(makerPrice)
+	case ExecutionPriceTaker:
+		return new(big.Int).Set(takerPrice)
+	case ExecutionPriceMidpoint:
+		step := big.NewInt(1)
+		if tick != nil {
+			step = tick
+		}
+		// Prices are positive, so Quo rounds half the sum down to a whole number of steps
+		sum := new(big.Int).Add(makerPrice, takerPrice)
+		twoSteps := new(big.Int).Mul(step, big.NewInt(2))
+		if !takerBuys {
+			sum.Add(sum, twoSteps).Sub(sum, big.NewInt(1))
+		}
+		sum.Quo(sum, twoSteps)
+		return sum.Mul(sum, step)
+	default:
+		return nil
+	}
+}
+
+// verifyExecutionPrice checks that a trade executed within tolerance of the price its policy
+// requires
+func verifyExecutionPrice(policy string, tolerance *big.Int, trade Trade, buyOrder, sellOrder *Order, state *OrderbookState) error {
+	if policy == ExecutionPriceAny {
+		return nil
+	}
+
+	maker, taker, err := makerAndTaker(trade, buyOrder, sellOrder)
+	if err != nil {
+		return &VerificationError{
+			Err:      ErrInvalidTrade,
+			OrderIDs: []string{buyOrder.ID, sellOrder.ID},
+			Detail:   err.Error(),
+		}
+	}
+
+	var tick *big.Int
+	if state.Params != nil {
+		tick = state.Params.TickSize
+	}
+
+	takerSide := tradeSide(trade, taker)
+	makerPrice := effectivePrice(maker, tradeSide(trade, maker), state.PriceUnit)
+	takerPrice := effectivePrice(taker, takerSide, state.PriceUnit)
+	expected := policyPrice(policy, makerPrice, takerPrice, takerSide == "buy", tick)
+
+	if withinTolerance(trade.Price, expected, tolerance) {
+		return nil
+	}
+	priceComp := trade.Price.Cmp(expected)
+	verr := &VerificationError{
+		Err:      ErrExecutionPrice,
+		OrderIDs: []string{maker.ID, taker.ID},
+		Expected: expected.String(),
+		Actual:   trade.Price.String(),
+		Detail: fmt.Sprintf("trade executed at %s instead of %s under the %s price policy",
+			trade.Price.String(), expected.String(), policy),
+	}
+	if (takerSide == "buy") == (priceComp > 0) {
+		verr.Detail = fmt.Sprintf("trade executed at %s, denying taker %s the improvement to %s owed under the %s price policy",
+			trade.Price.String(), taker.ID, expected.String(), policy)
+	}
+	return verr
+}
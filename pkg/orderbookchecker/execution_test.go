@@ -0,0 +1,135 @@
+package orderbookchecker
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestOrderbookVerifier_ExecutionPricePolicy(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	baseTime := time.Now().Truncate(time.Millisecond)
+
+	// The resting bid at 50201 is the maker; the later ask at 50000 is the selling taker
+	snapshot := OrderbookSnapshot{
+		SequenceNumber: 1,
+		Timestamp:      baseTime,
+		MarketID:       "BTC-USD",
+		Orders: []Order{
+			{ID: "buy-1", Side: "buy", Price: big.NewInt(50201), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-2 * time.Minute)},
+			{ID: "sell-1", Side: "sell", Price: big.NewInt(50000), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-time.Minute)},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policy   string
+		price    int64
+		maker    string
+		wantCode string
+		wantErr  bool
+	}{
+		{"any price within the limits", ExecutionPriceAny, 50000, "", "", false},
+		{"maker price", ExecutionPriceMaker, 50201, "", "", false},
+		{"taker denied improvement under maker policy", ExecutionPriceMaker, 50100, "", ErrorCodeExecutionPrice, false},
+		{"taker price", ExecutionPriceTaker, 50000, "", "", false},
+		{"taker improved under taker policy", ExecutionPriceTaker, 50100, "", ErrorCodeExecutionPrice, false},
+		{"midpoint rounded up for a selling taker", ExecutionPriceMidpoint, 50101, "", "", false},
+		{"midpoint rounded against the taker", ExecutionPriceMidpoint, 50100, "", ErrorCodeExecutionPrice, false},
+		{"maker contradicting the timestamps", ExecutionPriceMaker, 50201, "sell-1", ErrorCodeInvalidTrade, false},
+		{"unknown policy", "vwap", 50000, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewOrderbookVerifier(logger, WithExecutionPricePolicy(tt.policy))
+			trades := []Trade{{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(tt.price),
+				Quantity: big.NewInt(100), MakerOrderID: tt.maker}}
+
+			result, err := verifier.VerifySnapshot(trades, snapshot)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error for policy %q", tt.policy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			wantValid := tt.wantCode == ""
+			if result.Valid != wantValid {
+				t.Errorf("Expected valid=%v, got %v: %s", wantValid, result.Valid, result.ErrorMessage)
+			}
+			if result.ErrorCode != tt.wantCode {
+				t.Errorf("Expected error code %q, got %q", tt.wantCode, result.ErrorCode)
+			}
+		})
+	}
+}
+
+func TestPolicyPrice_Midpoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		maker     *big.Int
+		taker     *big.Int
+		takerBuys bool
+		tick      *big.Int
+		want      *big.Int
+	}{
+		{"exact midpoint", big.NewInt(100), big.NewInt(200), true, nil, big.NewInt(150)},
+		{"buying taker rounds down", big.NewInt(100), big.NewInt(201), true, nil, big.NewInt(150)},
+		{"selling taker rounds up", big.NewInt(201), big.NewInt(100), false, nil, big.NewInt(151)},
+		{"buying taker rounds down to the tick", cents(40), cents(43), true, cents(1), cents(41)},
+		{"selling taker rounds up to the tick", cents(43), cents(40), false, cents(1), cents(42)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policyPrice(ExecutionPriceMidpoint, tt.maker, tt.taker, tt.takerBuys, tt.tick); got.Cmp(tt.want) != 0 {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOrderbookVerifier_VerifyReplay_ExecutionPricePolicy(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	baseTime := time.Now().Truncate(time.Millisecond)
+
+	snapshot := withoutOrders(outcomeSnapshot(baseTime), "yes-buy-60", "no-buy-42")
+	snapshot.Params = testMarketParams()
+
+	// An incoming NO buy at 40 cents crosses the resting 38 cent ask
+	incoming := []Order{
+		{ID: "no-buy-in", Side: "buy", Price: cents(40), Quantity: big.NewInt(20), Timestamp: baseTime.Add(time.Second),
+			TokenID: testNoToken, Outcome: OutcomeNo},
+	}
+
+	tests := []struct {
+		policy string
+		price  *big.Int
+	}{
+		{ExecutionPriceAny, cents(38)},
+		{ExecutionPriceMaker, cents(38)},
+		{ExecutionPriceMidpoint, cents(39)},
+		{ExecutionPriceTaker, cents(40)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			verifier := NewOrderbookVerifier(logger, WithExecutionPricePolicy(tt.policy))
+			trades := []Trade{{ID: "trade-1", BuyOrderID: "no-buy-in", SellOrderID: "no-sell-38", Price: tt.price, Quantity: big.NewInt(20)}}
+
+			result, err := verifier.VerifyReplay(trades, snapshot, incoming)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if !result.Valid {
+				t.Errorf("Expected the fill at %s to be valid, got %+v", tt.price, result.FillDiffs)
+			}
+		})
+	}
+}
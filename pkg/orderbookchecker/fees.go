@@ -0,0 +1,153 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// maxFeeBps is a fee rate of the whole notional
+const maxFeeBps = 10000
+
+// notional returns the collateral paid for quantity tokens at price, price * quantity / unit,
+// rounded down. unit is the price of one full unit of collateral.
+func notional(quantity, price, unit *big.Int) *big.Int {
+	amount := new(big.Int).Mul(quantity, price)
+	return amount.Quo(amount, unit)
+}
+
+// feeAmount returns the fee at rateBps on the notional of quantity tokens traded at price,
+// rounded down
+func feeAmount(quantity, price, unit *big.Int, rateBps uint32) *big.Int {
+	fee := notional(quantity, price, unit)
+	fee.Mul(fee, big.NewInt(int64(rateBps)))
+	return fee.Quo(fee, big.NewInt(maxFeeBps))
+}
+
+// feeRate returns the rate owed by an order in the given role: the market's rate for the
+// role, capped at the rate the order's signer agreed to
+func feeRate(order *Order, role string, params *MarketParams) uint32 {
+	if params == nil {
+		return 0
+	}
+	rate := params.TakerFeeBps
+	if role == FeeRoleMaker {
+		rate = params.MakerFeeBps
+	}
+	if rate > order.FeeRateBps {
+		rate = order.FeeRateBps
+	}
+	return rate
+}
+
+// orderIndex maps order IDs to the orders
+func orderIndex(orders []Order) map[string]*Order {
+	index := make(map[string]*Order, len(orders))
+	for i := range orders {
+		index[orders[i].ID] = &orders[i]
+	}
+	return index
+}
+
+// verifyFees compares the fees charged on a trade with the fees owed by its maker and
+// taker. The maker is the order placed first, so a MakerOrderID contradicting the order
+// timestamps is a discrepancy. Each order pays on the price of its own token, so a
+// complementary order pays on unit - price. Missing fee amounts count as zero, and fees
+// within tolerance of those owed are accepted; a nil tolerance accepts none.
+func verifyFees(trade Trade, orders map[string]*Order, params *MarketParams, unit, tolerance *big.Int) []FeeDiscrepancy {
+	buyOrder, sellOrder := orders[trade.BuyOrderID], orders[trade.SellOrderID]
+	if buyOrder == nil || sellOrder == nil || trade.Price == nil || trade.Quantity == nil {
+		return nil
+	}
+
+	if trade.MakerOrderID != "" && trade.MakerOrderID != buyOrder.ID && trade.MakerOrderID != sellOrder.ID {
+		return []FeeDiscrepancy{{
+			TradeID: trade.ID,
+			OrderID: trade.MakerOrderID,
+			Reason:  fmt.Sprintf("maker order %s is not part of the trade", trade.MakerOrderID),
+		}}
+	}
+	if (params == nil || params.MakerFeeBps == 0 && params.TakerFeeBps == 0) &&
+		orZero(trade.MakerFee).Sign() == 0 && orZero(trade.TakerFee).Sign() == 0 {
+		return nil
+	}
+
+	// The order placed first rested on the book, whatever the trade reports
+	maker, taker, err := makerAndTaker(trade, buyOrder, sellOrder)
+	if err != nil {
+		return []FeeDiscrepancy{{
+			TradeID: trade.ID,
+			OrderID: trade.MakerOrderID,
+			Reason:  fmt.Sprintf("cannot assign fee roles: %v", err),
+		}}
+	}
+
+	var discrepancies []FeeDiscrepancy
+	for _, f := range []struct {
+		role    string
+		order   *Order
+		side    string
+		charged *big.Int
+	}{
+		{FeeRoleMaker, maker, tradeSide(trade, maker), trade.MakerFee},
+		{FeeRoleTaker, taker, tradeSide(trade, taker), trade.TakerFee},
+	} {
+		price := trade.Price
+		if f.order.Side != f.side {
+			price = new(big.Int).Sub(unit, trade.Price)
+		}
+
+		rate := feeRate(f.order, f.role, params)
+		expected := feeAmount(trade.Quantity, price, unit, rate)
+		charged := orZero(f.charged)
+		if withinTolerance(charged, expected, tolerance) {
+			continue
+		}
+
+		reason := "overcharged"
+		if charged.Cmp(expected) < 0 {
+			reason = "undercharged"
+		}
+		discrepancies = append(discrepancies, FeeDiscrepancy{
+			TradeID:  trade.ID,
+			OrderID:  f.order.ID,
+			Role:     f.role,
+			Expected: expected,
+			Charged:  new(big.Int).Set(charged),
+			Reason: fmt.Sprintf("%s %s %s: charged %s, owed %s at %d bps",
+				f.role, f.order.ID, reason, charged.String(), expected.String(), rate),
+		})
+	}
+
+	return discrepancies
+}
+
+// tradeSide returns the side an order takes in a trade, which for a complementary order
+// differs from the side of the order itself
+func tradeSide(trade Trade, order *Order) string {
+	if order.ID == trade.BuyOrderID {
+		return "buy"
+	}
+	return "sell"
+}
+
+// recordFeeDiscrepancies marks a trade with fee discrepancies as failed
+func (v *OrderbookVerifier) recordFeeDiscrepancies(result *VerificationResult, trade Trade, discrepancies []FeeDiscrepancy) {
+	result.FeeDiscrepancies = append(result.FeeDiscrepancies, discrepancies...)
+
+	first := discrepancies[0]
+	err := &VerificationError{Err: ErrFeeDiscrepancy, Detail: first.Reason}
+	if first.OrderID != "" {
+		err.OrderIDs = []string{first.OrderID}
+	}
+	if first.Expected != nil {
+		err.Expected, err.Actual = first.Expected.String(), first.Charged.String()
+	}
+	v.recordTradeFailure(result, trade, err)
+}
+
+// withinTolerance reports whether a and b differ by at most tolerance; a nil tolerance
+// requires them to be equal
+func withinTolerance(a, b, tolerance *big.Int) bool {
+	diff := new(big.Int).Sub(a, b)
+	return diff.Abs(diff).Cmp(orZero(tolerance)) <= 0
+}
@@ -0,0 +1,178 @@
+package orderbookchecker
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// shares returns n whole outcome tokens of 18 decimals
+func shares(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), priceUnit(18))
+}
+
+// feeSnapshot returns the outcome market with a 1% taker fee, orders of whole tokens signed
+// for up to 2%, and the 38 cent NO ask resting before the 42 cent NO bid
+func feeSnapshot(baseTime time.Time) OrderbookSnapshot {
+	snapshot := outcomeSnapshot(baseTime)
+	snapshot.Params = testMarketParams()
+	snapshot.Params.TakerFeeBps = 100
+	for i := range snapshot.Orders {
+		snapshot.Orders[i].Quantity = shares(snapshot.Orders[i].Quantity.Int64())
+		snapshot.Orders[i].FeeRateBps = 200
+	}
+	snapshot.Orders[4].Timestamp = baseTime.Add(-10 * time.Minute)
+	return snapshot
+}
+
+func TestFeeAmount(t *testing.T) {
+	usdc := priceUnit(6)
+	tests := []struct {
+		name     string
+		quantity *big.Int
+		price    *big.Int
+		unit     *big.Int
+		rateBps  uint32
+		want     int64
+	}{
+		// 50 tokens at 38 cents cost 19 USDC, of which 1% is 0.19 USDC
+		{"six decimals", big.NewInt(50_000000), big.NewInt(380000), usdc, 100, 190000},
+		{"eighteen decimals", shares(50), cents(38), priceUnit(18), 100, cents(19).Int64()},
+		{"rounded down", big.NewInt(3), big.NewInt(330000), usdc, 100, 0},
+		{"zero rate", shares(50), cents(38), priceUnit(18), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := feeAmount(tt.quantity, tt.price, tt.unit, tt.rateBps); got.Cmp(big.NewInt(tt.want)) != 0 {
+				t.Errorf("Expected fee %d, got %s", tt.want, got.String())
+			}
+		})
+	}
+}
+
+func TestOrderbookVerifier_Fees(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	// 50 tokens at 38 cents with a 1% taker fee owe 19 cents
+	trade := func(maker string, takerFee *big.Int) Trade {
+		return Trade{ID: "trade-1", BuyOrderID: "no-buy-42", SellOrderID: "no-sell-38", Price: cents(38), Quantity: shares(50),
+			MakerOrderID: maker, TakerFee: takerFee}
+	}
+
+	tests := []struct {
+		name       string
+		trade      Trade
+		mutate     func(*OrderbookSnapshot)
+		wantReason string // Leading words of the first discrepancy; empty if fees are correct
+	}{
+		{"fees match the schedule", trade("no-sell-38", cents(19)), nil, ""},
+		{"taker overcharged", trade("no-sell-38", cents(20)), nil, "taker no-buy-42 overcharged"},
+		{"taker undercharged", trade("no-sell-38", nil), nil, "taker no-buy-42 undercharged"},
+		{"maker charged a fee", func() Trade { tr := trade("no-sell-38", cents(19)); tr.MakerFee = big.NewInt(1); return tr }(), nil,
+			"maker no-sell-38 overcharged"},
+		{"fee capped at the signed rate", trade("no-sell-38", cents(19)), func(s *OrderbookSnapshot) {
+			s.Orders[2].FeeRateBps = 50
+		}, "taker no-buy-42 overcharged"},
+		{"signed rate equal to the schedule", trade("no-sell-38", cents(19)), func(s *OrderbookSnapshot) {
+			s.Orders[2].FeeRateBps = 100
+		}, ""},
+		{"maker derived from the timestamps", trade("", cents(19)), nil, ""},
+		{"maker contradicting the timestamps", trade("no-buy-42", cents(19)), nil, "cannot assign fee roles: maker order no-buy-42"},
+		{"simultaneous orders without a maker", trade("", cents(19)), func(s *OrderbookSnapshot) {
+			s.Orders[2].Timestamp = s.Orders[4].Timestamp
+		}, "cannot assign fee roles: orders no-buy-42 and no-sell-38"},
+		{"simultaneous orders naming the maker", trade("no-sell-38", cents(19)), func(s *OrderbookSnapshot) {
+			s.Orders[2].Timestamp = s.Orders[4].Timestamp
+		}, ""},
+		{"maker outside the trade", trade("yes-buy-60", cents(19)), nil, "maker order yes-buy-60"},
+		{"no fee schedule", trade("", nil), func(s *OrderbookSnapshot) { s.Params = nil }, ""},
+		{"fee without a schedule", trade("no-sell-38", cents(19)), func(s *OrderbookSnapshot) { s.Params = nil }, "taker no-buy-42 overcharged"},
+		// The complementary NO buyer of a mint at 60 cents pays its fee on 40 cents
+		{"mint taker pays on the complementary price", Trade{ID: "trade-1", BuyOrderID: "yes-buy-60", SellOrderID: "no-buy-42",
+			Price: cents(60), Quantity: shares(50), MatchType: MatchTypeMint, MakerOrderID: "yes-buy-60", TakerFee: cents(20)}, nil, ""},
+		{"mint taker charged on the traded price", Trade{ID: "trade-1", BuyOrderID: "yes-buy-60", SellOrderID: "no-buy-42",
+			Price: cents(60), Quantity: shares(50), MatchType: MatchTypeMint, MakerOrderID: "yes-buy-60", TakerFee: cents(30)}, nil,
+			"taker no-buy-42 overcharged"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := feeSnapshot(time.Now().Truncate(time.Millisecond))
+			if tt.mutate != nil {
+				tt.mutate(&snapshot)
+			}
+
+			result, err := verifier.VerifySnapshot([]Trade{tt.trade}, snapshot)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if tt.wantReason == "" {
+				if !result.Valid || len(result.FeeDiscrepancies) != 0 {
+					t.Errorf("Expected valid fees, got %s: %+v", result.ErrorMessage, result.FeeDiscrepancies)
+				}
+				return
+			}
+
+			if result.Valid || result.ErrorCode != ErrorCodeFeeDiscrepancy {
+				t.Errorf("Expected %s, got valid=%v code=%q: %s", ErrorCodeFeeDiscrepancy, result.Valid, result.ErrorCode, result.ErrorMessage)
+			}
+			if len(result.FeeDiscrepancies) == 0 || !strings.HasPrefix(result.FeeDiscrepancies[0].Reason, tt.wantReason) {
+				t.Errorf("Expected discrepancy starting with %q, got %+v", tt.wantReason, result.FeeDiscrepancies)
+			}
+			if result.VerifiedTrades != 0 || len(result.FailedTrades) != 1 {
+				t.Errorf("Expected the trade to fail, got %d verified, failed %v", result.VerifiedTrades, result.FailedTrades)
+			}
+		})
+	}
+}
+
+func TestOrderbookVerifier_VerifyReplay_Fees(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	incoming := []Order{
+		{ID: "no-buy-in", Side: "buy", Price: cents(40), Quantity: shares(20), Timestamp: baseTime.Add(time.Second),
+			TokenID: testNoToken, Outcome: OutcomeNo, FeeRateBps: 200},
+	}
+
+	// The incoming buy takes the resting 38 cent ask, owing 1% of 20 x 38 cents
+	owed := new(big.Int).Div(cents(760), big.NewInt(100))
+
+	tests := []struct {
+		name      string
+		maker     string
+		takerFee  *big.Int
+		wantDiffs int
+		wantFees  int
+	}{
+		{"resting order is the maker", "no-sell-38", owed, 0, 0},
+		{"incoming order reported as maker", "no-buy-in", owed, 1, 0},
+		{"taker overcharged", "no-sell-38", new(big.Int).Add(owed, big.NewInt(1)), 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trades := []Trade{{ID: "trade-1", BuyOrderID: "no-buy-in", SellOrderID: "no-sell-38", Price: cents(38), Quantity: shares(20),
+				MakerOrderID: tt.maker, TakerFee: tt.takerFee}}
+
+			result, err := verifier.VerifyReplay(trades, withoutOrders(feeSnapshot(baseTime), "yes-buy-60", "no-buy-42"), incoming)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(result.FillDiffs) != tt.wantDiffs || len(result.FeeDiscrepancies) != tt.wantFees {
+				t.Errorf("Expected %d fill diffs and %d fee discrepancies, got %+v and %+v",
+					tt.wantDiffs, tt.wantFees, result.FillDiffs, result.FeeDiscrepancies)
+			}
+			if wantValid := tt.wantDiffs == 0 && tt.wantFees == 0; result.Valid != wantValid {
+				t.Errorf("Expected valid=%v, got %v: %s", wantValid, result.Valid, result.ErrorMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package orderbookchecker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// FraudProofVersion is the version of the ABI layout of a fraud proof
+const FraudProofVersion = 1
+
+// fraudProver builds fraud proofs against the order tree of a snapshot, building the tree on
+// first use
+type fraudProver struct {
+	orders []Order
+	root   string // Merkle root the snapshot declares
+	tree   *merkle.Tree
+	leaves map[string]int // Leaf index of every order ID
+	sorted []Order
+}
+
+// prove builds the fraud proof for a trade that matched an order while an order with
+// priority over it was still resting. The proof is checked on-chain against the committed
+// root, so a snapshot whose orders do not hash to its declared root cannot be proven against.
+func (p *fraudProver) prove(trade Trade, skippedID, matchedID string) (*FraudProof, error) {
+	if p.tree == nil {
+		tree, sorted, err := BuildOrderTree(p.orders)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build order tree: %v", err)
+		}
+		p.tree, p.sorted = tree, sorted
+		p.leaves = make(map[string]int, len(sorted))
+		for i, order := range sorted {
+			p.leaves[order.ID] = i
+		}
+	}
+	if root := p.tree.Root().Hex(); !strings.EqualFold(root, p.root) {
+		return nil, fmt.Errorf("%w: snapshot declares %q but orders hash to %s", ErrMerkleRootMismatch, p.root, root)
+	}
+
+	skipped, skippedProof, err := p.orderProof(skippedID)
+	if err != nil {
+		return nil, err
+	}
+	matched, matchedProof, err := p.orderProof(matchedID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FraudProof{
+		OrderRoot:    p.tree.Root(),
+		SkippedOrder: skipped,
+		SkippedProof: skippedProof,
+		MatchedOrder: matched,
+		MatchedProof: matchedProof,
+		Trade:        trade,
+	}, nil
+}
+
+// orderProof returns a snapshot order and its inclusion proof
+func (p *fraudProver) orderProof(orderID string) (Order, *merkle.Proof, error) {
+	index, ok := p.leaves[orderID]
+	if !ok {
+		return Order{}, nil, fmt.Errorf("order %s is not part of the snapshot", orderID)
+	}
+	proof, err := p.tree.Prove(index)
+	if err != nil {
+		return Order{}, nil, err
+	}
+	return p.sorted[index], proof, nil
+}
+
+// recordFraudProof adds a fraud proof to the result when a trade failed because it skipped an
+// order with priority
+func (v *OrderbookVerifier) recordFraudProof(result *VerificationResult, prover *fraudProver, trade Trade, err error) {
+	var verr *VerificationError
+	if !errors.As(err, &verr) || !errors.Is(verr, ErrPriorityViolation) || len(verr.OrderIDs) != 2 {
+		return
+	}
+
+	proof, err := prover.prove(trade, verr.OrderIDs[1], verr.OrderIDs[0])
+	if err != nil {
+		v.logger.Sugar().Warnw("Failed to build fraud proof",
+			"trade_id", trade.ID,
+			"error", err,
+		)
+		return
+	}
+	result.FraudProofs = append(result.FraudProofs, *proof)
+}
+
+// EncodeABI returns the proof in the layout SettlementVerifier.challengeSettlement accepts,
+// abi.encode(SkippedOrderProof) with
+//
+//	struct OrderProof { bytes order; bytes32[] siblings; uint256 path; }
+//	struct SkippedOrderProof {
+//	    uint8 version; bytes32 orderRoot; OrderProof skipped; OrderProof matched; bytes trade;
+//	}
+//
+// Orders and the trade are given in their canonical encoding, so the orders are the leaves
+// proven against orderRoot.
+func (p *FraudProof) EncodeABI() ([]byte, error) {
+	skipped, err := encodeOrderProof(p.SkippedOrder, p.SkippedProof)
+	if err != nil {
+		return nil, fmt.Errorf("skipped order: %v", err)
+	}
+	matched, err := encodeOrderProof(p.MatchedOrder, p.MatchedProof)
+	if err != nil {
+		return nil, fmt.Errorf("matched order: %v", err)
+	}
+	trade, err := EncodeTrade(p.Trade)
+	if err != nil {
+		return nil, fmt.Errorf("trade: %v", err)
+	}
+
+	return abi.Encode(abi.Tuple(
+		abi.Uint64(FraudProofVersion),
+		abi.Bytes32(p.OrderRoot),
+		skipped,
+		matched,
+		abi.Bytes(trade),
+	)), nil
+}
+
+// encodeOrderProof encodes an order and its inclusion proof as an OrderProof tuple
+func encodeOrderProof(order Order, proof *merkle.Proof) (abi.Value, error) {
+	if proof == nil {
+		return abi.Value{}, fmt.Errorf("missing inclusion proof for order %s", order.ID)
+	}
+	leaf, err := OrderLeaf(order)
+	if err != nil {
+		return abi.Value{}, err
+	}
+
+	siblings := make([]abi.Value, len(proof.Siblings))
+	for i, sibling := range proof.Siblings {
+		siblings[i] = abi.Bytes32(sibling)
+	}
+	return abi.Tuple(abi.Bytes(leaf), abi.Array(siblings...), abi.Uint64(proof.Path)), nil
+}
+
+// Verify checks the proof on its own terms: both orders are included under the order root,
+// rest on the same side of the same token, and the skipped order was placed earlier at the
+// same or a better price than the order the trade matched
+func (p *FraudProof) Verify() error {
+	root := p.OrderRoot.Hex()
+	for _, o := range []struct {
+		order Order
+		proof *merkle.Proof
+	}{{p.SkippedOrder, p.SkippedProof}, {p.MatchedOrder, p.MatchedProof}} {
+		ok, err := VerifyOrderInclusion(root, o.order, o.proof)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("order %s is not included under %s", o.order.ID, root)
+		}
+	}
+
+	skipped, matched := p.SkippedOrder, p.MatchedOrder
+	if p.Trade.BuyOrderID != matched.ID && p.Trade.SellOrderID != matched.ID {
+		return fmt.Errorf("order %s is not part of trade %s", matched.ID, p.Trade.ID)
+	}
+	if skipped.Side != matched.Side || skipped.TokenID != matched.TokenID {
+		return fmt.Errorf("orders %s and %s are not in the same book", skipped.ID, matched.ID)
+	}
+	if skipped.Price == nil || matched.Price == nil {
+		return fmt.Errorf("orders %s and %s must both have a price", skipped.ID, matched.ID)
+	}
+
+	priceComp := skipped.Price.Cmp(matched.Price)
+	if matched.Side == "sell" {
+		priceComp = -priceComp
+	}
+	if priceComp < 0 || !skipped.Timestamp.Before(matched.Timestamp) {
+		return fmt.Errorf("order %s does not have priority over %s", skipped.ID, matched.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package orderbookchecker
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"go.uber.org/zap"
+)
+
+// skippedOrderSnapshot returns the replay book with a buy order that can take any of its
+// asks, committed to by its Merkle root
+func skippedOrderSnapshot(t *testing.T, baseTime time.Time) OrderbookSnapshot {
+	t.Helper()
+
+	snapshot := replaySnapshot(baseTime)
+	snapshot.Orders = append(snapshot.Orders, Order{
+		ID:        "buy-1",
+		Side:      "buy",
+		Price:     big.NewInt(50300),
+		Quantity:  big.NewInt(800),
+		Timestamp: baseTime.Add(-time.Minute),
+		UserID:    "user4",
+	})
+	root, err := ComputeMerkleRoot(snapshot.Orders)
+	if err != nil {
+		t.Fatalf("ComputeMerkleRoot failed: %v", err)
+	}
+	snapshot.MerkleRoot = root
+	return snapshot
+}
+
+func TestOrderbookVerifier_FraudProofs(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	snapshot := skippedOrderSnapshot(t, baseTime)
+
+	trades := []Trade{
+		{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-late", Price: big.NewInt(50100), Quantity: big.NewInt(300), Timestamp: baseTime},
+		{ID: "trade-2", BuyOrderID: "buy-1", SellOrderID: "sell-early", Price: big.NewInt(50100), Quantity: big.NewInt(900), Timestamp: baseTime},
+	}
+
+	result, err := verifier.VerifySnapshot(trades, snapshot)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(result.FailedTrades) != 2 {
+		t.Fatalf("Expected both trades to fail, got %v", result.FailedTrades)
+	}
+
+	// Only the priority violation produces a fraud proof, not the overfill
+	if len(result.FraudProofs) != 1 {
+		t.Fatalf("Expected 1 fraud proof, got %d", len(result.FraudProofs))
+	}
+	proof := result.FraudProofs[0]
+	if proof.SkippedOrder.ID != "sell-early" || proof.MatchedOrder.ID != "sell-late" || proof.Trade.ID != "trade-1" {
+		t.Errorf("Expected trade-1 skipping sell-early for sell-late, got %s skipping %s for %s",
+			proof.Trade.ID, proof.SkippedOrder.ID, proof.MatchedOrder.ID)
+	}
+
+	root, err := ComputeMerkleRoot(snapshot.Orders)
+	if err != nil {
+		t.Fatalf("ComputeMerkleRoot failed: %v", err)
+	}
+	if proof.OrderRoot.Hex() != root {
+		t.Errorf("Expected order root %s, got %s", root, proof.OrderRoot.Hex())
+	}
+	if err := proof.Verify(); err != nil {
+		t.Errorf("Expected fraud proof to verify, got: %v", err)
+	}
+
+	// A proof against a root the snapshot does not declare would be rejected on-chain
+	for _, declared := range []string{"", merkle.Keccak256([]byte("other")).Hex()} {
+		snapshot.MerkleRoot = declared
+		result, err := verifier.VerifySnapshot(trades, snapshot)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(result.FraudProofs) != 0 {
+			t.Errorf("Expected no fraud proof against declared root %q, got %d", declared, len(result.FraudProofs))
+		}
+	}
+}
+
+func TestFraudProof_Verify(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	trade := Trade{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-late", Price: big.NewInt(50100), Quantity: big.NewInt(300), Timestamp: baseTime}
+
+	tests := []struct {
+		name    string
+		mutate  func(*FraudProof)
+		wantErr bool
+	}{
+		{"valid proof", func(*FraudProof) {}, false},
+		{"tampered order", func(p *FraudProof) { p.SkippedOrder.Price = big.NewInt(50000) }, true},
+		{"swapped orders", func(p *FraudProof) {
+			p.SkippedOrder, p.MatchedOrder = p.MatchedOrder, p.SkippedOrder
+			p.SkippedProof, p.MatchedProof = p.MatchedProof, p.SkippedProof
+		}, true},
+		{"matched order not in the trade", func(p *FraudProof) { p.Trade.SellOrderID = "sell-high" }, true},
+		{"wrong root", func(p *FraudProof) { p.OrderRoot = merkle.Keccak256([]byte("other")) }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := verifier.VerifySnapshot([]Trade{trade}, skippedOrderSnapshot(t, baseTime))
+			if err != nil || len(result.FraudProofs) != 1 {
+				t.Fatalf("Expected a fraud proof, got %v (%v)", result, err)
+			}
+
+			proof := result.FraudProofs[0]
+			tt.mutate(&proof)
+			if err := proof.Verify(); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFraudProof_EncodeABI(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	trade := Trade{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-late", Price: big.NewInt(50100), Quantity: big.NewInt(300), Timestamp: baseTime}
+
+	result, err := verifier.VerifySnapshot([]Trade{trade}, skippedOrderSnapshot(t, baseTime))
+	if err != nil || len(result.FraudProofs) != 1 {
+		t.Fatalf("Expected a fraud proof, got %v (%v)", result, err)
+	}
+	proof := result.FraudProofs[0]
+
+	encoded, err := proof.EncodeABI()
+	if err != nil {
+		t.Fatalf("EncodeABI failed: %v", err)
+	}
+
+	// Decode the layout SettlementVerifier.verifySkippedOrderProof expects
+	decoded, err := abi.NewDecoder(encoded).Tuple(0)
+	if err != nil {
+		t.Fatalf("Tuple failed: %v", err)
+	}
+	if version, err := decoded.Uint64(0); err != nil || version != FraudProofVersion {
+		t.Errorf("Expected version %d, got %d (%v)", FraudProofVersion, version, err)
+	}
+	root, err := decoded.Bytes32(1)
+	if err != nil || merkle.Hash(root) != proof.OrderRoot {
+		t.Fatalf("Expected order root %s, got %x (%v)", proof.OrderRoot, root, err)
+	}
+
+	for i, order := range []Order{proof.SkippedOrder, proof.MatchedOrder} {
+		orderProof, err := decoded.Tuple(2 + i)
+		if err != nil {
+			t.Fatalf("Tuple %d failed: %v", 2+i, err)
+		}
+		leaf, err := orderProof.Bytes(0)
+		if err != nil {
+			t.Fatalf("Bytes failed: %v", err)
+		}
+		if want, _ := OrderLeaf(order); !bytes.Equal(leaf, want) {
+			t.Errorf("Order %s: leaf does not match its canonical encoding", order.ID)
+		}
+
+		siblings, n, err := orderProof.Array(1)
+		if err != nil {
+			t.Fatalf("Array failed: %v", err)
+		}
+		path, err := orderProof.Uint64(2)
+		if err != nil {
+			t.Fatalf("Uint64 failed: %v", err)
+		}
+		inclusion := &merkle.Proof{Path: path}
+		for j := 0; j < n; j++ {
+			sibling, err := siblings.Bytes32(j)
+			if err != nil {
+				t.Fatalf("Bytes32 failed: %v", err)
+			}
+			inclusion.Siblings = append(inclusion.Siblings, sibling)
+		}
+		if !merkle.Verify(merkle.Hash(root), leaf, inclusion, SnapshotHashFunc) {
+			t.Errorf("Order %s: decoded proof does not verify", order.ID)
+		}
+	}
+
+	tradeData, err := decoded.Bytes(4)
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if want, _ := EncodeTrade(trade); !bytes.Equal(tradeData, want) {
+		t.Errorf("Trade does not match its canonical encoding")
+	}
+}
@@ -0,0 +1,139 @@
+package orderbookchecker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// decimalString is a big integer that marshals to a quoted decimal string, so values beyond
+// 2^53 survive JSON parsers that use floating point numbers. It unmarshals from either a
+// quoted string or a bare JSON number for compatibility with older payloads.
+type decimalString big.Int
+
+// MarshalJSON implements json.Marshaler
+func (d *decimalString) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*big.Int)(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *decimalString) UnmarshalJSON(data []byte) error {
+	text := bytes.Trim(data, `"`)
+	if _, ok := (*big.Int)(d).SetString(string(text), 10); !ok {
+		return fmt.Errorf("invalid integer value: %s", data)
+	}
+	return nil
+}
+
+// MarshalJSON encodes big integer fields as decimal strings
+func (o Order) MarshalJSON() ([]byte, error) {
+	type orderJSON Order
+	return json.Marshal(struct {
+		orderJSON
+		Price    *decimalString `json:"price"`
+		Quantity *decimalString `json:"quantity"`
+		Salt     *decimalString `json:"salt,omitempty"`
+		Nonce    *decimalString `json:"nonce,omitempty"`
+	}{
+		orderJSON: orderJSON(o),
+		Price:     (*decimalString)(o.Price),
+		Quantity:  (*decimalString)(o.Quantity),
+		Salt:      (*decimalString)(o.Salt),
+		Nonce:     (*decimalString)(o.Nonce),
+	})
+}
+
+// UnmarshalJSON accepts big integer fields as either decimal strings or JSON numbers
+func (o *Order) UnmarshalJSON(data []byte) error {
+	type orderJSON Order
+	aux := struct {
+		*orderJSON
+		Price    *decimalString `json:"price"`
+		Quantity *decimalString `json:"quantity"`
+		Salt     *decimalString `json:"salt,omitempty"`
+		Nonce    *decimalString `json:"nonce,omitempty"`
+	}{orderJSON: (*orderJSON)(o)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	o.Price = (*big.Int)(aux.Price)
+	o.Quantity = (*big.Int)(aux.Quantity)
+	o.Salt = (*big.Int)(aux.Salt)
+	o.Nonce = (*big.Int)(aux.Nonce)
+	return nil
+}
+
+// MarshalJSON encodes big integer fields as decimal strings
+func (t Trade) MarshalJSON() ([]byte, error) {
+	type tradeJSON Trade
+	return json.Marshal(struct {
+		tradeJSON
+		Price    *decimalString `json:"price"`
+		Quantity *decimalString `json:"quantity"`
+		MakerFee *decimalString `json:"maker_fee,omitempty"`
+		TakerFee *decimalString `json:"taker_fee,omitempty"`
+	}{
+		tradeJSON: tradeJSON(t),
+		Price:     (*decimalString)(t.Price),
+		Quantity:  (*decimalString)(t.Quantity),
+		MakerFee:  (*decimalString)(t.MakerFee),
+		TakerFee:  (*decimalString)(t.TakerFee),
+	})
+}
+
+// UnmarshalJSON accepts big integer fields as either decimal strings or JSON numbers
+func (t *Trade) UnmarshalJSON(data []byte) error {
+	type tradeJSON Trade
+	aux := struct {
+		*tradeJSON
+		Price    *decimalString `json:"price"`
+		Quantity *decimalString `json:"quantity"`
+		MakerFee *decimalString `json:"maker_fee,omitempty"`
+		TakerFee *decimalString `json:"taker_fee,omitempty"`
+	}{tradeJSON: (*tradeJSON)(t)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	t.Price = (*big.Int)(aux.Price)
+	t.Quantity = (*big.Int)(aux.Quantity)
+	t.MakerFee = (*big.Int)(aux.MakerFee)
+	t.TakerFee = (*big.Int)(aux.TakerFee)
+	return nil
+}
+
+// MarshalJSON encodes big integer fields as decimal strings
+func (p MarketParams) MarshalJSON() ([]byte, error) {
+	type paramsJSON MarketParams
+	return json.Marshal(struct {
+		paramsJSON
+		TickSize     *decimalString `json:"tick_size,omitempty"`
+		MinOrderSize *decimalString `json:"min_order_size,omitempty"`
+	}{
+		paramsJSON:   paramsJSON(p),
+		TickSize:     (*decimalString)(p.TickSize),
+		MinOrderSize: (*decimalString)(p.MinOrderSize),
+	})
+}
+
+// UnmarshalJSON accepts big integer fields as either decimal strings or JSON numbers
+func (p *MarketParams) UnmarshalJSON(data []byte) error {
+	type paramsJSON MarketParams
+	aux := struct {
+		*paramsJSON
+		TickSize     *decimalString `json:"tick_size,omitempty"`
+		MinOrderSize *decimalString `json:"min_order_size,omitempty"`
+	}{paramsJSON: (*paramsJSON)(p)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	p.TickSize = (*big.Int)(aux.TickSize)
+	p.MinOrderSize = (*big.Int)(aux.MinOrderSize)
+	return nil
+}
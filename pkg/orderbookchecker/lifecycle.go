@@ -0,0 +1,86 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"time"
+)
+
+// normalizeTimeInForce maps an empty time in force to GTC and IOC to FAK
+func normalizeTimeInForce(timeInForce string) (string, error) {
+	switch timeInForce {
+	case "", TimeInForceGTC:
+		return TimeInForceGTC, nil
+	case TimeInForceIOC, TimeInForceFAK:
+		return TimeInForceFAK, nil
+	case TimeInForceGTD, TimeInForceFOK:
+		return timeInForce, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidTimeInForce, timeInForce)
+	}
+}
+
+// validateTimeInForce checks that an order has a known time in force and that only GTD
+// orders, and all of them, carry an expiration
+func validateTimeInForce(order Order) error {
+	timeInForce, err := normalizeTimeInForce(order.TimeInForce)
+	if err != nil {
+		return fmt.Errorf("order %s: %w", order.ID, err)
+	}
+
+	if timeInForce == TimeInForceGTD && order.Expiration == nil {
+		return fmt.Errorf("order %s: %w: GTD order without expiration", order.ID, ErrInvalidTimeInForce)
+	}
+	if timeInForce != TimeInForceGTD && order.Expiration != nil {
+		return fmt.Errorf("order %s: %w: expiration set on %s order", order.ID, ErrInvalidTimeInForce, timeInForce)
+	}
+
+	return nil
+}
+
+// isFillOrKill reports whether an order must be filled completely or not at all
+func isFillOrKill(order *Order) bool {
+	return order.TimeInForce == TimeInForceFOK
+}
+
+// isImmediate reports whether an order is only matched on arrival and never rests
+func isImmediate(order *Order) bool {
+	switch order.TimeInForce {
+	case TimeInForceFOK, TimeInForceFAK, TimeInForceIOC:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkOrderLive returns an error if the order was cancelled or had expired at the given time.
+// An order cancelled or expiring at exactly that time can no longer be matched.
+func checkOrderLive(order *Order, cancelledAt map[string]time.Time, at time.Time) error {
+	if cancelled, ok := cancelledAt[order.ID]; ok && !at.Before(cancelled) {
+		return fmt.Errorf("%w: order %s was cancelled at %v", ErrOrderCancelled, order.ID, cancelled)
+	}
+	if order.Expiration != nil && !at.Before(*order.Expiration) {
+		return fmt.Errorf("%w: order %s expired at %v", ErrOrderExpired, order.ID, *order.Expiration)
+	}
+	return nil
+}
+
+// hasLifecycle reports whether the order can stop being matchable, so trades against it
+// must carry a timestamp
+func hasLifecycle(order *Order, cancelledAt map[string]time.Time) bool {
+	_, cancelled := cancelledAt[order.ID]
+	return cancelled || order.Expiration != nil
+}
+
+// cancellationIndex returns the earliest cancellation time of each order
+func cancellationIndex(cancellations []Cancellation) (map[string]time.Time, error) {
+	index := make(map[string]time.Time, len(cancellations))
+	for _, c := range cancellations {
+		if c.OrderID == "" {
+			return nil, fmt.Errorf("cancellation without order ID")
+		}
+		if existing, ok := index[c.OrderID]; !ok || c.Timestamp.Before(existing) {
+			index[c.OrderID] = c.Timestamp
+		}
+	}
+	return index, nil
+}
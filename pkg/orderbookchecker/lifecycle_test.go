@@ -0,0 +1,208 @@
+package orderbookchecker
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// lifecycleSnapshot returns a book with a GTD sell expiring at baseTime and a FOK buy
+func lifecycleSnapshot(baseTime time.Time) OrderbookSnapshot {
+	expiration := baseTime
+	return OrderbookSnapshot{
+		SequenceNumber: 1,
+		Timestamp:      baseTime.Add(-time.Minute),
+		MarketID:       "BTC-USD",
+		Orders: []Order{
+			{ID: "buy-gtc", Side: "buy", Price: big.NewInt(50200), Quantity: big.NewInt(500), Timestamp: baseTime.Add(-5 * time.Minute)},
+			{ID: "buy-fok", Side: "buy", Price: big.NewInt(50100), Quantity: big.NewInt(600), Timestamp: baseTime.Add(-4 * time.Minute), TimeInForce: TimeInForceFOK},
+			{ID: "sell-gtd", Side: "sell", Price: big.NewInt(50000), Quantity: big.NewInt(500), Timestamp: baseTime.Add(-3 * time.Minute), TimeInForce: TimeInForceGTD, Expiration: &expiration},
+			{ID: "sell-gtc", Side: "sell", Price: big.NewInt(50100), Quantity: big.NewInt(500), Timestamp: baseTime.Add(-2 * time.Minute)},
+		},
+	}
+}
+
+func TestOrderbookVerifier_OrderLifecycle(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	before, after := baseTime.Add(-time.Second), baseTime.Add(time.Second)
+
+	tests := []struct {
+		name          string
+		trades        []Trade
+		cancellations []Cancellation
+		mutate        func(*OrderbookSnapshot)
+		wantCode      string
+		wantFailed    int
+	}{
+		{
+			name:   "GTD order before expiration",
+			trades: []Trade{{ID: "t-1", BuyOrderID: "buy-gtc", SellOrderID: "sell-gtd", Price: big.NewInt(50000), Quantity: big.NewInt(500), Timestamp: before}},
+		},
+		{
+			name:       "GTD order at expiration",
+			trades:     []Trade{{ID: "t-1", BuyOrderID: "buy-gtc", SellOrderID: "sell-gtd", Price: big.NewInt(50000), Quantity: big.NewInt(500), Timestamp: baseTime}},
+			wantCode:   ErrorCodeOrderExpired,
+			wantFailed: 1,
+		},
+		{
+			name:          "order cancelled before the trade",
+			trades:        []Trade{{ID: "t-1", BuyOrderID: "buy-gtc", SellOrderID: "sell-gtc", Price: big.NewInt(50100), Quantity: big.NewInt(100), Timestamp: after}},
+			cancellations: []Cancellation{{OrderID: "buy-gtc", Timestamp: baseTime}},
+			wantCode:      ErrorCodeOrderCancelled,
+			wantFailed:    1,
+		},
+		{
+			name:          "order cancelled after the trade",
+			trades:        []Trade{{ID: "t-1", BuyOrderID: "buy-gtc", SellOrderID: "sell-gtd", Price: big.NewInt(50000), Quantity: big.NewInt(500), Timestamp: before}},
+			cancellations: []Cancellation{{OrderID: "buy-gtc", Timestamp: baseTime}},
+		},
+		{
+			name:          "cancelled order traded without a timestamp",
+			trades:        []Trade{{ID: "t-1", BuyOrderID: "buy-gtc", SellOrderID: "sell-gtd", Price: big.NewInt(50000), Quantity: big.NewInt(100)}},
+			cancellations: []Cancellation{{OrderID: "buy-gtc", Timestamp: baseTime}},
+			wantCode:      ErrorCodeInvalidTrade,
+			wantFailed:    1,
+		},
+		{
+			name: "FOK order filled completely",
+			trades: []Trade{
+				{ID: "t-1", BuyOrderID: "buy-gtc", SellOrderID: "sell-gtd", Price: big.NewInt(50000), Quantity: big.NewInt(500), Timestamp: before},
+				{ID: "t-2", BuyOrderID: "buy-fok", SellOrderID: "sell-gtc", Price: big.NewInt(50100), Quantity: big.NewInt(500), Timestamp: before},
+			},
+			mutate: func(s *OrderbookSnapshot) { s.Orders[1].Quantity = big.NewInt(500) },
+		},
+		{
+			name: "FOK order partially filled",
+			trades: []Trade{
+				{ID: "t-1", BuyOrderID: "buy-gtc", SellOrderID: "sell-gtd", Price: big.NewInt(50000), Quantity: big.NewInt(500), Timestamp: before},
+				{ID: "t-2", BuyOrderID: "buy-fok", SellOrderID: "sell-gtc", Price: big.NewInt(50100), Quantity: big.NewInt(500), Timestamp: before},
+			},
+			wantCode:   ErrorCodeFOKPartialFill,
+			wantFailed: 1,
+		},
+		{
+			name: "trade between two partially filled FOK orders",
+			trades: []Trade{
+				{ID: "t-1", BuyOrderID: "buy-fok", SellOrderID: "sell-gtc", Price: big.NewInt(50100), Quantity: big.NewInt(500), Timestamp: before},
+			},
+			mutate: func(s *OrderbookSnapshot) {
+				s.Orders[3].Quantity, s.Orders[3].TimeInForce = big.NewInt(700), TimeInForceFOK
+				s.Orders = []Order{s.Orders[1], s.Orders[3]}
+			},
+			wantCode:   ErrorCodeFOKPartialFill,
+			wantFailed: 1,
+		},
+		{
+			name:     "GTD order without expiration",
+			mutate:   func(s *OrderbookSnapshot) { s.Orders[2].Expiration = nil },
+			wantCode: ErrorCodeInvalidTimeInForce,
+		},
+		{
+			name:     "unknown time in force",
+			mutate:   func(s *OrderbookSnapshot) { s.Orders[0].TimeInForce = "DAY" },
+			wantCode: ErrorCodeInvalidTimeInForce,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := lifecycleSnapshot(baseTime)
+			if tt.mutate != nil {
+				tt.mutate(&snapshot)
+			}
+
+			result, err := verifier.VerifySnapshotWithCancellations(tt.trades, snapshot, tt.cancellations)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			wantValid := tt.wantCode == "" && tt.wantFailed == 0
+			if result.Valid != wantValid {
+				t.Errorf("Expected valid=%v, got %v: %s", wantValid, result.Valid, result.ErrorMessage)
+			}
+			if result.ErrorCode != tt.wantCode {
+				t.Errorf("Expected error code %q, got %q", tt.wantCode, result.ErrorCode)
+			}
+			if len(result.FailedTrades) != tt.wantFailed {
+				t.Errorf("Expected %d failed trades, got %v", tt.wantFailed, result.FailedTrades)
+			}
+			if result.VerifiedTrades != len(tt.trades)-tt.wantFailed {
+				t.Errorf("Expected %d verified trades, got %d", len(tt.trades)-tt.wantFailed, result.VerifiedTrades)
+			}
+		})
+	}
+}
+
+func TestMatchingEngine_TimeInForce(t *testing.T) {
+	baseTime := time.Now().Truncate(time.Millisecond)
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	incoming := func(id, timeInForce string, qty int64, at time.Time) Order {
+		return Order{ID: id, Side: "buy", Price: big.NewInt(50100), Quantity: big.NewInt(qty), Timestamp: at, TimeInForce: timeInForce}
+	}
+
+	tests := []struct {
+		name          string
+		order         Order
+		cancellations []Cancellation
+		wantFills     []string // Sell order IDs matched, in order
+		wantResting   bool
+	}{
+		{"GTC sweeps both asks", incoming("in", TimeInForceGTC, 800, baseTime.Add(-time.Second)), nil, []string{"sell-gtd", "sell-gtc"}, false},
+		{"expired ask is skipped", incoming("in", "", 800, baseTime), nil, []string{"sell-gtc"}, true},
+		{"cancelled ask is skipped", incoming("in", "", 300, baseTime.Add(-time.Second)), []Cancellation{{OrderID: "sell-gtd", Timestamp: baseTime.Add(-2 * time.Second)}}, []string{"sell-gtc"}, false},
+		{"FOK that cannot fill is killed", incoming("in", TimeInForceFOK, 1200, baseTime.Add(-time.Second)), nil, nil, false},
+		{"FOK that can fill", incoming("in", TimeInForceFOK, 1000, baseTime.Add(-time.Second)), nil, []string{"sell-gtd", "sell-gtc"}, false},
+		{"expired ask leaves FOK unfillable", incoming("in", TimeInForceFOK, 1000, baseTime), nil, nil, false},
+		{"FAK drops its remainder", incoming("in", TimeInForceIOC, 1200, baseTime.Add(-time.Second)), nil, []string{"sell-gtd", "sell-gtc"}, false},
+		{"GTC rests its remainder", incoming("in", "", 1200, baseTime.Add(-time.Second)), nil, []string{"sell-gtd", "sell-gtc"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := verifier.buildOrderbookState(lifecycleSnapshot(baseTime).Orders, nil)
+			if err != nil {
+				t.Fatalf("buildOrderbookState failed: %v", err)
+			}
+			if state.CancelledAt, err = cancellationIndex(tt.cancellations); err != nil {
+				t.Fatalf("cancellationIndex failed: %v", err)
+			}
+
+			engine := NewMatchingEngine(state)
+			fills, err := engine.Submit(tt.order)
+			if err != nil {
+				t.Fatalf("Submit failed: %v", err)
+			}
+
+			if len(fills) != len(tt.wantFills) {
+				t.Fatalf("Expected fills against %v, got %+v", tt.wantFills, fills)
+			}
+			for i, fill := range fills {
+				if fill.SellOrderID != tt.wantFills[i] {
+					t.Errorf("Fill %d: expected sell order %s, got %s", i, tt.wantFills[i], fill.SellOrderID)
+				}
+			}
+
+			resting := false
+			for _, bid := range engine.bids {
+				resting = resting || bid.ID == tt.order.ID
+			}
+			if resting != tt.wantResting {
+				t.Errorf("Expected resting=%v, got %v", tt.wantResting, resting)
+			}
+
+			// A killed FOK order leaves the asks untouched
+			if tt.order.TimeInForce == TimeInForceFOK && len(fills) == 0 {
+				if engine.Remaining()["sell-gtd"].Cmp(big.NewInt(500)) != 0 || len(engine.asks) != 2 {
+					t.Errorf("Expected killed FOK order to leave the book unchanged")
+				}
+			}
+		})
+	}
+}
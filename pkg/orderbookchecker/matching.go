@@ -0,0 +1,319 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// MatchingEngine is a deterministic price-time priority matching engine used to
+// replay incoming orders on top of an orderbook snapshot. Each outcome token has its own
+// book, and an incoming order also matches complementary orders on the opposite token
+// by minting or merging when that gives a better price.
+type MatchingEngine struct {
+	bids        []Order              // Resting buy orders of all tokens, best first
+	asks        []Order              // Resting sell orders of all tokens, best first
+	remaining   map[string]*big.Int  // Unfilled quantity per order ID
+	unit        *big.Int             // Sum of complementary prices
+	cancelledAt map[string]time.Time // Cancellation time per cancelled order ID
+	tick        *big.Int             // Price tick, nil if prices are not on a grid
+	// executionPrice is the ExecutionPrice* policy for fill prices; empty or
+	// ExecutionPriceAny fills at the maker's price
+	executionPrice string
+	// selfTrade is the SelfTrade* mode for orders of the same user; empty allows self-trades
+	selfTrade string
+}
+
+// NewMatchingEngine creates a matching engine seeded with the resting orders of the given state
+func NewMatchingEngine(state *OrderbookState) *MatchingEngine {
+	engine := &MatchingEngine{
+		bids:        append([]Order(nil), state.BuyOrders...),
+		asks:        append([]Order(nil), state.SellOrders...),
+		remaining:   make(map[string]*big.Int, len(state.Remaining)),
+		unit:        state.PriceUnit,
+		cancelledAt: state.CancelledAt,
+	}
+	if state.Params != nil {
+		engine.tick = state.Params.TickSize
+	}
+	if engine.unit == nil {
+		engine.unit = priceUnit(DefaultCollateralDecimals)
+	}
+
+	for id, qty := range state.Remaining {
+		engine.remaining[id] = new(big.Int).Set(qty)
+	}
+
+	return engine
+}
+
+// Submit matches an incoming order against the resting book and returns the fills it produced.
+// Fills execute at the resting order's price, or the price given by the engine's execution
+// price policy, quoted in the incoming order's token. Resting
+// orders cancelled or expired at the incoming order's timestamp are skipped. Any unfilled
+// remainder of a GTC or GTD order rests on the book; FAK orders drop it, and FOK orders
+// that cannot be filled completely produce no fills at all. Under self-trade prevention a
+// resting order of the same user is either removed from the book or stops the incoming order.
+func (e *MatchingEngine) Submit(order Order) ([]Fill, error) {
+	if order.Price == nil || order.Quantity == nil || order.Quantity.Sign() <= 0 {
+		return nil, fmt.Errorf("incoming order %s must have a price and a positive quantity", order.ID)
+	}
+	if err := validateOutcome(order); err != nil {
+		return nil, err
+	}
+	if err := validateTimeInForce(order); err != nil {
+		return nil, err
+	}
+	if _, exists := e.remaining[order.ID]; exists {
+		return nil, fmt.Errorf("duplicate order ID: %s", order.ID)
+	}
+
+	var isBuy bool
+	switch order.Side {
+	case "buy":
+		isBuy = true
+	case "sell":
+		isBuy = false
+	default:
+		return nil, fmt.Errorf("invalid order side: %s", order.Side)
+	}
+
+	remaining := new(big.Int).Set(order.Quantity)
+	e.remaining[order.ID] = remaining
+
+	// An order that has already expired or been cancelled on arrival never matches
+	if checkOrderLive(&order, e.cancelledAt, order.Timestamp) != nil {
+		return nil, nil
+	}
+
+	// Keep the books so an unfillable fill-or-kill order can be rolled back
+	var bids, asks []Order
+	if isFillOrKill(&order) {
+		bids, asks = append([]Order(nil), e.bids...), append([]Order(nil), e.asks...)
+	}
+
+	var fills []Fill
+	removed := make(map[string]*big.Int) // Remaining of resting orders cancelled by self-trade prevention
+	cancelled := false
+	for remaining.Sign() > 0 {
+		book, idx, matchType, price := e.bestCounterparty(&order, isBuy)
+		if idx < 0 {
+			break
+		}
+		if (isBuy && price.Cmp(order.Price) > 0) || (!isBuy && price.Cmp(order.Price) < 0) {
+			break
+		}
+
+		resting := (*book)[idx]
+		if e.selfTrade != "" && e.selfTrade != SelfTradeAllow && isSelfTrade(&order, &resting) {
+			if e.selfTrade == SelfTradeCancelIncoming {
+				cancelled = true
+				break
+			}
+			removed[resting.ID] = e.remaining[resting.ID]
+			e.remaining[resting.ID] = new(big.Int)
+			*book = append((*book)[:idx], (*book)[idx+1:]...)
+			continue
+		}
+		if fillPrice := policyPrice(e.executionPrice, price, order.Price, isBuy, e.tick); fillPrice != nil {
+			price = fillPrice
+		}
+		fills = append(fills, e.match(order.ID, &resting, remaining, price, matchType, isBuy))
+		if e.remaining[resting.ID].Sign() == 0 {
+			*book = append((*book)[:idx], (*book)[idx+1:]...)
+		}
+	}
+
+	if remaining.Sign() > 0 && isFillOrKill(&order) {
+		for _, fill := range fills {
+			restingID := fill.SellOrderID
+			if !isBuy {
+				restingID = fill.BuyOrderID
+			}
+			e.remaining[restingID].Add(e.remaining[restingID], fill.Quantity)
+		}
+		for id, qty := range removed {
+			e.remaining[id] = qty
+		}
+		e.bids, e.asks = bids, asks
+		remaining.Set(order.Quantity)
+		return nil, nil
+	}
+
+	if remaining.Sign() > 0 && !isImmediate(&order) && !cancelled {
+		if isBuy {
+			e.bids = insertOrder(e.bids, order, buyHasPriority)
+		} else {
+			e.asks = insertOrder(e.asks, order, sellHasPriority)
+		}
+	}
+
+	return fills, nil
+}
+
+// Remaining returns the unfilled quantity of every order seen by the engine
+func (e *MatchingEngine) Remaining() map[string]*big.Int {
+	return e.remaining
+}
+
+// bestCounterparty finds the resting order that gives the incoming order the best price:
+// the best opposite-side order on the same token, or the best same-side order on the
+// complementary token. It returns the book holding the order, its index (-1 if there is
+// none), the match type and the price quoted in the incoming order's token. Equal prices
+// go to the earlier order, then to the normal match.
+func (e *MatchingEngine) bestCounterparty(order *Order, isBuy bool) (*[]Order, int, string, *big.Int) {
+	normalBook, complementBook, complementType := &e.asks, &e.bids, MatchTypeMint
+	if !isBuy {
+		normalBook, complementBook, complementType = &e.bids, &e.asks, MatchTypeMerge
+	}
+
+	// Books are sorted best first, so the first eligible order of each kind is its best
+	normalIdx := -1
+	for i := range *normalBook {
+		if (*normalBook)[i].TokenID == order.TokenID && e.live(&(*normalBook)[i], order.Timestamp) {
+			normalIdx = i
+			break
+		}
+	}
+	complementIdx := -1
+	for i := range *complementBook {
+		if complementary(order, &(*complementBook)[i]) && e.live(&(*complementBook)[i], order.Timestamp) {
+			complementIdx = i
+			break
+		}
+	}
+
+	if complementIdx < 0 {
+		if normalIdx < 0 {
+			return nil, -1, "", nil
+		}
+		return normalBook, normalIdx, MatchTypeNormal, (*normalBook)[normalIdx].Price
+	}
+
+	complement := &(*complementBook)[complementIdx]
+	complementPrice := new(big.Int).Sub(e.unit, complement.Price)
+	if normalIdx >= 0 {
+		normal := &(*normalBook)[normalIdx]
+		priceComp := normal.Price.Cmp(complementPrice)
+		if !isBuy {
+			priceComp = -priceComp
+		}
+		if priceComp < 0 || (priceComp == 0 && !complement.Timestamp.Before(normal.Timestamp)) {
+			return normalBook, normalIdx, MatchTypeNormal, normal.Price
+		}
+	}
+
+	return complementBook, complementIdx, complementType, complementPrice
+}
+
+// live reports whether a resting order can still be matched at the given time
+func (e *MatchingEngine) live(order *Order, at time.Time) bool {
+	return checkOrderLive(order, e.cancelledAt, at) == nil
+}
+
+// match fills the incoming order against a single resting order at the given price
+func (e *MatchingEngine) match(incomingID string, resting *Order, remaining, price *big.Int, matchType string, incomingIsBuy bool) Fill {
+	restingRemaining := e.remaining[resting.ID]
+
+	qty := new(big.Int).Set(remaining)
+	if restingRemaining.Cmp(qty) < 0 {
+		qty.Set(restingRemaining)
+	}
+
+	remaining.Sub(remaining, qty)
+	restingRemaining.Sub(restingRemaining, qty)
+
+	fill := Fill{
+		Price:        new(big.Int).Set(price),
+		Quantity:     qty,
+		MatchType:    matchType,
+		MakerOrderID: resting.ID,
+	}
+	if incomingIsBuy {
+		fill.BuyOrderID, fill.SellOrderID = incomingID, resting.ID
+	} else {
+		fill.BuyOrderID, fill.SellOrderID = resting.ID, incomingID
+	}
+
+	return fill
+}
+
+// insertOrder inserts an order into a book side, keeping it sorted by the given priority
+func insertOrder(book []Order, order Order, hasPriority func(a, b *Order) bool) []Order {
+	idx := sort.Search(len(book), func(i int) bool {
+		return hasPriority(&order, &book[i])
+	})
+
+	book = append(book, Order{})
+	copy(book[idx+1:], book[idx:])
+	book[idx] = order
+
+	return book
+}
+
+// diffFills compares expected fills with reported trades one-for-one, in sequence
+func diffFills(fills []Fill, trades []Trade) []FillDiff {
+	var diffs []FillDiff
+
+	n := len(fills)
+	if len(trades) > n {
+		n = len(trades)
+	}
+
+	for i := 0; i < n; i++ {
+		switch {
+		case i >= len(trades):
+			diffs = append(diffs, FillDiff{
+				Index:    i,
+				Kind:     FillDiffMissing,
+				Expected: &fills[i],
+				Reason: fmt.Sprintf("expected fill of %s between buy %s and sell %s was not reported",
+					fills[i].Quantity.String(), fills[i].BuyOrderID, fills[i].SellOrderID),
+			})
+		case i >= len(fills):
+			diffs = append(diffs, FillDiff{
+				Index:    i,
+				Kind:     FillDiffPhantom,
+				Reported: &trades[i],
+				Reason:   fmt.Sprintf("trade %s does not correspond to any fill produced by the matching engine", trades[i].ID),
+			})
+		default:
+			if reason := compareFill(fills[i], trades[i]); reason != "" {
+				diffs = append(diffs, FillDiff{
+					Index:    i,
+					Kind:     FillDiffMismatch,
+					Expected: &fills[i],
+					Reported: &trades[i],
+					Reason:   reason,
+				})
+			}
+		}
+	}
+
+	return diffs
+}
+
+// compareFill returns a description of how a reported trade differs from the expected fill,
+// or an empty string if they are identical
+func compareFill(fill Fill, trade Trade) string {
+	if fill.BuyOrderID != trade.BuyOrderID {
+		return fmt.Sprintf("buy order %s was matched but %s should have been", trade.BuyOrderID, fill.BuyOrderID)
+	}
+	if fill.SellOrderID != trade.SellOrderID {
+		return fmt.Sprintf("sell order %s was matched but %s should have been", trade.SellOrderID, fill.SellOrderID)
+	}
+	if trade.Price == nil || fill.Price.Cmp(trade.Price) != 0 {
+		return fmt.Sprintf("trade price %v does not match expected fill price %s", trade.Price, fill.Price.String())
+	}
+	if trade.Quantity == nil || fill.Quantity.Cmp(trade.Quantity) != 0 {
+		return fmt.Sprintf("trade quantity %v does not match expected fill quantity %s", trade.Quantity, fill.Quantity.String())
+	}
+	if matchType, _ := normalizeMatchType(trade.MatchType); matchType != fill.MatchType {
+		return fmt.Sprintf("trade match type %s does not match expected match type %s", trade.MatchType, fill.MatchType)
+	}
+	if trade.MakerOrderID != "" && trade.MakerOrderID != fill.MakerOrderID {
+		return fmt.Sprintf("maker order %s was reported but %s was resting", trade.MakerOrderID, fill.MakerOrderID)
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// priceUnit returns the price of one full unit of collateral with the given decimals
+func priceUnit(decimals uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+}
+
+// normalizeMatchType maps an empty match type to MatchTypeNormal and rejects unknown types
+func normalizeMatchType(matchType string) (string, error) {
+	switch matchType {
+	case "", MatchTypeNormal:
+		return MatchTypeNormal, nil
+	case MatchTypeMint, MatchTypeMerge:
+		return matchType, nil
+	default:
+		return "", fmt.Errorf("invalid match type: %s", matchType)
+	}
+}
+
+// validateOutcome checks that an order's outcome is empty or one of the binary outcomes
+func validateOutcome(order Order) error {
+	switch order.Outcome {
+	case "", OutcomeYes, OutcomeNo:
+		return nil
+	default:
+		return fmt.Errorf("order %s has invalid outcome: %s", order.ID, order.Outcome)
+	}
+}
+
+// complementary reports whether two orders trade the opposite outcome tokens of a market
+func complementary(a, b *Order) bool {
+	if a.TokenID == b.TokenID {
+		return false
+	}
+	return (a.Outcome == OutcomeYes && b.Outcome == OutcomeNo) ||
+		(a.Outcome == OutcomeNo && b.Outcome == OutcomeYes)
+}
+
+// effectivePrice returns the price at which an order acts on the given side of the priced
+// token: its own price on its own side, or the complementary price unit - price when it
+// stands in for the opposite side
+func effectivePrice(order *Order, side string, unit *big.Int) *big.Int {
+	if order.Side == side {
+		return order.Price
+	}
+	return new(big.Int).Sub(unit, order.Price)
+}
+
+// verifyMatchStructure checks that the two orders of a trade can be matched with its match type
+func verifyMatchStructure(matchType string, buyOrder, sellOrder *Order) error {
+	switch matchType {
+	case MatchTypeNormal:
+		if buyOrder.Side != "buy" || sellOrder.Side != "sell" {
+			return fmt.Errorf("normal match requires a buy and a sell, got %s and %s", buyOrder.Side, sellOrder.Side)
+		}
+		if buyOrder.TokenID != sellOrder.TokenID {
+			return fmt.Errorf("normal match between different tokens %s and %s", buyOrder.TokenID, sellOrder.TokenID)
+		}
+	case MatchTypeMint, MatchTypeMerge:
+		side := "buy"
+		if matchType == MatchTypeMerge {
+			side = "sell"
+		}
+		if buyOrder.Side != side || sellOrder.Side != side {
+			return fmt.Errorf("%s match requires two %s orders, got %s and %s", matchType, side, buyOrder.Side, sellOrder.Side)
+		}
+		if !complementary(buyOrder, sellOrder) {
+			return fmt.Errorf("%s match requires complementary outcome tokens, got %s (%s) and %s (%s)",
+				matchType, buyOrder.TokenID, buyOrder.Outcome, sellOrder.TokenID, sellOrder.Outcome)
+		}
+	}
+	return nil
+}
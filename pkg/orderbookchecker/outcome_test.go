@@ -0,0 +1,235 @@
+package orderbookchecker
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	testYesToken = "token-yes"
+	testNoToken  = "token-no"
+)
+
+// cents returns a price of n hundredths of a collateral unit
+func cents(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), priceUnit(DefaultCollateralDecimals-2))
+}
+
+// outcomeSnapshot returns a binary market with orders on both outcome tokens. The 42 cent
+// NO bid crosses the 38 cent NO ask and mints against the 60 cent YES bid, so a batch must
+// fill all 50 shares of one of them to leave a book that does not cross.
+func outcomeSnapshot(baseTime time.Time) OrderbookSnapshot {
+	order := func(id, side, token, outcome string, price int64, quantity int64, age time.Duration) Order {
+		return Order{
+			ID:        id,
+			Side:      side,
+			Price:     cents(price),
+			Quantity:  big.NewInt(quantity),
+			Timestamp: baseTime.Add(-age),
+			UserID:    "user-" + id,
+			TokenID:   token,
+			Outcome:   outcome,
+		}
+	}
+
+	return OrderbookSnapshot{
+		SequenceNumber: 1,
+		Timestamp:      baseTime,
+		MarketID:       "ELECTION-2024",
+		Orders: []Order{
+			order("yes-buy-60", "buy", testYesToken, OutcomeYes, 60, 100, 5*time.Minute),
+			order("yes-sell-65", "sell", testYesToken, OutcomeYes, 65, 100, 4*time.Minute),
+			order("no-buy-42", "buy", testNoToken, OutcomeNo, 42, 50, 3*time.Minute),
+			order("no-buy-35", "buy", testNoToken, OutcomeNo, 35, 100, 6*time.Minute),
+			order("no-sell-38", "sell", testNoToken, OutcomeNo, 38, 50, 2*time.Minute),
+			order("no-sell-45", "sell", testNoToken, OutcomeNo, 45, 100, 1*time.Minute),
+		},
+	}
+}
+
+// withoutOrders removes orders from a snapshot, so the book left does not cross in a replay
+func withoutOrders(snapshot OrderbookSnapshot, ids ...string) OrderbookSnapshot {
+	var orders []Order
+	for _, order := range snapshot.Orders {
+		if !slices.Contains(ids, order.ID) {
+			orders = append(orders, order)
+		}
+	}
+	snapshot.Orders = orders
+	return snapshot
+}
+
+func TestOrderbookVerifier_ComplementaryMatches(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	tests := []struct {
+		name  string
+		trade Trade
+		valid bool
+	}{
+		{
+			name:  "mint with prices summing to more than one",
+			trade: Trade{BuyOrderID: "yes-buy-60", SellOrderID: "no-buy-42", Price: cents(58), MatchType: MatchTypeMint},
+			valid: true,
+		},
+		{
+			name:  "mint with prices summing to less than one",
+			trade: Trade{BuyOrderID: "yes-buy-60", SellOrderID: "no-buy-35", Price: cents(60), MatchType: MatchTypeMint},
+		},
+		{
+			name:  "mint priced beyond the complementary limit",
+			trade: Trade{BuyOrderID: "yes-buy-60", SellOrderID: "no-buy-42", Price: cents(55), MatchType: MatchTypeMint},
+		},
+		{
+			name:  "merge with prices summing to more than one",
+			trade: Trade{BuyOrderID: "no-sell-38", SellOrderID: "yes-sell-65", Price: cents(62), MatchType: MatchTypeMerge},
+		},
+		{
+			name:  "merge with a buy order",
+			trade: Trade{BuyOrderID: "no-buy-42", SellOrderID: "yes-sell-65", Price: cents(58), MatchType: MatchTypeMerge},
+		},
+		{
+			name:  "mint of two buys on the same token",
+			trade: Trade{BuyOrderID: "no-buy-42", SellOrderID: "no-buy-35", Price: cents(42), MatchType: MatchTypeMint},
+		},
+		{
+			name:  "normal match across tokens",
+			trade: Trade{BuyOrderID: "yes-buy-60", SellOrderID: "no-sell-38", Price: cents(50)},
+		},
+		{
+			name:  "normal match within a token",
+			trade: Trade{BuyOrderID: "no-buy-42", SellOrderID: "no-sell-38", Price: cents(38)},
+			valid: true,
+		},
+		{
+			name:  "unknown match type",
+			trade: Trade{BuyOrderID: "yes-buy-60", SellOrderID: "no-buy-42", Price: cents(58), MatchType: "swap"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trade := tt.trade
+			trade.ID = "trade-1"
+			trade.Quantity = big.NewInt(50)
+
+			result, err := verifier.VerifySnapshot([]Trade{trade}, outcomeSnapshot(time.Now().Truncate(time.Millisecond)))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result.Valid != tt.valid {
+				t.Errorf("Expected valid=%v, got %v: %s", tt.valid, result.Valid, result.ErrorMessage)
+			}
+		})
+	}
+}
+
+func TestOrderbookVerifier_MergeWithinUnit(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	snapshot := withoutOrders(outcomeSnapshot(time.Now().Truncate(time.Millisecond)), "no-buy-42")
+	// YES sell at 0.55 and NO sell at 0.38 ask 0.93 in total for the unit they merge into
+	snapshot.Orders[1].Price, snapshot.Orders[1].Quantity = cents(55), big.NewInt(50)
+
+	trades := []Trade{
+		{ID: "trade-1", BuyOrderID: "no-sell-38", SellOrderID: "yes-sell-65", Price: cents(60), Quantity: big.NewInt(50), MatchType: MatchTypeMerge},
+	}
+
+	result, err := verifier.VerifySnapshot(trades, snapshot)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Valid {
+		t.Errorf("Expected valid merge, got invalid: %s", result.ErrorMessage)
+	}
+}
+
+func TestOrderbookVerifier_PriorityIsPerToken(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	// no-buy-35 is older and bids more, but rests on the NO book, so it does not outrank
+	// yes-buy-60 for a YES trade
+	snapshot := withoutOrders(outcomeSnapshot(time.Now().Truncate(time.Millisecond)), "no-sell-38", "no-sell-45")
+	snapshot.Orders[0].Quantity = big.NewInt(10)
+	snapshot.Orders[1].Price, snapshot.Orders[1].Quantity = cents(60), big.NewInt(10)
+	snapshot.Orders[3].Price = cents(70)
+
+	trades := []Trade{
+		{ID: "trade-1", BuyOrderID: "yes-buy-60", SellOrderID: "yes-sell-65", Price: cents(60), Quantity: big.NewInt(10)},
+	}
+
+	result, err := verifier.VerifySnapshot(trades, snapshot)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Valid {
+		t.Errorf("Expected valid trade, got invalid: %s", result.ErrorMessage)
+	}
+}
+
+func TestOrderbookVerifier_VerifyReplay_MintsAgainstComplement(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	snapshot := withoutOrders(outcomeSnapshot(baseTime), "yes-buy-60", "no-sell-38")
+
+	// A YES buy at 0.62 is cheaper to fill by minting against the NO bid at 0.42 (0.58)
+	// than by taking the YES ask at 0.65, and the NO bid at 0.35 (0.65) is out of range
+	incoming := []Order{
+		{
+			ID:        "yes-buy-62",
+			Side:      "buy",
+			Price:     cents(62),
+			Quantity:  big.NewInt(100),
+			Timestamp: baseTime.Add(time.Second),
+			UserID:    "user-taker",
+			TokenID:   testYesToken,
+			Outcome:   OutcomeYes,
+		},
+	}
+
+	trades := []Trade{
+		{ID: "trade-1", BuyOrderID: "yes-buy-62", SellOrderID: "no-buy-42", Price: cents(58), Quantity: big.NewInt(50), MatchType: MatchTypeMint},
+	}
+
+	result, err := verifier.VerifyReplay(trades, snapshot, incoming)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Valid {
+		t.Fatalf("Expected valid replay, got invalid: %s", result.ErrorMessage)
+	}
+	if got := result.ResidualQuantities["yes-buy-62"]; got == nil || got.Cmp(big.NewInt(50)) != 0 {
+		t.Errorf("Expected incoming residual of 50, got %v", got)
+	}
+
+	// Reporting the same fill as a normal trade is a mismatch
+	trades[0].MatchType = ""
+	result, err = verifier.VerifyReplay(trades, snapshot, incoming)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Valid || len(result.FillDiffs) != 1 {
+		t.Errorf("Expected a single match type mismatch, got %+v", result.FillDiffs)
+	}
+}
+
+func TestOrderbookVerifier_ConflictingOutcomes(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	snapshot := outcomeSnapshot(time.Now().Truncate(time.Millisecond))
+	snapshot.Orders[2].Outcome = OutcomeYes
+
+	if _, err := verifier.VerifySnapshot(nil, snapshot); err == nil {
+		t.Error("Expected error for a token with two outcomes")
+	}
+}
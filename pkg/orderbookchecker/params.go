@@ -0,0 +1,75 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// maxCollateralDecimals keeps the collateral unit within uint256
+const maxCollateralDecimals = 77
+
+// PriceUnit returns the price of one full unit of collateral, 10^CollateralDecimals
+func (p *MarketParams) PriceUnit() *big.Int {
+	return priceUnit(p.CollateralDecimals)
+}
+
+// Validate checks that the parameters describe a usable price domain
+func (p *MarketParams) Validate() error {
+	if p.CollateralDecimals > maxCollateralDecimals {
+		return fmt.Errorf("%w: collateral decimals %d must be at most %d",
+			ErrInvalidMarketParams, p.CollateralDecimals, maxCollateralDecimals)
+	}
+
+	unit := p.PriceUnit()
+	if p.TickSize != nil {
+		if p.TickSize.Sign() <= 0 || p.TickSize.Cmp(unit) >= 0 {
+			return fmt.Errorf("%w: tick size %s must be between 0 and %s",
+				ErrInvalidMarketParams, p.TickSize.String(), unit.String())
+		}
+		// Complementary prices unit - price must land on the same ticks
+		if new(big.Int).Mod(unit, p.TickSize).Sign() != 0 {
+			return fmt.Errorf("%w: tick size %s does not divide %s",
+				ErrInvalidMarketParams, p.TickSize.String(), unit.String())
+		}
+	}
+
+	if p.MinOrderSize != nil && p.MinOrderSize.Sign() < 0 {
+		return fmt.Errorf("%w: min order size %s is negative", ErrInvalidMarketParams, p.MinOrderSize.String())
+	}
+
+	if p.MakerFeeBps > maxFeeBps || p.TakerFeeBps > maxFeeBps {
+		return fmt.Errorf("%w: fee rates %d/%d bps must not exceed %d",
+			ErrInvalidMarketParams, p.MakerFeeBps, p.TakerFeeBps, maxFeeBps)
+	}
+
+	return nil
+}
+
+// CheckPrice checks that a price lies strictly between 0 and one unit and on the tick
+func (p *MarketParams) CheckPrice(price *big.Int) error {
+	unit := p.PriceUnit()
+	if price == nil || price.Sign() <= 0 || price.Cmp(unit) >= 0 {
+		return fmt.Errorf("%w: price %v must be between 0 and %s", ErrPriceOutOfRange, price, unit.String())
+	}
+
+	if p.TickSize != nil && new(big.Int).Mod(price, p.TickSize).Sign() != 0 {
+		return fmt.Errorf("%w: price %s is not a multiple of tick size %s",
+			ErrPriceOffTick, price.String(), p.TickSize.String())
+	}
+
+	return nil
+}
+
+// CheckOrder checks an order's price and its size against the minimum order size
+func (p *MarketParams) CheckOrder(order Order) error {
+	if err := p.CheckPrice(order.Price); err != nil {
+		return fmt.Errorf("order %s: %w", order.ID, err)
+	}
+
+	if p.MinOrderSize != nil && (order.Quantity == nil || order.Quantity.Cmp(p.MinOrderSize) < 0) {
+		return fmt.Errorf("order %s: %w: quantity %v is below %s",
+			order.ID, ErrOrderBelowMinSize, order.Quantity, p.MinOrderSize.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package orderbookchecker
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// testMarketParams uses one-cent ticks and a minimum order of 10 shares
+func testMarketParams() *MarketParams {
+	return &MarketParams{
+		TickSize:           cents(1),
+		MinOrderSize:       big.NewInt(10),
+		CollateralDecimals: DefaultCollateralDecimals,
+	}
+}
+
+func TestMarketParams_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*MarketParams)
+		valid  bool
+	}{
+		{"valid", func(p *MarketParams) {}, true},
+		{"no tick or minimum", func(p *MarketParams) { p.TickSize, p.MinOrderSize = nil, nil }, true},
+		{"six decimals", func(p *MarketParams) { p.CollateralDecimals, p.TickSize = 6, big.NewInt(10000) }, true},
+		{"zero decimals", func(p *MarketParams) { p.CollateralDecimals, p.TickSize = 0, nil }, true},
+		{"decimals beyond uint256", func(p *MarketParams) { p.CollateralDecimals = 78 }, false},
+		{"zero tick", func(p *MarketParams) { p.TickSize = big.NewInt(0) }, false},
+		{"tick of a full unit", func(p *MarketParams) { p.TickSize = cents(100) }, false},
+		{"tick not dividing the unit", func(p *MarketParams) { p.TickSize = cents(3) }, false},
+		{"negative minimum", func(p *MarketParams) { p.MinOrderSize = big.NewInt(-1) }, false},
+		{"fee schedule", func(p *MarketParams) { p.MakerFeeBps, p.TakerFeeBps = 0, 200 }, true},
+		{"fee rate above 100%", func(p *MarketParams) { p.TakerFeeBps = 10001 }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := testMarketParams()
+			tt.mutate(params)
+			if err := params.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%v, got error: %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestOrderbookVerifier_PriceDomain(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	tests := []struct {
+		name     string
+		mutate   func(*OrderbookSnapshot, *Trade)
+		wantCode string
+	}{
+		{"within the domain", func(s *OrderbookSnapshot, tr *Trade) {}, ""},
+		{"order price of zero", func(s *OrderbookSnapshot, tr *Trade) { s.Orders[1].Price = big.NewInt(0) }, ErrorCodePriceOutOfRange},
+		{"order price of a full unit", func(s *OrderbookSnapshot, tr *Trade) { s.Orders[0].Price = cents(100) }, ErrorCodePriceOutOfRange},
+		{"order price between ticks", func(s *OrderbookSnapshot, tr *Trade) {
+			s.Orders[0].Price = new(big.Int).Add(cents(60), big.NewInt(1))
+		}, ErrorCodePriceOffTick},
+		{"order below minimum size", func(s *OrderbookSnapshot, tr *Trade) { s.Orders[4].Quantity = big.NewInt(5) }, ErrorCodeOrderBelowMinSize},
+		{"trade price between ticks", func(s *OrderbookSnapshot, tr *Trade) {
+			tr.Price = new(big.Int).Add(cents(38), big.NewInt(5))
+		}, ErrorCodePriceOffTick},
+		{"invalid parameters", func(s *OrderbookSnapshot, tr *Trade) { s.Params.CollateralDecimals = 78 }, ErrorCodeInvalidMarketParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := outcomeSnapshot(time.Now().Truncate(time.Millisecond))
+			snapshot.Params = testMarketParams()
+			trade := Trade{ID: "trade-1", BuyOrderID: "no-buy-42", SellOrderID: "no-sell-38", Price: cents(38), Quantity: big.NewInt(50)}
+			tt.mutate(&snapshot, &trade)
+
+			result, err := verifier.VerifySnapshot([]Trade{trade}, snapshot)
+			if err != nil {
+				t.Fatalf("Expected a verdict rather than an error, got: %v", err)
+			}
+			if result.Valid != (tt.wantCode == "") {
+				t.Errorf("Expected valid=%v, got %v: %s", tt.wantCode == "", result.Valid, result.ErrorMessage)
+			}
+			if result.ErrorCode != tt.wantCode {
+				t.Errorf("Expected error code %q, got %q", tt.wantCode, result.ErrorCode)
+			}
+		})
+	}
+}
+
+func TestOrderbookVerifier_VerifyReplay_RejectsOffTickIncoming(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	snapshot := withoutOrders(outcomeSnapshot(baseTime), "yes-buy-60", "no-buy-42")
+	snapshot.Params = testMarketParams()
+
+	// The exchange would reject an off-tick order, so a trade against it is a phantom
+	incoming := []Order{
+		{
+			ID:        "no-buy-off-tick",
+			Side:      "buy",
+			Price:     new(big.Int).Add(cents(40), big.NewInt(1)),
+			Quantity:  big.NewInt(20),
+			Timestamp: baseTime.Add(time.Second),
+			TokenID:   testNoToken,
+			Outcome:   OutcomeNo,
+		},
+	}
+	trades := []Trade{
+		{ID: "trade-1", BuyOrderID: "no-buy-off-tick", SellOrderID: "no-sell-38", Price: cents(38), Quantity: big.NewInt(20)},
+	}
+
+	result, err := verifier.VerifyReplay(trades, snapshot, incoming)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Valid || len(result.FillDiffs) != 1 || result.FillDiffs[0].Kind != FillDiffPhantom {
+		t.Errorf("Expected a single phantom trade, got %+v", result.FillDiffs)
+	}
+	if result.ErrorCode != ErrorCodeInvalidOrder {
+		t.Errorf("Expected error code %q, got %q", ErrorCodeInvalidOrder, result.ErrorCode)
+	}
+	if len(result.Findings) == 0 || result.Findings[0].Code != ErrorCodeInvalidOrder ||
+		len(result.Findings[0].OrderIDs) != 1 || result.Findings[0].OrderIDs[0] != "no-buy-off-tick" {
+		t.Errorf("Expected the rejected incoming order as the first finding, got %+v", result.Findings)
+	}
+}
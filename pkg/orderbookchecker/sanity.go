@@ -0,0 +1,220 @@
+package orderbookchecker
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// newFinding builds a finding from an error wrapping one of the sentinel errors
+func newFinding(err error, tradeID string, orderIDs ...string) Finding {
+	return Finding{
+		Code:     ErrorCode(err),
+		OrderIDs: orderIDs,
+		TradeID:  tradeID,
+		Message:  err.Error(),
+	}
+}
+
+// checkSnapshotOrders returns a finding for every duplicate order ID, every order without a
+// positive price and quantity and every order timestamped after the snapshot. A non-positive
+// price is out of range in a market with parameters.
+func checkSnapshotOrders(snapshot OrderbookSnapshot) []Finding {
+	var findings []Finding
+
+	seen := make(map[string]bool, len(snapshot.Orders))
+	for _, order := range snapshot.Orders {
+		if seen[order.ID] {
+			findings = append(findings, newFinding(fmt.Errorf("%w: %s", ErrDuplicateOrderID, order.ID), "", order.ID))
+		}
+		seen[order.ID] = true
+
+		if order.Price == nil || order.Price.Sign() <= 0 {
+			err := fmt.Errorf("%w: order %s has non-positive price %v", ErrInvalidOrder, order.ID, order.Price)
+			// A market's parameters bound its prices, so there a present price is out of range
+			if order.Price != nil && snapshot.Params != nil {
+				err = snapshot.Params.CheckOrder(order)
+			}
+			findings = append(findings, newFinding(err, "", order.ID))
+		}
+		if order.Quantity == nil || order.Quantity.Sign() <= 0 {
+			findings = append(findings, newFinding(
+				fmt.Errorf("%w: order %s has non-positive quantity %v", ErrInvalidOrder, order.ID, order.Quantity), "", order.ID))
+		}
+
+		if !snapshot.Timestamp.IsZero() && order.Timestamp.After(snapshot.Timestamp) {
+			findings = append(findings, newFinding(
+				fmt.Errorf("%w: order %s placed at %v, snapshot taken at %v", ErrFutureTimestamp, order.ID, order.Timestamp, snapshot.Timestamp),
+				"", order.ID))
+		}
+	}
+
+	return findings
+}
+
+// wholeMillisecond reports whether t is a whole number of milliseconds. Snapshot hashes,
+// order leaves and binary payloads encode timestamps in milliseconds, so a finer timestamp is
+// not committed to: an operator could re-time or reorder orders within a millisecond without
+// changing any hash.
+func wholeMillisecond(t time.Time) bool {
+	return t.Nanosecond()%int(time.Millisecond) == 0
+}
+
+// checkOrderTimestamps returns an error if an order is placed or expires at a timestamp
+// finer than a millisecond
+func checkOrderTimestamps(order Order) error {
+	if !wholeMillisecond(order.Timestamp) {
+		return fmt.Errorf("%w: order %s placed at %s", ErrTimestampPrecision, order.ID, order.Timestamp.Format(time.RFC3339Nano))
+	}
+	if order.Expiration != nil && !wholeMillisecond(*order.Expiration) {
+		return fmt.Errorf("%w: order %s expires at %s", ErrTimestampPrecision, order.ID, order.Expiration.Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+// checkSnapshotTimestamps returns an error for the first timestamp of a snapshot or of its
+// orders that is finer than a millisecond
+func checkSnapshotTimestamps(snapshot OrderbookSnapshot) error {
+	if !wholeMillisecond(snapshot.Timestamp) {
+		return fmt.Errorf("%w: snapshot taken at %s", ErrTimestampPrecision, snapshot.Timestamp.Format(time.RFC3339Nano))
+	}
+	for _, order := range snapshot.Orders {
+		if err := checkOrderTimestamps(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTimestampPrecision returns a finding for every timestamp finer than a millisecond in
+// the snapshot and in the trades, incoming orders and cancellations verified against it
+func checkTimestampPrecision(snapshot OrderbookSnapshot, trades []Trade, incoming []Order, cancellations []Cancellation) []Finding {
+	var findings []Finding
+	if !wholeMillisecond(snapshot.Timestamp) {
+		findings = append(findings, newFinding(
+			fmt.Errorf("%w: snapshot taken at %s", ErrTimestampPrecision, snapshot.Timestamp.Format(time.RFC3339Nano)), ""))
+	}
+	for _, orders := range [][]Order{snapshot.Orders, incoming} {
+		for _, order := range orders {
+			if err := checkOrderTimestamps(order); err != nil {
+				findings = append(findings, newFinding(err, "", order.ID))
+			}
+		}
+	}
+	for _, trade := range trades {
+		if !wholeMillisecond(trade.Timestamp) {
+			findings = append(findings, newFinding(
+				fmt.Errorf("%w: trade %s executed at %s", ErrTimestampPrecision, trade.ID, trade.Timestamp.Format(time.RFC3339Nano)),
+				trade.ID, trade.BuyOrderID, trade.SellOrderID))
+		}
+	}
+	for _, c := range cancellations {
+		if !wholeMillisecond(c.Timestamp) {
+			findings = append(findings, newFinding(
+				fmt.Errorf("%w: order %s cancelled at %s", ErrTimestampPrecision, c.OrderID, c.Timestamp.Format(time.RFC3339Nano)),
+				"", c.OrderID))
+		}
+	}
+	return findings
+}
+
+// crossedBook returns a finding for each token whose best resting bid is at or above its
+// best resting ask, and for each complementary pair of tokens whose best bids could mint or
+// whose best asks could merge. An honest engine would have matched any of these.
+func crossedBook(state *OrderbookState, at time.Time) []Finding {
+	resting := func(order *Order) bool {
+		return state.Remaining[order.ID].Sign() > 0 && !isImmediate(order) &&
+			checkOrderLive(order, state.CancelledAt, at) == nil
+	}
+	bids, asks := bestByToken(state.BuyOrders, resting), bestByToken(state.SellOrders, resting)
+
+	var findings []Finding
+	for _, token := range sortedTokens(bids) {
+		bid := bids[token]
+		if ask, ok := asks[token]; ok && bid.Price.Cmp(ask.Price) >= 0 {
+			findings = append(findings, newFinding(fmt.Errorf("%w: bid %s at %s meets ask %s at %s",
+				ErrCrossedBook, bid.ID, bid.Price.String(), ask.ID, ask.Price.String()), "", bid.ID, ask.ID))
+		}
+	}
+
+	findings = append(findings, crossedComplements(bids, state.PriceUnit, "bids", func(sum *big.Int) bool {
+		return sum.Cmp(state.PriceUnit) >= 0
+	})...)
+	findings = append(findings, crossedComplements(asks, state.PriceUnit, "asks", func(sum *big.Int) bool {
+		return sum.Cmp(state.PriceUnit) <= 0
+	})...)
+
+	return findings
+}
+
+// crossedComplements returns a finding for each pair of complementary best orders whose
+// prices sum to a crossing value
+func crossedComplements(best map[string]*Order, unit *big.Int, side string, crosses func(sum *big.Int) bool) []Finding {
+	var findings []Finding
+
+	tokens := sortedTokens(best)
+	for i, token := range tokens {
+		for _, other := range tokens[i+1:] {
+			a, b := best[token], best[other]
+			if !complementary(a, b) {
+				continue
+			}
+			if sum := new(big.Int).Add(a.Price, b.Price); crosses(sum) {
+				findings = append(findings, newFinding(fmt.Errorf("%w: complementary %s %s and %s sum to %s against %s",
+					ErrCrossedBook, side, a.ID, b.ID, sum.String(), unit.String()), "", a.ID, b.ID))
+			}
+		}
+	}
+
+	return findings
+}
+
+// bestByToken returns the first resting order of each token in a book sorted best first
+func bestByToken(book []Order, resting func(*Order) bool) map[string]*Order {
+	best := make(map[string]*Order)
+	for i := range book {
+		order := &book[i]
+		if _, ok := best[order.TokenID]; !ok && resting(order) {
+			best[order.TokenID] = order
+		}
+	}
+	return best
+}
+
+// sortedTokens returns the token IDs of a map in ascending order
+func sortedTokens(orders map[string]*Order) []string {
+	tokens := make([]string, 0, len(orders))
+	for token := range orders {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// isSelfTrade reports whether two orders belong to the same known user
+func isSelfTrade(a, b *Order) bool {
+	return a.UserID != "" && a.UserID == b.UserID
+}
+
+// validateSelfTradePrevention checks that a mode is one of the SelfTrade* values
+func validateSelfTradePrevention(mode string) error {
+	switch mode {
+	case SelfTradeAllow, SelfTradeCancelResting, SelfTradeCancelIncoming:
+		return nil
+	default:
+		return fmt.Errorf("invalid self-trade prevention mode: %s", mode)
+	}
+}
+
+// batchEnd returns the time the residual book of a batch is observed at: the latest trade
+// timestamp, or the snapshot timestamp when no trade is later
+func batchEnd(snapshot OrderbookSnapshot, trades []Trade) time.Time {
+	end := snapshot.Timestamp
+	for _, trade := range trades {
+		if trade.Timestamp.After(end) {
+			end = trade.Timestamp
+		}
+	}
+	return end
+}
@@ -0,0 +1,287 @@
+package orderbookchecker
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestOrderbookVerifier_SnapshotSanity(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	tests := []struct {
+		name      string
+		mutate    func(*OrderbookSnapshot)
+		wantCodes []string
+	}{
+		{"sane snapshot", func(s *OrderbookSnapshot) {}, nil},
+		{"duplicate order ID", func(s *OrderbookSnapshot) { s.Orders[1].ID = s.Orders[0].ID }, []string{ErrorCodeDuplicateOrderID}},
+		{"zero quantity", func(s *OrderbookSnapshot) { s.Orders[0].Quantity = big.NewInt(0) }, []string{ErrorCodeInvalidOrder}},
+		{"missing price and negative quantity", func(s *OrderbookSnapshot) {
+			s.Orders[2].Price, s.Orders[2].Quantity = nil, big.NewInt(-5)
+		}, []string{ErrorCodeInvalidOrder, ErrorCodeInvalidOrder}},
+		{"order after the snapshot", func(s *OrderbookSnapshot) { s.Orders[3].Timestamp = s.Timestamp.Add(time.Second) }, []string{ErrorCodeFutureTimestamp}},
+		{"snapshot without a timestamp", func(s *OrderbookSnapshot) { s.Timestamp = time.Time{} }, nil},
+		// A YES ask at 55 cents meets the YES bid at 60 and merges with the NO ask at 38
+		{"crossed book left unmatched", func(s *OrderbookSnapshot) { s.Orders[1].Price = cents(55) },
+			[]string{ErrorCodeCrossedBook, ErrorCodeCrossedBook}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without the 42 cent NO bid the book does not cross, so no trades are owed
+			snapshot := withoutOrders(outcomeSnapshot(time.Now().Truncate(time.Millisecond)), "no-buy-42")
+			tt.mutate(&snapshot)
+
+			result, err := verifier.VerifySnapshot(nil, snapshot)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result.Valid != (len(tt.wantCodes) == 0) {
+				t.Errorf("Expected valid=%v, got %v: %s", len(tt.wantCodes) == 0, result.Valid, result.ErrorMessage)
+			}
+			if len(result.Findings) != len(tt.wantCodes) {
+				t.Fatalf("Expected findings %v, got %+v", tt.wantCodes, result.Findings)
+			}
+			for i, finding := range result.Findings {
+				if finding.Code != tt.wantCodes[i] {
+					t.Errorf("Finding %d: expected code %s, got %s", i, tt.wantCodes[i], finding.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderbookVerifier_VerifyReplay_CrossedBook(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+	baseTime := time.Now().Truncate(time.Millisecond)
+
+	tests := []struct {
+		name          string
+		remove        []string
+		mutate        func(*OrderbookSnapshot)
+		cancellations []Cancellation
+		wantOrders    [][]string
+	}{
+		{"resting book", []string{"yes-buy-60", "no-buy-42"}, nil, nil, nil},
+		// The NO bid at 0.42 crosses the NO ask at 0.38 and could mint against the YES bid at 0.60
+		{"crossed within and across tokens", nil, nil, nil, [][]string{
+			{"no-buy-42", "no-sell-38"},
+			{"no-buy-42", "yes-buy-60"},
+		}},
+		{"crossing order cancelled before the snapshot", nil, nil, []Cancellation{
+			{OrderID: "no-buy-42", Timestamp: baseTime.Add(-time.Second)},
+		}, nil},
+		// Asks at 0.55 and 0.45 merge back into a full unit of collateral
+		{"complementary asks summing to one", []string{"yes-buy-60", "no-buy-42", "no-sell-38"}, func(s *OrderbookSnapshot) {
+			s.Orders[0].Price = cents(55)
+		}, nil, [][]string{{"no-sell-45", "yes-sell-65"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := withoutOrders(outcomeSnapshot(baseTime), tt.remove...)
+			if tt.mutate != nil {
+				tt.mutate(&snapshot)
+			}
+
+			result, err := verifier.VerifyReplayWithCancellations(nil, snapshot, nil, tt.cancellations)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result.Valid != (len(tt.wantOrders) == 0) {
+				t.Errorf("Expected valid=%v, got %v: %s", len(tt.wantOrders) == 0, result.Valid, result.ErrorMessage)
+			}
+			if len(result.Findings) != len(tt.wantOrders) {
+				t.Fatalf("Expected %d findings, got %+v", len(tt.wantOrders), result.Findings)
+			}
+			for i, finding := range result.Findings {
+				if finding.Code != ErrorCodeCrossedBook {
+					t.Errorf("Finding %d: expected code %s, got %s", i, ErrorCodeCrossedBook, finding.Code)
+				}
+				if !slices.Equal(finding.OrderIDs, tt.wantOrders[i]) {
+					t.Errorf("Finding %d: expected orders %v, got %v", i, tt.wantOrders[i], finding.OrderIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderbookVerifier_SelfTradePrevention(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	snapshot := outcomeSnapshot(time.Now().Truncate(time.Millisecond))
+	snapshot.Orders[4].UserID = snapshot.Orders[2].UserID // no-sell-38 placed by the owner of no-buy-42
+	trades := []Trade{{ID: "trade-1", BuyOrderID: "no-buy-42", SellOrderID: "no-sell-38", Price: cents(38), Quantity: big.NewInt(50)}}
+
+	tests := []struct {
+		mode      string
+		wantValid bool
+		wantErr   bool
+	}{
+		{SelfTradeAllow, true, false},
+		{SelfTradeCancelResting, false, false},
+		{SelfTradeCancelIncoming, false, false},
+		{"decrement", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			verifier := NewOrderbookVerifier(logger, WithSelfTradePrevention(tt.mode))
+			result, err := verifier.VerifySnapshot(trades, snapshot)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if result.Valid != tt.wantValid {
+				t.Errorf("Expected valid=%v, got %v: %s", tt.wantValid, result.Valid, result.ErrorMessage)
+			}
+			if !tt.wantValid {
+				if len(result.Findings) != 1 || result.Findings[0].Code != ErrorCodeSelfTrade || result.Findings[0].TradeID != "trade-1" {
+					t.Errorf("Expected a self-trade finding for trade-1, got %+v", result.Findings)
+				} else if !slices.Equal(result.Findings[0].OrderIDs, []string{"no-buy-42", "no-sell-38"}) {
+					t.Errorf("Expected the finding to name both orders, got %v", result.Findings[0].OrderIDs)
+				}
+				taskResult := NewTaskResult(result, verifier.PolicyHash(), "", snapshot, "batch-1", trades)
+				if taskResult.ErrorCode != ErrorCodeSelfTrade || !taskResult.TradeFailed(0) {
+					t.Errorf("Expected the task result to fail trade-1 with %s, got %+v", ErrorCodeSelfTrade, taskResult)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchingEngine_SelfTradePrevention(t *testing.T) {
+	baseTime := time.Now().Truncate(time.Millisecond)
+	snapshot := withoutOrders(outcomeSnapshot(baseTime), "yes-buy-60", "no-buy-42")
+	snapshot.Orders[2].UserID = "user-self" // no-sell-38
+
+	// The incoming NO buy belongs to the owner of the best NO ask
+	incoming := Order{ID: "no-buy-in", Side: "buy", Price: cents(50), Quantity: big.NewInt(150), Timestamp: baseTime.Add(time.Second),
+		UserID: "user-self", TokenID: testNoToken, Outcome: OutcomeNo}
+
+	tests := []struct {
+		mode        string
+		wantFills   []string // Sell order IDs matched, in order
+		wantResting bool
+		wantSelfAsk int64 // Remaining quantity of no-sell-38
+	}{
+		{SelfTradeAllow, []string{"no-sell-38", "no-sell-45"}, false, 0},
+		{SelfTradeCancelResting, []string{"no-sell-45"}, true, 0},
+		{SelfTradeCancelIncoming, nil, false, 50},
+	}
+
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			state, err := verifier.buildOrderbookState(snapshot.Orders, nil)
+			if err != nil {
+				t.Fatalf("buildOrderbookState failed: %v", err)
+			}
+			engine := NewMatchingEngine(state)
+			engine.selfTrade = tt.mode
+
+			fills, err := engine.Submit(incoming)
+			if err != nil {
+				t.Fatalf("Submit failed: %v", err)
+			}
+
+			var matched []string
+			for _, fill := range fills {
+				matched = append(matched, fill.SellOrderID)
+			}
+			if !slices.Equal(matched, tt.wantFills) {
+				t.Errorf("Expected fills against %v, got %v", tt.wantFills, matched)
+			}
+
+			resting := slices.ContainsFunc(engine.bids, func(o Order) bool { return o.ID == incoming.ID })
+			if resting != tt.wantResting {
+				t.Errorf("Expected resting=%v, got %v", tt.wantResting, resting)
+			}
+			if got := engine.Remaining()["no-sell-38"]; got.Cmp(big.NewInt(tt.wantSelfAsk)) != 0 {
+				t.Errorf("Expected no-sell-38 to have %d remaining, got %s", tt.wantSelfAsk, got.String())
+			}
+		})
+	}
+}
+
+func TestTimestampPrecision(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+	baseTime := time.Now().Truncate(time.Millisecond)
+
+	// Two bids at the same price placed within one millisecond: the commitments cannot tell
+	// which came first, so swapping their times would not change any hash
+	snapshot := OrderbookSnapshot{
+		SequenceNumber: 1,
+		Timestamp:      baseTime,
+		MarketID:       "BTC-USD",
+		Orders: []Order{
+			{ID: "buy-a", Side: "buy", Price: big.NewInt(50000), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-time.Minute + 300*time.Microsecond)},
+			{ID: "buy-b", Side: "buy", Price: big.NewInt(50000), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-time.Minute + 600*time.Microsecond)},
+			{ID: "sell-1", Side: "sell", Price: big.NewInt(50000), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-30 * time.Second)},
+		},
+	}
+	swapped := snapshot
+	swapped.Orders = slices.Clone(snapshot.Orders)
+	swapped.Orders[0].Timestamp, swapped.Orders[1].Timestamp = snapshot.Orders[1].Timestamp, snapshot.Orders[0].Timestamp
+
+	hash, err := HashSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("HashSnapshot failed: %v", err)
+	}
+	if swappedHash, _ := HashSnapshot(swapped); swappedHash != hash {
+		t.Fatalf("Expected orders re-timed within a millisecond to hash alike")
+	}
+	for name, s := range map[string]OrderbookSnapshot{"honest": snapshot, "swapped": swapped} {
+		s.MerkleRoot, _ = ComputeMerkleRoot(s.Orders)
+		if err := VerifySnapshotIntegrity(s, hash); !errors.Is(err, ErrTimestampPrecision) {
+			t.Errorf("%s: expected ErrTimestampPrecision from the integrity check, got: %v", name, err)
+		}
+	}
+
+	trades := []Trade{{ID: "trade-1", BuyOrderID: "buy-a", SellOrderID: "sell-1", Price: big.NewInt(50000), Quantity: big.NewInt(100)}}
+	result, err := verifier.VerifySnapshot(trades, snapshot)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Valid || result.ErrorCode != ErrorCodeTimestampPrecision || len(result.Findings) != 2 {
+		t.Fatalf("Expected a %s finding per order, got valid=%v code=%q: %+v", ErrorCodeTimestampPrecision, result.Valid, result.ErrorCode, result.Findings)
+	}
+	for i, id := range []string{"buy-a", "buy-b"} {
+		if !slices.Equal(result.Findings[i].OrderIDs, []string{id}) {
+			t.Errorf("Finding %d: expected order %s, got %v", i, id, result.Findings[i].OrderIDs)
+		}
+	}
+
+	// The inputs verified against a snapshot are held to the same precision
+	precise := withoutOrders(snapshot, "buy-b")
+	precise.Orders[0].Timestamp = precise.Orders[0].Timestamp.Truncate(time.Millisecond)
+	subMillisecond := baseTime.Add(-time.Second + time.Microsecond)
+	result, err = verifier.VerifyReplayWithCancellations(
+		[]Trade{{ID: "trade-1", BuyOrderID: "buy-c", SellOrderID: "sell-1", Price: big.NewInt(50000), Quantity: big.NewInt(100), Timestamp: subMillisecond}},
+		precise,
+		[]Order{{ID: "buy-c", Side: "buy", Price: big.NewInt(50000), Quantity: big.NewInt(100), Timestamp: subMillisecond}},
+		[]Cancellation{{OrderID: "buy-a", Timestamp: subMillisecond}},
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var codes []string
+	for _, finding := range result.Findings {
+		codes = append(codes, finding.Code)
+	}
+	if result.Valid || !slices.Equal(codes, []string{ErrorCodeTimestampPrecision, ErrorCodeTimestampPrecision, ErrorCodeTimestampPrecision}) {
+		t.Errorf("Expected the incoming order, trade and cancellation to be rejected, got valid=%v: %+v", result.Valid, result.Findings)
+	}
+}
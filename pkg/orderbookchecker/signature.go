@@ -0,0 +1,272 @@
+package orderbookchecker
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// EIP-712 type strings
+const (
+	eip712DomainType = "EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"
+	orderType        = "Order(uint256 salt,address maker,address signer,address taker,uint256 tokenId," +
+		"uint256 makerAmount,uint256 takerAmount,uint256 expiration,uint256 nonce,uint256 feeRateBps," +
+		"uint8 side,uint8 signatureType)"
+)
+
+// signatureTypeEOA is the CTF Exchange signature type of orders signed by the maker's own key
+const signatureTypeEOA = 0
+
+// signatureLength is the length of an r || s || v signature
+const signatureLength = 65
+
+// EIP712Domain is the EIP-712 domain orders are signed against
+type EIP712Domain struct {
+	Name              string
+	Version           string
+	ChainID           uint64
+	VerifyingContract string // 0x-prefixed hex address
+}
+
+// CTFExchangeDomain is the domain of the Polymarket CTF Exchange on Polygon
+var CTFExchangeDomain = EIP712Domain{
+	Name:              "Polymarket CTF Exchange",
+	Version:           "1",
+	ChainID:           137,
+	VerifyingContract: "0x4bFb41d5B3570DeFd03C39a9A4D8dE6Bd8B8982E",
+}
+
+// Separator returns the EIP-712 domain separator
+func (d EIP712Domain) Separator() (merkle.Hash, error) {
+	contract, err := parseAddress(d.VerifyingContract)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("verifying contract: %v", err)
+	}
+	return merkle.Keccak256(abi.Encode(
+		abi.Bytes32(merkle.Keccak256([]byte(eip712DomainType))),
+		abi.Bytes32(merkle.Keccak256([]byte(d.Name))),
+		abi.Bytes32(merkle.Keccak256([]byte(d.Version))),
+		abi.Uint64(d.ChainID),
+		abi.Address(contract),
+	)), nil
+}
+
+// parseAddress decodes a 20-byte address from hex, with or without a 0x prefix
+func parseAddress(s string) ([20]byte, error) {
+	var a [20]byte
+	raw, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return a, fmt.Errorf("invalid address %q: %v", s, err)
+	}
+	if len(raw) != len(a) {
+		return a, fmt.Errorf("invalid address %q: expected %d bytes, got %d", s, len(a), len(raw))
+	}
+	copy(a[:], raw)
+	return a, nil
+}
+
+// orderAmounts returns the maker and taker amounts of the exchange order behind an order: a
+// buy gives its notional, price * quantity / unit collateral, for quantity tokens, a sell
+// the reverse. The exchange order cannot express a notional that is not a whole number of
+// collateral base units, so such an order is invalid.
+func orderAmounts(order Order, unit *big.Int) (*big.Int, *big.Int, error) {
+	if order.Price == nil || order.Quantity == nil {
+		return nil, nil, fmt.Errorf("%w: order %s has no price or quantity", ErrInvalidOrder, order.ID)
+	}
+	collateral := notional(order.Quantity, order.Price, unit)
+	if new(big.Int).Mul(collateral, unit).Cmp(new(big.Int).Mul(order.Price, order.Quantity)) != 0 {
+		return nil, nil, fmt.Errorf("%w: order %s: %s tokens at %s is not a whole number of collateral base units",
+			ErrInvalidOrder, order.ID, order.Quantity.String(), order.Price.String())
+	}
+
+	switch order.Side {
+	case "buy":
+		return collateral, order.Quantity, nil
+	case "sell":
+		return order.Quantity, collateral, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: order %s has invalid side %q", ErrInvalidOrder, order.ID, order.Side)
+	}
+}
+
+// OrderStructHash returns the EIP-712 hash of the CTF Exchange order an order was signed as.
+// The maker signs for itself, the order is open to any taker, and token IDs are decimal
+// uint256 values, an empty token ID meaning zero. unit is the price of one full unit of
+// collateral.
+func OrderStructHash(order Order, unit *big.Int) (merkle.Hash, error) {
+	maker, err := parseAddress(order.Maker)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("order %s maker: %v", order.ID, err)
+	}
+
+	tokenID := new(big.Int)
+	if order.TokenID != "" {
+		if _, ok := tokenID.SetString(order.TokenID, 10); !ok || tokenID.Sign() < 0 || tokenID.BitLen() > 256 {
+			return merkle.Hash{}, fmt.Errorf("order %s: token ID %q is not a uint256", order.ID, order.TokenID)
+		}
+	}
+	makerAmount, takerAmount, err := orderAmounts(order, unit)
+	if err != nil {
+		return merkle.Hash{}, err
+	}
+	var expiration uint64
+	if order.Expiration != nil {
+		// The exchange signs expirations in seconds, so a finer expiration could be moved
+		// within its second without invalidating the signature
+		if order.Expiration.Nanosecond() != 0 {
+			return merkle.Hash{}, fmt.Errorf("order %s: expiration %s is not a whole second", order.ID, order.Expiration.Format(time.RFC3339Nano))
+		}
+		expiration = uint64(order.Expiration.Unix())
+	}
+	side, err := encodeSide(order.Side)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("order %s: %v", order.ID, err)
+	}
+
+	var uints []abi.Value
+	for _, v := range []*big.Int{orZero(order.Salt), tokenID, makerAmount, takerAmount, orZero(order.Nonce)} {
+		encoded, err := abi.Uint256(v)
+		if err != nil {
+			return merkle.Hash{}, fmt.Errorf("order %s: %v", order.ID, err)
+		}
+		uints = append(uints, encoded)
+	}
+	salt, token, makerAmt, takerAmt, nonce := uints[0], uints[1], uints[2], uints[3], uints[4]
+
+	return merkle.Keccak256(abi.Encode(
+		abi.Bytes32(merkle.Keccak256([]byte(orderType))),
+		salt,
+		abi.Address(maker),
+		abi.Address(maker),      // signer
+		abi.Address([20]byte{}), // taker
+		token,
+		makerAmt,
+		takerAmt,
+		abi.Uint64(expiration),
+		nonce,
+		abi.Uint64(uint64(order.FeeRateBps)),
+		abi.Uint64(uint64(side)),
+		abi.Uint64(signatureTypeEOA),
+	)), nil
+}
+
+// OrderDigest returns the EIP-712 digest the maker of an order signs
+func OrderDigest(order Order, domain EIP712Domain, unit *big.Int) (merkle.Hash, error) {
+	separator, err := domain.Separator()
+	if err != nil {
+		return merkle.Hash{}, err
+	}
+	structHash, err := OrderStructHash(order, unit)
+	if err != nil {
+		return merkle.Hash{}, err
+	}
+	return merkle.Keccak256([]byte{0x19, 0x01}, separator[:], structHash[:]), nil
+}
+
+// SignOrder sets the maker of an order to the address of key and signs the order
+func SignOrder(order *Order, key *secp256k1.PrivateKey, domain EIP712Domain, unit *big.Int) error {
+	maker := publicKeyAddress(key.PubKey())
+	order.Maker = "0x" + hex.EncodeToString(maker[:])
+
+	digest, err := OrderDigest(*order, domain, unit)
+	if err != nil {
+		return err
+	}
+
+	// SignCompact returns v || r || s with v = 27 + recovery code; Ethereum orders it last
+	compact := ecdsa.SignCompact(key, digest[:], false)
+	signature := append(compact[1:], compact[0])
+	order.Signature = "0x" + hex.EncodeToString(signature)
+	return nil
+}
+
+// publicKeyAddress returns the Ethereum address of a public key: the last 20 bytes of the
+// keccak256 hash of its uncompressed coordinates
+func publicKeyAddress(key *secp256k1.PublicKey) [20]byte {
+	var address [20]byte
+	hash := merkle.Keccak256(key.SerializeUncompressed()[1:])
+	copy(address[:], hash[12:])
+	return address
+}
+
+// recoverAddress returns the address whose key produced an r || s || v signature of digest.
+// v may be 27 or 28, or 0 or 1; signatures with a high s value are rejected as malleable,
+// as the exchange does.
+func recoverAddress(digest merkle.Hash, signature []byte) ([20]byte, error) {
+	if len(signature) != signatureLength {
+		return [20]byte{}, fmt.Errorf("signature is %d bytes, expected %d", len(signature), signatureLength)
+	}
+
+	var s secp256k1.ModNScalar
+	if overflow := s.SetByteSlice(signature[32:64]); overflow || s.IsOverHalfOrder() {
+		return [20]byte{}, fmt.Errorf("signature s value is not in the lower half order")
+	}
+
+	v := signature[64]
+	if v < 27 {
+		v += 27
+	}
+	if v != 27 && v != 28 {
+		return [20]byte{}, fmt.Errorf("invalid signature recovery id %d", signature[64])
+	}
+
+	compact := append([]byte{v}, signature[:64]...)
+	key, _, err := ecdsa.RecoverCompact(compact, digest[:])
+	if err != nil {
+		return [20]byte{}, err
+	}
+	return publicKeyAddress(key), nil
+}
+
+// verifyOrderSignature checks that an order carries a signature by its maker over the order
+// as it appears in the snapshot
+func verifyOrderSignature(order Order, domain EIP712Domain, unit *big.Int) error {
+	if order.Signature == "" || order.Maker == "" {
+		return fmt.Errorf("%w: order %s has no maker signature", ErrUnsignedOrder, order.ID)
+	}
+
+	maker, err := parseAddress(order.Maker)
+	if err != nil {
+		return fmt.Errorf("%w: order %s: %v", ErrInvalidSignature, order.ID, err)
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(order.Signature, "0x"))
+	if err != nil {
+		return fmt.Errorf("%w: order %s: invalid signature hex: %v", ErrInvalidSignature, order.ID, err)
+	}
+	// An order the exchange cannot express is invalid whoever signed it
+	if _, _, err := orderAmounts(order, unit); err != nil {
+		return err
+	}
+	digest, err := OrderDigest(order, domain, unit)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	signer, err := recoverAddress(digest, signature)
+	if err != nil {
+		return fmt.Errorf("%w: order %s: %v", ErrInvalidSignature, order.ID, err)
+	}
+	if signer != maker {
+		return fmt.Errorf("%w: order %s is signed by 0x%x, not its maker %s",
+			ErrInvalidSignature, order.ID, signer, order.Maker)
+	}
+	return nil
+}
+
+// checkOrderSignatures returns a finding for every order not signed by its maker
+func checkOrderSignatures(orders []Order, domain EIP712Domain, unit *big.Int) []Finding {
+	var findings []Finding
+	for _, order := range orders {
+		if err := verifyOrderSignature(order, domain, unit); err != nil {
+			findings = append(findings, newFinding(err, "", order.ID))
+		}
+	}
+	return findings
+}
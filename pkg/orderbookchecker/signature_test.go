@@ -0,0 +1,191 @@
+package orderbookchecker
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"go.uber.org/zap"
+)
+
+// testKey returns a deterministic private key derived from a seed
+func testKey(seed string) *secp256k1.PrivateKey {
+	hash := merkle.Keccak256([]byte(seed))
+	return secp256k1.PrivKeyFromBytes(hash[:])
+}
+
+func TestEIP712_SpecVectors(t *testing.T) {
+	// The "Ether Mail" example of the EIP-712 specification
+	domain := EIP712Domain{
+		Name:              "Ether Mail",
+		Version:           "1",
+		ChainID:           1,
+		VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+	}
+	separator, err := domain.Separator()
+	if err != nil {
+		t.Fatalf("Separator failed: %v", err)
+	}
+	if separator.Hex() != "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f" {
+		t.Errorf("Unexpected domain separator %s", separator)
+	}
+
+	cow := publicKeyAddress(testKey("cow").PubKey())
+	if got := "0x" + hex.EncodeToString(cow[:]); got != "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826" {
+		t.Errorf("Unexpected address %s", got)
+	}
+
+	digest, _ := merkle.ParseHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2")
+	signature, _ := hex.DecodeString("4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d" +
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562" + "1c")
+	signer, err := recoverAddress(digest, signature)
+	if err != nil {
+		t.Fatalf("recoverAddress failed: %v", err)
+	}
+	if signer != cow {
+		t.Errorf("Expected signer %x, got %x", cow, signer)
+	}
+}
+
+// signedSnapshot returns a snapshot of two orders signed by their makers and a trade between
+// them
+func signedSnapshot(t *testing.T) (OrderbookSnapshot, []Trade) {
+	t.Helper()
+
+	baseTime := time.Unix(1700000000, 0).UTC()
+	unit := priceUnit(DefaultCollateralDecimals)
+	orders := []Order{
+		{ID: "buy-1", Side: "buy", Price: big.NewInt(6e17), Quantity: big.NewInt(1e18), Timestamp: baseTime.Add(-2 * time.Minute),
+			UserID: "alice", TokenID: "1234", Salt: big.NewInt(1), Nonce: big.NewInt(0)},
+		{ID: "sell-1", Side: "sell", Price: big.NewInt(5e17), Quantity: big.NewInt(1e18), Timestamp: baseTime.Add(-time.Minute),
+			UserID: "bob", TokenID: "1234", Salt: big.NewInt(2), Nonce: big.NewInt(0), FeeRateBps: 10},
+	}
+	for i, seed := range []string{"alice", "bob"} {
+		if err := SignOrder(&orders[i], testKey(seed), CTFExchangeDomain, unit); err != nil {
+			t.Fatalf("SignOrder failed: %v", err)
+		}
+	}
+
+	snapshot := OrderbookSnapshot{SequenceNumber: 1, Timestamp: baseTime, MarketID: "TEST", Orders: orders}
+	trades := []Trade{
+		{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(5e17), Quantity: big.NewInt(1e18), Timestamp: baseTime},
+	}
+	return snapshot, trades
+}
+
+func TestOrderbookVerifier_SignedOrders(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger, WithSignedOrders(CTFExchangeDomain))
+
+	tests := []struct {
+		name     string
+		mutate   func(*OrderbookSnapshot)
+		wantCode string
+	}{
+		{"signed orders", func(s *OrderbookSnapshot) {}, ""},
+		{"unsigned order", func(s *OrderbookSnapshot) { s.Orders[0].Signature = "" }, ErrorCodeUnsignedOrder},
+		{"price changed after signing", func(s *OrderbookSnapshot) { s.Orders[1].Price = big.NewInt(4e17) }, ErrorCodeInvalidSignature},
+		{"nonce changed after signing", func(s *OrderbookSnapshot) { s.Orders[1].Nonce = big.NewInt(1) }, ErrorCodeInvalidSignature},
+		// Half a base unit of collateral cannot be expressed as an exchange order amount
+		{"notional of a fractional base unit", func(s *OrderbookSnapshot) { s.Orders[1].Quantity = big.NewInt(1e18 + 1) }, ErrorCodeInvalidOrder},
+		{"signed by another key", func(s *OrderbookSnapshot) {
+			maker := s.Orders[0].Maker
+			if err := SignOrder(&s.Orders[0], testKey("mallory"), CTFExchangeDomain, priceUnit(DefaultCollateralDecimals)); err != nil {
+				t.Fatalf("SignOrder failed: %v", err)
+			}
+			s.Orders[0].Maker = maker
+		}, ErrorCodeInvalidSignature},
+		{"malleated signature", func(s *OrderbookSnapshot) {
+			// (r, n - s, v ^ 1) recovers the same key but is rejected by the exchange
+			sig, _ := hex.DecodeString(strings.TrimPrefix(s.Orders[0].Signature, "0x"))
+			n := secp256k1.S256().N
+			high := new(big.Int).Sub(n, new(big.Int).SetBytes(sig[32:64]))
+			high.FillBytes(sig[32:64])
+			sig[64] ^= 1
+			s.Orders[0].Signature = "0x" + hex.EncodeToString(sig)
+		}, ErrorCodeInvalidSignature},
+		{"expiration moved within its second after signing", func(s *OrderbookSnapshot) {
+			expiration := s.Timestamp.Truncate(time.Second).Add(time.Hour)
+			s.Orders[0].TimeInForce, s.Orders[0].Expiration = TimeInForceGTD, &expiration
+			if err := SignOrder(&s.Orders[0], testKey("alice"), CTFExchangeDomain, priceUnit(DefaultCollateralDecimals)); err != nil {
+				t.Fatalf("SignOrder failed: %v", err)
+			}
+			later := expiration.Add(900 * time.Millisecond)
+			s.Orders[0].Expiration = &later
+		}, ErrorCodeInvalidSignature},
+		{"signed for another exchange", func(s *OrderbookSnapshot) {
+			domain := CTFExchangeDomain
+			domain.ChainID = 80002
+			if err := SignOrder(&s.Orders[1], testKey("bob"), domain, priceUnit(DefaultCollateralDecimals)); err != nil {
+				t.Fatalf("SignOrder failed: %v", err)
+			}
+		}, ErrorCodeInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot, trades := signedSnapshot(t)
+			tt.mutate(&snapshot)
+
+			result, err := verifier.VerifySnapshot(trades, snapshot)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result.Valid != (tt.wantCode == "") || result.ErrorCode != tt.wantCode {
+				t.Fatalf("Expected error code %q, got valid=%v %q: %s", tt.wantCode, result.Valid, result.ErrorCode, result.ErrorMessage)
+			}
+			if tt.wantCode != "" && result.VerifiedTrades != 0 {
+				t.Errorf("Expected no trades verified against a rejected snapshot, got %d", result.VerifiedTrades)
+			}
+		})
+	}
+
+	fractional, _ := signedSnapshot(t)
+	fractional.Orders[0].Quantity = big.NewInt(1e18 + 1)
+	if err := SignOrder(&fractional.Orders[0], testKey("alice"), CTFExchangeDomain, priceUnit(DefaultCollateralDecimals)); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("Expected SignOrder to refuse a fractional notional, got: %v", err)
+	}
+
+	subSecond, _ := signedSnapshot(t)
+	expiration := subSecond.Timestamp.Truncate(time.Second).Add(time.Hour + 500*time.Millisecond)
+	subSecond.Orders[0].TimeInForce, subSecond.Orders[0].Expiration = TimeInForceGTD, &expiration
+	if err := SignOrder(&subSecond.Orders[0], testKey("alice"), CTFExchangeDomain, priceUnit(DefaultCollateralDecimals)); err == nil {
+		t.Error("Expected SignOrder to refuse an expiration that is not a whole second")
+	}
+
+	// Without WithSignedOrders signatures are not checked
+	snapshot, trades := signedSnapshot(t)
+	snapshot.Orders[0].Signature = ""
+	if result, err := NewOrderbookVerifier(logger).VerifySnapshot(trades, snapshot); err != nil || !result.Valid {
+		t.Errorf("Expected unsigned orders to be accepted by default, got %+v (%v)", result, err)
+	}
+}
+
+func TestOrderbookVerifier_VerifyReplay_ForgedIncomingOrder(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger, WithSignedOrders(CTFExchangeDomain))
+
+	snapshot, trades := signedSnapshot(t)
+	incoming := snapshot.Orders[1]
+	snapshot.Orders = snapshot.Orders[:1]
+	trades[0].Price = snapshot.Orders[0].Price // The resting buy sets the price
+
+	result, err := verifier.VerifyReplay(trades, snapshot, []Order{incoming})
+	if err != nil || !result.Valid {
+		t.Fatalf("Expected signed incoming order to match, got %+v (%v)", result, err)
+	}
+
+	incoming.Quantity = big.NewInt(2e18)
+	result, err = verifier.VerifyReplay(trades, snapshot, []Order{incoming})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Valid || result.ErrorCode != ErrorCodeInvalidOrder || len(result.FillDiffs) != 1 {
+		t.Errorf("Expected the forged order to be rejected and its trade to be a phantom fill, got %+v", result)
+	}
+}
@@ -0,0 +1,147 @@
+package orderbookchecker
+
+import (
+	"fmt"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// TaskResultVersion is the version of the ABI layout of a task result
+const TaskResultVersion = 2
+
+// TaskResult is the verdict an operator signs for a verification task. It holds only values
+// derived from the task input, so every honest operator produces the same encoding and the
+// aggregator can aggregate their signatures over it.
+type TaskResult struct {
+	Version      uint8       `json:"version"`
+	SnapshotHash merkle.Hash `json:"snapshot_hash"` // Zero when the task's snapshot hash is malformed
+	MerkleRoot   merkle.Hash `json:"merkle_root"`   // Order root declared by the snapshot, zero when malformed
+	PolicyHash   merkle.Hash `json:"policy_hash"`   // OrderbookVerifier.PolicyHash of the verifier that reached the verdict
+	BatchID      string      `json:"batch_id"`
+	Valid        bool        `json:"valid"`
+	ErrorCode    string      `json:"error_code,omitempty"`
+	TotalTrades  uint64      `json:"total_trades"`
+	// FailedTradeBitmap has bit i % 8 of byte i / 8 set when the i-th trade of the task failed
+	FailedTradeBitmap []byte `json:"failed_trade_bitmap"`
+	// FailedTradesHash is keccak256(abi.encode(string[])) of the failed trade IDs in the
+	// order the verifier reported them
+	FailedTradesHash merkle.Hash `json:"failed_trades_hash"`
+}
+
+// NewTaskResult builds the task result of a verification of trades against the snapshot
+// committed to by snapshotHash, reached under the policy committed to by policyHash
+func NewTaskResult(result *VerificationResult, policyHash merkle.Hash, snapshotHash string, snapshot OrderbookSnapshot, batchID string, trades []Trade) *TaskResult {
+	failed := make(map[string]bool, len(result.FailedTrades))
+	ids := make([]abi.Value, len(result.FailedTrades))
+	for i, id := range result.FailedTrades {
+		failed[id] = true
+		ids[i] = abi.String(id)
+	}
+
+	bitmap := make([]byte, (len(trades)+7)/8)
+	for i, trade := range trades {
+		if failed[trade.ID] {
+			bitmap[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	// Malformed commitments are already a verdict of the result, so they encode as zero
+	hash, _ := merkle.ParseHash(snapshotHash)
+	root, _ := merkle.ParseHash(snapshot.MerkleRoot)
+
+	return &TaskResult{
+		Version:           TaskResultVersion,
+		SnapshotHash:      hash,
+		MerkleRoot:        root,
+		PolicyHash:        policyHash,
+		BatchID:           batchID,
+		Valid:             result.Valid,
+		ErrorCode:         result.ErrorCode,
+		TotalTrades:       uint64(len(trades)),
+		FailedTradeBitmap: bitmap,
+		FailedTradesHash:  merkle.Keccak256(abi.Encode(abi.Array(ids...))),
+	}
+}
+
+// TradeFailed reports whether the i-th trade of the task failed
+func (r *TaskResult) TradeFailed(i int) bool {
+	if i < 0 || i/8 >= len(r.FailedTradeBitmap) {
+		return false
+	}
+	return r.FailedTradeBitmap[i/8]&(1<<(i%8)) != 0
+}
+
+// EncodeABI returns the result as abi.encode(TaskResult) with
+//
+//	struct TaskResult {
+//	    uint8 version; bytes32 snapshotHash; bytes32 merkleRoot; bytes32 policyHash;
+//	    string batchId; bool valid; string errorCode; uint256 totalTrades;
+//	    bytes failedTradeBitmap; bytes32 failedTradesHash;
+//	}
+func (r *TaskResult) EncodeABI() []byte {
+	return abi.Encode(abi.Tuple(
+		abi.Uint64(uint64(r.Version)),
+		abi.Bytes32(r.SnapshotHash),
+		abi.Bytes32(r.MerkleRoot),
+		abi.Bytes32(r.PolicyHash),
+		abi.String(r.BatchID),
+		abi.Bool(r.Valid),
+		abi.String(r.ErrorCode),
+		abi.Uint64(r.TotalTrades),
+		abi.Bytes(r.FailedTradeBitmap),
+		abi.Bytes32(r.FailedTradesHash),
+	))
+}
+
+// DecodeTaskResult decodes a task result encoded by EncodeABI
+func DecodeTaskResult(data []byte) (*TaskResult, error) {
+	d, err := abi.NewDecoder(data).Tuple(0)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := d.Uint64(0)
+	if err != nil {
+		return nil, err
+	}
+	if version != TaskResultVersion {
+		return nil, fmt.Errorf("unsupported task result version %d", version)
+	}
+
+	r := &TaskResult{Version: uint8(version)}
+	snapshotHash, err := d.Bytes32(1)
+	if err != nil {
+		return nil, err
+	}
+	merkleRoot, err := d.Bytes32(2)
+	if err != nil {
+		return nil, err
+	}
+	policyHash, err := d.Bytes32(3)
+	if err != nil {
+		return nil, err
+	}
+	r.SnapshotHash, r.MerkleRoot, r.PolicyHash = snapshotHash, merkleRoot, policyHash
+	if r.BatchID, err = d.String(4); err != nil {
+		return nil, err
+	}
+	if r.Valid, err = d.Bool(5); err != nil {
+		return nil, err
+	}
+	if r.ErrorCode, err = d.String(6); err != nil {
+		return nil, err
+	}
+	if r.TotalTrades, err = d.Uint64(7); err != nil {
+		return nil, err
+	}
+	if r.FailedTradeBitmap, err = d.Bytes(8); err != nil {
+		return nil, err
+	}
+	failedTradesHash, err := d.Bytes32(9)
+	if err != nil {
+		return nil, err
+	}
+	r.FailedTradesHash = failedTradesHash
+	return r, nil
+}
@@ -0,0 +1,77 @@
+package orderbookchecker
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"go.uber.org/zap"
+)
+
+func TestTaskResult_EncodeABI(t *testing.T) {
+	var trades []Trade
+	for i := 0; i < 10; i++ {
+		trades = append(trades, Trade{ID: fmt.Sprintf("trade-%d", i)})
+	}
+	verification := &VerificationResult{
+		Valid:        false,
+		ErrorCode:    ErrorCodeOverfill,
+		FailedTrades: []string{"trade-9", "trade-2"},
+	}
+	snapshot := OrderbookSnapshot{MerkleRoot: merkle.Keccak256([]byte("root")).Hex()}
+	snapshotHash := merkle.Keccak256([]byte("snapshot")).Hex()
+	policyHash := NewOrderbookVerifier(zap.NewNop()).PolicyHash()
+
+	result := NewTaskResult(verification, policyHash, snapshotHash, snapshot, "batch-1", trades)
+	if result.PolicyHash != policyHash {
+		t.Errorf("Expected policy hash %s, got %s", policyHash, result.PolicyHash)
+	}
+	if want := []byte{0x04, 0x02}; !bytes.Equal(result.FailedTradeBitmap, want) {
+		t.Errorf("Expected bitmap %08b, got %08b", want, result.FailedTradeBitmap)
+	}
+	for i := range trades {
+		if got := result.TradeFailed(i); got != (i == 2 || i == 9) {
+			t.Errorf("Trade %d: expected failed=%v", i, !got)
+		}
+	}
+	wantHash := merkle.Keccak256(abi.Encode(abi.Array(abi.String("trade-9"), abi.String("trade-2"))))
+	if result.FailedTradesHash != wantHash {
+		t.Errorf("Expected failed trades hash %s, got %s", wantHash, result.FailedTradesHash)
+	}
+
+	encoded := result.EncodeABI()
+	decoded, err := DecodeTaskResult(encoded)
+	if err != nil {
+		t.Fatalf("DecodeTaskResult failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, result) {
+		t.Errorf("Expected %+v, got %+v", result, decoded)
+	}
+	if again := NewTaskResult(verification, policyHash, snapshotHash, snapshot, "batch-1", trades).EncodeABI(); !bytes.Equal(again, encoded) {
+		t.Error("Expected identical encodings of the same verdict")
+	}
+
+	// The same verdict under another policy is a different result
+	strict := NewOrderbookVerifier(zap.NewNop(), WithSelfTradePrevention(SelfTradeCancelResting)).PolicyHash()
+	if other := NewTaskResult(verification, strict, snapshotHash, snapshot, "batch-1", trades).EncodeABI(); bytes.Equal(other, encoded) {
+		t.Error("Expected the policy hash to change the encoding")
+	}
+
+	// Malformed commitments encode as zero instead of failing the task
+	malformed := NewTaskResult(verification, policyHash, "0x1234", OrderbookSnapshot{MerkleRoot: "not-a-root"}, "batch-1", trades)
+	if malformed.SnapshotHash != (merkle.Hash{}) || malformed.MerkleRoot != (merkle.Hash{}) {
+		t.Errorf("Expected zero hashes, got %+v", malformed)
+	}
+
+	if _, err := DecodeTaskResult(encoded[:len(encoded)/2]); err == nil {
+		t.Error("Expected truncated result to be rejected")
+	}
+	unknown := *result
+	unknown.Version = TaskResultVersion + 1
+	if _, err := DecodeTaskResult(unknown.EncodeABI()); err == nil {
+		t.Error("Expected unknown version to be rejected")
+	}
+}
@@ -3,6 +3,8 @@ package orderbookchecker
 import (
 	"math/big"
 	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
 )
 
 // Order represents a single order in the orderbook
@@ -13,6 +15,39 @@ type Order struct {
 	Quantity  *big.Int  `json:"quantity"`  // Quantity in wei or smallest unit
 	Timestamp time.Time `json:"timestamp"` // When order was placed
 	UserID    string    `json:"user_id"`   // User identifier
+	// TokenID identifies the outcome token the order trades; orders on the same token share a book
+	TokenID string `json:"token_id,omitempty"`
+	// Outcome is the outcome the token pays out on, OutcomeYes or OutcomeNo
+	Outcome string `json:"outcome,omitempty"`
+	// TimeInForce is one of the TimeInForce* values; empty means TimeInForceGTC
+	TimeInForce string `json:"time_in_force,omitempty"`
+	// Expiration is when a GTD order stops being matchable; only set for GTD orders
+	Expiration *time.Time `json:"expiration,omitempty"`
+	// FeeRateBps is the highest fee rate, in basis points, the signer agreed to pay
+	FeeRateBps uint32 `json:"fee_rate_bps,omitempty"`
+	// Maker is the 0x-prefixed address that signed the order; empty for unsigned orders
+	Maker string `json:"maker,omitempty"`
+	// Salt and Nonce are the salt and exchange nonce of the signed order
+	Salt  *big.Int `json:"salt,omitempty"`
+	Nonce *big.Int `json:"nonce,omitempty"`
+	// Signature is the maker's 65-byte r || s || v EIP-712 signature, 0x-prefixed
+	Signature string `json:"signature,omitempty"`
+}
+
+// Time-in-force values of an order
+const (
+	TimeInForceGTC = "GTC" // Good till cancelled
+	TimeInForceGTD = "GTD" // Good till date: matchable until its expiration
+	TimeInForceFOK = "FOK" // Fill or kill: filled completely or not at all
+	TimeInForceFAK = "FAK" // Fill and kill: filled as far as possible, the rest is cancelled
+	TimeInForceIOC = "IOC" // Immediate or cancel, an alias of FAK
+)
+
+// Cancellation records that an order was cancelled at a point in time. Trades against the
+// order at or after that time are invalid.
+type Cancellation struct {
+	OrderID   string    `json:"order_id"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // Trade represents an executed trade from on-chain data
@@ -25,6 +60,14 @@ type Trade struct {
 	Timestamp   time.Time `json:"timestamp"`
 	TxHash      string    `json:"tx_hash"`
 	BlockNumber uint64    `json:"block_number"`
+	// MatchType is one of the MatchType* values; empty means MatchTypeNormal
+	MatchType string `json:"match_type,omitempty"`
+	// MakerOrderID is the resting order of the trade, either BuyOrderID or SellOrderID;
+	// the other order is the taker
+	MakerOrderID string `json:"maker_order_id,omitempty"`
+	// MakerFee and TakerFee are the collateral amounts charged to each side
+	MakerFee *big.Int `json:"maker_fee,omitempty"`
+	TakerFee *big.Int `json:"taker_fee,omitempty"`
 }
 
 // OrderbookSnapshot represents a snapshot of the orderbook at a specific point in time
@@ -35,19 +78,218 @@ type OrderbookSnapshot struct {
 	Orders         []Order   `json:"orders"`
 	MerkleRoot     string    `json:"merkle_root"`
 	PrevHash       string    `json:"prev_hash"`
+	// Params constrains the prices and sizes of the market; snapshots without parameters
+	// are not checked against a price domain
+	Params *MarketParams `json:"params,omitempty"`
+}
+
+// MarketParams are the per-market trading rules of a prediction market. Prices lie strictly
+// between 0 and one unit of collateral, 10^CollateralDecimals.
+type MarketParams struct {
+	TickSize           *big.Int `json:"tick_size,omitempty"`      // Prices must be a multiple of the tick size; nil for no tick
+	MinOrderSize       *big.Int `json:"min_order_size,omitempty"` // Smallest order quantity; nil for no minimum
+	CollateralDecimals uint8    `json:"collateral_decimals"`
+	MakerFeeBps        uint32   `json:"maker_fee_bps,omitempty"` // Fee rate charged to the resting order
+	TakerFeeBps        uint32   `json:"taker_fee_bps,omitempty"` // Fee rate charged to the incoming order
+}
+
+// Fee roles of the two orders of a trade
+const (
+	FeeRoleMaker = "maker"
+	FeeRoleTaker = "taker"
+)
+
+// FeeDiscrepancy describes a fee charged on a trade that does not match the fee owed
+type FeeDiscrepancy struct {
+	TradeID  string   `json:"trade_id"`
+	OrderID  string   `json:"order_id,omitempty"`
+	Role     string   `json:"role,omitempty"` // FeeRoleMaker or FeeRoleTaker
+	Expected *big.Int `json:"expected,omitempty"`
+	Charged  *big.Int `json:"charged,omitempty"`
+	Reason   string   `json:"reason"`
+}
+
+// FraudProof is evidence that a trade skipped an order with time priority: the skipped and
+// the matched order with their inclusion proofs against the snapshot's order root, and the
+// trade itself. It shows that the skipped order was in the snapshot, not that it was still
+// unfilled when the trade executed.
+type FraudProof struct {
+	OrderRoot    merkle.Hash   `json:"order_root"`
+	SkippedOrder Order         `json:"skipped_order"`
+	SkippedProof *merkle.Proof `json:"skipped_proof"`
+	MatchedOrder Order         `json:"matched_order"`
+	MatchedProof *merkle.Proof `json:"matched_proof"`
+	Trade        Trade         `json:"trade"`
+}
+
+// Outcomes of a binary market. The YES and NO tokens of a market are complementary: one YES
+// and one NO token can always be minted from, or merged back into, one unit of collateral.
+const (
+	OutcomeYes = "YES"
+	OutcomeNo  = "NO"
+)
+
+// Match types of a trade. A complementary order stands in for the opposite side of the
+// priced token: buying NO at p is equivalent to selling YES at unit - p, and selling NO at
+// p is equivalent to buying YES at unit - p.
+const (
+	// MatchTypeNormal matches a buy and a sell of the same token
+	MatchTypeNormal = "normal"
+	// MatchTypeMint matches buys of complementary tokens, minting a new YES/NO pair from
+	// collateral; BuyOrderID is the priced token's buy, SellOrderID the complementary buy
+	MatchTypeMint = "mint"
+	// MatchTypeMerge matches sells of complementary tokens, merging the pair back into
+	// collateral; SellOrderID is the priced token's sell, BuyOrderID the complementary sell
+	MatchTypeMerge = "merge"
+)
+
+// Execution price policies select the price a trade between a maker and a taker executes at
+const (
+	// ExecutionPriceAny accepts any price within both orders' limits
+	ExecutionPriceAny = "any"
+	// ExecutionPriceMaker executes at the maker's price, giving the taker all price improvement
+	ExecutionPriceMaker = "maker"
+	// ExecutionPriceMidpoint executes halfway between the two prices, rounded to the tick in
+	// the taker's favour
+	ExecutionPriceMidpoint = "midpoint"
+	// ExecutionPriceTaker executes at the taker's price, giving the maker all price improvement
+	ExecutionPriceTaker = "taker"
+)
+
+// Self-trade prevention modes decide what happens when the two orders of a match belong to
+// the same user
+const (
+	// SelfTradeAllow lets orders of the same user match each other
+	SelfTradeAllow = "allow"
+	// SelfTradeCancelResting cancels the resting order and keeps matching the incoming one
+	SelfTradeCancelResting = "cancel_resting"
+	// SelfTradeCancelIncoming cancels what is left of the incoming order
+	SelfTradeCancelIncoming = "cancel_incoming"
+)
+
+// Rules are the checks that can be disabled with WithDisabledRules. Every other check
+// always applies.
+const (
+	// RuleFees checks the fees charged on each trade against the market's fee schedule
+	RuleFees = "fees"
+	// RuleFillOrKill requires the trades of a batch to fill fill-or-kill orders completely.
+	// In replay mode the matching engine applies it.
+	RuleFillOrKill = "fill_or_kill"
+	// RuleResidualBook requires the book a batch leaves, or in replay mode the snapshot, not
+	// to cross
+	RuleResidualBook = "residual_book"
+)
+
+// Finding is a structured report of a rule broken by a snapshot or a trade. Code is one of
+// the ErrorCode* values; Expected and Actual are set for rules comparing two values.
+type Finding struct {
+	Code     string   `json:"code"`
+	OrderIDs []string `json:"order_ids,omitempty"`
+	TradeID  string   `json:"trade_id,omitempty"`
+	Expected string   `json:"expected,omitempty"`
+	Actual   string   `json:"actual,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// DefaultCollateralDecimals is the number of decimals of the collateral: a price of
+// 10^18 pays one unit of collateral per outcome token
+const DefaultCollateralDecimals = 18
+
+// Error codes reported in VerificationResult.ErrorCode
+const (
+	ErrorCodeMerkleRootMismatch   = "MERKLE_ROOT_MISMATCH"
+	ErrorCodeSnapshotHashMismatch = "SNAPSHOT_HASH_MISMATCH"
+	ErrorCodeInvalidMarketParams  = "INVALID_MARKET_PARAMS"
+	ErrorCodePriceOutOfRange      = "PRICE_OUT_OF_RANGE"
+	ErrorCodePriceOffTick         = "PRICE_OFF_TICK"
+	ErrorCodeOrderBelowMinSize    = "ORDER_BELOW_MIN_SIZE"
+	ErrorCodeInvalidTimeInForce   = "INVALID_TIME_IN_FORCE"
+	ErrorCodeOrderCancelled       = "ORDER_CANCELLED"
+	ErrorCodeOrderExpired         = "ORDER_EXPIRED"
+	ErrorCodeFOKPartialFill       = "FOK_PARTIAL_FILL"
+	ErrorCodeFeeDiscrepancy       = "FEE_DISCREPANCY"
+	ErrorCodeExecutionPrice       = "EXECUTION_PRICE_MISMATCH"
+	ErrorCodeCrossedBook          = "CROSSED_BOOK"
+	ErrorCodeDuplicateOrderID     = "DUPLICATE_ORDER_ID"
+	ErrorCodeInvalidOrder         = "INVALID_ORDER"
+	ErrorCodeFutureTimestamp      = "FUTURE_TIMESTAMP"
+	ErrorCodeTimestampPrecision   = "TIMESTAMP_PRECISION"
+	ErrorCodeSelfTrade            = "SELF_TRADE"
+	ErrorCodeUnsignedOrder        = "UNSIGNED_ORDER"
+	ErrorCodeInvalidSignature     = "INVALID_SIGNATURE"
+	ErrorCodeUnknownOrder         = "UNKNOWN_ORDER"
+	ErrorCodeInvalidTrade         = "INVALID_TRADE"
+	ErrorCodeInvalidMatch         = "INVALID_MATCH"
+	ErrorCodePriceViolation       = "PRICE_VIOLATION"
+	ErrorCodeOverfill             = "OVERFILL"
+	ErrorCodePriorityViolation    = "PRIORITY_VIOLATION"
+	ErrorCodeFillMismatch         = "FILL_MISMATCH"
+)
+
+// Verification modes supported by the verifier
+const (
+	// VerificationModeTrades checks each reported trade against the snapshot in isolation
+	VerificationModeTrades = "trades"
+	// VerificationModeReplay re-runs the matching engine and compares its fills with the reported trades
+	VerificationModeReplay = "replay"
+)
+
+// Kinds of differences between expected fills and reported trades
+const (
+	FillDiffMismatch = "mismatch" // Reported trade differs from the expected fill at the same position
+	FillDiffMissing  = "missing"  // Matching engine produced a fill that was never reported
+	FillDiffPhantom  = "phantom"  // Reported trade has no corresponding fill
+)
+
+// Fill represents a single match produced by the matching engine during replay
+type Fill struct {
+	BuyOrderID  string   `json:"buy_order_id"`
+	SellOrderID string   `json:"sell_order_id"`
+	Price       *big.Int `json:"price"`
+	Quantity    *big.Int `json:"quantity"`
+	MatchType   string   `json:"match_type,omitempty"`
+	// MakerOrderID is the resting order the incoming order matched against
+	MakerOrderID string `json:"maker_order_id,omitempty"`
+}
+
+// FillDiff describes a discrepancy between an expected fill and a reported trade
+type FillDiff struct {
+	Index    int    `json:"index"`              // Position in the fill/trade sequence
+	Kind     string `json:"kind"`               // One of the FillDiff* kinds
+	Expected *Fill  `json:"expected,omitempty"` // Fill produced by the replay, if any
+	Reported *Trade `json:"reported,omitempty"` // Trade reported by the operator, if any
+	Reason   string `json:"reason"`
 }
 
 // VerificationResult represents the result of orderbook verification
 type VerificationResult struct {
+	Mode           string   `json:"mode,omitempty"`
 	Valid          bool     `json:"valid"`
+	ErrorCode      string   `json:"error_code,omitempty"`
 	ErrorMessage   string   `json:"error_message,omitempty"`
 	FailedTrades   []string `json:"failed_trades,omitempty"`
 	VerifiedTrades int      `json:"verified_trades"`
 	TotalTrades    int      `json:"total_trades"`
+	// ResidualQuantities holds the unfilled quantity of every snapshot order after
+	// all verified trades in the batch have been applied
+	ResidualQuantities map[string]*big.Int `json:"residual_quantities,omitempty"`
+	// FillDiffs lists every difference between replayed fills and reported trades (replay mode only)
+	FillDiffs []FillDiff `json:"fill_diffs,omitempty"`
+	// FeeDiscrepancies lists every fee charged that does not match the fee owed
+	FeeDiscrepancies []FeeDiscrepancy `json:"fee_discrepancies,omitempty"`
+	// Findings lists every snapshot sanity violation and a finding for each failed trade
+	Findings []Finding `json:"findings,omitempty"`
+	// FraudProofs holds the evidence for every trade that skipped an order with priority
+	FraudProofs []FraudProof `json:"fraud_proofs,omitempty"`
 }
 
 // OrderbookState represents the internal state of the orderbook during verification
 type OrderbookState struct {
-	BuyOrders  []Order // Sorted by price (highest first), then by timestamp
-	SellOrders []Order // Sorted by price (lowest first), then by timestamp
+	BuyOrders  []Order             // Sorted by price (highest first), then by timestamp
+	SellOrders []Order             // Sorted by price (lowest first), then by timestamp
+	Remaining  map[string]*big.Int // Unfilled quantity per order ID, debited as trades are replayed
+	PriceUnit  *big.Int            // Price of one full unit of collateral, the sum of complementary prices
+	Params     *MarketParams       // Market parameters of the snapshot, if any
+	// CancelledAt holds the cancellation time of every cancelled order
+	CancelledAt map[string]time.Time
 }
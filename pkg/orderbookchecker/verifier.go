@@ -1,81 +1,585 @@
 package orderbookchecker
 
 import (
+	"errors"
 	"fmt"
-	"go.uber.org/zap"
+	"math/big"
 	"sort"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"go.uber.org/zap"
 )
 
 // OrderbookVerifier handles verification of orderbook snapshots against executed trades
 type OrderbookVerifier struct {
-	logger *zap.Logger
+	logger         *zap.Logger
+	executionPrice string          // One of the ExecutionPrice* policies
+	selfTrade      string          // One of the SelfTrade* modes
+	orderDomain    *EIP712Domain   // Domain orders must be signed against, nil if signatures are not checked
+	disabledRules  map[string]bool // Rule* checks that are skipped
+	feeTolerance   *big.Int        // Fee difference accepted, in collateral base units
+	priceTolerance *big.Int        // Execution price deviation accepted under the price policy
+}
+
+// VerifierOption configures an OrderbookVerifier
+type VerifierOption func(*OrderbookVerifier)
+
+// WithExecutionPricePolicy requires trades to execute at the price given by one of the
+// ExecutionPrice* policies; the default, ExecutionPriceAny, accepts any price within both
+// orders' limits
+func WithExecutionPricePolicy(policy string) VerifierOption {
+	return func(v *OrderbookVerifier) {
+		v.executionPrice = policy
+	}
+}
+
+// WithSelfTradePrevention rejects trades between orders of the same user unless the mode
+// is SelfTradeAllow, the default. In replay mode the matching engine applies the mode.
+func WithSelfTradePrevention(mode string) VerifierOption {
+	return func(v *OrderbookVerifier) {
+		v.selfTrade = mode
+	}
+}
+
+// WithSignedOrders requires every order to carry its maker's EIP-712 signature against the
+// domain. A snapshot holding an unsigned or forged order is rejected; in replay mode such
+// incoming orders are rejected by the exchange and never match.
+func WithSignedOrders(domain EIP712Domain) VerifierOption {
+	return func(v *OrderbookVerifier) {
+		v.orderDomain = &domain
+	}
+}
+
+// WithDisabledRules skips the given Rule* checks
+func WithDisabledRules(rules ...string) VerifierOption {
+	return func(v *OrderbookVerifier) {
+		if v.disabledRules == nil {
+			v.disabledRules = make(map[string]bool, len(rules))
+		}
+		for _, rule := range rules {
+			v.disabledRules[rule] = true
+		}
+	}
+}
+
+// WithFeeTolerance accepts fees that differ from those owed by at most tolerance collateral
+// base units, for engines that round fees differently
+func WithFeeTolerance(tolerance *big.Int) VerifierOption {
+	return func(v *OrderbookVerifier) {
+		v.feeTolerance = tolerance
+	}
+}
+
+// WithPriceTolerance accepts trades that execute within tolerance base units of the price
+// the execution price policy requires. It has no effect under ExecutionPriceAny or in replay
+// mode, where the matching engine sets the price.
+func WithPriceTolerance(tolerance *big.Int) VerifierOption {
+	return func(v *OrderbookVerifier) {
+		v.priceTolerance = tolerance
+	}
 }
 
 // NewOrderbookVerifier creates a new instance of OrderbookVerifier
-func NewOrderbookVerifier(logger *zap.Logger) *OrderbookVerifier {
-	return &OrderbookVerifier{
-		logger: logger,
+func NewOrderbookVerifier(logger *zap.Logger, opts ...VerifierOption) *OrderbookVerifier {
+	v := &OrderbookVerifier{
+		logger:         logger,
+		executionPrice: ExecutionPriceAny,
+		selfTrade:      SelfTradeAllow,
 	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
 }
 
-// VerifySnapshot verifies that the executed trades are consistent with the orderbook snapshot
+// VerifySnapshot verifies that the executed trades are consistent with the orderbook snapshot.
+// Trades are replayed in the order given, and every verified trade debits the remaining
+// quantity of both orders it consumed, so later trades are checked against what is left.
+// The fees charged on each trade are checked against the market's fee schedule. The snapshot
+// also holds the orders the batch matched, so unlike in replay mode it may cross, but the
+// residual book the batch leaves may not.
 func (v *OrderbookVerifier) VerifySnapshot(trades []Trade, snapshot OrderbookSnapshot) (*VerificationResult, error) {
+	return v.VerifySnapshotWithCancellations(trades, snapshot, nil)
+}
+
+// VerifySnapshotWithCancellations verifies the trades like VerifySnapshot, additionally
+// rejecting trades against orders that had been cancelled or had expired at the trade
+// timestamp, and fill-or-kill orders that the batch only partially filled
+func (v *OrderbookVerifier) VerifySnapshotWithCancellations(trades []Trade, snapshot OrderbookSnapshot, cancellations []Cancellation) (*VerificationResult, error) {
 	v.logger.Sugar().Infow("Starting orderbook verification",
 		"sequence_number", snapshot.SequenceNumber,
 		"market_id", snapshot.MarketID,
 		"total_trades", len(trades),
 		"total_orders", len(snapshot.Orders),
+		"cancellations", len(cancellations),
 	)
 
+	if err := v.ValidateOptions(); err != nil {
+		return buildFailure(VerificationModeTrades, len(trades), err)
+	}
+	if findings := append(checkTimestampPrecision(snapshot, trades, nil, cancellations), v.checkSnapshot(snapshot)...); len(findings) > 0 {
+		return v.sanityFailure(VerificationModeTrades, len(trades), findings), nil
+	}
+
 	// Build orderbook state from snapshot
-	state, err := v.buildOrderbookState(snapshot.Orders)
+	state, err := v.buildOrderbookState(snapshot.Orders, snapshot.Params)
 	if err != nil {
-		return &VerificationResult{
-			Valid:        false,
-			ErrorMessage: fmt.Sprintf("failed to build orderbook state: %v", err),
-			TotalTrades:  len(trades),
-		}, err
+		return buildFailure(VerificationModeTrades, len(trades), err)
+	}
+	if state.CancelledAt, err = cancellationIndex(cancellations); err != nil {
+		return buildFailure(VerificationModeTrades, len(trades), err)
 	}
 
 	// Verify each trade
 	result := &VerificationResult{
+		Mode:        VerificationModeTrades,
 		Valid:       true,
 		TotalTrades: len(trades),
 	}
 
+	orders := orderIndex(snapshot.Orders)
+	prover := &fraudProver{orders: snapshot.Orders, root: snapshot.MerkleRoot}
+	var verified []Trade
 	for _, trade := range trades {
 		if err := v.verifyTrade(trade, state); err != nil {
-			v.logger.Sugar().Errorw("Trade verification failed",
-				"trade_id", trade.ID,
-				"error", err,
-			)
-			result.Valid = false
-			result.FailedTrades = append(result.FailedTrades, trade.ID)
-			if result.ErrorMessage == "" {
-				result.ErrorMessage = fmt.Sprintf("trade %s failed: %v", trade.ID, err)
-			}
+			v.recordTradeFailure(result, trade, err)
+			v.recordFraudProof(result, prover, trade, err)
+		} else if discrepancies := v.checkFees(trade, orders, state); len(discrepancies) > 0 {
+			v.recordFeeDiscrepancies(result, trade, discrepancies)
 		} else {
 			result.VerifiedTrades++
+			verified = append(verified, trade)
 		}
 	}
 
+	// A fill-or-kill order must be completely filled by the trades that touched it. A trade
+	// between two partially filled FOK orders fails once, for the first of them.
+	partialFOK := make(map[string]error)
+	for _, order := range snapshot.Orders {
+		if !v.enabled(RuleFillOrKill) || !isFillOrKill(&order) || order.Quantity == nil {
+			continue
+		}
+		remaining := state.Remaining[order.ID]
+		if remaining.Sign() <= 0 || remaining.Cmp(order.Quantity) == 0 {
+			continue
+		}
+
+		filled := new(big.Int).Sub(order.Quantity, remaining)
+		err := &VerificationError{
+			Err:      ErrFOKPartialFill,
+			OrderIDs: []string{order.ID},
+			Expected: order.Quantity.String(),
+			Actual:   filled.String(),
+			Detail:   fmt.Sprintf("order %s filled %s of %s", order.ID, filled.String(), order.Quantity.String()),
+		}
+		for _, trade := range verified {
+			if trade.BuyOrderID == order.ID || trade.SellOrderID == order.ID {
+				if _, ok := partialFOK[trade.ID]; !ok {
+					partialFOK[trade.ID] = err
+				}
+			}
+		}
+	}
+	for _, trade := range verified {
+		if err, ok := partialFOK[trade.ID]; ok {
+			v.recordTradeFailure(result, trade, err)
+			result.VerifiedTrades--
+			delete(partialFOK, trade.ID)
+		}
+	}
+
+	// A batch whose trades all hold must leave a book an honest engine would not match any
+	// further
+	if result.Valid && v.enabled(RuleResidualBook) {
+		v.recordFindings(result, crossedBook(state, batchEnd(snapshot, trades)))
+	}
+
+	result.ResidualQuantities = state.Remaining
+
 	v.logger.Sugar().Infow("Verification completed",
 		"valid", result.Valid,
 		"verified_trades", result.VerifiedTrades,
 		"failed_trades", len(result.FailedTrades),
+		"fee_discrepancies", len(result.FeeDiscrepancies),
 	)
 
 	return result, nil
 }
 
-// buildOrderbookState constructs the orderbook state from a list of orders
-func (v *OrderbookVerifier) buildOrderbookState(orders []Order) (*OrderbookState, error) {
+// VerifyReplay re-runs a price-time priority matching engine over the snapshot and the ordered
+// stream of incoming orders, then compares the produced fills with the reported trades one-for-one.
+// The snapshot is the resting book the stream arrives at, so a crossed snapshot is a finding.
+func (v *OrderbookVerifier) VerifyReplay(trades []Trade, snapshot OrderbookSnapshot, incoming []Order) (*VerificationResult, error) {
+	return v.VerifyReplayWithCancellations(trades, snapshot, incoming, nil)
+}
+
+// VerifyReplayWithCancellations replays like VerifyReplay, removing resting orders from the
+// book once they are cancelled or expire relative to each incoming order's timestamp
+func (v *OrderbookVerifier) VerifyReplayWithCancellations(trades []Trade, snapshot OrderbookSnapshot, incoming []Order, cancellations []Cancellation) (*VerificationResult, error) {
+	v.logger.Sugar().Infow("Starting orderbook replay verification",
+		"sequence_number", snapshot.SequenceNumber,
+		"market_id", snapshot.MarketID,
+		"total_trades", len(trades),
+		"total_orders", len(snapshot.Orders),
+		"incoming_orders", len(incoming),
+		"cancellations", len(cancellations),
+	)
+
+	if err := v.ValidateOptions(); err != nil {
+		return buildFailure(VerificationModeReplay, len(trades), err)
+	}
+	if findings := append(checkTimestampPrecision(snapshot, trades, incoming, cancellations), v.checkSnapshot(snapshot)...); len(findings) > 0 {
+		return v.sanityFailure(VerificationModeReplay, len(trades), findings), nil
+	}
+
+	state, err := v.buildOrderbookState(snapshot.Orders, snapshot.Params)
+	if err != nil {
+		return buildFailure(VerificationModeReplay, len(trades), err)
+	}
+	if state.CancelledAt, err = cancellationIndex(cancellations); err != nil {
+		return buildFailure(VerificationModeReplay, len(trades), err)
+	}
+
+	// The snapshot is the resting book the incoming orders arrive at, so it must not cross
+	var crossed []Finding
+	if v.enabled(RuleResidualBook) {
+		crossed = crossedBook(state, snapshot.Timestamp)
+	}
+
+	// Replay the incoming order stream to obtain the fills an honest engine would produce
+	engine := NewMatchingEngine(state)
+	engine.executionPrice = v.executionPrice
+	engine.selfTrade = v.selfTrade
+	orders := orderIndex(snapshot.Orders)
+	var fills []Fill
+	var rejected []Finding
+	for i, order := range incoming {
+		// The exchange rejects malformed orders and orders outside the market's price domain,
+		// so they never match; an operator that forwards one has submitted an invalid order
+		if err := v.rejectIncoming(order, snapshot.Params); err != nil {
+			rejected = append(rejected, newFinding(
+				fmt.Errorf("%w: incoming order %s rejected by the exchange: %v", ErrInvalidOrder, order.ID, err),
+				"", order.ID,
+			))
+			continue
+		}
+
+		orderFills, err := engine.Submit(order)
+		if err != nil {
+			return &VerificationResult{
+				Mode:         VerificationModeReplay,
+				Valid:        false,
+				ErrorMessage: fmt.Sprintf("failed to replay incoming order: %v", err),
+				TotalTrades:  len(trades),
+			}, err
+		}
+		fills = append(fills, orderFills...)
+		orders[order.ID] = &incoming[i]
+	}
+
+	result := &VerificationResult{
+		Mode:               VerificationModeReplay,
+		Valid:              true,
+		TotalTrades:        len(trades),
+		ResidualQuantities: engine.Remaining(),
+		FillDiffs:          diffFills(fills, trades),
+	}
+	v.recordFindings(result, crossed)
+	v.recordFindings(result, rejected)
+
+	failed := make(map[int]bool, len(result.FillDiffs))
+	for _, diff := range result.FillDiffs {
+		v.logger.Sugar().Errorw("Replay fill mismatch",
+			"index", diff.Index,
+			"kind", diff.Kind,
+			"reason", diff.Reason,
+		)
+		result.Valid = false
+		if result.ErrorMessage == "" {
+			result.ErrorCode = ErrorCodeFillMismatch
+			result.ErrorMessage = fmt.Sprintf("fill %d %s: %s", diff.Index, diff.Kind, diff.Reason)
+		}
+		result.Findings = append(result.Findings, diffFinding(diff))
+		if diff.Reported != nil {
+			failed[diff.Index] = true
+		}
+	}
+
+	for i, trade := range trades {
+		if failed[i] {
+			result.FailedTrades = append(result.FailedTrades, trade.ID)
+		} else if discrepancies := v.checkFees(trade, orders, state); len(discrepancies) > 0 {
+			v.recordFeeDiscrepancies(result, trade, discrepancies)
+		} else {
+			result.VerifiedTrades++
+		}
+	}
+
+	v.logger.Sugar().Infow("Replay verification completed",
+		"valid", result.Valid,
+		"expected_fills", len(fills),
+		"verified_trades", result.VerifiedTrades,
+		"fill_diffs", len(result.FillDiffs),
+		"fee_discrepancies", len(result.FeeDiscrepancies),
+	)
+
+	return result, nil
+}
+
+// recordTradeFailure marks a trade as failed, keeping the first failure as the result's error
+func (v *OrderbookVerifier) recordTradeFailure(result *VerificationResult, trade Trade, err error) {
+	v.logger.Sugar().Errorw("Trade verification failed",
+		"trade_id", trade.ID,
+		"error", err,
+	)
+	result.Valid = false
+	result.FailedTrades = append(result.FailedTrades, trade.ID)
+	result.Findings = append(result.Findings, tradeFinding(trade, err))
+	if result.ErrorMessage == "" {
+		result.ErrorCode = ErrorCode(err)
+		result.ErrorMessage = fmt.Sprintf("trade %s failed: %v", trade.ID, err)
+	}
+}
+
+// tradeFinding describes a failed trade, taking the offending orders and values from a
+// VerificationError and otherwise naming both orders of the trade
+func tradeFinding(trade Trade, err error) Finding {
+	finding := newFinding(err, trade.ID, trade.BuyOrderID, trade.SellOrderID)
+
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		finding.OrderIDs = verr.OrderIDs
+		finding.Expected = verr.Expected
+		finding.Actual = verr.Actual
+	}
+	return finding
+}
+
+// diffFinding describes a difference between the replayed fills and the reported trades
+func diffFinding(diff FillDiff) Finding {
+	finding := Finding{
+		Code:    ErrorCodeFillMismatch,
+		Message: fmt.Sprintf("fill %d %s: %s", diff.Index, diff.Kind, diff.Reason),
+	}
+	if diff.Reported != nil {
+		finding.TradeID = diff.Reported.ID
+		finding.OrderIDs = []string{diff.Reported.BuyOrderID, diff.Reported.SellOrderID}
+	} else if diff.Expected != nil {
+		finding.OrderIDs = []string{diff.Expected.BuyOrderID, diff.Expected.SellOrderID}
+	}
+	return finding
+}
+
+// recordFindings marks the result invalid for each finding, keeping the first as the
+// result's error
+func (v *OrderbookVerifier) recordFindings(result *VerificationResult, findings []Finding) {
+	for _, finding := range findings {
+		v.logger.Sugar().Errorw("Snapshot sanity check failed",
+			"code", finding.Code,
+			"order_ids", finding.OrderIDs,
+			"message", finding.Message,
+		)
+		result.Valid = false
+		result.Findings = append(result.Findings, finding)
+		if result.ErrorMessage == "" {
+			result.ErrorCode = finding.Code
+			result.ErrorMessage = finding.Message
+		}
+	}
+}
+
+// sanityFailure reports a snapshot whose orders fail the sanity checks, without verifying
+// any trades against it
+func (v *OrderbookVerifier) sanityFailure(mode string, totalTrades int, findings []Finding) *VerificationResult {
+	result := &VerificationResult{
+		Mode:        mode,
+		TotalTrades: totalTrades,
+	}
+	v.recordFindings(result, findings)
+	return result
+}
+
+// ValidateOptions checks the verifier's configured policies. Verification fails on the same
+// error, so callers can check a configuration up front.
+func (v *OrderbookVerifier) ValidateOptions() error {
+	if err := validateExecutionPricePolicy(v.executionPrice); err != nil {
+		return err
+	}
+	if v.orderDomain != nil {
+		if _, err := v.orderDomain.Separator(); err != nil {
+			return fmt.Errorf("invalid order signing domain: %v", err)
+		}
+	}
+	for rule := range v.disabledRules {
+		if rule != RuleFees && rule != RuleFillOrKill && rule != RuleResidualBook {
+			return fmt.Errorf("unknown verification rule: %s", rule)
+		}
+	}
+	if v.feeTolerance != nil && v.feeTolerance.Sign() < 0 {
+		return fmt.Errorf("fee tolerance %s is negative", v.feeTolerance.String())
+	}
+	if v.priceTolerance != nil && v.priceTolerance.Sign() < 0 {
+		return fmt.Errorf("price tolerance %s is negative", v.priceTolerance.String())
+	}
+	return validateSelfTradePrevention(v.selfTrade)
+}
+
+// PolicyHash commits to the verification policy, so a signed verdict names the rules it was
+// reached under. It is keccak256 of
+//
+//	abi.encode(string executionPrice, string selfTrade, bytes32 orderDomainSeparator,
+//	    string[] disabledRules, uint256 feeTolerance, uint256 priceTolerance)
+//
+// with a zero separator when signatures are not checked, the disabled rules sorted, and
+// unset tolerances as zero. Options rejected by ValidateOptions do not hash meaningfully.
+func (v *OrderbookVerifier) PolicyHash() merkle.Hash {
+	var separator merkle.Hash
+	if v.orderDomain != nil {
+		separator, _ = v.orderDomain.Separator()
+	}
+
+	rules := make([]string, 0, len(v.disabledRules))
+	for rule, disabled := range v.disabledRules {
+		if disabled {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Strings(rules)
+	ruleValues := make([]abi.Value, len(rules))
+	for i, rule := range rules {
+		ruleValues[i] = abi.String(rule)
+	}
+
+	return merkle.Keccak256(abi.Encode(
+		abi.String(v.executionPrice),
+		abi.String(v.selfTrade),
+		abi.Bytes32(separator),
+		abi.Array(ruleValues...),
+		tolerance(v.feeTolerance),
+		tolerance(v.priceTolerance),
+	))
+}
+
+// tolerance encodes an optional tolerance, zero when unset or negative
+func tolerance(t *big.Int) abi.Value {
+	if t == nil || t.Sign() < 0 {
+		return abi.Uint64(0)
+	}
+	value, _ := abi.Uint256(t)
+	return value
+}
+
+// enabled reports whether a Rule* check applies
+func (v *OrderbookVerifier) enabled(rule string) bool {
+	return !v.disabledRules[rule]
+}
+
+// checkFees returns the fee discrepancies of a trade unless fee checks are disabled
+func (v *OrderbookVerifier) checkFees(trade Trade, orders map[string]*Order, state *OrderbookState) []FeeDiscrepancy {
+	if !v.enabled(RuleFees) {
+		return nil
+	}
+	return verifyFees(trade, orders, state.Params, state.PriceUnit, v.feeTolerance)
+}
+
+// checkSnapshot runs the sanity checks on a snapshot's orders and, if signed orders are
+// required, checks every order's signature
+func (v *OrderbookVerifier) checkSnapshot(snapshot OrderbookSnapshot) []Finding {
+	findings := checkSnapshotOrders(snapshot)
+	if v.orderDomain != nil {
+		findings = append(findings, checkOrderSignatures(snapshot.Orders, *v.orderDomain, orderPriceUnit(snapshot.Params))...)
+	}
+	return findings
+}
+
+// orderPriceUnit returns the price unit orders of a market are signed with
+func orderPriceUnit(params *MarketParams) *big.Int {
+	if params != nil {
+		return params.PriceUnit()
+	}
+	return priceUnit(DefaultCollateralDecimals)
+}
+
+// rejectIncoming returns the reason the exchange would reject an incoming order, if any
+func (v *OrderbookVerifier) rejectIncoming(order Order, params *MarketParams) error {
+	if err := validateTimeInForce(order); err != nil {
+		return err
+	}
+	if v.orderDomain != nil {
+		if err := verifyOrderSignature(order, *v.orderDomain, orderPriceUnit(params)); err != nil {
+			return err
+		}
+	}
+	if params != nil {
+		return params.CheckOrder(order)
+	}
+	return nil
+}
+
+// buildFailure reports a snapshot that could not be loaded into an orderbook state. Orders
+// that break the market's rules are a verdict on the settlement and produce an invalid
+// result; any other error is returned to the caller.
+func buildFailure(mode string, totalTrades int, err error) (*VerificationResult, error) {
+	result := &VerificationResult{
+		Mode:         mode,
+		Valid:        false,
+		ErrorCode:    ErrorCode(err),
+		ErrorMessage: fmt.Sprintf("failed to build orderbook state: %v", err),
+		TotalTrades:  totalTrades,
+	}
+	if result.ErrorCode != "" {
+		return result, nil
+	}
+	return result, err
+}
+
+// buildOrderbookState constructs the orderbook state from a list of orders, checking each
+// against the market parameters if there are any
+func (v *OrderbookVerifier) buildOrderbookState(orders []Order, params *MarketParams) (*OrderbookState, error) {
 	state := &OrderbookState{
 		BuyOrders:  make([]Order, 0),
 		SellOrders: make([]Order, 0),
+		Remaining:  make(map[string]*big.Int, len(orders)),
+		PriceUnit:  priceUnit(DefaultCollateralDecimals),
+		Params:     params,
 	}
 
+	if params != nil {
+		if err := params.Validate(); err != nil {
+			return nil, err
+		}
+		state.PriceUnit = params.PriceUnit()
+	}
+
+	// Every token must pay out on a single outcome
+	outcomes := make(map[string]string)
+
 	for _, order := range orders {
+		if err := validateOutcome(order); err != nil {
+			return nil, err
+		}
+		if outcome, ok := outcomes[order.TokenID]; ok && outcome != order.Outcome {
+			return nil, fmt.Errorf("token %s has conflicting outcomes %s and %s", order.TokenID, outcome, order.Outcome)
+		}
+		outcomes[order.TokenID] = order.Outcome
+
+		if err := validateTimeInForce(order); err != nil {
+			return nil, err
+		}
+		if params != nil {
+			if err := params.CheckOrder(order); err != nil {
+				return nil, err
+			}
+		}
+
+		if order.Quantity != nil {
+			state.Remaining[order.ID] = new(big.Int).Set(order.Quantity)
+		} else {
+			state.Remaining[order.ID] = new(big.Int)
+		}
+
 		if order.Side == "buy" {
 			state.BuyOrders = append(state.BuyOrders, order)
 		} else if order.Side == "sell" {
@@ -86,54 +590,136 @@ func (v *OrderbookVerifier) buildOrderbookState(orders []Order) (*OrderbookState
 	}
 
 	// Sort buy orders by price (highest first), then by timestamp
-	sort.Slice(state.BuyOrders, func(i, j int) bool {
-		priceComp := state.BuyOrders[i].Price.Cmp(state.BuyOrders[j].Price)
-		if priceComp == 0 {
-			return state.BuyOrders[i].Timestamp.Before(state.BuyOrders[j].Timestamp)
-		}
-		return priceComp > 0
+	sort.SliceStable(state.BuyOrders, func(i, j int) bool {
+		return buyHasPriority(&state.BuyOrders[i], &state.BuyOrders[j])
 	})
 
 	// Sort sell orders by price (lowest first), then by timestamp
-	sort.Slice(state.SellOrders, func(i, j int) bool {
-		priceComp := state.SellOrders[i].Price.Cmp(state.SellOrders[j].Price)
-		if priceComp == 0 {
-			return state.SellOrders[i].Timestamp.Before(state.SellOrders[j].Timestamp)
-		}
-		return priceComp < 0
+	sort.SliceStable(state.SellOrders, func(i, j int) bool {
+		return sellHasPriority(&state.SellOrders[i], &state.SellOrders[j])
 	})
 
 	return state, nil
 }
 
+// buyHasPriority reports whether buy order a ranks ahead of b (higher price, then earlier time)
+func buyHasPriority(a, b *Order) bool {
+	priceComp := a.Price.Cmp(b.Price)
+	if priceComp == 0 {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	return priceComp > 0
+}
+
+// sellHasPriority reports whether sell order a ranks ahead of b (lower price, then earlier time)
+func sellHasPriority(a, b *Order) bool {
+	priceComp := a.Price.Cmp(b.Price)
+	if priceComp == 0 {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	return priceComp < 0
+}
+
 // verifyTrade verifies a single trade against the orderbook state
 func (v *OrderbookVerifier) verifyTrade(trade Trade, state *OrderbookState) error {
+	matchType, err := normalizeMatchType(trade.MatchType)
+	if err != nil {
+		return &VerificationError{
+			Err:      ErrInvalidMatch,
+			OrderIDs: []string{trade.BuyOrderID, trade.SellOrderID},
+			Detail:   err.Error(),
+		}
+	}
+
+	// Settlement prices are bound by the same domain as order prices
+	if state.Params != nil {
+		if err := state.Params.CheckPrice(trade.Price); err != nil {
+			return err
+		}
+	}
+
+	// Complementary matches pair two orders of the same side on opposite outcome tokens
+	buyBook, sellBook := state.BuyOrders, state.SellOrders
+	switch matchType {
+	case MatchTypeMint:
+		sellBook = state.BuyOrders
+	case MatchTypeMerge:
+		buyBook = state.SellOrders
+	}
+
 	// Find the buy and sell orders involved in this trade
-	buyOrder, err := v.findOrderByID(trade.BuyOrderID, state.BuyOrders)
+	buyOrder, err := v.findOrderByID(trade.BuyOrderID, buyBook)
 	if err != nil {
-		return fmt.Errorf("buy order not found: %s", trade.BuyOrderID)
+		return &VerificationError{
+			Err:      ErrUnknownOrder,
+			OrderIDs: []string{trade.BuyOrderID},
+			Detail:   fmt.Sprintf("buy order not found: %s", trade.BuyOrderID),
+		}
 	}
 
-	sellOrder, err := v.findOrderByID(trade.SellOrderID, state.SellOrders)
+	sellOrder, err := v.findOrderByID(trade.SellOrderID, sellBook)
 	if err != nil {
-		return fmt.Errorf("sell order not found: %s", trade.SellOrderID)
+		return &VerificationError{
+			Err:      ErrUnknownOrder,
+			OrderIDs: []string{trade.SellOrderID},
+			Detail:   fmt.Sprintf("sell order not found: %s", trade.SellOrderID),
+		}
+	}
+
+	if err := verifyMatchStructure(matchType, buyOrder, sellOrder); err != nil {
+		return &VerificationError{
+			Err:      ErrInvalidMatch,
+			OrderIDs: []string{buyOrder.ID, sellOrder.ID},
+			Detail:   err.Error(),
+		}
+	}
+
+	if v.selfTrade != SelfTradeAllow && isSelfTrade(buyOrder, sellOrder) {
+		return &VerificationError{
+			Err:      ErrSelfTrade,
+			OrderIDs: []string{buyOrder.ID, sellOrder.ID},
+			Detail:   fmt.Sprintf("orders %s and %s both belong to %s under the %s self-trade prevention mode", buyOrder.ID, sellOrder.ID, buyOrder.UserID, v.selfTrade),
+		}
+	}
+
+	// Neither order may have been cancelled or expired when the trade executed
+	for _, order := range []*Order{buyOrder, sellOrder} {
+		if trade.Timestamp.IsZero() && hasLifecycle(order, state.CancelledAt) {
+			return &VerificationError{
+				Err:      ErrInvalidTrade,
+				OrderIDs: []string{order.ID},
+				Detail:   fmt.Sprintf("trade timestamp is required to match order %s, which is cancelled or expires", order.ID),
+			}
+		}
+		if err := checkOrderLive(order, state.CancelledAt, trade.Timestamp); err != nil {
+			return err
+		}
 	}
 
 	// Verify price matching rules
-	if err := v.verifyPriceMatching(trade, buyOrder, sellOrder); err != nil {
-		return fmt.Errorf("price matching failed: %v", err)
+	if err := v.verifyPriceMatching(trade, matchType, buyOrder, sellOrder, state.PriceUnit); err != nil {
+		return err
+	}
+
+	// The execution price policy decides how price improvement is split
+	if err := verifyExecutionPrice(v.executionPrice, v.priceTolerance, trade, buyOrder, sellOrder, state); err != nil {
+		return err
 	}
 
-	// Verify quantity constraints
-	if err := v.verifyQuantityConstraints(trade, buyOrder, sellOrder); err != nil {
-		return fmt.Errorf("quantity constraints failed: %v", err)
+	// Verify quantity constraints against what is left of each order
+	if err := v.verifyQuantityConstraints(trade, buyOrder, sellOrder, state); err != nil {
+		return err
 	}
 
 	// Verify time priority (simplified - assumes orders are already sorted)
 	if err := v.verifyTimePriority(trade, buyOrder, sellOrder, state); err != nil {
-		return fmt.Errorf("time priority failed: %v", err)
+		return err
 	}
 
+	// The trade is valid, so consume the filled quantity from both orders
+	state.Remaining[buyOrder.ID].Sub(state.Remaining[buyOrder.ID], trade.Quantity)
+	state.Remaining[sellOrder.ID].Sub(state.Remaining[sellOrder.ID], trade.Quantity)
+
 	return nil
 }
 
@@ -147,67 +733,163 @@ func (v *OrderbookVerifier) findOrderByID(orderID string, orders []Order) (*Orde
 	return nil, fmt.Errorf("order not found: %s", orderID)
 }
 
-// verifyPriceMatching verifies that the trade price is valid according to limit order rules
-func (v *OrderbookVerifier) verifyPriceMatching(trade Trade, buyOrder, sellOrder *Order) error {
+// verifyPriceMatching verifies that the trade price is valid according to limit order rules.
+// The price is quoted in the priced token; a complementary order must accept unit - price.
+func (v *OrderbookVerifier) verifyPriceMatching(trade Trade, matchType string, buyOrder, sellOrder *Order, unit *big.Int) error {
+	if trade.Price == nil {
+		return &VerificationError{
+			Err:      ErrInvalidTrade,
+			OrderIDs: []string{buyOrder.ID, sellOrder.ID},
+			Detail:   "trade price is required",
+		}
+	}
+
+	// Minting needs the two buyers to fund a full unit of collateral, merging must not
+	// pay the two sellers more than the unit it releases
+	sum := new(big.Int).Add(buyOrder.Price, sellOrder.Price)
+	switch matchType {
+	case MatchTypeMint:
+		if sum.Cmp(unit) < 0 {
+			return &VerificationError{
+				Err:      ErrPriceViolation,
+				OrderIDs: []string{buyOrder.ID, sellOrder.ID},
+				Expected: unit.String(),
+				Actual:   sum.String(),
+				Detail: fmt.Sprintf("mint prices %s + %s sum to less than %s",
+					buyOrder.Price.String(), sellOrder.Price.String(), unit.String()),
+			}
+		}
+	case MatchTypeMerge:
+		if sum.Cmp(unit) > 0 {
+			return &VerificationError{
+				Err:      ErrPriceViolation,
+				OrderIDs: []string{buyOrder.ID, sellOrder.ID},
+				Expected: unit.String(),
+				Actual:   sum.String(),
+				Detail: fmt.Sprintf("merge prices %s + %s sum to more than %s",
+					buyOrder.Price.String(), sellOrder.Price.String(), unit.String()),
+			}
+		}
+	}
+
 	// Buy order price must be >= trade price
-	if buyOrder.Price.Cmp(trade.Price) < 0 {
-		return fmt.Errorf("buy order price %s is less than trade price %s",
-			buyOrder.Price.String(), trade.Price.String())
+	if buyPrice := effectivePrice(buyOrder, "buy", unit); buyPrice.Cmp(trade.Price) < 0 {
+		return &VerificationError{
+			Err:      ErrPriceViolation,
+			OrderIDs: []string{buyOrder.ID},
+			Expected: buyPrice.String(),
+			Actual:   trade.Price.String(),
+			Detail:   fmt.Sprintf("buy order price %s is less than trade price %s", buyPrice.String(), trade.Price.String()),
+		}
 	}
 
 	// Sell order price must be <= trade price
-	if sellOrder.Price.Cmp(trade.Price) > 0 {
-		return fmt.Errorf("sell order price %s is greater than trade price %s",
-			sellOrder.Price.String(), trade.Price.String())
+	if sellPrice := effectivePrice(sellOrder, "sell", unit); sellPrice.Cmp(trade.Price) > 0 {
+		return &VerificationError{
+			Err:      ErrPriceViolation,
+			OrderIDs: []string{sellOrder.ID},
+			Expected: sellPrice.String(),
+			Actual:   trade.Price.String(),
+			Detail:   fmt.Sprintf("sell order price %s is greater than trade price %s", sellPrice.String(), trade.Price.String()),
+		}
 	}
 
-	// Trade price should typically be the worse of the two order prices (price-time priority)
-	// In practice, this is usually the sell order price for a buy-initiated trade
 	return nil
 }
 
-// verifyQuantityConstraints verifies that the trade quantity doesn't exceed order quantities
-func (v *OrderbookVerifier) verifyQuantityConstraints(trade Trade, buyOrder, sellOrder *Order) error {
-	// Trade quantity must not exceed buy order quantity
-	if trade.Quantity.Cmp(buyOrder.Quantity) > 0 {
-		return fmt.Errorf("trade quantity %s exceeds buy order quantity %s",
-			trade.Quantity.String(), buyOrder.Quantity.String())
+// verifyQuantityConstraints verifies that the trade quantity doesn't exceed the remaining order quantities
+func (v *OrderbookVerifier) verifyQuantityConstraints(trade Trade, buyOrder, sellOrder *Order, state *OrderbookState) error {
+	if trade.Quantity == nil || trade.Quantity.Sign() <= 0 {
+		return &VerificationError{
+			Err:      ErrInvalidTrade,
+			OrderIDs: []string{buyOrder.ID, sellOrder.ID},
+			Actual:   fmt.Sprint(trade.Quantity),
+			Detail:   "trade quantity must be positive",
+		}
+	}
+
+	if err := v.verifyRemainingQuantity(trade, "buy", buyOrder, state.Remaining[buyOrder.ID]); err != nil {
+		return err
+	}
+
+	return v.verifyRemainingQuantity(trade, "sell", sellOrder, state.Remaining[sellOrder.ID])
+}
+
+// verifyRemainingQuantity checks a single order's unfilled quantity against the trade quantity
+func (v *OrderbookVerifier) verifyRemainingQuantity(trade Trade, side string, order *Order, remaining *big.Int) error {
+	// A fully filled order cannot take part in any further trades
+	if remaining.Sign() <= 0 {
+		return &VerificationError{
+			Err:      ErrOverfill,
+			OrderIDs: []string{order.ID},
+			Expected: remaining.String(),
+			Actual:   trade.Quantity.String(),
+			Detail:   fmt.Sprintf("%s order %s is already fully filled", side, order.ID),
+		}
 	}
 
-	// Trade quantity must not exceed sell order quantity
-	if trade.Quantity.Cmp(sellOrder.Quantity) > 0 {
-		return fmt.Errorf("trade quantity %s exceeds sell order quantity %s",
-			trade.Quantity.String(), sellOrder.Quantity.String())
+	// Trade quantity must not exceed what is left of the order
+	if trade.Quantity.Cmp(remaining) > 0 {
+		return &VerificationError{
+			Err:      ErrOverfill,
+			OrderIDs: []string{order.ID},
+			Expected: remaining.String(),
+			Actual:   trade.Quantity.String(),
+			Detail: fmt.Sprintf("trade quantity %s overfills %s order %s (remaining %s of %s)",
+				trade.Quantity.String(), side, order.ID, remaining.String(), fmt.Sprint(order.Quantity)),
+		}
 	}
 
 	return nil
 }
 
-// verifyTimePriority verifies that the matched orders respect price-time priority
+// verifyTimePriority verifies that the matched orders respect price-time priority within
+// the book of their own outcome token
 func (v *OrderbookVerifier) verifyTimePriority(trade Trade, buyOrder, sellOrder *Order, state *OrderbookState) error {
-	// For buy orders: check that no earlier buy order at same or better price exists
-	for _, order := range state.BuyOrders {
-		if order.ID == buyOrder.ID {
+	if err := v.verifyOrderPriority(buyOrder, trade.Timestamp, state); err != nil {
+		return err
+	}
+	return v.verifyOrderPriority(sellOrder, trade.Timestamp, state)
+}
+
+// verifyOrderPriority checks that no earlier order at the same or a better price is still
+// resting on the matched order's side of its token's book at the trade time
+func (v *OrderbookVerifier) verifyOrderPriority(matched *Order, at time.Time, state *OrderbookState) error {
+	book := state.BuyOrders
+	if matched.Side == "sell" {
+		book = state.SellOrders
+	}
+
+	for _, order := range book {
+		if order.ID == matched.ID {
 			break // We've reached the matched order, so priority is respected
 		}
-		if order.Price.Cmp(buyOrder.Price) >= 0 && order.Timestamp.Before(buyOrder.Timestamp) {
-			// There's an earlier order at same or better price that should have been matched first
-			return fmt.Errorf("buy order %s has priority over %s (price: %s vs %s, time: %v vs %v)",
-				order.ID, buyOrder.ID, order.Price.String(), buyOrder.Price.String(),
-				order.Timestamp, buyOrder.Timestamp)
+		if order.TokenID != matched.TokenID {
+			continue // Each outcome token has its own book
+		}
+		if state.Remaining[order.ID].Sign() <= 0 {
+			continue // Already consumed by earlier trades in the batch
+		}
+		if checkOrderLive(&order, state.CancelledAt, at) != nil {
+			continue // Cancelled or expired before the trade
 		}
-	}
 
-	// For sell orders: check that no earlier sell order at same or better price exists
-	for _, order := range state.SellOrders {
-		if order.ID == sellOrder.ID {
-			break // We've reached the matched order, so priority is respected
+		priceComp := order.Price.Cmp(matched.Price)
+		atLeastAsGood := priceComp >= 0
+		if matched.Side == "sell" {
+			atLeastAsGood = priceComp <= 0
 		}
-		if order.Price.Cmp(sellOrder.Price) <= 0 && order.Timestamp.Before(sellOrder.Timestamp) {
+		if atLeastAsGood && order.Timestamp.Before(matched.Timestamp) {
 			// There's an earlier order at same or better price that should have been matched first
-			return fmt.Errorf("sell order %s has priority over %s (price: %s vs %s, time: %v vs %v)",
-				order.ID, sellOrder.ID, order.Price.String(), sellOrder.Price.String(),
-				order.Timestamp, sellOrder.Timestamp)
+			return &VerificationError{
+				Err:      ErrPriorityViolation,
+				OrderIDs: []string{matched.ID, order.ID},
+				Expected: order.ID,
+				Actual:   matched.ID,
+				Detail: fmt.Sprintf("%s order %s has priority over %s (price: %s vs %s, time: %v vs %v)",
+					matched.Side, order.ID, matched.ID, order.Price.String(), matched.Price.String(),
+					order.Timestamp, matched.Timestamp),
+			}
 		}
 	}
 
@@ -1,10 +1,13 @@
 package orderbookchecker
 
 import (
+	"errors"
 	"math/big"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
 	"go.uber.org/zap"
 )
 
@@ -15,7 +18,7 @@ func TestOrderbookVerifier_VerifySnapshot_ValidTrade(t *testing.T) {
 	// Create a simple orderbook snapshot
 	snapshot := OrderbookSnapshot{
 		SequenceNumber: 1,
-		Timestamp:      time.Now(),
+		Timestamp:      time.Now().Truncate(time.Millisecond),
 		MarketID:       "BTC-USD",
 		Orders: []Order{
 			{
@@ -23,7 +26,7 @@ func TestOrderbookVerifier_VerifySnapshot_ValidTrade(t *testing.T) {
 				Side:      "buy",
 				Price:     big.NewInt(50200), // Higher than sell price to allow matching
 				Quantity:  big.NewInt(1000),
-				Timestamp: time.Now().Add(-2 * time.Minute),
+				Timestamp: time.Now().Truncate(time.Millisecond).Add(-2 * time.Minute),
 				UserID:    "user1",
 			},
 			{
@@ -31,7 +34,7 @@ func TestOrderbookVerifier_VerifySnapshot_ValidTrade(t *testing.T) {
 				Side:      "sell",
 				Price:     big.NewInt(50100),
 				Quantity:  big.NewInt(800),
-				Timestamp: time.Now().Add(-1 * time.Minute),
+				Timestamp: time.Now().Truncate(time.Millisecond).Add(-1 * time.Minute),
 				UserID:    "user2",
 			},
 		},
@@ -47,7 +50,7 @@ func TestOrderbookVerifier_VerifySnapshot_ValidTrade(t *testing.T) {
 			SellOrderID: "sell-1",
 			Price:       big.NewInt(50100), // Match sell price (price-time priority)
 			Quantity:    big.NewInt(800),   // Match sell quantity
-			Timestamp:   time.Now(),
+			Timestamp:   time.Now().Truncate(time.Millisecond),
 			TxHash:      "0x123",
 			BlockNumber: 1000,
 		},
@@ -78,7 +81,7 @@ func TestOrderbookVerifier_VerifySnapshot_InvalidPrice(t *testing.T) {
 	// Create orderbook snapshot
 	snapshot := OrderbookSnapshot{
 		SequenceNumber: 1,
-		Timestamp:      time.Now(),
+		Timestamp:      time.Now().Truncate(time.Millisecond),
 		MarketID:       "BTC-USD",
 		Orders: []Order{
 			{
@@ -86,7 +89,7 @@ func TestOrderbookVerifier_VerifySnapshot_InvalidPrice(t *testing.T) {
 				Side:      "buy",
 				Price:     big.NewInt(50200), // Higher than sell price to allow matching
 				Quantity:  big.NewInt(1000),
-				Timestamp: time.Now().Add(-2 * time.Minute),
+				Timestamp: time.Now().Truncate(time.Millisecond).Add(-2 * time.Minute),
 				UserID:    "user1",
 			},
 			{
@@ -94,7 +97,7 @@ func TestOrderbookVerifier_VerifySnapshot_InvalidPrice(t *testing.T) {
 				Side:      "sell",
 				Price:     big.NewInt(50100),
 				Quantity:  big.NewInt(800),
-				Timestamp: time.Now().Add(-1 * time.Minute),
+				Timestamp: time.Now().Truncate(time.Millisecond).Add(-1 * time.Minute),
 				UserID:    "user2",
 			},
 		},
@@ -110,7 +113,7 @@ func TestOrderbookVerifier_VerifySnapshot_InvalidPrice(t *testing.T) {
 			SellOrderID: "sell-1",
 			Price:       big.NewInt(49000), // Invalid: below buy order price
 			Quantity:    big.NewInt(800),
-			Timestamp:   time.Now(),
+			Timestamp:   time.Now().Truncate(time.Millisecond),
 			TxHash:      "0x123",
 			BlockNumber: 1000,
 		},
@@ -137,7 +140,7 @@ func TestOrderbookVerifier_VerifySnapshot_ExcessiveQuantity(t *testing.T) {
 	// Create orderbook snapshot
 	snapshot := OrderbookSnapshot{
 		SequenceNumber: 1,
-		Timestamp:      time.Now(),
+		Timestamp:      time.Now().Truncate(time.Millisecond),
 		MarketID:       "BTC-USD",
 		Orders: []Order{
 			{
@@ -145,7 +148,7 @@ func TestOrderbookVerifier_VerifySnapshot_ExcessiveQuantity(t *testing.T) {
 				Side:      "buy",
 				Price:     big.NewInt(50200), // Higher than sell price to allow matching
 				Quantity:  big.NewInt(500),   // Small quantity
-				Timestamp: time.Now().Add(-2 * time.Minute),
+				Timestamp: time.Now().Truncate(time.Millisecond).Add(-2 * time.Minute),
 				UserID:    "user1",
 			},
 			{
@@ -153,7 +156,7 @@ func TestOrderbookVerifier_VerifySnapshot_ExcessiveQuantity(t *testing.T) {
 				Side:      "sell",
 				Price:     big.NewInt(50100),
 				Quantity:  big.NewInt(800),
-				Timestamp: time.Now().Add(-1 * time.Minute),
+				Timestamp: time.Now().Truncate(time.Millisecond).Add(-1 * time.Minute),
 				UserID:    "user2",
 			},
 		},
@@ -169,7 +172,7 @@ func TestOrderbookVerifier_VerifySnapshot_ExcessiveQuantity(t *testing.T) {
 			SellOrderID: "sell-1",
 			Price:       big.NewInt(50100),
 			Quantity:    big.NewInt(600), // Exceeds buy order quantity of 500
-			Timestamp:   time.Now(),
+			Timestamp:   time.Now().Truncate(time.Millisecond),
 			TxHash:      "0x123",
 			BlockNumber: 1000,
 		},
@@ -196,7 +199,7 @@ func TestOrderbookVerifier_VerifySnapshot_MissingOrder(t *testing.T) {
 	// Create orderbook snapshot with only one order
 	snapshot := OrderbookSnapshot{
 		SequenceNumber: 1,
-		Timestamp:      time.Now(),
+		Timestamp:      time.Now().Truncate(time.Millisecond),
 		MarketID:       "BTC-USD",
 		Orders: []Order{
 			{
@@ -204,7 +207,7 @@ func TestOrderbookVerifier_VerifySnapshot_MissingOrder(t *testing.T) {
 				Side:      "buy",
 				Price:     big.NewInt(50000),
 				Quantity:  big.NewInt(1000),
-				Timestamp: time.Now().Add(-2 * time.Minute),
+				Timestamp: time.Now().Truncate(time.Millisecond).Add(-2 * time.Minute),
 				UserID:    "user1",
 			},
 		},
@@ -220,7 +223,7 @@ func TestOrderbookVerifier_VerifySnapshot_MissingOrder(t *testing.T) {
 			SellOrderID: "sell-nonexistent",
 			Price:       big.NewInt(50100),
 			Quantity:    big.NewInt(800),
-			Timestamp:   time.Now(),
+			Timestamp:   time.Now().Truncate(time.Millisecond),
 			TxHash:      "0x123",
 			BlockNumber: 1000,
 		},
@@ -250,7 +253,7 @@ func TestOrderbookVerifier_BuildOrderbookState(t *testing.T) {
 			Side:      "buy",
 			Price:     big.NewInt(52000),
 			Quantity:  big.NewInt(1000),
-			Timestamp: time.Now().Add(-3 * time.Minute),
+			Timestamp: time.Now().Truncate(time.Millisecond).Add(-3 * time.Minute),
 			UserID:    "user1",
 		},
 		{
@@ -258,7 +261,7 @@ func TestOrderbookVerifier_BuildOrderbookState(t *testing.T) {
 			Side:      "buy",
 			Price:     big.NewInt(50000),
 			Quantity:  big.NewInt(1000),
-			Timestamp: time.Now().Add(-2 * time.Minute),
+			Timestamp: time.Now().Truncate(time.Millisecond).Add(-2 * time.Minute),
 			UserID:    "user2",
 		},
 		{
@@ -266,7 +269,7 @@ func TestOrderbookVerifier_BuildOrderbookState(t *testing.T) {
 			Side:      "sell",
 			Price:     big.NewInt(50100),
 			Quantity:  big.NewInt(800),
-			Timestamp: time.Now().Add(-1 * time.Minute),
+			Timestamp: time.Now().Truncate(time.Millisecond).Add(-1 * time.Minute),
 			UserID:    "user3",
 		},
 		{
@@ -274,12 +277,12 @@ func TestOrderbookVerifier_BuildOrderbookState(t *testing.T) {
 			Side:      "sell",
 			Price:     big.NewInt(52100),
 			Quantity:  big.NewInt(800),
-			Timestamp: time.Now(),
+			Timestamp: time.Now().Truncate(time.Millisecond),
 			UserID:    "user4",
 		},
 	}
 
-	state, err := verifier.buildOrderbookState(orders)
+	state, err := verifier.buildOrderbookState(orders, nil)
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
 	}
@@ -315,12 +318,12 @@ func TestOrderbookVerifier_TimePriority(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	verifier := NewOrderbookVerifier(logger)
 
-	baseTime := time.Now().Add(-10 * time.Minute)
+	baseTime := time.Now().Truncate(time.Millisecond).Add(-10 * time.Minute)
 
 	// Create orderbook with multiple orders at same price
 	snapshot := OrderbookSnapshot{
 		SequenceNumber: 1,
-		Timestamp:      time.Now(),
+		Timestamp:      time.Now().Truncate(time.Millisecond),
 		MarketID:       "BTC-USD",
 		Orders: []Order{
 			{
@@ -360,7 +363,7 @@ func TestOrderbookVerifier_TimePriority(t *testing.T) {
 			SellOrderID: "sell-1",
 			Price:       big.NewInt(50100),
 			Quantity:    big.NewInt(800),
-			Timestamp:   time.Now(),
+			Timestamp:   time.Now().Truncate(time.Millisecond),
 			TxHash:      "0x123",
 			BlockNumber: 1000,
 		},
@@ -375,3 +378,526 @@ func TestOrderbookVerifier_TimePriority(t *testing.T) {
 		t.Errorf("Expected valid result for time priority respected, got invalid: %s", result.ErrorMessage)
 	}
 }
+
+func TestOrderbookVerifier_VerifySnapshot_CumulativeOverfill(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	// Create orderbook snapshot with a single large sell order
+	snapshot := OrderbookSnapshot{
+		SequenceNumber: 1,
+		Timestamp:      time.Now().Truncate(time.Millisecond),
+		MarketID:       "BTC-USD",
+		Orders: []Order{
+			{
+				ID:        "buy-1",
+				Side:      "buy",
+				Price:     big.NewInt(50200),
+				Quantity:  big.NewInt(5000),
+				Timestamp: time.Now().Truncate(time.Millisecond).Add(-2 * time.Minute),
+				UserID:    "user1",
+			},
+			{
+				ID:        "sell-1",
+				Side:      "sell",
+				Price:     big.NewInt(50100),
+				Quantity:  big.NewInt(1000),
+				Timestamp: time.Now().Truncate(time.Millisecond).Add(-1 * time.Minute),
+				UserID:    "user2",
+			},
+		},
+		MerkleRoot: "test-merkle-root",
+		PrevHash:   "test-prev-hash",
+	}
+
+	// Each trade fits within the original sell quantity, but together they overfill it
+	trades := []Trade{
+		{
+			ID:          "trade-1",
+			BuyOrderID:  "buy-1",
+			SellOrderID: "sell-1",
+			Price:       big.NewInt(50100),
+			Quantity:    big.NewInt(800),
+			Timestamp:   time.Now().Truncate(time.Millisecond),
+			TxHash:      "0x123",
+			BlockNumber: 1000,
+		},
+		{
+			ID:          "trade-2",
+			BuyOrderID:  "buy-1",
+			SellOrderID: "sell-1",
+			Price:       big.NewInt(50100),
+			Quantity:    big.NewInt(800), // Only 200 left on sell-1
+			Timestamp:   time.Now().Truncate(time.Millisecond),
+			TxHash:      "0x124",
+			BlockNumber: 1001,
+		},
+		{
+			ID:          "trade-3",
+			BuyOrderID:  "buy-1",
+			SellOrderID: "sell-1",
+			Price:       big.NewInt(50100),
+			Quantity:    big.NewInt(200), // Exactly fills sell-1
+			Timestamp:   time.Now().Truncate(time.Millisecond),
+			TxHash:      "0x125",
+			BlockNumber: 1002,
+		},
+		{
+			ID:          "trade-4",
+			BuyOrderID:  "buy-1",
+			SellOrderID: "sell-1",
+			Price:       big.NewInt(50100),
+			Quantity:    big.NewInt(1), // sell-1 is already fully filled
+			Timestamp:   time.Now().Truncate(time.Millisecond),
+			TxHash:      "0x126",
+			BlockNumber: 1003,
+		},
+	}
+
+	result, err := verifier.VerifySnapshot(trades, snapshot)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Valid {
+		t.Error("Expected invalid result due to cumulative overfill")
+	}
+
+	if result.VerifiedTrades != 2 {
+		t.Errorf("Expected 2 verified trades, got %d", result.VerifiedTrades)
+	}
+
+	if len(result.FailedTrades) != 2 || result.FailedTrades[0] != "trade-2" || result.FailedTrades[1] != "trade-4" {
+		t.Errorf("Expected trade-2 and trade-4 to fail, got %v", result.FailedTrades)
+	}
+
+	if got := result.ResidualQuantities["sell-1"]; got == nil || got.Sign() != 0 {
+		t.Errorf("Expected sell-1 residual of 0, got %v", got)
+	}
+
+	if got := result.ResidualQuantities["buy-1"]; got == nil || got.Cmp(big.NewInt(4000)) != 0 {
+		t.Errorf("Expected buy-1 residual of 4000, got %v", got)
+	}
+}
+
+func TestOrderbookVerifier_TimePriority_ConsumedOrderSkipped(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond).Add(-10 * time.Minute)
+
+	snapshot := OrderbookSnapshot{
+		SequenceNumber: 1,
+		Timestamp:      time.Now().Truncate(time.Millisecond),
+		MarketID:       "BTC-USD",
+		Orders: []Order{
+			{
+				ID:        "buy-early",
+				Side:      "buy",
+				Price:     big.NewInt(50200),
+				Quantity:  big.NewInt(500),
+				Timestamp: baseTime,
+				UserID:    "user1",
+			},
+			{
+				ID:        "buy-late",
+				Side:      "buy",
+				Price:     big.NewInt(50200),
+				Quantity:  big.NewInt(1000),
+				Timestamp: baseTime.Add(1 * time.Minute),
+				UserID:    "user2",
+			},
+			{
+				ID:        "sell-1",
+				Side:      "sell",
+				Price:     big.NewInt(50100),
+				Quantity:  big.NewInt(800),
+				Timestamp: baseTime.Add(2 * time.Minute),
+				UserID:    "user3",
+			},
+		},
+		MerkleRoot: "test-merkle-root",
+		PrevHash:   "test-prev-hash",
+	}
+
+	// Once buy-early is exhausted, buy-late is next in line
+	trades := []Trade{
+		{
+			ID:          "trade-1",
+			BuyOrderID:  "buy-early",
+			SellOrderID: "sell-1",
+			Price:       big.NewInt(50100),
+			Quantity:    big.NewInt(500),
+			Timestamp:   time.Now().Truncate(time.Millisecond),
+			TxHash:      "0x123",
+			BlockNumber: 1000,
+		},
+		{
+			ID:          "trade-2",
+			BuyOrderID:  "buy-late",
+			SellOrderID: "sell-1",
+			Price:       big.NewInt(50100),
+			Quantity:    big.NewInt(300),
+			Timestamp:   time.Now().Truncate(time.Millisecond),
+			TxHash:      "0x124",
+			BlockNumber: 1001,
+		},
+	}
+
+	result, err := verifier.VerifySnapshot(trades, snapshot)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !result.Valid {
+		t.Errorf("Expected valid result once earlier order is consumed, got invalid: %s", result.ErrorMessage)
+	}
+}
+
+func replaySnapshot(baseTime time.Time) OrderbookSnapshot {
+	return OrderbookSnapshot{
+		SequenceNumber: 1,
+		Timestamp:      baseTime,
+		MarketID:       "BTC-USD",
+		Orders: []Order{
+			{
+				ID:        "sell-early",
+				Side:      "sell",
+				Price:     big.NewInt(50100),
+				Quantity:  big.NewInt(500),
+				Timestamp: baseTime.Add(-3 * time.Minute),
+				UserID:    "user1",
+			},
+			{
+				ID:        "sell-late",
+				Side:      "sell",
+				Price:     big.NewInt(50100),
+				Quantity:  big.NewInt(500),
+				Timestamp: baseTime.Add(-2 * time.Minute),
+				UserID:    "user2",
+			},
+			{
+				ID:        "sell-high",
+				Side:      "sell",
+				Price:     big.NewInt(50300),
+				Quantity:  big.NewInt(500),
+				Timestamp: baseTime.Add(-4 * time.Minute),
+				UserID:    "user3",
+			},
+		},
+		MerkleRoot: "test-merkle-root",
+		PrevHash:   "test-prev-hash",
+	}
+}
+
+func TestOrderbookVerifier_VerifyReplay_MatchingFills(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	snapshot := replaySnapshot(baseTime)
+
+	// Incoming buy sweeps both orders at 50100 in time order and rests the remainder
+	incoming := []Order{
+		{
+			ID:        "buy-1",
+			Side:      "buy",
+			Price:     big.NewInt(50200),
+			Quantity:  big.NewInt(800),
+			Timestamp: baseTime.Add(1 * time.Second),
+			UserID:    "user4",
+		},
+	}
+
+	trades := []Trade{
+		{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-early", Price: big.NewInt(50100), Quantity: big.NewInt(500)},
+		{ID: "trade-2", BuyOrderID: "buy-1", SellOrderID: "sell-late", Price: big.NewInt(50100), Quantity: big.NewInt(300)},
+	}
+
+	result, err := verifier.VerifyReplay(trades, snapshot, incoming)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !result.Valid {
+		t.Fatalf("Expected valid replay, got invalid: %s", result.ErrorMessage)
+	}
+
+	if result.VerifiedTrades != 2 {
+		t.Errorf("Expected 2 verified trades, got %d", result.VerifiedTrades)
+	}
+
+	if got := result.ResidualQuantities["sell-late"]; got == nil || got.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("Expected sell-late residual of 200, got %v", got)
+	}
+}
+
+func TestOrderbookVerifier_VerifyReplay_DetectsSkippedAndPhantom(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	snapshot := replaySnapshot(baseTime)
+
+	incoming := []Order{
+		{
+			ID:        "buy-1",
+			Side:      "buy",
+			Price:     big.NewInt(50200),
+			Quantity:  big.NewInt(500),
+			Timestamp: baseTime.Add(1 * time.Second),
+			UserID:    "user4",
+		},
+	}
+
+	// sell-early is skipped in favour of sell-late, and an extra trade is fabricated
+	trades := []Trade{
+		{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-late", Price: big.NewInt(50100), Quantity: big.NewInt(500)},
+		{ID: "trade-2", BuyOrderID: "buy-1", SellOrderID: "sell-high", Price: big.NewInt(50300), Quantity: big.NewInt(100)},
+	}
+
+	result, err := verifier.VerifyReplay(trades, snapshot, incoming)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Valid {
+		t.Fatal("Expected invalid replay result")
+	}
+
+	if len(result.FillDiffs) != 2 {
+		t.Fatalf("Expected 2 fill diffs, got %d", len(result.FillDiffs))
+	}
+
+	if result.FillDiffs[0].Kind != FillDiffMismatch || result.FillDiffs[0].Expected.SellOrderID != "sell-early" {
+		t.Errorf("Expected mismatch against sell-early, got %+v", result.FillDiffs[0])
+	}
+
+	if result.FillDiffs[1].Kind != FillDiffPhantom {
+		t.Errorf("Expected phantom trade, got %s", result.FillDiffs[1].Kind)
+	}
+
+	if len(result.FailedTrades) != 2 {
+		t.Errorf("Expected 2 failed trades, got %d", len(result.FailedTrades))
+	}
+}
+
+func TestOrderbookVerifier_TradeFindings(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	verifier := NewOrderbookVerifier(logger)
+
+	baseTime := time.Now().Truncate(time.Millisecond)
+	snapshot := replaySnapshot(baseTime)
+	snapshot.Orders = append(snapshot.Orders, Order{
+		ID:        "buy-1",
+		Side:      "buy",
+		Price:     big.NewInt(50200),
+		Quantity:  big.NewInt(800),
+		Timestamp: baseTime.Add(-time.Minute),
+		UserID:    "user4",
+	})
+
+	trade := func(sellID string, price, qty int64) Trade {
+		return Trade{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: sellID, Price: big.NewInt(price), Quantity: big.NewInt(qty), Timestamp: baseTime}
+	}
+
+	tests := []struct {
+		name         string
+		trade        Trade
+		sentinel     error
+		wantCode     string
+		wantOrderIDs []string
+		wantExpected string
+		wantActual   string
+	}{
+		{"unknown order", trade("sell-missing", 50100, 100), ErrUnknownOrder, ErrorCodeUnknownOrder, []string{"sell-missing"}, "", ""},
+		{"price above the buy limit", trade("sell-high", 50300, 100), ErrPriceViolation, ErrorCodePriceViolation, []string{"buy-1"}, "50200", "50300"},
+		{"overfilled sell order", trade("sell-early", 50100, 600), ErrOverfill, ErrorCodeOverfill, []string{"sell-early"}, "500", "600"},
+		{"later order matched first", trade("sell-late", 50100, 300), ErrPriorityViolation, ErrorCodePriorityViolation, []string{"sell-late", "sell-early"}, "sell-early", "sell-late"},
+		{"zero quantity", trade("sell-early", 50100, 0), ErrInvalidTrade, ErrorCodeInvalidTrade, []string{"buy-1", "sell-early"}, "", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := verifier.buildOrderbookState(snapshot.Orders, nil)
+			if err != nil {
+				t.Fatalf("buildOrderbookState failed: %v", err)
+			}
+
+			verifyErr := verifier.verifyTrade(tt.trade, state)
+			if !errors.Is(verifyErr, tt.sentinel) {
+				t.Fatalf("Expected error wrapping %v, got: %v", tt.sentinel, verifyErr)
+			}
+			var verr *VerificationError
+			if !errors.As(verifyErr, &verr) {
+				t.Fatalf("Expected a VerificationError, got %T", verifyErr)
+			}
+
+			result, err := verifier.VerifySnapshot([]Trade{tt.trade}, snapshot)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result.Valid || result.ErrorCode != tt.wantCode {
+				t.Errorf("Expected invalid result with code %s, got valid=%v code %q", tt.wantCode, result.Valid, result.ErrorCode)
+			}
+			if len(result.Findings) != 1 {
+				t.Fatalf("Expected 1 finding, got %+v", result.Findings)
+			}
+
+			finding := result.Findings[0]
+			if finding.Code != tt.wantCode || finding.TradeID != tt.trade.ID {
+				t.Errorf("Expected finding %s for %s, got %s for %s", tt.wantCode, tt.trade.ID, finding.Code, finding.TradeID)
+			}
+			if !slices.Equal(finding.OrderIDs, tt.wantOrderIDs) {
+				t.Errorf("Expected order IDs %v, got %v", tt.wantOrderIDs, finding.OrderIDs)
+			}
+			if finding.Expected != tt.wantExpected || finding.Actual != tt.wantActual {
+				t.Errorf("Expected %q vs %q, got %q vs %q", tt.wantExpected, tt.wantActual, finding.Expected, finding.Actual)
+			}
+		})
+	}
+}
+
+func TestOrderInclusionProof(t *testing.T) {
+	baseTime := time.Now().Truncate(time.Millisecond)
+	orders := replaySnapshot(baseTime).Orders
+
+	root, err := ComputeMerkleRoot(orders)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	proof, err := ProveOrderInclusion(orders, "sell-late")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	ok, err := VerifyOrderInclusion(root, orders[1], proof)
+	if err != nil || !ok {
+		t.Errorf("Expected inclusion proof to verify, got ok=%v err=%v", ok, err)
+	}
+
+	// Changing any committed field must invalidate the proof
+	tampered := orders[1]
+	tampered.Quantity = big.NewInt(1)
+	if ok, _ := VerifyOrderInclusion(root, tampered, proof); ok {
+		t.Error("Expected inclusion proof to fail for a tampered order")
+	}
+}
+
+func TestOrderbookVerifier_RulesAndTolerances(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	baseTime := time.Now().Truncate(time.Millisecond)
+	before := baseTime.Add(-time.Second)
+
+	// The FOK bid for 600 is only filled for 500
+	partialFOK := func(v *OrderbookVerifier) (*VerificationResult, error) {
+		return v.VerifySnapshot([]Trade{
+			{ID: "t-1", BuyOrderID: "buy-gtc", SellOrderID: "sell-gtd", Price: big.NewInt(50000), Quantity: big.NewInt(500), Timestamp: before},
+			{ID: "t-2", BuyOrderID: "buy-fok", SellOrderID: "sell-gtc", Price: big.NewInt(50100), Quantity: big.NewInt(500), Timestamp: before},
+		}, lifecycleSnapshot(baseTime))
+	}
+	// The NO bid at 0.42 crosses the NO ask at 0.38
+	crossedSnapshot := func(v *OrderbookVerifier) (*VerificationResult, error) {
+		return v.VerifyReplay(nil, outcomeSnapshot(baseTime), nil)
+	}
+	// 50 tokens at 38 cents with a 1% taker fee owe 19 cents
+	takerFee := func(fee *big.Int) func(v *OrderbookVerifier) (*VerificationResult, error) {
+		return func(v *OrderbookVerifier) (*VerificationResult, error) {
+			return v.VerifySnapshot([]Trade{{ID: "trade-1", BuyOrderID: "no-buy-42", SellOrderID: "no-sell-38",
+				Price: cents(38), Quantity: shares(50), MakerOrderID: "no-sell-38", TakerFee: fee}}, feeSnapshot(baseTime))
+		}
+	}
+	// The maker policy owes the selling taker the resting bid's 50201
+	makerPrice := func(price int64) func(v *OrderbookVerifier) (*VerificationResult, error) {
+		return func(v *OrderbookVerifier) (*VerificationResult, error) {
+			return v.VerifySnapshot([]Trade{{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1",
+				Price: big.NewInt(price), Quantity: big.NewInt(100)}}, OrderbookSnapshot{
+				SequenceNumber: 1,
+				Timestamp:      baseTime,
+				MarketID:       "BTC-USD",
+				Orders: []Order{
+					{ID: "buy-1", Side: "buy", Price: big.NewInt(50201), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-2 * time.Minute)},
+					{ID: "sell-1", Side: "sell", Price: big.NewInt(50000), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-time.Minute)},
+				},
+			})
+		}
+	}
+
+	tests := []struct {
+		name     string
+		opts     []VerifierOption
+		verify   func(*OrderbookVerifier) (*VerificationResult, error)
+		wantCode string
+	}{
+		{"fill-or-kill enforced", nil, partialFOK, ErrorCodeFOKPartialFill},
+		{"fill-or-kill disabled", []VerifierOption{WithDisabledRules(RuleFillOrKill)}, partialFOK, ""},
+		{"crossed book enforced", nil, crossedSnapshot, ErrorCodeCrossedBook},
+		{"crossed book disabled", []VerifierOption{WithDisabledRules(RuleResidualBook)}, crossedSnapshot, ""},
+		{"fee overcharged", nil, takerFee(cents(20)), ErrorCodeFeeDiscrepancy},
+		{"fee within tolerance", []VerifierOption{WithFeeTolerance(cents(1))}, takerFee(cents(20)), ""},
+		{"fee beyond tolerance", []VerifierOption{WithFeeTolerance(cents(1))}, takerFee(cents(21)), ErrorCodeFeeDiscrepancy},
+		{"fees disabled", []VerifierOption{WithDisabledRules(RuleFees)}, takerFee(cents(30)), ""},
+		{"price within tolerance", []VerifierOption{WithExecutionPricePolicy(ExecutionPriceMaker), WithPriceTolerance(big.NewInt(1))},
+			makerPrice(50200), ""},
+		{"price beyond tolerance", []VerifierOption{WithExecutionPricePolicy(ExecutionPriceMaker), WithPriceTolerance(big.NewInt(1))},
+			makerPrice(50199), ErrorCodeExecutionPrice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.verify(NewOrderbookVerifier(logger, tt.opts...))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result.Valid != (tt.wantCode == "") {
+				t.Errorf("Expected valid=%v, got %v: %s", tt.wantCode == "", result.Valid, result.ErrorMessage)
+			}
+			if result.ErrorCode != tt.wantCode {
+				t.Errorf("Expected error code %q, got %q", tt.wantCode, result.ErrorCode)
+			}
+		})
+	}
+
+	for name, opt := range map[string]VerifierOption{
+		"unknown rule":             WithDisabledRules("signatures"),
+		"negative fee tolerance":   WithFeeTolerance(big.NewInt(-1)),
+		"negative price tolerance": WithPriceTolerance(big.NewInt(-1)),
+	} {
+		if err := NewOrderbookVerifier(logger, opt).ValidateOptions(); err == nil {
+			t.Errorf("%s: expected ValidateOptions to fail", name)
+		}
+	}
+}
+
+func TestOrderbookVerifier_PolicyHash(t *testing.T) {
+	logger := zap.NewNop()
+	policy := func(opts ...VerifierOption) merkle.Hash {
+		return NewOrderbookVerifier(logger, opts...).PolicyHash()
+	}
+
+	// Explicit defaults and the order rules are disabled in do not change the policy
+	defaults := policy()
+	if got := policy(WithExecutionPricePolicy(ExecutionPriceAny), WithSelfTradePrevention(SelfTradeAllow), WithFeeTolerance(big.NewInt(0))); got != defaults {
+		t.Errorf("Expected explicit defaults to hash as the defaults, got %s and %s", got, defaults)
+	}
+	if a, b := policy(WithDisabledRules(RuleFees, RuleFillOrKill)), policy(WithDisabledRules(RuleFillOrKill), WithDisabledRules(RuleFees)); a != b {
+		t.Errorf("Expected the disabled rules to hash independently of their order, got %s and %s", a, b)
+	}
+
+	seen := map[merkle.Hash]string{defaults: "defaults"}
+	for name, opt := range map[string]VerifierOption{
+		"execution price": WithExecutionPricePolicy(ExecutionPriceMaker),
+		"self-trade":      WithSelfTradePrevention(SelfTradeCancelIncoming),
+		"signed orders":   WithSignedOrders(CTFExchangeDomain),
+		"disabled rule":   WithDisabledRules(RuleResidualBook),
+		"fee tolerance":   WithFeeTolerance(big.NewInt(1)),
+		"price tolerance": WithPriceTolerance(big.NewInt(1)),
+	} {
+		hash := policy(opt)
+		if other, ok := seen[hash]; ok {
+			t.Errorf("%s: expected a policy hash distinct from %s", name, other)
+		}
+		seen[hash] = name
+	}
+}
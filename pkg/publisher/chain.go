@@ -0,0 +1,77 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/store"
+)
+
+// VerifyChainDir verifies the snapshot chain of every market in a local snapshot directory
+func VerifyChainDir(dir string) ([]*orderbookchecker.ChainReport, error) {
+	return VerifyChains(context.Background(), store.NewFSStore(dir))
+}
+
+// VerifyChains verifies the snapshot chain of every market in the store, returning one
+// report per market sorted by market ID
+func VerifyChains(ctx context.Context, snapshotStore store.SnapshotStore) ([]*orderbookchecker.ChainReport, error) {
+	markets, err := snapshotStore.ListMarkets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*orderbookchecker.ChainReport, 0, len(markets))
+	for _, marketID := range markets {
+		report, err := VerifyChainStore(ctx, snapshotStore, marketID)
+		if err != nil {
+			return nil, fmt.Errorf("market %s: %v", marketID, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// VerifyChainStore walks the committed snapshots of a market in sequence order and verifies
+// that they form a single hash chain. Snapshots that cannot be loaded or whose content does
+// not match their sequence are reported as issues alongside the chain problems found by
+// orderbookchecker.VerifyChain.
+func VerifyChainStore(ctx context.Context, snapshotStore store.SnapshotStore, marketID string) (*orderbookchecker.ChainReport, error) {
+	sequences, err := snapshotStore.List(ctx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		snapshots []orderbookchecker.OrderbookSnapshot
+		issues    []orderbookchecker.ChainIssue
+	)
+	for _, seq := range sequences {
+		snapshot, err := snapshotStore.Get(ctx, marketID, seq)
+		if err != nil {
+			issues = append(issues, orderbookchecker.ChainIssue{
+				Kind:           orderbookchecker.ChainIssueInvalid,
+				SequenceNumber: seq,
+				Message:        fmt.Sprintf("failed to load snapshot: %v", err),
+			})
+			continue
+		}
+
+		if snapshot.SequenceNumber != seq {
+			issues = append(issues, orderbookchecker.ChainIssue{
+				Kind:           orderbookchecker.ChainIssueReordered,
+				SequenceNumber: snapshot.SequenceNumber,
+				Message:        fmt.Sprintf("sequence %d is stored as sequence %d", snapshot.SequenceNumber, seq),
+			})
+		}
+
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	report := orderbookchecker.VerifyChain(snapshots)
+	report.MarketID = marketID
+	report.Snapshots = len(sequences)
+	report.Issues = append(issues, report.Issues...)
+	report.Valid = len(report.Issues) == 0
+	return report, nil
+}
@@ -0,0 +1,99 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/store"
+)
+
+var (
+	// ErrSnapshotExists is returned when publishing would overwrite an existing sequence
+	ErrSnapshotExists = store.ErrSnapshotExists
+	// ErrCorruptSnapshot is returned by Recover when the last snapshot cannot be loaded
+	ErrCorruptSnapshot = errors.New("corrupt snapshot")
+)
+
+// Recover restores the sequence number and previous hash from the committed snapshots of
+// a market already in the store, so a restarted publisher continues the existing chain.
+// Uncommitted files left by a crash are ignored and overwritten by the next publish.
+// If the last snapshot cannot be loaded Recover fails with ErrCorruptSnapshot, unless
+// repair is set and the store supports it, in which case the corrupt sequence is moved
+// aside and recovery resumes from the snapshot before it.
+func (sp *SnapshotPublisher) Recover(marketID string, repair bool) error {
+	ctx := context.Background()
+
+	sequences, err := sp.store.List(ctx, marketID)
+	if err != nil {
+		return err
+	}
+
+	for i := len(sequences) - 1; i >= 0; i-- {
+		seq := sequences[i]
+
+		hash, err := sp.hashStoredSnapshot(marketID, seq)
+		if err == nil {
+			sp.chains[marketID] = &chainState{sequenceNum: seq + 1, prevHash: hash}
+
+			sp.logger.Sugar().Infow("Recovered publisher state",
+				"market_id", marketID,
+				"last_sequence", seq,
+				"prev_hash", hash,
+			)
+			return nil
+		}
+
+		if !repair {
+			return fmt.Errorf("%w: sequence %d: %v (rerun with repair to quarantine it)", ErrCorruptSnapshot, seq, err)
+		}
+
+		repairer, ok := sp.store.(store.Repairer)
+		if !ok {
+			return fmt.Errorf("%w: sequence %d: %v (store does not support repair)", ErrCorruptSnapshot, seq, err)
+		}
+		if err := repairer.Quarantine(ctx, marketID, seq); err != nil {
+			return err
+		}
+
+		sp.logger.Sugar().Warnw("Quarantined corrupt snapshot",
+			"market_id", marketID,
+			"sequence_number", seq,
+			"error", err,
+		)
+	}
+
+	// No usable snapshot; start a new chain
+	delete(sp.chains, marketID)
+	return nil
+}
+
+// RecoverAll recovers the chain of every market already in the store
+func (sp *SnapshotPublisher) RecoverAll(repair bool) error {
+	markets, err := sp.store.ListMarkets(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, marketID := range markets {
+		if err := sp.Recover(marketID, repair); err != nil {
+			return fmt.Errorf("market %s: %w", marketID, err)
+		}
+	}
+	return nil
+}
+
+// hashStoredSnapshot loads a committed snapshot and returns its chain hash
+func (sp *SnapshotPublisher) hashStoredSnapshot(marketID string, sequenceNum uint64) (string, error) {
+	snapshot, err := sp.LoadSnapshot(marketID, sequenceNum)
+	if err != nil {
+		return "", err
+	}
+
+	if snapshot.SequenceNumber != sequenceNum {
+		return "", fmt.Errorf("stored snapshot contains sequence %d", snapshot.SequenceNumber)
+	}
+
+	return orderbookchecker.HashSnapshot(*snapshot)
+}
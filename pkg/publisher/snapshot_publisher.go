@@ -1,41 +1,81 @@
 package publisher
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"context"
 	"fmt"
 	"math/big"
-	"os"
-	"path/filepath"
-	"sort"
 	"time"
 
 	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/store"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/taskpayload"
 	"go.uber.org/zap"
 )
 
-// SnapshotPublisher handles the creation and publishing of orderbook snapshots
+// SnapshotPublisher handles the creation and publishing of orderbook snapshots. Each market
+// has its own sequence numbers and hash chain.
 type SnapshotPublisher struct {
-	logger      *zap.Logger
-	outputDir   string
+	logger *zap.Logger
+	store  store.SnapshotStore
+	chains map[string]*chainState
+	params map[string]*orderbookchecker.MarketParams
+}
+
+// chainState is the position of a market's snapshot chain
+type chainState struct {
 	sequenceNum uint64
 	prevHash    string
 }
 
-// NewSnapshotPublisher creates a new snapshot publisher
+// NewSnapshotPublisher creates a new snapshot publisher writing to a local directory,
+// starting new chains. Call Recover to continue the chains already present in the directory.
 func NewSnapshotPublisher(logger *zap.Logger, outputDir string) *SnapshotPublisher {
+	return NewSnapshotPublisherWithStore(logger, store.NewFSStore(outputDir))
+}
+
+// NewSnapshotPublisherWithStore creates a new snapshot publisher writing to the given store
+func NewSnapshotPublisherWithStore(logger *zap.Logger, snapshotStore store.SnapshotStore) *SnapshotPublisher {
 	return &SnapshotPublisher{
-		logger:      logger,
-		outputDir:   outputDir,
-		sequenceNum: 1,
-		prevHash:    "0x0000000000000000000000000000000000000000000000000000000000000000",
+		logger: logger,
+		store:  snapshotStore,
+		chains: make(map[string]*chainState),
+		params: make(map[string]*orderbookchecker.MarketParams),
+	}
+}
+
+// chain returns the chain state of a market, starting a new chain if it has none
+func (sp *SnapshotPublisher) chain(marketID string) *chainState {
+	chain, ok := sp.chains[marketID]
+	if !ok {
+		chain = &chainState{sequenceNum: 1, prevHash: orderbookchecker.GenesisPrevHash}
+		sp.chains[marketID] = chain
 	}
+	return chain
+}
+
+// SetMarketParams attaches market parameters to every snapshot subsequently published for
+// the market. Passing nil publishes snapshots without parameters.
+func (sp *SnapshotPublisher) SetMarketParams(marketID string, params *orderbookchecker.MarketParams) error {
+	if params == nil {
+		delete(sp.params, marketID)
+		return nil
+	}
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	sp.params[marketID] = params
+	return nil
+}
+
+// Store returns the store the publisher writes to
+func (sp *SnapshotPublisher) Store() store.SnapshotStore {
+	return sp.store
 }
 
 // PublishSnapshot creates and publishes a new orderbook snapshot
 func (sp *SnapshotPublisher) PublishSnapshot(marketID string, orders []orderbookchecker.Order, trades []orderbookchecker.Trade) (*orderbookchecker.OrderbookSnapshot, error) {
-	timestamp := time.Now().UTC()
+	// Timestamps are committed at millisecond precision by the canonical encoding
+	timestamp := time.Now().UTC().Truncate(time.Millisecond)
 
 	// Calculate merkle root for orders
 	merkleRoot, err := sp.calculateMerkleRoot(orders)
@@ -44,30 +84,31 @@ func (sp *SnapshotPublisher) PublishSnapshot(marketID string, orders []orderbook
 	}
 
 	// Create snapshot
+	chain := sp.chain(marketID)
 	snapshot := &orderbookchecker.OrderbookSnapshot{
-		SequenceNumber: sp.sequenceNum,
+		SequenceNumber: chain.sequenceNum,
 		Timestamp:      timestamp,
 		MarketID:       marketID,
 		Orders:         orders,
 		MerkleRoot:     merkleRoot,
-		PrevHash:       sp.prevHash,
+		PrevHash:       chain.prevHash,
+		Params:         sp.params[marketID],
 	}
 
-	// Save snapshot to disk
-	if err := sp.saveSnapshot(snapshot); err != nil {
-		return nil, fmt.Errorf("failed to save snapshot: %v", err)
+	// Hash the snapshot before anything is written so the chain only advances on success
+	hash, err := orderbookchecker.HashSnapshot(*snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash snapshot: %v", err)
 	}
 
-	// Save trades separately
-	if len(trades) > 0 {
-		if err := sp.saveTrades(trades, sp.sequenceNum); err != nil {
-			return nil, fmt.Errorf("failed to save trades: %v", err)
-		}
+	// The store refuses to overwrite a committed sequence
+	if err := sp.store.Put(context.Background(), snapshot, trades); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
 	}
 
 	// Update state for next snapshot
-	sp.prevHash = sp.hashSnapshot(snapshot)
-	sp.sequenceNum++
+	chain.prevHash = hash
+	chain.sequenceNum++
 
 	sp.logger.Sugar().Infow("Published snapshot",
 		"sequence_number", snapshot.SequenceNumber,
@@ -75,129 +116,30 @@ func (sp *SnapshotPublisher) PublishSnapshot(marketID string, orders []orderbook
 		"orders_count", len(orders),
 		"trades_count", len(trades),
 		"merkle_root", merkleRoot,
+		"snapshot_hash", hash,
 	)
 
 	return snapshot, nil
 }
 
-// calculateMerkleRoot computes a simple merkle root for the orders
+// calculateMerkleRoot computes the merkle root for the orders using the canonical snapshot tree
 func (sp *SnapshotPublisher) calculateMerkleRoot(orders []orderbookchecker.Order) (string, error) {
-	if len(orders) == 0 {
-		return "0x0000000000000000000000000000000000000000000000000000000000000000", nil
-	}
-
-	// Sort orders by ID for deterministic hashing
-	sortedOrders := make([]orderbookchecker.Order, len(orders))
-	copy(sortedOrders, orders)
-	sort.Slice(sortedOrders, func(i, j int) bool {
-		return sortedOrders[i].ID < sortedOrders[j].ID
-	})
-
-	// Create leaf hashes
-	var leaves []string
-	for _, order := range sortedOrders {
-		orderBytes, err := json.Marshal(order)
-		if err != nil {
-			return "", fmt.Errorf("failed to marshal order %s: %v", order.ID, err)
-		}
-		hash := sha256.Sum256(orderBytes)
-		leaves = append(leaves, hex.EncodeToString(hash[:]))
-	}
-
-	// Build merkle tree (simplified - just hash all leaves together for now)
-	// In production, you'd want a proper merkle tree implementation
-	allLeaves := ""
-	for _, leaf := range leaves {
-		allLeaves += leaf
-	}
-
-	finalHash := sha256.Sum256([]byte(allLeaves))
-	return "0x" + hex.EncodeToString(finalHash[:]), nil
-}
-
-// hashSnapshot creates a hash of the snapshot for the next prevHash
-func (sp *SnapshotPublisher) hashSnapshot(snapshot *orderbookchecker.OrderbookSnapshot) string {
-	data := fmt.Sprintf("%d-%s-%s-%s",
-		snapshot.SequenceNumber,
-		snapshot.MarketID,
-		snapshot.MerkleRoot,
-		snapshot.Timestamp.Format(time.RFC3339),
-	)
-	hash := sha256.Sum256([]byte(data))
-	return "0x" + hex.EncodeToString(hash[:])
-}
-
-// saveSnapshot saves the snapshot to disk
-func (sp *SnapshotPublisher) saveSnapshot(snapshot *orderbookchecker.OrderbookSnapshot) error {
-	// Ensure output directory exists
-	if err := os.MkdirAll(sp.outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %v", err)
-	}
-
-	// Save snapshot
-	filename := filepath.Join(sp.outputDir, fmt.Sprintf("snapshot_%d.json", snapshot.SequenceNumber))
-	data, err := json.MarshalIndent(snapshot, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal snapshot: %v", err)
-	}
-
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("failed to write snapshot file: %v", err)
-	}
-
-	return nil
-}
-
-// saveTrades saves the trades to disk
-func (sp *SnapshotPublisher) saveTrades(trades []orderbookchecker.Trade, sequenceNum uint64) error {
-	filename := filepath.Join(sp.outputDir, fmt.Sprintf("trades_%d.json", sequenceNum))
-	data, err := json.MarshalIndent(trades, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal trades: %v", err)
-	}
-
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("failed to write trades file: %v", err)
-	}
-
-	return nil
+	return orderbookchecker.ComputeMerkleRoot(orders)
 }
 
-// LoadSnapshot loads a snapshot from disk
-func (sp *SnapshotPublisher) LoadSnapshot(sequenceNum uint64) (*orderbookchecker.OrderbookSnapshot, error) {
-	filename := filepath.Join(sp.outputDir, fmt.Sprintf("snapshot_%d.json", sequenceNum))
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read snapshot file: %v", err)
-	}
-
-	var snapshot orderbookchecker.OrderbookSnapshot
-	if err := json.Unmarshal(data, &snapshot); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal snapshot: %v", err)
-	}
-
-	return &snapshot, nil
+// LoadSnapshot loads a committed snapshot from the store
+func (sp *SnapshotPublisher) LoadSnapshot(marketID string, sequenceNum uint64) (*orderbookchecker.OrderbookSnapshot, error) {
+	return sp.store.Get(context.Background(), marketID, sequenceNum)
 }
 
-// LoadTrades loads trades from disk
-func (sp *SnapshotPublisher) LoadTrades(sequenceNum uint64) ([]orderbookchecker.Trade, error) {
-	filename := filepath.Join(sp.outputDir, fmt.Sprintf("trades_%d.json", sequenceNum))
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read trades file: %v", err)
-	}
-
-	var trades []orderbookchecker.Trade
-	if err := json.Unmarshal(data, &trades); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal trades: %v", err)
-	}
-
-	return trades, nil
+// LoadTrades loads the trades of a committed snapshot from the store
+func (sp *SnapshotPublisher) LoadTrades(marketID string, sequenceNum uint64) ([]orderbookchecker.Trade, error) {
+	return sp.store.GetTrades(context.Background(), marketID, sequenceNum)
 }
 
 // GenerateSampleData creates sample orderbook data for testing
 func (sp *SnapshotPublisher) GenerateSampleData(marketID string) ([]orderbookchecker.Order, []orderbookchecker.Trade) {
-	baseTime := time.Now().UTC().Add(-5 * time.Minute)
+	baseTime := time.Now().UTC().Truncate(time.Millisecond).Add(-5 * time.Minute)
 
 	orders := []orderbookchecker.Order{
 		{
@@ -219,7 +161,7 @@ func (sp *SnapshotPublisher) GenerateSampleData(marketID string) ([]orderbookche
 		{
 			ID:        "order-sell-001",
 			Side:      "sell",
-			Price:     big.NewInt(530000000000000000),  // 0.53 ETH
+			Price:     big.NewInt(540000000000000000),  // 0.54 ETH
 			Quantity:  big.NewInt(1500000000000000000), // 1.5 ETH
 			Timestamp: baseTime.Add(30 * time.Second),
 			UserID:    "user-charlie",
@@ -251,11 +193,17 @@ func (sp *SnapshotPublisher) GenerateSampleData(marketID string) ([]orderbookche
 }
 
 // CreateTaskInput creates a TaskInput from snapshot and trades for AVS processing
-func (sp *SnapshotPublisher) CreateTaskInput(snapshot *orderbookchecker.OrderbookSnapshot, trades []orderbookchecker.Trade, tradeBatchID string) map[string]interface{} {
-	return map[string]interface{}{
-		"snapshot_hash":  snapshot.MerkleRoot,
-		"trade_batch_id": tradeBatchID,
-		"snapshot":       snapshot,
-		"trades":         trades,
+// The snapshot hash is the chain hash, which commits to the full snapshot content
+func (sp *SnapshotPublisher) CreateTaskInput(snapshot *orderbookchecker.OrderbookSnapshot, trades []orderbookchecker.Trade, tradeBatchID string) (*taskpayload.TaskInput, error) {
+	hash, err := orderbookchecker.HashSnapshot(*snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash snapshot: %v", err)
 	}
+
+	return &taskpayload.TaskInput{
+		SnapshotHash: hash,
+		TradeBatchID: tradeBatchID,
+		Snapshot:     *snapshot,
+		Trades:       trades,
+	}, nil
 }
@@ -0,0 +1,223 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"go.uber.org/zap"
+)
+
+func publishSample(t *testing.T, pub *SnapshotPublisher) uint64 {
+	return publishMarket(t, pub, "TEST-MARKET")
+}
+
+func publishMarket(t *testing.T, pub *SnapshotPublisher, marketID string) uint64 {
+	orders, trades := pub.GenerateSampleData(marketID)
+	snapshot, err := pub.PublishSnapshot(marketID, orders, trades)
+	if err != nil {
+		t.Fatalf("PublishSnapshot failed: %v", err)
+	}
+	return snapshot.SequenceNumber
+}
+
+// verifySingleChain verifies a directory expected to hold exactly one market
+func verifySingleChain(t *testing.T, dir string) *orderbookchecker.ChainReport {
+	reports, err := VerifyChainDir(dir)
+	if err != nil {
+		t.Fatalf("VerifyChainDir failed: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("Expected one market, got %d", len(reports))
+	}
+	return reports[0]
+}
+
+func TestSnapshotPublisher_RecoverContinuesChain(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	dir := t.TempDir()
+
+	pub := NewSnapshotPublisher(logger, dir)
+	publishSample(t, pub)
+	publishSample(t, pub)
+
+	// Simulate a restart
+	restarted := NewSnapshotPublisher(logger, dir)
+	if err := restarted.Recover("TEST-MARKET", false); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	if seq := publishSample(t, restarted); seq != 3 {
+		t.Errorf("Expected sequence 3 after restart, got %d", seq)
+	}
+
+	report := verifySingleChain(t, dir)
+	if !report.Valid || report.LastSequence != 3 {
+		t.Errorf("Expected valid chain up to sequence 3, got %+v", report)
+	}
+}
+
+func TestSnapshotPublisher_RefusesOverwrite(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	dir := t.TempDir()
+
+	publishSample(t, NewSnapshotPublisher(logger, dir))
+
+	// Without Recover a new publisher would start again at sequence 1
+	pub := NewSnapshotPublisher(logger, dir)
+	orders, trades := pub.GenerateSampleData("TEST-MARKET")
+	if _, err := pub.PublishSnapshot("TEST-MARKET", orders, trades); !errors.Is(err, ErrSnapshotExists) {
+		t.Errorf("Expected ErrSnapshotExists, got %v", err)
+	}
+}
+
+func TestSnapshotPublisher_RepairCorruptSnapshot(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	dir := t.TempDir()
+
+	pub := NewSnapshotPublisher(logger, dir)
+	publishSample(t, pub)
+	publishSample(t, pub)
+
+	// Truncate the last snapshot as if the process died mid-write
+	last := filepath.Join(dir, "TEST-MARKET", "snapshot_2.json")
+	if err := os.WriteFile(last, []byte(`{"sequence_number": 2, "ord`), 0644); err != nil {
+		t.Fatalf("Failed to corrupt snapshot: %v", err)
+	}
+
+	restarted := NewSnapshotPublisher(logger, dir)
+	if err := restarted.Recover("TEST-MARKET", false); !errors.Is(err, ErrCorruptSnapshot) {
+		t.Fatalf("Expected ErrCorruptSnapshot, got %v", err)
+	}
+
+	if err := restarted.Recover("TEST-MARKET", true); err != nil {
+		t.Fatalf("Recover with repair failed: %v", err)
+	}
+	if _, err := os.Stat(last + ".corrupt"); err != nil {
+		t.Errorf("Expected corrupt snapshot to be quarantined: %v", err)
+	}
+
+	if seq := publishSample(t, restarted); seq != 2 {
+		t.Errorf("Expected sequence 2 after repair, got %d", seq)
+	}
+
+	report := verifySingleChain(t, dir)
+	if !report.Valid {
+		t.Errorf("Expected valid chain after repair, got issues: %+v", report.Issues)
+	}
+}
+
+func TestSnapshotPublisher_UncommittedSnapshotIgnored(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	dir := t.TempDir()
+
+	pub := NewSnapshotPublisher(logger, dir)
+	publishSample(t, pub)
+
+	// A crash after writing snapshot_2.json but before its commit marker
+	if err := os.WriteFile(filepath.Join(dir, "TEST-MARKET", "snapshot_2.json"), []byte(`{"sequence_number": 2}`), 0644); err != nil {
+		t.Fatalf("Failed to write partial snapshot: %v", err)
+	}
+
+	sequences, err := pub.Store().List(context.Background(), "TEST-MARKET")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(sequences) != 1 || sequences[0] != 1 {
+		t.Errorf("Expected only sequence 1 to be committed, got %v", sequences)
+	}
+
+	// The restarted publisher overwrites the uncommitted sequence
+	restarted := NewSnapshotPublisher(logger, dir)
+	if err := restarted.Recover("TEST-MARKET", false); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if seq := publishSample(t, restarted); seq != 2 {
+		t.Errorf("Expected sequence 2, got %d", seq)
+	}
+	if _, err := restarted.LoadSnapshot("TEST-MARKET", 2); err != nil {
+		t.Errorf("Expected sequence 2 to be committed: %v", err)
+	}
+}
+
+func TestSnapshotPublisher_IndependentMarkets(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	dir := t.TempDir()
+
+	pub := NewSnapshotPublisher(logger, dir)
+	publishMarket(t, pub, "MARKET-A")
+	publishMarket(t, pub, "MARKET-B")
+	publishMarket(t, pub, "MARKET-A")
+
+	// Each market keeps its own sequence numbers across a restart
+	restarted := NewSnapshotPublisher(logger, dir)
+	if err := restarted.RecoverAll(false); err != nil {
+		t.Fatalf("RecoverAll failed: %v", err)
+	}
+	if seq := publishMarket(t, restarted, "MARKET-A"); seq != 3 {
+		t.Errorf("Expected MARKET-A sequence 3, got %d", seq)
+	}
+	if seq := publishMarket(t, restarted, "MARKET-B"); seq != 2 {
+		t.Errorf("Expected MARKET-B sequence 2, got %d", seq)
+	}
+
+	reports, err := VerifyChainDir(dir)
+	if err != nil {
+		t.Fatalf("VerifyChainDir failed: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected two market chains, got %d", len(reports))
+	}
+	for _, report := range reports {
+		if !report.Valid {
+			t.Errorf("Expected valid chain for %s, got issues: %+v", report.MarketID, report.Issues)
+		}
+	}
+}
+
+func TestSnapshotPublisher_MarketParams(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	dir := t.TempDir()
+
+	pub := NewSnapshotPublisher(logger, dir)
+
+	invalid := &orderbookchecker.MarketParams{TickSize: big.NewInt(3), CollateralDecimals: orderbookchecker.DefaultCollateralDecimals}
+	if err := pub.SetMarketParams("TEST-MARKET", invalid); !errors.Is(err, orderbookchecker.ErrInvalidMarketParams) {
+		t.Fatalf("Expected ErrInvalidMarketParams, got %v", err)
+	}
+
+	// The sample prices are multiples of 0.005
+	params := &orderbookchecker.MarketParams{
+		TickSize:           big.NewInt(5000000000000000),
+		CollateralDecimals: orderbookchecker.DefaultCollateralDecimals,
+	}
+	if err := pub.SetMarketParams("TEST-MARKET", params); err != nil {
+		t.Fatalf("SetMarketParams failed: %v", err)
+	}
+	seq := publishSample(t, pub)
+
+	snapshot, err := pub.LoadSnapshot("TEST-MARKET", seq)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if snapshot.Params == nil || snapshot.Params.TickSize.Cmp(params.TickSize) != 0 {
+		t.Errorf("Expected stored snapshot to carry the market parameters, got %+v", snapshot.Params)
+	}
+
+	trades, err := pub.LoadTrades("TEST-MARKET", seq)
+	if err != nil {
+		t.Fatalf("LoadTrades failed: %v", err)
+	}
+	result, err := orderbookchecker.NewOrderbookVerifier(logger).VerifySnapshot(trades, *snapshot)
+	if err != nil || !result.Valid {
+		t.Errorf("Expected sample data to satisfy the market parameters, got %+v, %v", result, err)
+	}
+
+	if report := verifySingleChain(t, dir); !report.Valid {
+		t.Errorf("Expected valid chain, got issues: %+v", report.Issues)
+	}
+}
@@ -0,0 +1,359 @@
+// Package settlement contains Go bindings for the SettlementVerifier contract
+// (contracts/src/l1-contracts/SettlementVerifier.sol), a client that registers settlements
+// and files challenges, and an in-process simulated backend for tests and demos. The
+// bindings run on any chain.Backend, such as chain.RPCBackend for a real node.
+//
+// The bindings follow the layout abigen would produce but are maintained by hand on top of
+// pkg/abi, so the signatures below must be kept in sync with the contract.
+package settlement
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// Function and event signatures of SettlementVerifier
+const (
+	sigRegisterSettlement      = "registerSettlement(bytes32,bytes32,bytes32,string)"
+	sigChallengeSettlement     = "challengeSettlement(bytes32,bytes)"
+	sigResolveChallenge        = "resolveChallenge(bytes32,bool)"
+	sigAuthorizeChallenger     = "authorizeChallenger(address)"
+	sigGetSettlement           = "getSettlement(bytes32)"
+	sigGetChallenge            = "getChallenge(bytes32)"
+	sigOperatorStakes          = "operatorStakes(address)"
+	sigVerifySkippedOrderProof = "verifySkippedOrderProof(bytes)"
+	sigVerifySettlementProof   = "verifySettlementProof(bytes32,bytes)"
+
+	sigSettlementRegistered = "SettlementRegistered(bytes32,bytes32,address,bytes32,bytes32,string)"
+	sigSettlementChallenged = "SettlementChallenged(bytes32,address,bytes32,bytes32)"
+	sigChallengeResolved    = "ChallengeResolved(bytes32,bytes32,bool,address)"
+	sigOperatorSlashed      = "OperatorSlashed(bytes32,address,uint256)"
+)
+
+// Settlement statuses, matching SettlementVerifier.SettlementStatus
+const (
+	StatusActive uint8 = iota
+	StatusChallenged
+	StatusFrozen
+	StatusSlashed
+)
+
+// SettlementID returns the ID the contract assigns to a settlement of txID registered by
+// operator in the block with the given timestamp
+func SettlementID(txID merkle.Hash, operator chain.Address, blockTimestamp uint64) merkle.Hash {
+	return packedID(txID, operator, blockTimestamp)
+}
+
+// ChallengeID returns the ID the contract assigns to a challenge of a settlement filed by
+// challenger in the block with the given timestamp
+func ChallengeID(settlementID merkle.Hash, challenger chain.Address, blockTimestamp uint64) merkle.Hash {
+	return packedID(settlementID, challenger, blockTimestamp)
+}
+
+// packedID hashes abi.encodePacked(id, account, block.timestamp)
+func packedID(id merkle.Hash, account chain.Address, timestamp uint64) merkle.Hash {
+	var ts [32]byte
+	binary.BigEndian.PutUint64(ts[24:], timestamp)
+	return merkle.Keccak256(id[:], account[:], ts[:])
+}
+
+// Settlement mirrors SettlementVerifier.Settlement
+type Settlement struct {
+	TxID              merkle.Hash
+	Operator          chain.Address
+	Timestamp         uint64
+	Status            uint8
+	SnapshotHash      merkle.Hash
+	OrderRoot         merkle.Hash
+	TradeBatchID      string
+	ChallengeDeadline uint64
+}
+
+// Challenge mirrors SettlementVerifier.Challenge
+type Challenge struct {
+	Challenger   chain.Address
+	SettlementID merkle.Hash
+	Proof        []byte
+	Timestamp    uint64
+	Resolved     bool
+	Successful   bool
+}
+
+// SettlementRegistered is the SettlementRegistered event
+type SettlementRegistered struct {
+	SettlementID merkle.Hash
+	TxID         merkle.Hash
+	Operator     chain.Address
+	SnapshotHash merkle.Hash
+	OrderRoot    merkle.Hash
+	TradeBatchID string
+}
+
+// SettlementChallenged is the SettlementChallenged event
+type SettlementChallenged struct {
+	SettlementID merkle.Hash
+	Challenger   chain.Address
+	TxID         merkle.Hash
+	ChallengeID  merkle.Hash
+}
+
+// ChallengeResolved is the ChallengeResolved event
+type ChallengeResolved struct {
+	ChallengeID  merkle.Hash
+	SettlementID merkle.Hash
+	Successful   bool
+	Challenger   chain.Address
+}
+
+// SettlementVerifier is a binding to a deployed SettlementVerifier contract
+type SettlementVerifier struct {
+	address chain.Address
+	backend chain.Backend
+}
+
+// NewSettlementVerifier binds the contract deployed at address
+func NewSettlementVerifier(address chain.Address, backend chain.Backend) *SettlementVerifier {
+	return &SettlementVerifier{address: address, backend: backend}
+}
+
+// Address returns the address of the bound contract
+func (c *SettlementVerifier) Address() chain.Address {
+	return c.address
+}
+
+// transact sends a transaction to the contract
+func (c *SettlementVerifier) transact(ctx context.Context, opts chain.TransactOpts, data []byte) (*chain.Receipt, error) {
+	return c.backend.SendTransaction(ctx, chain.CallMsg{From: opts.From, To: c.address, Value: opts.Value, Data: data})
+}
+
+// call executes a read-only call to the contract
+func (c *SettlementVerifier) call(ctx context.Context, data []byte) (*abi.Decoder, error) {
+	out, err := c.backend.CallContract(ctx, chain.CallMsg{To: c.address, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return abi.NewDecoder(out), nil
+}
+
+// RegisterSettlement registers a settlement, staking opts.Value. Challenges must prove
+// fraud against orderRoot.
+func (c *SettlementVerifier) RegisterSettlement(ctx context.Context, opts chain.TransactOpts, txID, snapshotHash, orderRoot merkle.Hash, tradeBatchID string) (*chain.Receipt, error) {
+	return c.transact(ctx, opts, chain.Calldata(sigRegisterSettlement,
+		abi.Bytes32(txID), abi.Bytes32(snapshotHash), abi.Bytes32(orderRoot), abi.String(tradeBatchID)))
+}
+
+// ChallengeSettlement challenges a settlement with a skipped-order fraud proof against its
+// order root; other proofs revert
+func (c *SettlementVerifier) ChallengeSettlement(ctx context.Context, opts chain.TransactOpts, settlementID merkle.Hash, proof []byte) (*chain.Receipt, error) {
+	return c.transact(ctx, opts, chain.Calldata(sigChallengeSettlement, abi.Bytes32(settlementID), abi.Bytes(proof)))
+}
+
+// ResolveChallenge resolves a challenge; only the owner may call it
+func (c *SettlementVerifier) ResolveChallenge(ctx context.Context, opts chain.TransactOpts, challengeID merkle.Hash, successful bool) (*chain.Receipt, error) {
+	return c.transact(ctx, opts, chain.Calldata(sigResolveChallenge, abi.Bytes32(challengeID), abi.Bool(successful)))
+}
+
+// AuthorizeChallenger allows an address to submit challenges; only the owner may call it
+func (c *SettlementVerifier) AuthorizeChallenger(ctx context.Context, opts chain.TransactOpts, challenger chain.Address) (*chain.Receipt, error) {
+	return c.transact(ctx, opts, chain.Calldata(sigAuthorizeChallenger, abi.Address(challenger)))
+}
+
+// GetSettlement returns a settlement; unknown settlements have a zero operator
+func (c *SettlementVerifier) GetSettlement(ctx context.Context, settlementID merkle.Hash) (*Settlement, error) {
+	d, err := c.call(ctx, chain.Calldata(sigGetSettlement, abi.Bytes32(settlementID)))
+	if err != nil {
+		return nil, err
+	}
+	return decodeSettlement(d)
+}
+
+// GetChallenge returns a challenge; unknown challenges have a zero challenger
+func (c *SettlementVerifier) GetChallenge(ctx context.Context, challengeID merkle.Hash) (*Challenge, error) {
+	d, err := c.call(ctx, chain.Calldata(sigGetChallenge, abi.Bytes32(challengeID)))
+	if err != nil {
+		return nil, err
+	}
+	return decodeChallenge(d)
+}
+
+// OperatorStakes returns the stake an operator holds in the contract
+func (c *SettlementVerifier) OperatorStakes(ctx context.Context, operator chain.Address) (*big.Int, error) {
+	d, err := c.call(ctx, chain.Calldata(sigOperatorStakes, abi.Address(operator)))
+	if err != nil {
+		return nil, err
+	}
+	return d.Uint256(0)
+}
+
+// VerifySkippedOrderProof checks the inclusion proofs of an ABI-encoded skipped-order fraud proof
+func (c *SettlementVerifier) VerifySkippedOrderProof(ctx context.Context, proof []byte) (bool, error) {
+	d, err := c.call(ctx, chain.Calldata(sigVerifySkippedOrderProof, abi.Bytes(proof)))
+	if err != nil {
+		return false, err
+	}
+	return d.Bool(0)
+}
+
+// VerifySettlementProof checks an ABI-encoded skipped-order fraud proof against the order
+// root registered for a settlement, as challengeSettlement does
+func (c *SettlementVerifier) VerifySettlementProof(ctx context.Context, settlementID merkle.Hash, proof []byte) (bool, error) {
+	d, err := c.call(ctx, chain.Calldata(sigVerifySettlementProof, abi.Bytes32(settlementID), abi.Bytes(proof)))
+	if err != nil {
+		return false, err
+	}
+	return d.Bool(0)
+}
+
+// decodeSettlement decodes the Settlement struct returned by getSettlement
+func decodeSettlement(d *abi.Decoder) (*Settlement, error) {
+	t, err := d.Tuple(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Settlement
+	var txID, snapshotHash, orderRoot [32]byte
+	if txID, err = t.Bytes32(0); err != nil {
+		return nil, err
+	}
+	if s.Operator, err = t.Address(1); err != nil {
+		return nil, err
+	}
+	if s.Timestamp, err = t.Uint64(2); err != nil {
+		return nil, err
+	}
+	status, err := t.Uint64(3)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotHash, err = t.Bytes32(4); err != nil {
+		return nil, err
+	}
+	if orderRoot, err = t.Bytes32(5); err != nil {
+		return nil, err
+	}
+	if s.TradeBatchID, err = t.String(6); err != nil {
+		return nil, err
+	}
+	if s.ChallengeDeadline, err = t.Uint64(7); err != nil {
+		return nil, err
+	}
+	if status > uint64(StatusSlashed) {
+		return nil, fmt.Errorf("%w: unknown settlement status %d", abi.ErrInvalidEncoding, status)
+	}
+
+	s.TxID, s.SnapshotHash, s.OrderRoot, s.Status = txID, snapshotHash, orderRoot, uint8(status)
+	return &s, nil
+}
+
+// decodeChallenge decodes the Challenge struct returned by getChallenge
+func decodeChallenge(d *abi.Decoder) (*Challenge, error) {
+	t, err := d.Tuple(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Challenge
+	var settlementID [32]byte
+	if c.Challenger, err = t.Address(0); err != nil {
+		return nil, err
+	}
+	if settlementID, err = t.Bytes32(1); err != nil {
+		return nil, err
+	}
+	if c.Proof, err = t.Bytes(2); err != nil {
+		return nil, err
+	}
+	if c.Timestamp, err = t.Uint64(3); err != nil {
+		return nil, err
+	}
+	if c.Resolved, err = t.Bool(4); err != nil {
+		return nil, err
+	}
+	if c.Successful, err = t.Bool(5); err != nil {
+		return nil, err
+	}
+
+	c.SettlementID = settlementID
+	return &c, nil
+}
+
+// ParseSettlementRegistered decodes a SettlementRegistered log
+func (c *SettlementVerifier) ParseSettlementRegistered(log chain.Log) (*SettlementRegistered, error) {
+	if err := c.checkLog(log, sigSettlementRegistered, 4); err != nil {
+		return nil, err
+	}
+	d := abi.NewDecoder(log.Data)
+	snapshotHash, err := d.Bytes32(0)
+	if err != nil {
+		return nil, err
+	}
+	orderRoot, err := d.Bytes32(1)
+	if err != nil {
+		return nil, err
+	}
+	batchID, err := d.String(2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SettlementRegistered{
+		SettlementID: log.Topics[1],
+		TxID:         log.Topics[2],
+		Operator:     chain.TopicAddress(log.Topics[3]),
+		SnapshotHash: snapshotHash,
+		OrderRoot:    orderRoot,
+		TradeBatchID: batchID,
+	}, nil
+}
+
+// ParseSettlementChallenged decodes a SettlementChallenged log
+func (c *SettlementVerifier) ParseSettlementChallenged(log chain.Log) (*SettlementChallenged, error) {
+	if err := c.checkLog(log, sigSettlementChallenged, 4); err != nil {
+		return nil, err
+	}
+	challengeID, err := abi.NewDecoder(log.Data).Bytes32(0)
+	if err != nil {
+		return nil, err
+	}
+	return &SettlementChallenged{
+		SettlementID: log.Topics[1],
+		Challenger:   chain.TopicAddress(log.Topics[2]),
+		TxID:         log.Topics[3],
+		ChallengeID:  challengeID,
+	}, nil
+}
+
+// ParseChallengeResolved decodes a ChallengeResolved log
+func (c *SettlementVerifier) ParseChallengeResolved(log chain.Log) (*ChallengeResolved, error) {
+	if err := c.checkLog(log, sigChallengeResolved, 4); err != nil {
+		return nil, err
+	}
+	successful, err := abi.NewDecoder(log.Data).Bool(0)
+	if err != nil {
+		return nil, err
+	}
+	return &ChallengeResolved{
+		ChallengeID:  log.Topics[1],
+		SettlementID: log.Topics[2],
+		Successful:   successful,
+		Challenger:   chain.TopicAddress(log.Topics[3]),
+	}, nil
+}
+
+// checkLog checks that a log is the given event emitted by the bound contract
+func (c *SettlementVerifier) checkLog(log chain.Log, signature string, topics int) error {
+	if log.Address != c.address {
+		return fmt.Errorf("log emitted by %s, not %s", log.Address, c.address)
+	}
+	if len(log.Topics) != topics || log.Topics[0] != chain.EventTopic(signature) {
+		return fmt.Errorf("log is not a %s event", signature)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package settlement
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"go.uber.org/zap"
+)
+
+// Client registers settlements for published snapshots and challenges settlements whose
+// verification failed, sending transactions from a single account
+type Client struct {
+	logger   *zap.Logger
+	contract *SettlementVerifier
+	from     chain.Address
+}
+
+// NewClient creates a client sending transactions to the contract from the given account
+func NewClient(logger *zap.Logger, contract *SettlementVerifier, from chain.Address) *Client {
+	return &Client{
+		logger:   logger,
+		contract: contract,
+		from:     from,
+	}
+}
+
+// RegisterSettlement registers the settlement of a trade batch against the hash and the order
+// root of the snapshot it was published with, staking stake, and returns the settlement ID.
+// Challenges of the settlement must prove fraud against that order root.
+func (c *Client) RegisterSettlement(ctx context.Context, txID merkle.Hash, snapshotHash, orderRoot, batchID string, stake *big.Int) (merkle.Hash, error) {
+	hash, err := merkle.ParseHash(snapshotHash)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("invalid snapshot hash: %v", err)
+	}
+	root, err := merkle.ParseHash(orderRoot)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("invalid order root: %v", err)
+	}
+
+	receipt, err := c.contract.RegisterSettlement(ctx, chain.TransactOpts{From: c.from, Value: stake}, txID, hash, root, batchID)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("failed to register settlement: %v", err)
+	}
+
+	for _, log := range receipt.Logs {
+		if event, err := c.contract.ParseSettlementRegistered(log); err == nil {
+			c.logger.Sugar().Infow("Settlement registered",
+				"settlement_id", event.SettlementID.Hex(),
+				"snapshot_hash", snapshotHash,
+				"order_root", orderRoot,
+				"batch_id", batchID,
+				"block_number", receipt.BlockNumber,
+			)
+			return event.SettlementID, nil
+		}
+	}
+
+	return merkle.Hash{}, fmt.Errorf("settlement registration %s emitted no SettlementRegistered event", receipt.TxHash)
+}
+
+// ChallengeIfInvalid files a challenge against a settlement whose verification failed and
+// returns the challenge ID. The proof is the ABI-encoded fraud proof of the first skipped
+// order, the only evidence the contract can check; valid results and failures without a
+// fraud proof are not challenged and return false.
+func (c *Client) ChallengeIfInvalid(ctx context.Context, settlementID merkle.Hash, result *orderbookchecker.VerificationResult) (merkle.Hash, bool, error) {
+	if result.Valid || len(result.FraudProofs) == 0 {
+		return merkle.Hash{}, false, nil
+	}
+
+	proof, err := result.FraudProofs[0].EncodeABI()
+	if err != nil {
+		return merkle.Hash{}, false, fmt.Errorf("failed to encode fraud proof: %v", err)
+	}
+
+	receipt, err := c.contract.ChallengeSettlement(ctx, chain.TransactOpts{From: c.from}, settlementID, proof)
+	if err != nil {
+		return merkle.Hash{}, false, fmt.Errorf("failed to challenge settlement: %v", err)
+	}
+
+	for _, log := range receipt.Logs {
+		if event, err := c.contract.ParseSettlementChallenged(log); err == nil && event.SettlementID == settlementID {
+			c.logger.Sugar().Infow("Settlement challenged",
+				"settlement_id", settlementID.Hex(),
+				"challenge_id", event.ChallengeID.Hex(),
+				"error_code", result.ErrorCode,
+				"fraud_proofs", len(result.FraudProofs),
+			)
+			return event.ChallengeID, true, nil
+		}
+	}
+
+	return merkle.Hash{}, false, fmt.Errorf("challenge %s emitted no SettlementChallenged event", receipt.TxHash)
+}
@@ -0,0 +1,329 @@
+package settlement
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+	"go.uber.org/zap"
+)
+
+var (
+	owner      = chain.Address{0x01}
+	operator   = chain.Address{0x02}
+	challenger = chain.Address{0x03}
+
+	ether = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+)
+
+// newTestContract deploys the contract on a simulated backend with funded accounts and an
+// authorized challenger
+func newTestContract(t *testing.T) (*SimulatedBackend, *SettlementVerifier) {
+	t.Helper()
+
+	backend := NewSimulatedBackend(owner, time.Unix(1700000000, 0))
+	for _, account := range []chain.Address{owner, operator, challenger} {
+		backend.Fund(account, new(big.Int).Mul(big.NewInt(10), ether))
+	}
+
+	contract := NewSettlementVerifier(backend.ContractAddress(), backend)
+	if _, err := contract.AuthorizeChallenger(context.Background(), chain.TransactOpts{From: owner}, challenger); err != nil {
+		t.Fatalf("AuthorizeChallenger failed: %v", err)
+	}
+	return backend, contract
+}
+
+func TestSimulatedBackend_SettlementLifecycle(t *testing.T) {
+	ctx := context.Background()
+	backend, contract := newTestContract(t)
+
+	txID := merkle.Keccak256([]byte("test-tx-id"))
+	snapshotHash := merkle.Keccak256([]byte("test-snapshot-hash"))
+	fraudProof := skippedOrderResult(t).FraudProofs[0]
+	orderRoot := fraudProof.OrderRoot
+	proof, err := fraudProof.EncodeABI()
+	if err != nil {
+		t.Fatalf("EncodeABI failed: %v", err)
+	}
+
+	if _, err := contract.RegisterSettlement(ctx, chain.TransactOpts{From: operator, Value: new(big.Int).Div(ether, big.NewInt(2))}, txID, snapshotHash, orderRoot, "batch-1"); !errors.Is(err, chain.ErrReverted) {
+		t.Fatalf("Expected insufficient stake to revert, got: %v", err)
+	}
+
+	stake := new(big.Int).Mul(big.NewInt(2), ether)
+	receipt, err := contract.RegisterSettlement(ctx, chain.TransactOpts{From: operator, Value: stake}, txID, snapshotHash, orderRoot, "batch-1")
+	if err != nil {
+		t.Fatalf("RegisterSettlement failed: %v", err)
+	}
+	registered, err := contract.ParseSettlementRegistered(receipt.Logs[0])
+	if err != nil {
+		t.Fatalf("ParseSettlementRegistered failed: %v", err)
+	}
+	settlementID := SettlementID(txID, operator, receipt.BlockTimestamp)
+	if registered.SettlementID != settlementID || registered.Operator != operator || registered.OrderRoot != orderRoot ||
+		registered.TradeBatchID != "batch-1" {
+		t.Errorf("Unexpected SettlementRegistered event %+v", registered)
+	}
+
+	settlement, err := contract.GetSettlement(ctx, settlementID)
+	if err != nil {
+		t.Fatalf("GetSettlement failed: %v", err)
+	}
+	if settlement.TxID != txID || settlement.SnapshotHash != snapshotHash || settlement.OrderRoot != orderRoot || settlement.Status != StatusActive ||
+		settlement.ChallengeDeadline != receipt.BlockTimestamp+uint64(ChallengePeriod/time.Second) {
+		t.Errorf("Unexpected settlement %+v", settlement)
+	}
+
+	if _, err := contract.ChallengeSettlement(ctx, chain.TransactOpts{From: operator}, settlementID, proof); !errors.Is(err, chain.ErrReverted) {
+		t.Fatalf("Expected unauthorized challenge to revert, got: %v", err)
+	}
+	if _, err := contract.ChallengeSettlement(ctx, chain.TransactOpts{From: challenger}, settlementID, []byte("proof")); !errors.Is(err, chain.ErrReverted) {
+		t.Fatalf("Expected a malformed proof to revert, got: %v", err)
+	}
+
+	receipt, err = contract.ChallengeSettlement(ctx, chain.TransactOpts{From: challenger}, settlementID, proof)
+	if err != nil {
+		t.Fatalf("ChallengeSettlement failed: %v", err)
+	}
+	challengeID := ChallengeID(settlementID, challenger, receipt.BlockTimestamp)
+	challenged, err := contract.ParseSettlementChallenged(receipt.Logs[0])
+	if err != nil || challenged.SettlementID != settlementID || challenged.Challenger != challenger || challenged.TxID != txID ||
+		challenged.ChallengeID != challengeID {
+		t.Errorf("Unexpected SettlementChallenged event %+v (%v)", challenged, err)
+	}
+
+	challenge, err := contract.GetChallenge(ctx, challengeID)
+	if err != nil {
+		t.Fatalf("GetChallenge failed: %v", err)
+	}
+	if challenge.Challenger != challenger || !bytes.Equal(challenge.Proof, proof) || challenge.Resolved {
+		t.Errorf("Unexpected challenge %+v", challenge)
+	}
+
+	if _, err := contract.ResolveChallenge(ctx, chain.TransactOpts{From: challenger}, challengeID, true); !errors.Is(err, chain.ErrReverted) {
+		t.Fatalf("Expected resolution by a non-owner to revert, got: %v", err)
+	}
+
+	before := backend.Balance(challenger)
+	receipt, err = contract.ResolveChallenge(ctx, chain.TransactOpts{From: owner}, challengeID, true)
+	if err != nil {
+		t.Fatalf("ResolveChallenge failed: %v", err)
+	}
+	resolved, err := contract.ParseChallengeResolved(receipt.Logs[len(receipt.Logs)-1])
+	if err != nil || resolved.ChallengeID != challengeID || !resolved.Successful {
+		t.Errorf("Unexpected ChallengeResolved event %+v (%v)", resolved, err)
+	}
+
+	// Half the stake goes to the challenger
+	if got := new(big.Int).Sub(backend.Balance(challenger), before); got.Cmp(ether) != 0 {
+		t.Errorf("Expected challenger reward of 1 ether, got %s", got)
+	}
+	if remaining, err := contract.OperatorStakes(ctx, operator); err != nil || remaining.Cmp(ether) != 0 {
+		t.Errorf("Expected remaining stake of 1 ether, got %v (%v)", remaining, err)
+	}
+	if settlement, err := contract.GetSettlement(ctx, settlementID); err != nil || settlement.Status != StatusSlashed {
+		t.Errorf("Expected slashed settlement, got %+v (%v)", settlement, err)
+	}
+}
+
+func TestSimulatedBackend_ChallengeAfterDeadline(t *testing.T) {
+	ctx := context.Background()
+	backend, contract := newTestContract(t)
+
+	receipt, err := contract.RegisterSettlement(ctx, chain.TransactOpts{From: operator, Value: ether}, merkle.Hash{1}, merkle.Hash{2}, merkle.Hash{3}, "batch-1")
+	if err != nil {
+		t.Fatalf("RegisterSettlement failed: %v", err)
+	}
+	settlementID := SettlementID(merkle.Hash{1}, operator, receipt.BlockTimestamp)
+
+	backend.AdjustTime(8 * 24 * time.Hour)
+	if _, err := contract.ChallengeSettlement(ctx, chain.TransactOpts{From: challenger}, settlementID, []byte("proof")); !errors.Is(err, chain.ErrReverted) {
+		t.Fatalf("Expected late challenge to revert, got: %v", err)
+	}
+}
+
+// skippedOrderResult verifies a trade that skipped an earlier order at the same price
+func skippedOrderResult(t *testing.T) *orderbookchecker.VerificationResult {
+	t.Helper()
+
+	baseTime := time.Unix(1700000000, 0).UTC()
+	snapshot := orderbookchecker.OrderbookSnapshot{
+		SequenceNumber: 1,
+		Timestamp:      baseTime,
+		MarketID:       "TEST",
+		Orders: []orderbookchecker.Order{
+			{ID: "buy-1", Side: "buy", Price: big.NewInt(600), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-time.Minute)},
+			{ID: "sell-early", Side: "sell", Price: big.NewInt(500), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-3 * time.Minute)},
+			{ID: "sell-late", Side: "sell", Price: big.NewInt(500), Quantity: big.NewInt(100), Timestamp: baseTime.Add(-2 * time.Minute)},
+		},
+	}
+	root, err := orderbookchecker.ComputeMerkleRoot(snapshot.Orders)
+	if err != nil {
+		t.Fatalf("ComputeMerkleRoot failed: %v", err)
+	}
+	snapshot.MerkleRoot = root
+	trades := []orderbookchecker.Trade{
+		{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-late", Price: big.NewInt(500), Quantity: big.NewInt(100), Timestamp: baseTime},
+	}
+
+	logger, _ := zap.NewDevelopment()
+	result, err := orderbookchecker.NewOrderbookVerifier(logger).VerifySnapshot(trades, snapshot)
+	if err != nil {
+		t.Fatalf("VerifySnapshot failed: %v", err)
+	}
+	if len(result.FraudProofs) != 1 {
+		t.Fatalf("Expected a fraud proof, got %+v", result)
+	}
+	return result
+}
+
+func TestClient_ChallengeIfInvalid(t *testing.T) {
+	ctx := context.Background()
+	logger, _ := zap.NewDevelopment()
+
+	invalid := &orderbookchecker.VerificationResult{Valid: false, ErrorCode: orderbookchecker.ErrorCodeOverfill}
+	orderRoot := skippedOrderResult(t).FraudProofs[0].OrderRoot.Hex()
+
+	tests := []struct {
+		name          string
+		result        *orderbookchecker.VerificationResult
+		wantChallenge bool
+	}{
+		{"valid result", &orderbookchecker.VerificationResult{Valid: true}, false},
+		{"skipped order", skippedOrderResult(t), true},
+		{"invalid without fraud proof", invalid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, contract := newTestContract(t)
+			operatorClient := NewClient(logger, contract, operator)
+			challengerClient := NewClient(logger, contract, challenger)
+
+			settlementID, err := operatorClient.RegisterSettlement(ctx, merkle.Hash{1}, merkle.Keccak256([]byte("snapshot")).Hex(), orderRoot, "batch-1", ether)
+			if err != nil {
+				t.Fatalf("RegisterSettlement failed: %v", err)
+			}
+
+			challengeID, challenged, err := challengerClient.ChallengeIfInvalid(ctx, settlementID, tt.result)
+			if err != nil {
+				t.Fatalf("ChallengeIfInvalid failed: %v", err)
+			}
+			if challenged != tt.wantChallenge {
+				t.Fatalf("Expected challenged=%v, got %v", tt.wantChallenge, challenged)
+			}
+
+			settlement, err := contract.GetSettlement(ctx, settlementID)
+			if err != nil {
+				t.Fatalf("GetSettlement failed: %v", err)
+			}
+			wantStatus := StatusActive
+			if tt.wantChallenge {
+				wantStatus = StatusChallenged
+			}
+			if settlement.Status != wantStatus {
+				t.Errorf("Expected status %d, got %d", wantStatus, settlement.Status)
+			}
+			if !tt.wantChallenge {
+				return
+			}
+
+			challenge, err := contract.GetChallenge(ctx, challengeID)
+			if err != nil || challenge.Challenger != challenger {
+				t.Fatalf("Expected challenge by %s, got %+v (%v)", challenger, challenge, err)
+			}
+
+			ok, err := contract.VerifySkippedOrderProof(ctx, challenge.Proof)
+			if err != nil || !ok {
+				t.Errorf("Expected challenge proof to verify on-chain, got %v (%v)", ok, err)
+			}
+			if ok, err := contract.VerifySettlementProof(ctx, settlementID, challenge.Proof); err != nil || !ok {
+				t.Errorf("Expected challenge proof to verify against the settlement, got %v (%v)", ok, err)
+			}
+
+			// Byte 64 starts the order root, after the tuple offset and the version
+			tampered := append([]byte(nil), challenge.Proof...)
+			tampered[64] ^= 0xff
+			if ok, err := contract.VerifySkippedOrderProof(ctx, tampered); err != nil || ok {
+				t.Errorf("Expected proof against another root to fail, got %v (%v)", ok, err)
+			}
+			if ok, err := contract.VerifySettlementProof(ctx, settlementID, tampered); err != nil || ok {
+				t.Errorf("Expected proof against another root to fail for the settlement, got %v (%v)", ok, err)
+			}
+		})
+	}
+}
+
+// withoutLogs is a backend whose receipts carry no events
+type withoutLogs struct {
+	chain.Backend
+}
+
+func (b withoutLogs) SendTransaction(ctx context.Context, msg chain.CallMsg) (*chain.Receipt, error) {
+	receipt, err := b.Backend.SendTransaction(ctx, msg)
+	if receipt != nil {
+		receipt.Logs = nil
+	}
+	return receipt, err
+}
+
+func TestClient_ChallengeIfInvalid_MissingEvent(t *testing.T) {
+	ctx := context.Background()
+	logger, _ := zap.NewDevelopment()
+	backend, contract := newTestContract(t)
+
+	result := skippedOrderResult(t)
+	settlementID, err := NewClient(logger, contract, operator).RegisterSettlement(ctx, merkle.Hash{1}, merkle.Keccak256([]byte("snapshot")).Hex(),
+		result.FraudProofs[0].OrderRoot.Hex(), "batch-1", ether)
+	if err != nil {
+		t.Fatalf("RegisterSettlement failed: %v", err)
+	}
+
+	// The challenge is filed, but without its event the client cannot know the ID the
+	// contract assigned
+	blind := NewClient(logger, NewSettlementVerifier(backend.ContractAddress(), withoutLogs{backend}), challenger)
+	if _, _, err := blind.ChallengeIfInvalid(ctx, settlementID, result); err == nil || !strings.Contains(err.Error(), "no SettlementChallenged event") {
+		t.Errorf("Expected a missing SettlementChallenged event to fail, got: %v", err)
+	}
+}
+
+func TestClient_ChallengeIfInvalid_ForeignRoot(t *testing.T) {
+	ctx := context.Background()
+	logger, _ := zap.NewDevelopment()
+	_, contract := newTestContract(t)
+
+	// The settlement commits to another snapshot's orders, so a proof that holds on its own
+	// tree proves nothing about it
+	foreignRoot := merkle.Keccak256([]byte("other snapshot"))
+	settlementID, err := NewClient(logger, contract, operator).RegisterSettlement(ctx, merkle.Hash{1}, merkle.Keccak256([]byte("snapshot")).Hex(),
+		foreignRoot.Hex(), "batch-1", ether)
+	if err != nil {
+		t.Fatalf("RegisterSettlement failed: %v", err)
+	}
+
+	result := skippedOrderResult(t)
+	proof, err := result.FraudProofs[0].EncodeABI()
+	if err != nil {
+		t.Fatalf("EncodeABI failed: %v", err)
+	}
+	if ok, err := contract.VerifySkippedOrderProof(ctx, proof); err != nil || !ok {
+		t.Fatalf("Expected the proof to verify on its own root, got %v (%v)", ok, err)
+	}
+	if ok, err := contract.VerifySettlementProof(ctx, settlementID, proof); err != nil || ok {
+		t.Errorf("Expected the proof not to verify against the settlement, got %v (%v)", ok, err)
+	}
+
+	if _, _, err := NewClient(logger, contract, challenger).ChallengeIfInvalid(ctx, settlementID, result); err == nil || !strings.Contains(err.Error(), "Invalid fraud proof") {
+		t.Errorf("Expected a challenge on a foreign root to revert, got: %v", err)
+	}
+	if settlement, err := contract.GetSettlement(ctx, settlementID); err != nil || settlement.Status != StatusActive {
+		t.Errorf("Expected the settlement to stay active, got %+v (%v)", settlement, err)
+	}
+}
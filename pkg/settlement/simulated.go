@@ -0,0 +1,502 @@
+package settlement
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/abi"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/chain"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+)
+
+// Constants of SettlementVerifier
+var (
+	ChallengePeriod = 7 * 24 * time.Hour
+	MinStake        = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil) // 1 ether
+)
+
+const (
+	slashPercentage   = 50
+	fraudProofVersion = 1
+)
+
+// SimulatedBackend is an in-process chain holding a single SettlementVerifier deployment. It
+// executes calls with a Go model of the contract, one block per transaction, so bindings and
+// clients can be exercised without a node.
+type SimulatedBackend struct {
+	mu          sync.Mutex
+	contract    chain.Address
+	owner       chain.Address
+	blockNumber uint64
+	blockTime   uint64
+	balances    map[chain.Address]*big.Int
+
+	settlements map[merkle.Hash]*Settlement
+	challenges  map[merkle.Hash]*Challenge
+	stakes      map[chain.Address]*big.Int
+	authorized  map[chain.Address]bool
+}
+
+// NewSimulatedBackend deploys SettlementVerifier owned by owner, with the chain clock starting at start
+func NewSimulatedBackend(owner chain.Address, start time.Time) *SimulatedBackend {
+	var contract chain.Address
+	hash := merkle.Keccak256([]byte("SettlementVerifier"), owner[:])
+	copy(contract[:], hash[12:])
+
+	return &SimulatedBackend{
+		contract:    contract,
+		owner:       owner,
+		blockTime:   uint64(start.Unix()),
+		balances:    make(map[chain.Address]*big.Int),
+		settlements: make(map[merkle.Hash]*Settlement),
+		challenges:  make(map[merkle.Hash]*Challenge),
+		stakes:      make(map[chain.Address]*big.Int),
+		authorized:  make(map[chain.Address]bool),
+	}
+}
+
+// ContractAddress returns the address of the SettlementVerifier deployment
+func (b *SimulatedBackend) ContractAddress() chain.Address {
+	return b.contract
+}
+
+// Fund credits an account with wei
+func (b *SimulatedBackend) Fund(account chain.Address, amount *big.Int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.credit(account, amount)
+}
+
+// Balance returns the wei held by an account
+func (b *SimulatedBackend) Balance(account chain.Address) *big.Int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return new(big.Int).Set(b.balance(account))
+}
+
+// AdjustTime moves the chain clock forward
+func (b *SimulatedBackend) AdjustTime(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.blockTime += uint64(d / time.Second)
+}
+
+// balance returns an account's balance, zero if it has none
+func (b *SimulatedBackend) balance(account chain.Address) *big.Int {
+	if balance, ok := b.balances[account]; ok {
+		return balance
+	}
+	return new(big.Int)
+}
+
+// credit adds wei to an account
+func (b *SimulatedBackend) credit(account chain.Address, amount *big.Int) {
+	b.balances[account] = new(big.Int).Add(b.balance(account), amount)
+}
+
+// CallContract implements chain.Backend
+func (b *SimulatedBackend) CallContract(ctx context.Context, msg chain.CallMsg) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if msg.To != b.contract {
+		return nil, fmt.Errorf("no contract deployed at %s", msg.To)
+	}
+	sig, args, err := b.dispatch(msg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sig {
+	case sigGetSettlement:
+		id, err := args.Bytes32(0)
+		if err != nil {
+			return nil, err
+		}
+		return encodeSettlement(b.settlements[id]), nil
+	case sigGetChallenge:
+		id, err := args.Bytes32(0)
+		if err != nil {
+			return nil, err
+		}
+		return encodeChallenge(b.challenges[id]), nil
+	case sigOperatorStakes:
+		operator, err := args.Address(0)
+		if err != nil {
+			return nil, err
+		}
+		stake, _ := abi.Uint256(b.stake(operator))
+		return abi.Encode(stake), nil
+	case sigVerifySkippedOrderProof:
+		proof, err := args.Bytes(0)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := verifySkippedOrderProof(proof)
+		if err != nil {
+			return nil, err
+		}
+		return abi.Encode(abi.Bool(ok)), nil
+	case sigVerifySettlementProof:
+		settlementID, err := args.Bytes32(0)
+		if err != nil {
+			return nil, err
+		}
+		proof, err := args.Bytes(1)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := b.verifySettlementProof(settlementID, proof)
+		if err != nil {
+			return nil, err
+		}
+		return abi.Encode(abi.Bool(ok)), nil
+	default:
+		return nil, chain.Revert(fmt.Sprintf("%s is not a view function", sig))
+	}
+}
+
+// SendTransaction implements chain.Backend. Each transaction is mined in its own block, one
+// second after the previous one; a reverted transaction leaves the state untouched.
+func (b *SimulatedBackend) SendTransaction(ctx context.Context, msg chain.CallMsg) (*chain.Receipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if msg.To != b.contract {
+		return nil, fmt.Errorf("no contract deployed at %s", msg.To)
+	}
+	value := new(big.Int)
+	if msg.Value != nil {
+		value.Set(msg.Value)
+	}
+	if value.Sign() < 0 || b.balance(msg.From).Cmp(value) < 0 {
+		return nil, fmt.Errorf("insufficient funds for value %s", value.String())
+	}
+
+	sig, args, err := b.dispatch(msg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	b.blockNumber++
+	b.blockTime++
+	receipt := &chain.Receipt{
+		BlockNumber:    b.blockNumber,
+		BlockTimestamp: b.blockTime,
+	}
+	var nonce [8]byte
+	binary.BigEndian.PutUint64(nonce[:], b.blockNumber)
+	receipt.TxHash = merkle.Keccak256(msg.From[:], nonce[:], msg.Data)
+
+	if sig != sigRegisterSettlement && value.Sign() > 0 {
+		err = chain.Revert(fmt.Sprintf("%s is not payable", sig))
+	} else {
+		switch sig {
+		case sigRegisterSettlement:
+			err = b.registerSettlement(msg.From, value, args, receipt)
+		case sigChallengeSettlement:
+			err = b.challengeSettlement(msg.From, args, receipt)
+		case sigResolveChallenge:
+			err = b.resolveChallenge(msg.From, args, receipt)
+		case sigAuthorizeChallenger:
+			err = b.authorizeChallenger(msg.From, args)
+		default:
+			err = chain.Revert(fmt.Sprintf("%s is a view function", sig))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// dispatch matches calldata to a function of the contract and returns a decoder over its
+// arguments
+func (b *SimulatedBackend) dispatch(data []byte) (string, *abi.Decoder, error) {
+	return chain.Dispatch(data,
+		sigRegisterSettlement, sigChallengeSettlement, sigResolveChallenge, sigAuthorizeChallenger,
+		sigGetSettlement, sigGetChallenge, sigOperatorStakes, sigVerifySkippedOrderProof, sigVerifySettlementProof,
+	)
+}
+
+// stake returns an operator's stake, zero if it has none
+func (b *SimulatedBackend) stake(operator chain.Address) *big.Int {
+	if stake, ok := b.stakes[operator]; ok {
+		return stake
+	}
+	return new(big.Int)
+}
+
+// emit appends a log of the contract to the receipt
+func (b *SimulatedBackend) emit(receipt *chain.Receipt, signature string, data []byte, topics ...merkle.Hash) {
+	receipt.Logs = append(receipt.Logs, chain.Log{
+		Address: b.contract,
+		Topics:  append([]merkle.Hash{chain.EventTopic(signature)}, topics...),
+		Data:    data,
+	})
+}
+
+// registerSettlement executes registerSettlement, staking the transaction value
+func (b *SimulatedBackend) registerSettlement(from chain.Address, value *big.Int, args *abi.Decoder, receipt *chain.Receipt) error {
+	txID, err := args.Bytes32(0)
+	if err != nil {
+		return err
+	}
+	snapshotHash, err := args.Bytes32(1)
+	if err != nil {
+		return err
+	}
+	orderRoot, err := args.Bytes32(2)
+	if err != nil {
+		return err
+	}
+	batchID, err := args.String(3)
+	if err != nil {
+		return err
+	}
+
+	if value.Cmp(MinStake) < 0 {
+		return chain.Revert("Insufficient stake")
+	}
+	settlementID := SettlementID(txID, from, b.blockTime)
+	if _, ok := b.settlements[settlementID]; ok {
+		return chain.Revert("Settlement already exists")
+	}
+
+	b.settlements[settlementID] = &Settlement{
+		TxID:              txID,
+		Operator:          from,
+		Timestamp:         b.blockTime,
+		Status:            StatusActive,
+		SnapshotHash:      snapshotHash,
+		OrderRoot:         orderRoot,
+		TradeBatchID:      batchID,
+		ChallengeDeadline: b.blockTime + uint64(ChallengePeriod/time.Second),
+	}
+	b.balances[from] = new(big.Int).Sub(b.balance(from), value)
+	b.stakes[from] = new(big.Int).Add(b.stake(from), value)
+
+	b.emit(receipt, sigSettlementRegistered, abi.Encode(abi.Bytes32(snapshotHash), abi.Bytes32(orderRoot), abi.String(batchID)),
+		settlementID, txID, chain.AddressTopic(from))
+	return nil
+}
+
+// challengeSettlement executes challengeSettlement
+func (b *SimulatedBackend) challengeSettlement(from chain.Address, args *abi.Decoder, receipt *chain.Receipt) error {
+	settlementID, err := args.Bytes32(0)
+	if err != nil {
+		return err
+	}
+	proof, err := args.Bytes(1)
+	if err != nil {
+		return err
+	}
+
+	if !b.authorized[from] {
+		return chain.Revert("Not authorized to challenge")
+	}
+	settlement, ok := b.settlements[settlementID]
+	if !ok {
+		return chain.Revert("Settlement does not exist")
+	}
+	if settlement.Status != StatusActive {
+		return chain.Revert("Settlement not active")
+	}
+	if b.blockTime > settlement.ChallengeDeadline {
+		return chain.Revert("Challenge period expired")
+	}
+	if ok, err := b.verifySettlementProof(settlementID, proof); err != nil || !ok {
+		return chain.Revert("Invalid fraud proof")
+	}
+
+	challengeID := ChallengeID(settlementID, from, b.blockTime)
+	b.challenges[challengeID] = &Challenge{
+		Challenger:   from,
+		SettlementID: settlementID,
+		Proof:        proof,
+		Timestamp:    b.blockTime,
+	}
+	settlement.Status = StatusChallenged
+
+	b.emit(receipt, sigSettlementChallenged, abi.Encode(abi.Bytes32(challengeID)), settlementID, chain.AddressTopic(from), settlement.TxID)
+	return nil
+}
+
+// resolveChallenge executes resolveChallenge, paying half the operator's stake to the
+// challenger when the challenge succeeds
+func (b *SimulatedBackend) resolveChallenge(from chain.Address, args *abi.Decoder, receipt *chain.Receipt) error {
+	challengeID, err := args.Bytes32(0)
+	if err != nil {
+		return err
+	}
+	successful, err := args.Bool(1)
+	if err != nil {
+		return err
+	}
+
+	if from != b.owner {
+		return chain.Revert("Ownable: caller is not the owner")
+	}
+	challenge, ok := b.challenges[challengeID]
+	if !ok {
+		challenge = &Challenge{}
+	}
+	if challenge.Resolved {
+		return chain.Revert("Challenge already resolved")
+	}
+	settlement, ok := b.settlements[challenge.SettlementID]
+	if !ok || settlement.Status != StatusChallenged {
+		return chain.Revert("Settlement not challenged")
+	}
+
+	challenge.Resolved = true
+	challenge.Successful = successful
+
+	if successful {
+		settlement.Status = StatusSlashed
+		stake := b.stake(settlement.Operator)
+		slashAmount := new(big.Int).Div(new(big.Int).Mul(stake, big.NewInt(slashPercentage)), big.NewInt(100))
+		if slashAmount.Sign() > 0 {
+			b.stakes[settlement.Operator] = new(big.Int).Sub(stake, slashAmount)
+			b.credit(challenge.Challenger, slashAmount)
+
+			amount, _ := abi.Uint256(slashAmount)
+			b.emit(receipt, sigOperatorSlashed, abi.Encode(amount),
+				challenge.SettlementID, chain.AddressTopic(settlement.Operator))
+		}
+	} else {
+		settlement.Status = StatusActive
+	}
+
+	b.emit(receipt, sigChallengeResolved, abi.Encode(abi.Bool(successful)),
+		challengeID, challenge.SettlementID, chain.AddressTopic(challenge.Challenger))
+	return nil
+}
+
+// authorizeChallenger executes authorizeChallenger
+func (b *SimulatedBackend) authorizeChallenger(from chain.Address, args *abi.Decoder) error {
+	challenger, err := args.Address(0)
+	if err != nil {
+		return err
+	}
+	if from != b.owner {
+		return chain.Revert("Ownable: caller is not the owner")
+	}
+	b.authorized[challenger] = true
+	return nil
+}
+
+// encodeSettlement encodes a settlement as getSettlement returns it, zero for an unknown one
+func encodeSettlement(s *Settlement) []byte {
+	if s == nil {
+		s = &Settlement{}
+	}
+	return abi.Encode(abi.Tuple(
+		abi.Bytes32(s.TxID),
+		abi.Address(s.Operator),
+		abi.Uint64(s.Timestamp),
+		abi.Uint64(uint64(s.Status)),
+		abi.Bytes32(s.SnapshotHash),
+		abi.Bytes32(s.OrderRoot),
+		abi.String(s.TradeBatchID),
+		abi.Uint64(s.ChallengeDeadline),
+	))
+}
+
+// encodeChallenge encodes a challenge as getChallenge returns it, zero for an unknown one
+func encodeChallenge(c *Challenge) []byte {
+	if c == nil {
+		c = &Challenge{}
+	}
+	return abi.Encode(abi.Tuple(
+		abi.Address(c.Challenger),
+		abi.Bytes32(c.SettlementID),
+		abi.Bytes(c.Proof),
+		abi.Uint64(c.Timestamp),
+		abi.Bool(c.Resolved),
+		abi.Bool(c.Successful),
+	))
+}
+
+// verifySettlementProof mirrors SettlementVerifier.verifySettlementProof: the proof must be
+// built on the order root registered for the settlement
+func (b *SimulatedBackend) verifySettlementProof(settlementID merkle.Hash, data []byte) (bool, error) {
+	settlement, ok := b.settlements[settlementID]
+	if !ok {
+		return false, chain.Revert("Settlement does not exist")
+	}
+	p, err := abi.NewDecoder(data).Tuple(0)
+	if err != nil {
+		return false, err
+	}
+	root, err := p.Bytes32(1)
+	if err != nil {
+		return false, err
+	}
+	if root != settlement.OrderRoot {
+		return false, nil
+	}
+	return verifySkippedOrderProof(data)
+}
+
+// verifySkippedOrderProof mirrors SettlementVerifier.verifySkippedOrderProof: it decodes
+// abi.encode(SkippedOrderProof) and checks both inclusion proofs against the order root
+func verifySkippedOrderProof(data []byte) (bool, error) {
+	p, err := abi.NewDecoder(data).Tuple(0)
+	if err != nil {
+		return false, err
+	}
+	version, err := p.Uint64(0)
+	if err != nil {
+		return false, err
+	}
+	if version != fraudProofVersion {
+		return false, chain.Revert("Unsupported fraud proof version")
+	}
+	root, err := p.Bytes32(1)
+	if err != nil {
+		return false, err
+	}
+
+	for i := 2; i <= 3; i++ {
+		orderProof, err := p.Tuple(i)
+		if err != nil {
+			return false, err
+		}
+		leaf, err := orderProof.Bytes(0)
+		if err != nil {
+			return false, err
+		}
+		siblings, n, err := orderProof.Array(1)
+		if err != nil {
+			return false, err
+		}
+		path, err := orderProof.Uint256(2)
+		if err != nil {
+			return false, err
+		}
+
+		// Bits of the path beyond the siblings are ignored on-chain
+		proof := &merkle.Proof{}
+		for j := 0; j < n; j++ {
+			sibling, err := siblings.Bytes32(j)
+			if err != nil {
+				return false, err
+			}
+			proof.Siblings = append(proof.Siblings, sibling)
+			if path.Bit(j) == 1 {
+				proof.Path |= 1 << uint(j)
+			}
+		}
+		if !merkle.Verify(root, leaf, proof, merkle.Keccak256) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
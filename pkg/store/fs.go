@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/fsutil"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// corruptSuffix is appended to files moved aside by Quarantine
+const corruptSuffix = ".corrupt"
+
+// FSStore stores snapshots in a local directory with one subdirectory per market:
+//
+//	<dir>/<market>/trades_N.json, <dir>/<market>/snapshot_N.json, <dir>/<market>/snapshot_N.committed
+//
+// Publishing a sequence writes the trades, then the snapshot, then the commit marker, each
+// atomically; files without a marker are ignored by readers and overwritten by Put.
+type FSStore struct {
+	dir string
+}
+
+// NewFSStore creates a store over the given directory
+func NewFSStore(dir string) *FSStore {
+	return &FSStore{dir: dir}
+}
+
+// Dir returns the directory backing the store
+func (s *FSStore) Dir() string {
+	return s.dir
+}
+
+// marketDir returns the directory holding a market's snapshots
+func (s *FSStore) marketDir(marketID string) (string, error) {
+	if err := ValidateMarketID(marketID); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, marketID), nil
+}
+
+// Put atomically writes the trades, snapshot and commit marker of a sequence
+func (s *FSStore) Put(ctx context.Context, snapshot *orderbookchecker.OrderbookSnapshot, trades []orderbookchecker.Trade) error {
+	dir, err := s.marketDir(snapshot.MarketID)
+	if err != nil {
+		return err
+	}
+
+	seq := snapshot.SequenceNumber
+	if committed(dir, seq) {
+		return fmt.Errorf("%w: market %s sequence %d", ErrSnapshotExists, snapshot.MarketID, seq)
+	}
+
+	hash, err := orderbookchecker.HashSnapshot(*snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to hash snapshot: %v", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create market directory: %v", err)
+	}
+
+	// A sequence without trades has no trades file, so remove one left behind by an
+	// earlier uncommitted attempt
+	tradesFile := filepath.Join(dir, tradesName(seq))
+	if len(trades) == 0 {
+		if err := os.Remove(tradesFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale trades file: %v", err)
+		}
+	} else if err := writeJSON(tradesFile, trades); err != nil {
+		return fmt.Errorf("failed to write trades file: %v", err)
+	}
+
+	if err := writeJSON(filepath.Join(dir, snapshotName(seq)), snapshot); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %v", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(filepath.Join(dir, markerName(seq)), []byte(hash+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write commit marker: %v", err)
+	}
+
+	return nil
+}
+
+// Get loads a committed snapshot
+func (s *FSStore) Get(ctx context.Context, marketID string, sequenceNum uint64) (*orderbookchecker.OrderbookSnapshot, error) {
+	dir, err := s.committedDir(marketID, sequenceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, snapshotName(sequenceNum)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %v", err)
+	}
+
+	var snapshot orderbookchecker.OrderbookSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %v", err)
+	}
+
+	return &snapshot, nil
+}
+
+// GetTrades loads the trades of a committed snapshot
+func (s *FSStore) GetTrades(ctx context.Context, marketID string, sequenceNum uint64) ([]orderbookchecker.Trade, error) {
+	dir, err := s.committedDir(marketID, sequenceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, tradesName(sequenceNum)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []orderbookchecker.Trade{}, nil
+		}
+		return nil, fmt.Errorf("failed to read trades file: %v", err)
+	}
+
+	var trades []orderbookchecker.Trade
+	if err := json.Unmarshal(data, &trades); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trades: %v", err)
+	}
+
+	return trades, nil
+}
+
+// List returns the committed sequences of a market
+func (s *FSStore) List(ctx context.Context, marketID string) ([]uint64, error) {
+	dir, err := s.marketDir(marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory: %v", err)
+	}
+
+	var sequences []uint64
+	for _, entry := range entries {
+		if seq, ok := parseMarkerName(entry.Name()); ok && !entry.IsDir() {
+			sequences = append(sequences, seq)
+		}
+	}
+
+	sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+	return sequences, nil
+}
+
+// ListMarkets returns the market subdirectories that contain a committed snapshot
+func (s *FSStore) ListMarkets(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory: %v", err)
+	}
+
+	var markets []string
+	for _, entry := range entries {
+		if !entry.IsDir() || ValidateMarketID(entry.Name()) != nil {
+			continue
+		}
+
+		sequences, err := s.List(ctx, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if len(sequences) > 0 {
+			markets = append(markets, entry.Name())
+		}
+	}
+
+	// ReadDir returns entries sorted by name
+	return markets, nil
+}
+
+// Watch polls the directory for newly committed sequences
+func (s *FSStore) Watch(ctx context.Context, marketID string, after uint64, interval time.Duration) <-chan WatchEvent {
+	return pollWatch(ctx, after, interval, func(ctx context.Context) ([]uint64, error) {
+		return s.List(ctx, marketID)
+	})
+}
+
+// Quarantine renames the snapshot, trades and commit marker of a sequence to *.corrupt
+func (s *FSStore) Quarantine(ctx context.Context, marketID string, sequenceNum uint64) error {
+	dir, err := s.marketDir(marketID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{snapshotName(sequenceNum), tradesName(sequenceNum), markerName(sequenceNum)} {
+		path := filepath.Join(dir, name)
+		if err := os.Rename(path, path+corruptSuffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to quarantine %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// committedDir returns the market directory of a committed sequence, or ErrNotFound
+func (s *FSStore) committedDir(marketID string, sequenceNum uint64) (string, error) {
+	dir, err := s.marketDir(marketID)
+	if err != nil {
+		return "", err
+	}
+	if !committed(dir, sequenceNum) {
+		return "", fmt.Errorf("%w: market %s sequence %d", ErrNotFound, marketID, sequenceNum)
+	}
+	return dir, nil
+}
+
+// committed reports whether the commit marker of a sequence exists in a market directory
+func committed(dir string, sequenceNum uint64) bool {
+	_, err := os.Stat(filepath.Join(dir, markerName(sequenceNum)))
+	return err == nil
+}
+
+// writeJSON atomically writes v as indented JSON
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %v", err)
+	}
+	return fsutil.WriteFileAtomic(path, data, 0644)
+}
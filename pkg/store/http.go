@@ -0,0 +1,368 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// HTTPStore stores snapshots in an S3-compatible object store, addressed path-style as
+// <baseURL>/<key>. Objects use the FSStore names under a per-market prefix:
+//
+//	<market>/trades_N.json, <market>/snapshot_N.json, <market>/snapshot_N.committed
+//
+// and sequences are listed with ListObjectsV2. Requests are not signed; set Header for
+// token authentication, or point baseURL at a gateway that signs requests.
+type HTTPStore struct {
+	baseURL string
+	client  *http.Client
+
+	// Header is added to every request
+	Header http.Header
+}
+
+// NewHTTPStore creates a store for the bucket at baseURL, e.g. http://localhost:9000/snapshots.
+// A nil client uses http.DefaultClient.
+func NewHTTPStore(baseURL string, client *http.Client) *HTTPStore {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPStore{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+		Header:  make(http.Header),
+	}
+}
+
+// listBucketResult is the subset of the ListObjectsV2 response used by List and ListMarkets
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// Put uploads the trades, snapshot and commit marker of a sequence, in that order
+func (s *HTTPStore) Put(ctx context.Context, snapshot *orderbookchecker.OrderbookSnapshot, trades []orderbookchecker.Trade) error {
+	marketID, seq := snapshot.MarketID, snapshot.SequenceNumber
+	if err := ValidateMarketID(marketID); err != nil {
+		return err
+	}
+
+	committed, err := s.committed(ctx, marketID, seq)
+	if err != nil {
+		return err
+	}
+	if committed {
+		return fmt.Errorf("%w: market %s sequence %d", ErrSnapshotExists, marketID, seq)
+	}
+
+	hash, err := orderbookchecker.HashSnapshot(*snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to hash snapshot: %v", err)
+	}
+
+	if len(trades) == 0 {
+		if err := s.delete(ctx, s.key(marketID, tradesName(seq))); err != nil {
+			return err
+		}
+	} else if err := s.putJSON(ctx, s.key(marketID, tradesName(seq)), trades); err != nil {
+		return err
+	}
+
+	if err := s.putJSON(ctx, s.key(marketID, snapshotName(seq)), snapshot); err != nil {
+		return err
+	}
+
+	return s.put(ctx, s.key(marketID, markerName(seq)), []byte(hash+"\n"))
+}
+
+// Get downloads a committed snapshot
+func (s *HTTPStore) Get(ctx context.Context, marketID string, sequenceNum uint64) (*orderbookchecker.OrderbookSnapshot, error) {
+	if err := s.requireCommitted(ctx, marketID, sequenceNum); err != nil {
+		return nil, err
+	}
+
+	data, found, err := s.get(ctx, s.key(marketID, snapshotName(sequenceNum)))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("snapshot object missing for committed sequence %d", sequenceNum)
+	}
+
+	var snapshot orderbookchecker.OrderbookSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %v", err)
+	}
+	return &snapshot, nil
+}
+
+// GetTrades downloads the trades of a committed snapshot
+func (s *HTTPStore) GetTrades(ctx context.Context, marketID string, sequenceNum uint64) ([]orderbookchecker.Trade, error) {
+	if err := s.requireCommitted(ctx, marketID, sequenceNum); err != nil {
+		return nil, err
+	}
+
+	data, found, err := s.get(ctx, s.key(marketID, tradesName(sequenceNum)))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return []orderbookchecker.Trade{}, nil
+	}
+
+	var trades []orderbookchecker.Trade
+	if err := json.Unmarshal(data, &trades); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trades: %v", err)
+	}
+	return trades, nil
+}
+
+// List pages through the commit markers of a market with ListObjectsV2
+func (s *HTTPStore) List(ctx context.Context, marketID string) ([]uint64, error) {
+	if err := ValidateMarketID(marketID); err != nil {
+		return nil, err
+	}
+
+	var sequences []uint64
+	err := s.listObjects(ctx, s.key(marketID, snapshotPrefix), "", func(result *listBucketResult) {
+		for _, object := range result.Contents {
+			name := strings.TrimPrefix(object.Key, s.key(marketID, ""))
+			if seq, ok := parseMarkerName(name); ok {
+				sequences = append(sequences, seq)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+	return sequences, nil
+}
+
+// ListMarkets lists the top-level market prefixes of the bucket and keeps those with a
+// committed snapshot
+func (s *HTTPStore) ListMarkets(ctx context.Context) ([]string, error) {
+	var prefixes []string
+	err := s.listObjects(ctx, "", "/", func(result *listBucketResult) {
+		for _, prefix := range result.CommonPrefixes {
+			prefixes = append(prefixes, strings.TrimSuffix(prefix.Prefix, "/"))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var markets []string
+	for _, marketID := range prefixes {
+		if ValidateMarketID(marketID) != nil {
+			continue
+		}
+
+		sequences, err := s.List(ctx, marketID)
+		if err != nil {
+			return nil, err
+		}
+		if len(sequences) > 0 {
+			markets = append(markets, marketID)
+		}
+	}
+
+	sort.Strings(markets)
+	return markets, nil
+}
+
+// listObjects calls page for every page of a ListObjectsV2 listing
+func (s *HTTPStore) listObjects(ctx context.Context, prefix, delimiter string, page func(*listBucketResult)) error {
+	var token string
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, s.baseURL+"?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		body, err := readBody(resp)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("list objects failed: %s", resp.Status)
+		}
+
+		var result listBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("failed to parse list response: %v", err)
+		}
+		page(&result)
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Watch polls the bucket for newly committed sequences
+func (s *HTTPStore) Watch(ctx context.Context, marketID string, after uint64, interval time.Duration) <-chan WatchEvent {
+	return pollWatch(ctx, after, interval, func(ctx context.Context) ([]uint64, error) {
+		return s.List(ctx, marketID)
+	})
+}
+
+// key returns the object key of a file name within a market
+func (s *HTTPStore) key(marketID, name string) string {
+	return marketID + "/" + name
+}
+
+// objectURL returns the URL of an object key, escaping each path segment
+func (s *HTTPStore) objectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return s.baseURL + "/" + strings.Join(segments, "/")
+}
+
+func (s *HTTPStore) committed(ctx context.Context, marketID string, sequenceNum uint64) (bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, s.objectURL(s.key(marketID, markerName(sequenceNum))), nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check commit marker: %s", resp.Status)
+	}
+}
+
+func (s *HTTPStore) requireCommitted(ctx context.Context, marketID string, sequenceNum uint64) error {
+	if err := ValidateMarketID(marketID); err != nil {
+		return err
+	}
+
+	committed, err := s.committed(ctx, marketID, sequenceNum)
+	if err != nil {
+		return err
+	}
+	if !committed {
+		return fmt.Errorf("%w: market %s sequence %d", ErrNotFound, marketID, sequenceNum)
+	}
+	return nil
+}
+
+func (s *HTTPStore) get(ctx context.Context, key string) ([]byte, bool, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, true, nil
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("failed to get %s: %s", key, resp.Status)
+	}
+}
+
+func (s *HTTPStore) put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(key), data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to put %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+func (s *HTTPStore) putJSON(ctx context.Context, key string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %v", key, err)
+	}
+	return s.put(ctx, key, data)
+}
+
+func (s *HTTPStore) delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+func (s *HTTPStore) do(ctx context.Context, method, rawURL string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	for name, values := range s.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %v", method, rawURL, err)
+	}
+	return resp, nil
+}
+
+func readBody(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal stand-in for an S3-compatible bucket: object GET/PUT/HEAD/DELETE and
+// ListObjectsV2 with pagination and delimiters
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	pageLen int
+}
+
+func newFakeS3(t *testing.T) (*httptest.Server, *fakeS3) {
+	fake := &fakeS3{bucket: "snapshots", objects: make(map[string][]byte), pageLen: 2}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return server, fake
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/"+f.bucket {
+		f.list(w, r)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/"+f.bucket+"/")
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	delimiter := r.URL.Query().Get("delimiter")
+	after := r.URL.Query().Get("continuation-token")
+
+	var result listBucketResult
+	var keys []string
+	seenPrefixes := make(map[string]bool)
+	for key := range f.objects {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+
+		// Keys below a delimiter are rolled up into a common prefix
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common := key[:len(prefix)+i+len(delimiter)]
+				if !seenPrefixes[common] {
+					seenPrefixes[common] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, struct {
+						Prefix string `xml:"Prefix"`
+					}{Prefix: common})
+				}
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > f.pageLen {
+		keys = keys[:f.pageLen]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		result.Contents = append(result.Contents, struct {
+			Key string `xml:"Key"`
+		}{Key: key})
+	}
+
+	xml.NewEncoder(w).Encode(result)
+}
+
+func TestHTTPStore(t *testing.T) {
+	server, fake := newFakeS3(t)
+
+	testSnapshotStore(t, NewHTTPStore(server.URL+"/"+fake.bucket, server.Client()), "TEST-MARKET")
+
+	if _, ok := fake.objects["TEST-MARKET/snapshot_1.committed"]; !ok {
+		t.Error("Expected commit marker under the market prefix")
+	}
+}
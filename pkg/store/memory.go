@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// memoryEntry holds the encoded snapshot and trades of a sequence. Values are stored as
+// JSON so callers cannot mutate stored snapshots through shared pointers.
+type memoryEntry struct {
+	snapshot []byte
+	trades   []byte
+}
+
+// MemoryStore keeps snapshots in memory, for tests and local simulations
+type MemoryStore struct {
+	mu      sync.RWMutex
+	markets map[string]map[uint64]memoryEntry
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{markets: make(map[string]map[uint64]memoryEntry)}
+}
+
+// Put stores a snapshot and its trades
+func (s *MemoryStore) Put(ctx context.Context, snapshot *orderbookchecker.OrderbookSnapshot, trades []orderbookchecker.Trade) error {
+	if err := ValidateMarketID(snapshot.MarketID); err != nil {
+		return err
+	}
+
+	snapshotData, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %v", err)
+	}
+
+	if trades == nil {
+		trades = []orderbookchecker.Trade{}
+	}
+	tradesData, err := json.Marshal(trades)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trades: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	market := s.markets[snapshot.MarketID]
+	if market == nil {
+		market = make(map[uint64]memoryEntry)
+		s.markets[snapshot.MarketID] = market
+	}
+
+	if _, ok := market[snapshot.SequenceNumber]; ok {
+		return fmt.Errorf("%w: sequence %d", ErrSnapshotExists, snapshot.SequenceNumber)
+	}
+
+	market[snapshot.SequenceNumber] = memoryEntry{snapshot: snapshotData, trades: tradesData}
+	return nil
+}
+
+// Get returns a stored snapshot
+func (s *MemoryStore) Get(ctx context.Context, marketID string, sequenceNum uint64) (*orderbookchecker.OrderbookSnapshot, error) {
+	entry, err := s.entry(marketID, sequenceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot orderbookchecker.OrderbookSnapshot
+	if err := json.Unmarshal(entry.snapshot, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %v", err)
+	}
+	return &snapshot, nil
+}
+
+// GetTrades returns the trades of a stored snapshot
+func (s *MemoryStore) GetTrades(ctx context.Context, marketID string, sequenceNum uint64) ([]orderbookchecker.Trade, error) {
+	entry, err := s.entry(marketID, sequenceNum)
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []orderbookchecker.Trade
+	if err := json.Unmarshal(entry.trades, &trades); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trades: %v", err)
+	}
+	return trades, nil
+}
+
+// List returns the stored sequences of a market
+func (s *MemoryStore) List(ctx context.Context, marketID string) ([]uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sequences := make([]uint64, 0, len(s.markets[marketID]))
+	for seq := range s.markets[marketID] {
+		sequences = append(sequences, seq)
+	}
+
+	sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+	return sequences, nil
+}
+
+// ListMarkets returns the markets with at least one stored sequence
+func (s *MemoryStore) ListMarkets(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var markets []string
+	for marketID, sequences := range s.markets {
+		if len(sequences) > 0 {
+			markets = append(markets, marketID)
+		}
+	}
+
+	sort.Strings(markets)
+	return markets, nil
+}
+
+// Watch polls the store for newly stored sequences
+func (s *MemoryStore) Watch(ctx context.Context, marketID string, after uint64, interval time.Duration) <-chan WatchEvent {
+	return pollWatch(ctx, after, interval, func(ctx context.Context) ([]uint64, error) {
+		return s.List(ctx, marketID)
+	})
+}
+
+// Quarantine removes a sequence from the store
+func (s *MemoryStore) Quarantine(ctx context.Context, marketID string, sequenceNum uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.markets[marketID], sequenceNum)
+	return nil
+}
+
+func (s *MemoryStore) entry(marketID string, sequenceNum uint64) (memoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.markets[marketID][sequenceNum]
+	if !ok {
+		return memoryEntry{}, fmt.Errorf("%w: market %s sequence %d", ErrNotFound, marketID, sequenceNum)
+	}
+	return entry, nil
+}
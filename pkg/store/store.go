@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// DefaultWatchInterval is the polling interval of a watch given a non-positive interval
+const DefaultWatchInterval = 5 * time.Second
+
+var (
+	// ErrSnapshotExists is returned by Put when the sequence has already been committed
+	ErrSnapshotExists = errors.New("snapshot already exists")
+	// ErrNotFound is returned when a sequence has not been committed
+	ErrNotFound = errors.New("snapshot not found")
+)
+
+// SnapshotStore persists published orderbook snapshots and their trades, keyed by market
+// and sequence number. Each market has its own sequence numbers. A sequence is only
+// visible to readers once Put has completed.
+type SnapshotStore interface {
+	// Put publishes a snapshot and the trades settled against it. It fails with
+	// ErrSnapshotExists if the sequence has already been committed.
+	Put(ctx context.Context, snapshot *orderbookchecker.OrderbookSnapshot, trades []orderbookchecker.Trade) error
+
+	// Get returns a committed snapshot, or ErrNotFound
+	Get(ctx context.Context, marketID string, sequenceNum uint64) (*orderbookchecker.OrderbookSnapshot, error)
+
+	// GetTrades returns the trades of a committed snapshot; a sequence without trades
+	// returns an empty list
+	GetTrades(ctx context.Context, marketID string, sequenceNum uint64) ([]orderbookchecker.Trade, error)
+
+	// List returns the committed sequence numbers of a market in ascending order
+	List(ctx context.Context, marketID string) ([]uint64, error)
+
+	// ListMarkets returns the markets with at least one committed snapshot, sorted by ID
+	ListMarkets(ctx context.Context) ([]string, error)
+
+	// Watch delivers sequences committed after the given sequence, in order, polling at the
+	// given interval until ctx is cancelled. A non-positive interval polls at
+	// DefaultWatchInterval.
+	Watch(ctx context.Context, marketID string, after uint64, interval time.Duration) <-chan WatchEvent
+}
+
+// Repairer is implemented by stores that can move a corrupt sequence out of the way so the
+// chain can continue from the sequence before it
+type Repairer interface {
+	Quarantine(ctx context.Context, marketID string, sequenceNum uint64) error
+}
+
+// WatchEvent is a newly committed sequence, or an error encountered while polling
+type WatchEvent struct {
+	Sequence uint64
+	Err      error
+}
+
+// ValidateMarketID checks that a market ID can be used as a directory or key prefix
+func ValidateMarketID(marketID string) error {
+	if marketID == "" {
+		return fmt.Errorf("market ID is required")
+	}
+	if marketID == "." || marketID == ".." || strings.ContainsAny(marketID, "/\\") {
+		return fmt.Errorf("invalid market ID: %q", marketID)
+	}
+	return nil
+}
+
+// Object names shared by the file and object store layouts
+const (
+	snapshotPrefix = "snapshot_"
+	tradesPrefix   = "trades_"
+	jsonSuffix     = ".json"
+	markerSuffix   = ".committed"
+)
+
+func snapshotName(sequenceNum uint64) string {
+	return fmt.Sprintf("%s%d%s", snapshotPrefix, sequenceNum, jsonSuffix)
+}
+
+func tradesName(sequenceNum uint64) string {
+	return fmt.Sprintf("%s%d%s", tradesPrefix, sequenceNum, jsonSuffix)
+}
+
+func markerName(sequenceNum uint64) string {
+	return fmt.Sprintf("%s%d%s", snapshotPrefix, sequenceNum, markerSuffix)
+}
+
+// parseMarkerName returns the sequence number of a commit marker name
+func parseMarkerName(name string) (uint64, bool) {
+	if !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, markerSuffix) {
+		return 0, false
+	}
+
+	seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), markerSuffix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seq, true
+}
+
+// pollWatch implements Watch for stores without change notifications by listing the market
+// at every interval
+func pollWatch(ctx context.Context, after uint64, interval time.Duration, list func(context.Context) ([]uint64, error)) <-chan WatchEvent {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	events := make(chan WatchEvent)
+
+	send := func(event WatchEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			sequences, err := list(ctx)
+			if err != nil && ctx.Err() == nil {
+				if !send(WatchEvent{Err: err}) {
+					return
+				}
+			}
+
+			for _, seq := range sequences {
+				if seq <= after {
+					continue
+				}
+				if !send(WatchEvent{Sequence: seq}) {
+					return
+				}
+				after = seq
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+func testSnapshot(t *testing.T, marketID string, seq uint64) *orderbookchecker.OrderbookSnapshot {
+	snapshot := &orderbookchecker.OrderbookSnapshot{
+		SequenceNumber: seq,
+		Timestamp:      time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		MarketID:       marketID,
+		Orders: []orderbookchecker.Order{
+			{ID: "buy-1", Side: "buy", Price: big.NewInt(100), Quantity: big.NewInt(50), UserID: "user1"},
+		},
+		PrevHash: orderbookchecker.GenesisPrevHash,
+	}
+
+	root, err := orderbookchecker.ComputeMerkleRoot(snapshot.Orders)
+	if err != nil {
+		t.Fatalf("ComputeMerkleRoot failed: %v", err)
+	}
+	snapshot.MerkleRoot = root
+	return snapshot
+}
+
+// testSnapshotStore exercises the SnapshotStore contract shared by every implementation
+func testSnapshotStore(t *testing.T, s SnapshotStore, marketID string) {
+	ctx := context.Background()
+
+	trades := []orderbookchecker.Trade{
+		{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(95), Quantity: big.NewInt(30)},
+	}
+
+	if _, err := s.Get(ctx, marketID, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound before Put, got %v", err)
+	}
+
+	if err := s.Put(ctx, testSnapshot(t, marketID, 1), trades); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := s.Put(ctx, testSnapshot(t, marketID, 2), nil); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	if err := s.Put(ctx, testSnapshot(t, marketID, 1), nil); !errors.Is(err, ErrSnapshotExists) {
+		t.Errorf("Expected ErrSnapshotExists, got %v", err)
+	}
+
+	snapshot, err := s.Get(ctx, marketID, 1)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if snapshot.SequenceNumber != 1 || len(snapshot.Orders) != 1 || snapshot.Orders[0].Price.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("Unexpected snapshot: %+v", snapshot)
+	}
+
+	gotTrades, err := s.GetTrades(ctx, marketID, 1)
+	if err != nil {
+		t.Fatalf("GetTrades failed: %v", err)
+	}
+	if len(gotTrades) != 1 || gotTrades[0].ID != "trade-1" {
+		t.Errorf("Unexpected trades: %+v", gotTrades)
+	}
+
+	if gotTrades, err := s.GetTrades(ctx, marketID, 2); err != nil || len(gotTrades) != 0 {
+		t.Errorf("Expected no trades for sequence 2, got %v (err=%v)", gotTrades, err)
+	}
+
+	sequences, err := s.List(ctx, marketID)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(sequences) != 2 || sequences[0] != 1 || sequences[1] != 2 {
+		t.Errorf("Expected sequences [1 2], got %v", sequences)
+	}
+
+	// Watch reports sequences after the starting point, including ones committed later
+	watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	events := s.Watch(watchCtx, marketID, 1, 10*time.Millisecond)
+	expectEvent := func(want uint64) {
+		select {
+		case event := <-events:
+			if event.Err != nil || event.Sequence != want {
+				t.Fatalf("Expected sequence %d, got %+v", want, event)
+			}
+		case <-watchCtx.Done():
+			t.Fatalf("Timed out waiting for sequence %d", want)
+		}
+	}
+
+	expectEvent(2)
+	if err := s.Put(ctx, testSnapshot(t, marketID, 3), nil); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	expectEvent(3)
+
+	// Each market has its own sequence numbers
+	if err := s.Put(ctx, testSnapshot(t, "OTHER-MARKET", 1), nil); err != nil {
+		t.Fatalf("Put for a second market failed: %v", err)
+	}
+	if sequences, err := s.List(ctx, "OTHER-MARKET"); err != nil || len(sequences) != 1 {
+		t.Errorf("Expected one sequence for the second market, got %v (err=%v)", sequences, err)
+	}
+
+	markets, err := s.ListMarkets(ctx)
+	if err != nil {
+		t.Fatalf("ListMarkets failed: %v", err)
+	}
+	if len(markets) != 2 || markets[0] != "OTHER-MARKET" || markets[1] != marketID {
+		t.Errorf("Expected markets [OTHER-MARKET %s], got %v", marketID, markets)
+	}
+
+	if err := s.Put(ctx, testSnapshot(t, "../escape", 1), nil); err == nil {
+		t.Error("Expected error for invalid market ID")
+	}
+}
+
+func TestFSStore(t *testing.T) {
+	testSnapshotStore(t, NewFSStore(t.TempDir()), "TEST-MARKET")
+}
+
+func TestMemoryStore(t *testing.T) {
+	testSnapshotStore(t, NewMemoryStore(), "TEST-MARKET")
+}
+
+func TestWatch_NonPositiveInterval(t *testing.T) {
+	s := NewMemoryStore()
+	if err := s.Put(context.Background(), testSnapshot(t, "TEST-MARKET", 1), nil); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	select {
+	case event := <-s.Watch(ctx, "TEST-MARKET", 0, 0):
+		if event.Err != nil || event.Sequence != 1 {
+			t.Errorf("Expected sequence 1, got %+v", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for sequence 1")
+	}
+}
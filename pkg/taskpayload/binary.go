@@ -0,0 +1,312 @@
+package taskpayload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// Binary payloads
+//
+// A binary payload starts with the magic bytes 0x00 'T' 'S' 'K', which no JSON document
+// starts with, followed by the version and a type code. Orders, trades and the snapshot use
+// their canonical encodings (see pkg/orderbookchecker/encoding.go), so large books are
+// neither re-encoded as text nor re-parsed from it. Integers are big-endian.
+//
+//	magic, version uint8, type uint8,
+//	snapshot_hash bytes32, trade_batch_id string, mode string,
+//	snapshot encoding, trade count uint32 then each trade encoding,
+//	incoming order count uint32 then each order encoding,
+//	cancellation count uint32 then for each: order_id string, timestamp
+//
+// A reference payload (type code 2) carries only hashes and locators:
+//
+//	magic, version uint8, type uint8,
+//	snapshot_hash bytes32, snapshot_locator string, trade_batch_id string,
+//	trade_batch_hash bytes32, trade_batch_locator string, mode string
+//
+// Strings and encodings are prefixed with their uint32 length, and timestamps are int64
+// milliseconds since the Unix epoch.
+
+// binaryMagic prefixes every binary payload
+var binaryMagic = []byte{0x00, 'T', 'S', 'K'}
+
+// Type codes of binary payloads
+const (
+	typeCodeOrderbookVerification    byte = 1
+	typeCodeOrderbookVerificationRef byte = 2
+)
+
+// writer accumulates binary payload fields
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	w.buf.Write(b[:])
+}
+
+func (w *writer) bytes(b []byte) {
+	w.uint32(uint32(len(b)))
+	w.buf.Write(b)
+}
+
+func (w *writer) string(s string) {
+	w.bytes([]byte(s))
+}
+
+func (w *writer) timestamp(t time.Time) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(t.UnixMilli()))
+	w.buf.Write(b[:])
+}
+
+// encodeBinary returns the binary payload of an envelope
+func encodeBinary(envelope *Envelope) ([]byte, error) {
+	input := envelope.Input
+	hash, err := merkle.ParseHash(input.SnapshotHash)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot_hash: %v", err)
+	}
+
+	w := &writer{}
+	w.buf.Write(binaryMagic)
+	w.buf.WriteByte(envelope.Version)
+	w.buf.WriteByte(typeCodeOrderbookVerification)
+	w.buf.Write(hash[:])
+	w.string(input.TradeBatchID)
+	w.string(input.Mode)
+
+	snapshot, err := orderbookchecker.EncodeSnapshot(input.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	w.bytes(snapshot)
+	if err := w.batch(input); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+// batch writes the trades, incoming orders and cancellations of an input
+func (w *writer) batch(input *TaskInput) error {
+	w.uint32(uint32(len(input.Trades)))
+	for _, trade := range input.Trades {
+		encoded, err := orderbookchecker.EncodeTrade(trade)
+		if err != nil {
+			return err
+		}
+		w.bytes(encoded)
+	}
+
+	w.uint32(uint32(len(input.IncomingOrders)))
+	for _, order := range input.IncomingOrders {
+		encoded, err := orderbookchecker.EncodeOrder(order)
+		if err != nil {
+			return err
+		}
+		w.bytes(encoded)
+	}
+
+	w.uint32(uint32(len(input.Cancellations)))
+	for _, cancellation := range input.Cancellations {
+		w.string(cancellation.OrderID)
+		w.timestamp(cancellation.Timestamp)
+	}
+	return nil
+}
+
+// encodeRefBinary returns the binary payload of a reference envelope
+func encodeRefBinary(envelope *Envelope) ([]byte, error) {
+	ref := envelope.Ref
+	snapshotHash, err := merkle.ParseHash(ref.SnapshotHash)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot_hash: %v", err)
+	}
+	batchHash, err := merkle.ParseHash(ref.TradeBatchHash)
+	if err != nil {
+		return nil, fmt.Errorf("trade_batch_hash: %v", err)
+	}
+
+	w := &writer{}
+	w.buf.Write(binaryMagic)
+	w.buf.WriteByte(envelope.Version)
+	w.buf.WriteByte(typeCodeOrderbookVerificationRef)
+	w.buf.Write(snapshotHash[:])
+	w.string(ref.SnapshotLocator)
+	w.string(ref.TradeBatchID)
+	w.buf.Write(batchHash[:])
+	w.string(ref.TradeBatchLocator)
+	w.string(ref.Mode)
+	return w.buf.Bytes(), nil
+}
+
+// reader consumes binary payload fields, remembering the first error encountered
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = fmt.Errorf("unexpected end of payload: need %d bytes, have %d", n, len(r.data))
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) uint8() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) bytes() []byte {
+	return r.next(int(r.uint32()))
+}
+
+func (r *reader) string() string {
+	return string(r.bytes())
+}
+
+func (r *reader) timestamp() time.Time {
+	if b := r.next(8); b != nil {
+		return time.UnixMilli(int64(binary.BigEndian.Uint64(b))).UTC()
+	}
+	return time.Time{}
+}
+
+// count reads an element count; every element takes at least minSize bytes, which bounds
+// the count by the remaining data
+func (r *reader) count(minSize int) int {
+	n := int(r.uint32())
+	if r.err == nil && n > len(r.data)/minSize {
+		r.err = fmt.Errorf("count %d exceeds the payload", n)
+		return 0
+	}
+	return n
+}
+
+// decodeBinary decodes a binary payload
+func decodeBinary(data []byte) (*Envelope, error) {
+	r := &reader{data: data[len(binaryMagic):]}
+	version := r.uint8()
+	typeCode := r.uint8()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if version != Version2 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	switch typeCode {
+	case typeCodeOrderbookVerification:
+	case typeCodeOrderbookVerificationRef:
+		return decodeRefBinary(r, version)
+	default:
+		return nil, fmt.Errorf("%w: type code %d", ErrUnknownType, typeCode)
+	}
+
+	input := &TaskInput{}
+	input.SnapshotHash = r.hash()
+	input.TradeBatchID = r.string()
+	input.Mode = r.string()
+
+	snapshot := r.bytes()
+	if r.err != nil {
+		return nil, r.err
+	}
+	var err error
+	if input.Snapshot, err = orderbookchecker.DecodeSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+
+	if err := r.batch(input); err != nil {
+		return nil, err
+	}
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+	return &Envelope{Version: version, Type: TypeOrderbookVerification, Input: input}, nil
+}
+
+// batch reads the trades, incoming orders and cancellations of an input
+func (r *reader) batch(input *TaskInput) error {
+	for i, n := 0, r.count(4); i < n && r.err == nil; i++ {
+		encoded := r.bytes()
+		if r.err != nil {
+			break
+		}
+		trade, err := orderbookchecker.DecodeTrade(encoded)
+		if err != nil {
+			return err
+		}
+		input.Trades = append(input.Trades, trade)
+	}
+
+	for i, n := 0, r.count(4); i < n && r.err == nil; i++ {
+		encoded := r.bytes()
+		if r.err != nil {
+			break
+		}
+		order, err := orderbookchecker.DecodeOrder(encoded)
+		if err != nil {
+			return err
+		}
+		input.IncomingOrders = append(input.IncomingOrders, order)
+	}
+
+	for i, n := 0, r.count(12); i < n && r.err == nil; i++ {
+		cancellation := orderbookchecker.Cancellation{OrderID: r.string(), Timestamp: r.timestamp()}
+		input.Cancellations = append(input.Cancellations, cancellation)
+	}
+	return r.err
+}
+
+// hash reads a bytes32 hash as hex
+func (r *reader) hash() string {
+	var hash merkle.Hash
+	copy(hash[:], r.next(len(hash)))
+	return hash.Hex()
+}
+
+// done reports any error, or any data left after the last field
+func (r *reader) done() error {
+	if r.err == nil && len(r.data) > 0 {
+		r.err = fmt.Errorf("%d trailing bytes", len(r.data))
+	}
+	return r.err
+}
+
+// decodeRefBinary decodes the body of a binary reference payload
+func decodeRefBinary(r *reader, version uint8) (*Envelope, error) {
+	ref := &TaskRef{}
+	ref.SnapshotHash = r.hash()
+	ref.SnapshotLocator = r.string()
+	ref.TradeBatchID = r.string()
+	ref.TradeBatchHash = r.hash()
+	ref.TradeBatchLocator = r.string()
+	ref.Mode = r.string()
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+	return &Envelope{Version: version, Type: TypeOrderbookVerificationRef, Ref: ref}, nil
+}
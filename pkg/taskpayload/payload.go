@@ -0,0 +1,143 @@
+// Package taskpayload defines the versioned payload of AVS tasks, shared by the aggregator
+// that creates tasks and the performer that executes them.
+package taskpayload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// Payload versions
+const (
+	// Version1 is a bare JSON TaskInput, as created before payloads were versioned
+	Version1 uint8 = 1
+	// Version2 wraps the input in an Envelope naming its payload type, encoded as JSON or binary
+	Version2 uint8 = 2
+)
+
+// Payload types carried by an envelope
+const (
+	TypeOrderbookVerification = "orderbook_verification"
+	// TypeOrderbookVerificationRef carries a TaskRef instead of an inline TaskInput
+	TypeOrderbookVerificationRef = "orderbook_verification_ref"
+)
+
+// Encodings of a version 2 payload
+const (
+	EncodingJSON   = "json"
+	EncodingBinary = "binary"
+)
+
+var (
+	// ErrUnsupportedVersion is returned for payloads of a version this build cannot read
+	ErrUnsupportedVersion = errors.New("unsupported payload version")
+	// ErrUnknownType is returned for payloads of a type this build cannot execute
+	ErrUnknownType = errors.New("unknown payload type")
+)
+
+// TaskInput represents the input data for orderbook verification tasks
+type TaskInput struct {
+	SnapshotHash string                             `json:"snapshot_hash"`
+	Snapshot     orderbookchecker.OrderbookSnapshot `json:"snapshot"`
+	Trades       []orderbookchecker.Trade           `json:"trades"`
+	TradeBatchID string                             `json:"trade_batch_id"`
+	// Mode selects the verification strategy ("trades" when empty, or "replay")
+	Mode string `json:"mode,omitempty"`
+	// IncomingOrders is the ordered stream of orders replayed on top of the snapshot in replay mode
+	IncomingOrders []orderbookchecker.Order `json:"incoming_orders,omitempty"`
+	// Cancellations lists orders cancelled after the snapshot; trades against an order at or
+	// after its cancellation are invalid
+	Cancellations []orderbookchecker.Cancellation `json:"cancellations,omitempty"`
+}
+
+// Envelope is a decoded task payload. Version 1 payloads decode as an envelope of type
+// TypeOrderbookVerification. Input is set for TypeOrderbookVerification and Ref for
+// TypeOrderbookVerificationRef.
+type Envelope struct {
+	Version uint8      `json:"version"`
+	Type    string     `json:"type"`
+	Input   *TaskInput `json:"input,omitempty"`
+	Ref     *TaskRef   `json:"ref,omitempty"`
+}
+
+// NewEnvelope wraps an orderbook verification input in a current envelope
+func NewEnvelope(input *TaskInput) *Envelope {
+	return &Envelope{Version: Version2, Type: TypeOrderbookVerification, Input: input}
+}
+
+// ValidateEncoding checks that encoding is a known payload encoding
+func ValidateEncoding(encoding string) error {
+	if encoding != EncodingJSON && encoding != EncodingBinary {
+		return fmt.Errorf("unknown payload encoding %q", encoding)
+	}
+	return nil
+}
+
+// Encode returns input as a version 2 payload in the given encoding
+func Encode(input *TaskInput, encoding string) ([]byte, error) {
+	switch encoding {
+	case EncodingJSON:
+		return json.Marshal(NewEnvelope(input))
+	case EncodingBinary:
+		return encodeBinary(NewEnvelope(input))
+	default:
+		return nil, ValidateEncoding(encoding)
+	}
+}
+
+// Decode decodes a payload of any supported version and encoding. Binary payloads are
+// recognised by their magic prefix; JSON payloads without a version field are version 1.
+func Decode(data []byte) (*Envelope, error) {
+	if bytes.HasPrefix(data, binaryMagic) {
+		return decodeBinary(data)
+	}
+
+	var header struct {
+		Version *uint8          `json:"version"`
+		Type    string          `json:"type"`
+		Input   json.RawMessage `json:"input"`
+		Ref     json.RawMessage `json:"ref"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	if header.Version == nil {
+		var input TaskInput
+		if err := json.Unmarshal(data, &input); err != nil {
+			return nil, err
+		}
+		return &Envelope{Version: Version1, Type: TypeOrderbookVerification, Input: &input}, nil
+	}
+
+	if *header.Version != Version2 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, *header.Version)
+	}
+	switch header.Type {
+	case TypeOrderbookVerification:
+	case TypeOrderbookVerificationRef:
+		if len(header.Ref) == 0 {
+			return nil, fmt.Errorf("payload has no ref")
+		}
+		var ref TaskRef
+		if err := json.Unmarshal(header.Ref, &ref); err != nil {
+			return nil, err
+		}
+		return &Envelope{Version: Version2, Type: header.Type, Ref: &ref}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownType, header.Type)
+	}
+	if len(header.Input) == 0 {
+		return nil, fmt.Errorf("payload has no input")
+	}
+
+	var input TaskInput
+	if err := json.Unmarshal(header.Input, &input); err != nil {
+		return nil, err
+	}
+	return &Envelope{Version: Version2, Type: header.Type, Input: &input}, nil
+}
@@ -0,0 +1,153 @@
+package taskpayload
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// testInput returns a replay task over a committed snapshot with market parameters
+func testInput(t *testing.T) *TaskInput {
+	t.Helper()
+	baseTime := time.UnixMilli(1700000000123).UTC()
+	expiration := baseTime.Add(time.Hour)
+
+	snapshot := orderbookchecker.OrderbookSnapshot{
+		SequenceNumber: 7,
+		Timestamp:      baseTime,
+		MarketID:       "TEST-MARKET",
+		PrevHash:       orderbookchecker.GenesisPrevHash,
+		Orders: []orderbookchecker.Order{
+			{ID: "sell-1", Side: "sell", Price: big.NewInt(6e17), Quantity: big.NewInt(3e18), Timestamp: baseTime.Add(-time.Minute),
+				UserID: "bob", TokenID: "1234", Outcome: orderbookchecker.OutcomeYes, TimeInForce: orderbookchecker.TimeInForceGTD,
+				Expiration: &expiration, FeeRateBps: 10},
+		},
+		Params: &orderbookchecker.MarketParams{TickSize: big.NewInt(1e16), CollateralDecimals: 18, TakerFeeBps: 10},
+	}
+	root, err := orderbookchecker.ComputeMerkleRoot(snapshot.Orders)
+	if err != nil {
+		t.Fatalf("ComputeMerkleRoot failed: %v", err)
+	}
+	snapshot.MerkleRoot = root
+	hash, err := orderbookchecker.HashSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("HashSnapshot failed: %v", err)
+	}
+
+	return &TaskInput{
+		SnapshotHash: hash,
+		Snapshot:     snapshot,
+		TradeBatchID: "batch-7",
+		Mode:         orderbookchecker.VerificationModeReplay,
+		Trades: []orderbookchecker.Trade{
+			{ID: "trade-1", BuyOrderID: "buy-1", SellOrderID: "sell-1", Price: big.NewInt(6e17), Quantity: big.NewInt(1e18),
+				Timestamp: baseTime, TxHash: "0xabc", BlockNumber: 12, MakerOrderID: "sell-1", TakerFee: big.NewInt(4e15)},
+		},
+		IncomingOrders: []orderbookchecker.Order{
+			{ID: "buy-1", Side: "buy", Price: big.NewInt(6e17), Quantity: big.NewInt(1e18), Timestamp: baseTime,
+				UserID: "alice", TokenID: "1234", Outcome: orderbookchecker.OutcomeYes},
+		},
+		Cancellations: []orderbookchecker.Cancellation{
+			{OrderID: "sell-1", Timestamp: baseTime.Add(time.Second)},
+		},
+	}
+}
+
+func TestDecode_Encodings(t *testing.T) {
+	input := testInput(t)
+
+	v1, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("Failed to marshal input: %v", err)
+	}
+	v2JSON, err := Encode(input, EncodingJSON)
+	if err != nil {
+		t.Fatalf("Encode json failed: %v", err)
+	}
+	v2Binary, err := Encode(input, EncodingBinary)
+	if err != nil {
+		t.Fatalf("Encode binary failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		payload     []byte
+		wantVersion uint8
+	}{
+		{"version 1 json", v1, Version1},
+		{"version 2 json", v2JSON, Version2},
+		{"version 2 binary", v2Binary, Version2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, err := Decode(tt.payload)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if envelope.Version != tt.wantVersion || envelope.Type != TypeOrderbookVerification {
+				t.Errorf("Unexpected envelope version %d, type %q", envelope.Version, envelope.Type)
+			}
+			if !reflect.DeepEqual(envelope.Input, input) {
+				t.Errorf("Decoded input differs:\n got %+v\nwant %+v", envelope.Input, input)
+			}
+			if err := orderbookchecker.VerifySnapshotIntegrity(envelope.Input.Snapshot, envelope.Input.SnapshotHash); err != nil {
+				t.Errorf("Decoded snapshot lost its commitment: %v", err)
+			}
+		})
+	}
+
+	if len(v2Binary) >= len(v2JSON) {
+		t.Errorf("Expected the binary payload to be smaller than JSON, got %d and %d bytes", len(v2Binary), len(v2JSON))
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	binaryPayload, err := Encode(testInput(t), EncodingBinary)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	futureBinary := append([]byte(nil), binaryPayload...)
+	futureBinary[len(binaryMagic)] = Version2 + 1
+
+	tests := []struct {
+		name    string
+		payload []byte
+		wantErr error
+		want    string
+	}{
+		{"future json version", []byte(`{"version":3,"type":"orderbook_verification","input":{}}`), ErrUnsupportedVersion, ""},
+		{"unknown json type", []byte(`{"version":2,"type":"price_feed","input":{}}`), ErrUnknownType, ""},
+		{"json without input", []byte(`{"version":2,"type":"orderbook_verification"}`), nil, "no input"},
+		{"json ref without ref", []byte(`{"version":2,"type":"orderbook_verification_ref","input":{}}`), nil, "no ref"},
+		{"future binary version", futureBinary, ErrUnsupportedVersion, ""},
+		{"truncated binary", binaryPayload[:len(binaryPayload)-3], nil, "unexpected end of payload"},
+		{"trailing binary", append(append([]byte(nil), binaryPayload...), 0), nil, "trailing bytes"},
+		{"not json", []byte("snapshot"), nil, "invalid character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.payload)
+			if err == nil {
+				t.Fatal("Expected decode error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+			if tt.want != "" && !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := Encode(testInput(t), "protobuf"); err == nil {
+		t.Error("Expected unknown encoding to be rejected")
+	}
+}
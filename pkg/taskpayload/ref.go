@@ -0,0 +1,154 @@
+package taskpayload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/cas"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/merkle"
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// Trade batch blobs
+//
+// A reference payload points at two content-addressed blobs: the canonical snapshot
+// encoding, whose hash is the snapshot hash, and a trade batch blob holding the trades,
+// incoming orders and cancellations of the task:
+//
+//	magic 0x00 'T' 'B' 'A', version uint8,
+//	trade count uint32 then each trade encoding,
+//	incoming order count uint32 then each order encoding,
+//	cancellation count uint32 then for each: order_id string, timestamp
+//
+// using the field encodings of binary payloads.
+
+// tradeBatchMagic prefixes every trade batch blob
+var tradeBatchMagic = []byte{0x00, 'T', 'B', 'A'}
+
+// TradeBatchVersion is the version of trade batch blobs
+const TradeBatchVersion uint8 = 1
+
+// TaskRef is a task whose data is published to content-addressed storage. The performer
+// fetches both blobs through its fetcher and checks them against their hashes, so the
+// locators only need to be reachable, not trusted.
+type TaskRef struct {
+	SnapshotHash      string `json:"snapshot_hash"`
+	SnapshotLocator   string `json:"snapshot_locator"`
+	TradeBatchID      string `json:"trade_batch_id"`
+	TradeBatchHash    string `json:"trade_batch_hash"`
+	TradeBatchLocator string `json:"trade_batch_locator"`
+	// Mode selects the verification strategy, as in TaskInput
+	Mode string `json:"mode,omitempty"`
+}
+
+// NewRefEnvelope wraps a task reference in a current envelope
+func NewRefEnvelope(ref *TaskRef) *Envelope {
+	return &Envelope{Version: Version2, Type: TypeOrderbookVerificationRef, Ref: ref}
+}
+
+// EncodeRef returns ref as a version 2 payload in the given encoding
+func EncodeRef(ref *TaskRef, encoding string) ([]byte, error) {
+	switch encoding {
+	case EncodingJSON:
+		return json.Marshal(NewRefEnvelope(ref))
+	case EncodingBinary:
+		return encodeRefBinary(NewRefEnvelope(ref))
+	default:
+		return nil, ValidateEncoding(encoding)
+	}
+}
+
+// encodeTradeBatch returns the trade batch blob of an input
+func encodeTradeBatch(input *TaskInput) ([]byte, error) {
+	w := &writer{}
+	w.buf.Write(tradeBatchMagic)
+	w.buf.WriteByte(TradeBatchVersion)
+	if err := w.batch(input); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+// decodeTradeBatch decodes a trade batch blob into input
+func decodeTradeBatch(data []byte, input *TaskInput) error {
+	if !bytes.HasPrefix(data, tradeBatchMagic) {
+		return fmt.Errorf("not a trade batch blob")
+	}
+	r := &reader{data: data[len(tradeBatchMagic):]}
+	if version := r.uint8(); r.err == nil && version != TradeBatchVersion {
+		return fmt.Errorf("unsupported trade batch version %d", version)
+	}
+	if err := r.batch(input); err != nil {
+		return err
+	}
+	return r.done()
+}
+
+// PutRef publishes the snapshot and trade batch of input to store and returns a reference
+// to them. The reference keeps the snapshot hash of input, so a snapshot that does not
+// match its commitment is rejected when the reference is resolved.
+func PutRef(ctx context.Context, store cas.Store, input *TaskInput) (*TaskRef, error) {
+	snapshot, err := orderbookchecker.EncodeSnapshot(input.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	snapshotLocator, err := store.Put(ctx, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish snapshot: %v", err)
+	}
+
+	batch, err := encodeTradeBatch(input)
+	if err != nil {
+		return nil, err
+	}
+	batchLocator, err := store.Put(ctx, batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish trade batch: %v", err)
+	}
+
+	return &TaskRef{
+		SnapshotHash:      input.SnapshotHash,
+		SnapshotLocator:   snapshotLocator,
+		TradeBatchID:      input.TradeBatchID,
+		TradeBatchHash:    merkle.Keccak256(batch).Hex(),
+		TradeBatchLocator: batchLocator,
+		Mode:              input.Mode,
+	}, nil
+}
+
+// Resolve fetches the blobs a reference points at, checks them against their hashes and
+// returns the task input they make up
+func Resolve(ctx context.Context, ref *TaskRef, fetcher cas.Fetcher) (*TaskInput, error) {
+	snapshotHash, err := merkle.ParseHash(ref.SnapshotHash)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot_hash: %v", err)
+	}
+	batchHash, err := merkle.ParseHash(ref.TradeBatchHash)
+	if err != nil {
+		return nil, fmt.Errorf("trade_batch_hash: %v", err)
+	}
+
+	snapshot, err := cas.FetchVerified(ctx, fetcher, ref.SnapshotLocator, snapshotHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch snapshot: %w", err)
+	}
+	batch, err := cas.FetchVerified(ctx, fetcher, ref.TradeBatchLocator, batchHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trade batch: %w", err)
+	}
+
+	input := &TaskInput{
+		SnapshotHash: ref.SnapshotHash,
+		TradeBatchID: ref.TradeBatchID,
+		Mode:         ref.Mode,
+	}
+	if input.Snapshot, err = orderbookchecker.DecodeSnapshot(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %v", err)
+	}
+	if err := decodeTradeBatch(batch, input); err != nil {
+		return nil, fmt.Errorf("failed to decode trade batch: %v", err)
+	}
+	return input, nil
+}
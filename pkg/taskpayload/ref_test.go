@@ -0,0 +1,126 @@
+package taskpayload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/cas"
+)
+
+func TestRef_Resolve(t *testing.T) {
+	ctx := context.Background()
+	input := testInput(t)
+	store := cas.NewDirStore(t.TempDir())
+
+	ref, err := PutRef(ctx, store, input)
+	if err != nil {
+		t.Fatalf("PutRef failed: %v", err)
+	}
+
+	for _, encoding := range []string{EncodingJSON, EncodingBinary} {
+		t.Run(encoding, func(t *testing.T) {
+			payload, err := EncodeRef(ref, encoding)
+			if err != nil {
+				t.Fatalf("EncodeRef failed: %v", err)
+			}
+			envelope, err := Decode(payload)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if envelope.Type != TypeOrderbookVerificationRef || envelope.Input != nil {
+				t.Fatalf("Unexpected envelope %+v", envelope)
+			}
+			if !reflect.DeepEqual(envelope.Ref, ref) {
+				t.Errorf("Decoded ref differs:\n got %+v\nwant %+v", envelope.Ref, ref)
+			}
+
+			resolved, err := Resolve(ctx, envelope.Ref, store)
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if !reflect.DeepEqual(resolved, input) {
+				t.Errorf("Resolved input differs:\n got %+v\nwant %+v", resolved, input)
+			}
+		})
+	}
+
+	inline, err := Encode(input, EncodingBinary)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	payload, err := EncodeRef(ref, EncodingBinary)
+	if err != nil {
+		t.Fatalf("EncodeRef failed: %v", err)
+	}
+	if len(payload) >= len(inline) {
+		t.Errorf("Expected the ref payload to be smaller than the inline payload, got %d and %d bytes", len(payload), len(inline))
+	}
+}
+
+func TestRef_ResolveErrors(t *testing.T) {
+	ctx := context.Background()
+	input := testInput(t)
+
+	tests := []struct {
+		name    string
+		mutate  func(t *testing.T, dir string, ref *TaskRef)
+		wantErr error
+		want    string
+	}{
+		{"tampered snapshot blob", func(t *testing.T, dir string, ref *TaskRef) {
+			path := filepath.Join(dir, strings.TrimPrefix(ref.SnapshotLocator, cas.SchemeCAS+"://"))
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read blob: %v", err)
+			}
+			data[len(data)-1] ^= 1
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatalf("Failed to write blob: %v", err)
+			}
+		}, cas.ErrHashMismatch, "snapshot"},
+		{"wrong trade batch hash", func(t *testing.T, dir string, ref *TaskRef) {
+			ref.TradeBatchHash = ref.SnapshotHash
+		}, cas.ErrHashMismatch, "trade batch"},
+		{"missing trade batch", func(t *testing.T, dir string, ref *TaskRef) {
+			path := filepath.Join(dir, strings.TrimPrefix(ref.TradeBatchLocator, cas.SchemeCAS+"://"))
+			if err := os.Remove(path); err != nil {
+				t.Fatalf("Failed to remove blob: %v", err)
+			}
+		}, cas.ErrNotFound, ""},
+		{"blobs swapped", func(t *testing.T, dir string, ref *TaskRef) {
+			ref.SnapshotHash, ref.TradeBatchHash = ref.TradeBatchHash, ref.SnapshotHash
+			ref.SnapshotLocator, ref.TradeBatchLocator = ref.TradeBatchLocator, ref.SnapshotLocator
+		}, nil, "failed to decode snapshot"},
+		{"malformed hash", func(t *testing.T, dir string, ref *TaskRef) {
+			ref.SnapshotHash = "0x1234"
+		}, nil, "snapshot_hash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := cas.NewDirStore(dir)
+			ref, err := PutRef(ctx, store, input)
+			if err != nil {
+				t.Fatalf("PutRef failed: %v", err)
+			}
+			tt.mutate(t, dir, ref)
+
+			_, err = Resolve(ctx, ref, store)
+			if err == nil {
+				t.Fatal("Expected resolve error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+			if tt.want != "" && !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package taskpayload
+
+import (
+	"fmt"
+
+	"github.com/Layr-Labs/hourglass-avs-template/pkg/orderbookchecker"
+)
+
+// Verify checks the snapshot of an input against its commitments and then verifies the
+// trades using the requested mode. Integrity failures produce an invalid result rather than
+// an error, since a tampered snapshot is a verdict on the settlement, not a processing
+// failure.
+func Verify(verifier *orderbookchecker.OrderbookVerifier, input *TaskInput) (*orderbookchecker.VerificationResult, error) {
+	mode := input.Mode
+	if mode == "" {
+		mode = orderbookchecker.VerificationModeTrades
+	}
+
+	if err := orderbookchecker.VerifySnapshotIntegrity(input.Snapshot, input.SnapshotHash); err != nil {
+		return &orderbookchecker.VerificationResult{
+			Mode:         mode,
+			Valid:        false,
+			ErrorCode:    orderbookchecker.ErrorCode(err),
+			ErrorMessage: fmt.Sprintf("snapshot integrity check failed: %v", err),
+			TotalTrades:  len(input.Trades),
+		}, nil
+	}
+
+	switch mode {
+	case orderbookchecker.VerificationModeTrades:
+		return verifier.VerifySnapshotWithCancellations(input.Trades, input.Snapshot, input.Cancellations)
+	case orderbookchecker.VerificationModeReplay:
+		return verifier.VerifyReplayWithCancellations(input.Trades, input.Snapshot, input.IncomingOrders, input.Cancellations)
+	default:
+		return nil, fmt.Errorf("unknown verification mode: %q", mode)
+	}
+}
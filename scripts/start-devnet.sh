@@ -99,8 +99,8 @@ docker run -d \
     --name polymarket-avs-performer \
     --network hourglass-network \
     -p 8080:8080 \
-    -e LOG_LEVEL=info \
-    -e LOG_FORMAT=json \
+    -e PERFORMER_LOG_LEVEL=info \
+    -e PERFORMER_LOG_FORMAT=json \
     polymarket-avs:latest \
     || print_warning "AVS performer already running"
 